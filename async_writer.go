@@ -0,0 +1,207 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"sync"
+	"sync/atomic"
+)
+
+// OverflowPolicy decides what an AsyncWriter does when its queue is full.
+type OverflowPolicy int
+
+const (
+	// OverflowBlock makes Write wait for room in the queue.
+	OverflowBlock OverflowPolicy = iota
+	// OverflowDropNewest discards the record being written.
+	OverflowDropNewest
+	// OverflowDropOldest discards the oldest queued record to make room.
+	OverflowDropOldest
+)
+
+// AsyncOptions configures the writer returned by AsyncWriter.
+type AsyncOptions struct {
+	// QueueSize is the number of records the queue holds. The default is 1024.
+	QueueSize int
+	// Policy applies when the queue is full. The default is OverflowBlock.
+	Policy OverflowPolicy
+}
+
+// QueueWriter writes records to another writer from a background goroutine.
+// It is returned by AsyncWriter.
+type QueueWriter struct {
+	w      io.Writer
+	policy OverflowPolicy
+
+	mu       sync.Mutex
+	notEmpty sync.Cond
+	notFull  sync.Cond
+	idle     sync.Cond
+	ring     [][]byte
+	head     int
+	n        int
+	closed   bool
+	done     chan struct{}
+	dropped  atomic.Uint64
+	// busy is set while the worker writes a record taken from the queue.
+	busy bool
+}
+
+// AsyncWriter returns a writer that queues records in a bounded ring buffer
+// and writes them to w from a worker goroutine, so slow disks and networks do
+// not hold up the caller or the handler lock. Write errors from w are reported
+// to ErrorHandler. Close drains the queue and then closes w if it is an
+// io.Closer.
+func AsyncWriter(w io.Writer, opts ...*AsyncOptions) *QueueWriter {
+	opt := new(AsyncOptions)
+	if len(opts) > 0 && opts[0] != nil {
+		*opt = *opts[0]
+	}
+	if opt.QueueSize <= 0 {
+		opt.QueueSize = 1024
+	}
+	q := &QueueWriter{
+		w:      w,
+		policy: opt.Policy,
+		ring:   make([][]byte, opt.QueueSize),
+		done:   make(chan struct{}),
+	}
+	q.notEmpty.L = &q.mu
+	q.notFull.L = &q.mu
+	q.idle.L = &q.mu
+	go q.run()
+	return q
+}
+
+// Write queues a copy of p. It returns an error only after Close.
+func (q *QueueWriter) Write(p []byte) (int, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for !q.closed && q.n == len(q.ring) && q.policy == OverflowBlock {
+		q.notFull.Wait()
+	}
+	if q.closed {
+		return 0, errWriterClosed
+	}
+	if q.n == len(q.ring) {
+		q.dropped.Add(1)
+		if q.policy == OverflowDropNewest {
+			return len(p), nil
+		}
+		q.ring[q.head] = nil
+		q.head = (q.head + 1) % len(q.ring)
+		q.n--
+	}
+	q.ring[(q.head+q.n)%len(q.ring)] = bytes.Clone(p)
+	q.n++
+	q.notEmpty.Signal()
+	return len(p), nil
+}
+
+// Dropped returns the number of records discarded by the overflow policy.
+func (q *QueueWriter) Dropped() uint64 {
+	return q.dropped.Load()
+}
+
+// Len returns the number of records waiting in the queue.
+func (q *QueueWriter) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.n
+}
+
+// Sync waits until the queued records are written and then syncs the
+// underlying writer.
+func (q *QueueWriter) Sync() error {
+	q.mu.Lock()
+	for q.n > 0 || q.busy {
+		q.idle.Wait()
+	}
+	q.mu.Unlock()
+	return syncWriter(q.w)
+}
+
+// Close writes the queued records, stops the worker and closes the underlying
+// writer if it is an io.Closer.
+func (q *QueueWriter) Close() error {
+	return q.CloseContext(context.Background())
+}
+
+// CloseContext is Close that waits for the queue to drain only until ctx is
+// done. The records still queued then are discarded and counted in Dropped,
+// and the underlying writer is closed once the record being written is, so
+// the worker is not closed underneath. It returns ctx.Err() in that case.
+func (q *QueueWriter) CloseContext(ctx context.Context) error {
+	q.mu.Lock()
+	if q.closed {
+		q.mu.Unlock()
+		return nil
+	}
+	q.closed = true
+	q.notEmpty.Broadcast()
+	q.notFull.Broadcast()
+	q.mu.Unlock()
+
+	select {
+	case <-q.done:
+		return q.closeUnderlying()
+	case <-ctx.Done():
+	}
+	q.mu.Lock()
+	q.dropped.Add(uint64(q.n))
+	for ; q.n > 0; q.n-- {
+		q.ring[q.head] = nil
+		q.head = (q.head + 1) % len(q.ring)
+	}
+	if !q.busy {
+		q.idle.Broadcast()
+	}
+	q.mu.Unlock()
+	go func() {
+		<-q.done
+		errorHandler(q.closeUnderlying())
+	}()
+	return ctx.Err()
+}
+
+func (q *QueueWriter) closeUnderlying() error {
+	if c, ok := q.w.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+func (q *QueueWriter) run() {
+	defer close(q.done)
+	for {
+		q.mu.Lock()
+		for q.n == 0 && !q.closed {
+			q.notEmpty.Wait()
+		}
+		if q.n == 0 {
+			q.mu.Unlock()
+			return
+		}
+		p := q.ring[q.head]
+		q.ring[q.head] = nil
+		q.head = (q.head + 1) % len(q.ring)
+		q.n--
+		q.busy = true
+		q.notFull.Signal()
+		q.mu.Unlock()
+
+		n, err := q.w.Write(p)
+		if err == nil && n != len(p) {
+			err = io.ErrShortWrite
+		}
+		errorHandler(err)
+
+		q.mu.Lock()
+		q.busy = false
+		if q.n == 0 {
+			q.idle.Broadcast()
+		}
+		q.mu.Unlock()
+	}
+}
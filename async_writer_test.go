@@ -0,0 +1,121 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// gateWriter blocks writes until open is closed.
+type gateWriter struct {
+	open chan struct{}
+	mu   sync.Mutex
+	buf  bytes.Buffer
+}
+
+func (w *gateWriter) Write(p []byte) (int, error) {
+	<-w.open
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.buf.Write(p)
+}
+
+func TestAsyncWriterOverflowPolicies(t *testing.T) {
+	for _, test := range []struct {
+		policy  OverflowPolicy
+		want    string
+		dropped uint64
+	}{
+		{OverflowBlock, "0 1 2 3 4 ", 0},
+		{OverflowDropNewest, "0 1 2 ", 2},
+		{OverflowDropOldest, "0 3 4 ", 2},
+	} {
+		out := &gateWriter{open: make(chan struct{})}
+		w := AsyncWriter(out, &AsyncOptions{QueueSize: 2, Policy: test.policy})
+
+		// The worker takes the first record and blocks in Write; the next two
+		// fill the queue.
+		w.Write([]byte("0 "))
+		for {
+			w.mu.Lock()
+			n := w.n
+			w.mu.Unlock()
+			if n == 0 {
+				break
+			}
+		}
+		w.Write([]byte("1 "))
+		w.Write([]byte("2 "))
+
+		var wg sync.WaitGroup
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w.Write([]byte("3 "))
+			w.Write([]byte("4 "))
+		}()
+		if test.policy != OverflowBlock {
+			wg.Wait()
+		}
+		close(out.open)
+		wg.Wait()
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if got := out.buf.String(); got != test.want || w.Dropped() != test.dropped {
+			t.Fatalf("policy %d: output = %q, dropped = %d, want %q and %d", test.policy, got, w.Dropped(), test.want, test.dropped)
+		}
+	}
+}
+
+func TestAsyncWriterClosesUnderlyingWriter(t *testing.T) {
+	out := &closeBuffer{}
+	logger := New(AsyncWriter(out), Text())
+	logger.Info("hello")
+	if err := logger.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if !out.closed || !strings.Contains(out.String(), "msg=hello") {
+		t.Fatalf("closed = %v, output = %q", out.closed, out.String())
+	}
+	if _, err := logger.Writer().Write([]byte("late")); err == nil {
+		t.Fatal("Write after Close succeeded")
+	}
+}
+
+func TestLoggerCloseContext(t *testing.T) {
+	out := &gateWriter{open: make(chan struct{})}
+	logger := New(AsyncWriter(out, &AsyncOptions{QueueSize: 10}), Text())
+	for i := 0; i < 5; i++ {
+		logger.Info("record")
+	}
+	// The worker takes the first record and blocks in Write.
+	for logger.Writer().(*QueueWriter).Len() != 4 {
+		time.Sleep(time.Millisecond)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	dropped, err := logger.CloseContext(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("err = %v, want DeadlineExceeded", err)
+	}
+	if dropped != 4 || logger.Stats().QueueDropped != 4 {
+		t.Fatalf("dropped = %d, stats = %d, want 4", dropped, logger.Stats().QueueDropped)
+	}
+	close(out.open)
+
+	out = &gateWriter{open: make(chan struct{})}
+	close(out.open)
+	logger = New(AsyncWriter(out), Text())
+	logger.Info("record")
+	if dropped, err := logger.CloseContext(context.Background()); err != nil || dropped != 0 {
+		t.Fatalf("CloseContext = %d, %v", dropped, err)
+	}
+	if !strings.Contains(out.buf.String(), "msg=record") {
+		t.Fatalf("output = %q", out.buf.String())
+	}
+}
@@ -0,0 +1,350 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Backoff controls how a failed delivery is retried. Each retry waits twice as
+// long as the previous one, starting at Min and capped at Max, with up to half
+// of the delay removed at random so that many processes do not retry in step.
+type Backoff struct {
+	// Min is the first delay. The default is 500 milliseconds.
+	Min time.Duration
+	// Max caps the delay. The default is 30 seconds.
+	Max time.Duration
+	// MaxRetries is the number of retries after the first attempt. The default
+	// is 5; a negative value disables retries.
+	MaxRetries int
+}
+
+func (b Backoff) withDefaults() Backoff {
+	if b.Min <= 0 {
+		b.Min = 500 * time.Millisecond
+	}
+	if b.Max <= 0 {
+		b.Max = 30 * time.Second
+	}
+	if b.Max < b.Min {
+		b.Max = b.Min
+	}
+	if b.MaxRetries == 0 {
+		b.MaxRetries = 5
+	}
+	return b
+}
+
+// delay returns the jittered wait before the given retry, counting from 0.
+func (b Backoff) delay(retry int) time.Duration {
+	d := b.Max
+	if retry < 32 && b.Min<<retry < b.Max && b.Min<<retry > 0 {
+		d = b.Min << retry
+	}
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
+
+// permanentError marks a delivery error that retrying cannot fix.
+type permanentError struct {
+	err error
+}
+
+func (e permanentError) Error() string { return e.err.Error() }
+func (e permanentError) Unwrap() error { return e.err }
+
+// retry calls fn until it succeeds, fails permanently or runs out of retries.
+func (b Backoff) retry(fn func() error) error {
+	var err error
+	for i := 0; ; i++ {
+		if err = fn(); err == nil {
+			return nil
+		}
+		var perm permanentError
+		if errors.As(err, &perm) || b.MaxRetries < 0 || i >= b.MaxRetries {
+			return err
+		}
+		time.Sleep(b.delay(i))
+	}
+}
+
+// batchRecord is a record copied from Write with the time it was written.
+type batchRecord struct {
+	time time.Time
+	data []byte
+}
+
+// batchConfig configures a BatchWriter; the exporters fill in their defaults.
+type batchConfig struct {
+	maxRecords  int
+	maxBytes    int
+	maxBuffered int
+	interval    time.Duration
+	maxInFlight int
+	backoff     Backoff
+	// send delivers batch. On failure it returns the records to retry, which
+	// may be fewer than batch when the endpoint accepted some of them.
+	send func(batch []batchRecord) ([]batchRecord, error)
+}
+
+// BatchWriter collects records and delivers them in batches from a background
+// goroutine. It is returned by the HTTP exporters in this package. Write never
+// blocks on delivery: records that do not fit in the buffer are dropped and
+// counted, and delivery errors are reported to ErrorHandler.
+type BatchWriter struct {
+	cfg batchConfig
+
+	mu       sync.Mutex
+	batch    []batchRecord
+	size     int
+	pending  [][]batchRecord
+	buffered int
+	closed   bool
+	// abandoned is set when CloseContext gave up waiting: the batches not
+	// started by then are dropped rather than sent.
+	abandoned bool
+
+	wake     chan struct{}
+	flush    chan chan struct{}
+	stop     chan struct{}
+	done     chan struct{}
+	inFlight chan struct{}
+	sending  sync.WaitGroup
+	dropped  atomic.Uint64
+}
+
+func newBatchWriter(cfg batchConfig) *BatchWriter {
+	if cfg.maxRecords <= 0 {
+		cfg.maxRecords = 1000
+	}
+	if cfg.maxBytes <= 0 {
+		cfg.maxBytes = 1 << 20
+	}
+	if cfg.maxBuffered < cfg.maxBytes {
+		cfg.maxBuffered = 8 * cfg.maxBytes
+	}
+	if cfg.interval <= 0 {
+		cfg.interval = time.Second
+	}
+	if cfg.maxInFlight <= 0 {
+		cfg.maxInFlight = 1
+	}
+	cfg.backoff = cfg.backoff.withDefaults()
+	w := &BatchWriter{
+		cfg:      cfg,
+		wake:     make(chan struct{}, 1),
+		flush:    make(chan chan struct{}),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+		inFlight: make(chan struct{}, cfg.maxInFlight),
+	}
+	go w.run()
+	return w
+}
+
+// Write copies p as one record. It returns an error only after Close.
+func (w *BatchWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return 0, errWriterClosed
+	}
+	if w.buffered+len(p) > w.cfg.maxBuffered {
+		w.dropped.Add(1)
+		return len(p), nil
+	}
+	w.batch = append(w.batch, batchRecord{time: time.Now(), data: bytes.Clone(p)})
+	w.size += len(p)
+	w.buffered += len(p)
+	if len(w.batch) >= w.cfg.maxRecords || w.size >= w.cfg.maxBytes {
+		w.cutLocked()
+		select {
+		case w.wake <- struct{}{}:
+		default:
+		}
+	}
+	return len(p), nil
+}
+
+var errWriterClosed = errors.New("log: write to closed writer")
+
+// cutLocked moves the current batch to the delivery queue.
+func (w *BatchWriter) cutLocked() {
+	if len(w.batch) == 0 {
+		return
+	}
+	w.pending = append(w.pending, w.batch)
+	w.batch = nil
+	w.size = 0
+}
+
+// Dropped returns the number of records discarded because the buffer was full.
+func (w *BatchWriter) Dropped() uint64 {
+	return w.dropped.Load()
+}
+
+// Sync delivers the buffered records and waits until they are sent, including
+// any retries. Delivery errors are reported to ErrorHandler.
+func (w *BatchWriter) Sync() error {
+	done := make(chan struct{})
+	select {
+	case w.flush <- done:
+		<-done
+	case <-w.done:
+	}
+	return nil
+}
+
+// Close delivers the buffered records, including any retries, and stops the
+// background goroutine.
+func (w *BatchWriter) Close() error {
+	return w.CloseContext(context.Background())
+}
+
+// CloseContext is Close that waits for delivery only until ctx is done. The
+// records whose delivery has not started by then are discarded and counted in
+// Dropped; requests already in flight complete in the background. It returns
+// ctx.Err() in that case.
+func (w *BatchWriter) CloseContext(ctx context.Context) error {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return nil
+	}
+	w.closed = true
+	w.mu.Unlock()
+	close(w.stop)
+	select {
+	case <-w.done:
+		return nil
+	case <-ctx.Done():
+	}
+	w.mu.Lock()
+	w.abandoned = true
+	n := len(w.batch)
+	for _, batch := range w.pending {
+		n += len(batch)
+	}
+	w.dropped.Add(uint64(n))
+	w.batch = nil
+	w.pending = nil
+	w.mu.Unlock()
+	return ctx.Err()
+}
+
+func (w *BatchWriter) run() {
+	defer close(w.done)
+	ticker := time.NewTicker(w.cfg.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-w.wake:
+			w.deliver(false)
+		case <-ticker.C:
+			w.deliver(true)
+		case done := <-w.flush:
+			w.deliver(true)
+			w.sending.Wait()
+			close(done)
+		case <-w.stop:
+			w.deliver(true)
+			w.sending.Wait()
+			return
+		}
+	}
+}
+
+// deliver starts sending the queued batches and, if partial is set, the
+// current one. It waits while maxInFlight batches are being sent.
+func (w *BatchWriter) deliver(partial bool) {
+	w.mu.Lock()
+	if partial {
+		w.cutLocked()
+	}
+	batches := w.pending
+	w.pending = nil
+	w.mu.Unlock()
+
+	for _, batch := range batches {
+		w.inFlight <- struct{}{}
+		w.mu.Lock()
+		abandoned := w.abandoned
+		w.mu.Unlock()
+		if abandoned {
+			<-w.inFlight
+			w.dropped.Add(uint64(len(batch)))
+			continue
+		}
+		w.sending.Add(1)
+		go w.send(batch)
+	}
+}
+
+func (w *BatchWriter) send(batch []batchRecord) {
+	defer func() {
+		<-w.inFlight
+		w.sending.Done()
+	}()
+	remaining := batch
+	err := w.cfg.backoff.retry(func() (err error) {
+		remaining, err = w.cfg.send(remaining)
+		return err
+	})
+	errorHandler(err)
+
+	size := 0
+	for _, r := range batch {
+		size += len(r.data)
+	}
+	w.mu.Lock()
+	w.buffered -= size
+	w.mu.Unlock()
+}
+
+// httpPost sends body to url and returns the response body. Transport errors,
+// 429 and 5xx responses can be retried; other failures are permanent.
+func httpPost(client *http.Client, url string, header http.Header, contentType string, body []byte) ([]byte, error) {
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, permanentError{err}
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	req.Header.Set("Content-Type", contentType)
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 == 2 {
+		return io.ReadAll(resp.Body)
+	}
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	err = fmt.Errorf("log: POST %s: %s: %s", url, resp.Status, bytes.TrimSpace(msg))
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500 {
+		return nil, err
+	}
+	return nil, permanentError{err}
+}
+
+func checkHTTPURL(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" || u.Host == "" {
+		return fmt.Errorf("log: invalid endpoint %q, want an http or https URL", rawURL)
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package log
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestBatchWriterDropsWhenFull(t *testing.T) {
+	release := make(chan struct{})
+	var delivered int
+	w := newBatchWriter(batchConfig{
+		maxRecords:  1,
+		maxBytes:    4,
+		maxBuffered: 8,
+		interval:    time.Hour,
+		send: func(batch []batchRecord) ([]batchRecord, error) {
+			<-release
+			delivered += len(batch)
+			return nil, nil
+		},
+	})
+	for i := 0; i < 3; i++ {
+		if _, err := w.Write([]byte("abcd")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	close(release)
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if delivered != 2 || w.Dropped() != 1 {
+		t.Fatalf("delivered = %d, dropped = %d, want 2 and 1", delivered, w.Dropped())
+	}
+	if _, err := w.Write([]byte("late")); err == nil {
+		t.Fatal("Write after Close succeeded")
+	}
+}
+
+func TestBatchWriterCloseContextTimeout(t *testing.T) {
+	release := make(chan struct{})
+	var delivered int
+	w := newBatchWriter(batchConfig{
+		maxRecords:  1,
+		interval:    time.Hour,
+		maxInFlight: 1,
+		send: func(batch []batchRecord) ([]batchRecord, error) {
+			<-release
+			delivered += len(batch)
+			return nil, nil
+		},
+	})
+	for i := 0; i < 3; i++ {
+		if _, err := w.Write([]byte("abcd")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := w.CloseContext(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("err = %v, want DeadlineExceeded", err)
+	}
+	// The batch in flight completes; the others are not sent.
+	close(release)
+	<-w.done
+	if delivered != 1 || w.Dropped() != 2 {
+		t.Fatalf("delivered = %d, dropped = %d, want 1 and 2", delivered, w.Dropped())
+	}
+}
@@ -175,6 +175,12 @@ func BenchmarkNexuerFieldForms(b *testing.B) {
 				logger.InfoS(getMessage(0), log.String("request_id", "req-1"), log.Int("status", 200), log.Bool("ok", true))
 			}
 		}},
+		{"ConstructedAttrs", func(h log.Handler) func() {
+			logger := log.New(io.Discard, h)
+			return func() {
+				logger.InfoAttrs(getMessage(0), log.String("request_id", "req-1"), log.Int("status", 200), log.Bool("ok", true))
+			}
+		}},
 	}
 	runNexuerCases(b, cases)
 }
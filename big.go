@@ -0,0 +1,52 @@
+package log
+
+import "math/big"
+
+// appendBig writes a *big.Int, *big.Float or *big.Rat, or one of those
+// values, with its Append methods. The text matches MarshalText, and JSON
+// writes a big.Int as a number like its MarshalJSON. It reports whether a is
+// one of them; nil pointers are left to the other encoders.
+func (s *handleState) appendBig(a any) bool {
+	switch v := a.(type) {
+	case big.Int:
+		// An integer is a number in JSON and never needs quoting in text.
+		*s.buf = v.Append(*s.buf, 10)
+	case *big.Int:
+		if v == nil {
+			return false
+		}
+		*s.buf = v.Append(*s.buf, 10)
+	case big.Float:
+		s.appendBigFloat(&v)
+	case *big.Float:
+		if v == nil {
+			return false
+		}
+		s.appendBigFloat(v)
+	case big.Rat:
+		s.appendBigRat(&v)
+	case *big.Rat:
+		if v == nil {
+			return false
+		}
+		s.appendBigRat(v)
+	default:
+		return false
+	}
+	return true
+}
+
+func (s *handleState) appendBigFloat(x *big.Float) {
+	var buf [64]byte
+	s.appendString(bytesToString(x.Append(buf[:0], 'g', -1)))
+}
+
+func (s *handleState) appendBigRat(x *big.Rat) {
+	var buf [64]byte
+	b := x.Num().Append(buf[:0], 10)
+	if !x.IsInt() {
+		b = append(b, '/')
+		b = x.Denom().Append(b, 10)
+	}
+	s.appendString(bytesToString(b))
+}
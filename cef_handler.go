@@ -0,0 +1,174 @@
+package log
+
+import (
+	"context"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nexuer/log/internal/buffer"
+)
+
+// Reference: ArcSight Common Event Format (CEF) 25 and IBM QRadar LEEF 2.0.
+
+// CEFOptions configures the handler returned by CEF.
+type CEFOptions struct {
+	// HandlerOptions.Name is emitted as the logger extension unless it is
+	// renamed by Extensions. Replacer is applied to record fields.
+	HandlerOptions
+	// Vendor, Product and Version fill the device header fields.
+	Vendor  string
+	Product string
+	Version string
+	// SignatureID is the Device Event Class ID (CEF) or Event ID (LEEF). The
+	// default is "log".
+	SignatureID string
+	// Extensions maps field keys, with dots for group members, to extension
+	// keys. Unmapped fields keep their key with characters outside [A-Za-z0-9]
+	// replaced by '_'.
+	Extensions map[string]string
+	// LEEF writes LEEF 2.0 events instead of CEF.
+	LEEF bool
+}
+
+type cefHandler struct {
+	opts CEFOptions
+	flat flatFields
+}
+
+// CEF returns a Handler that writes ArcSight CEF events, or LEEF events when
+// CEFOptions.LEEF is set, so records can be sent to a SIEM without a parsing
+// stage. The message becomes the event name (CEF) or msg attribute (LEEF), the
+// level becomes the 0-10 severity, and fields become extensions.
+func CEF(opts ...*CEFOptions) Handler {
+	opt := new(CEFOptions)
+	if len(opts) > 0 && opts[0] != nil {
+		*opt = *opts[0]
+	}
+	if opt.SignatureID == "" {
+		opt.SignatureID = "log"
+	}
+	return &cefHandler{opts: *opt, flat: newFlatFields(&opt.HandlerOptions)}
+}
+
+// CEFSeverity maps level to the 0-10 severity used by CEF and LEEF.
+func CEFSeverity(level Level) int {
+	switch {
+	case level < LevelInfo:
+		return 1
+	case level < LevelWarn:
+		return 3
+	case level < LevelError:
+		return 6
+	case level < LevelFatal:
+		return 8
+	default:
+		return 10
+	}
+}
+
+func (h *cefHandler) WithFields(_ context.Context, fields ...Field) Handler {
+	if len(fields) == 0 {
+		return h
+	}
+	return &cefHandler{opts: h.opts, flat: h.flat.withFields(fields)}
+}
+
+func (h *cefHandler) WithGroup(name string) Handler {
+	if name == "" {
+		return h
+	}
+	return &cefHandler{opts: h.opts, flat: h.flat.withGroup(name)}
+}
+
+func (h *cefHandler) Handle(ctx context.Context, w io.Writer, level Level, msg string, kvs ...any) error {
+	msg = h.opts.Redactor.Redact(msg)
+	buf := buffer.New()
+	defer buf.Free()
+
+	sep := byte(' ')
+	if h.opts.LEEF {
+		sep = '\t'
+		_, _ = buf.WriteString("LEEF:2.0|")
+	} else {
+		_, _ = buf.WriteString("CEF:0|")
+	}
+	for _, header := range []string{h.opts.Vendor, h.opts.Product, h.opts.Version, h.opts.SignatureID} {
+		appendCEFHeader(buf, header)
+		_ = buf.WriteByte('|')
+	}
+	if h.opts.LEEF {
+		_, _ = buf.WriteString("devTime=")
+		*buf = strconv.AppendInt(*buf, time.Now().UnixMilli(), 10)
+		_, _ = buf.WriteString("\tsev=")
+		*buf = strconv.AppendInt(*buf, int64(CEFSeverity(level)), 10)
+		if msg != "" {
+			h.appendExtension(buf, sep, MessageKey, msg)
+		}
+	} else {
+		appendCEFHeader(buf, msg)
+		_ = buf.WriteByte('|')
+		*buf = strconv.AppendInt(*buf, int64(CEFSeverity(level)), 10)
+		_, _ = buf.WriteString("|rt=")
+		*buf = strconv.AppendInt(*buf, time.Now().UnixMilli(), 10)
+	}
+	if name := recordName(ctx, h.opts.Name); name != "" {
+		h.appendExtension(buf, sep, NameKey, name)
+	}
+
+	h.flat.walk(ctx, kvs, func(groups []string, key string, v Value) {
+		if len(groups) > 0 {
+			key = strings.Join(groups, string(keyComponentSep)) + string(keyComponentSep) + key
+		}
+		h.appendExtension(buf, sep, key, plainValueString(v))
+	})
+	_ = buf.WriteByte('\n')
+	return h.flat.write(ctx, w, buf)
+}
+
+func (h *cefHandler) appendExtension(buf *buffer.Buffer, sep byte, key, value string) {
+	if mapped, ok := h.opts.Extensions[key]; ok {
+		key = mapped
+	}
+	_ = buf.WriteByte(sep)
+	for i := 0; i < len(key); i++ {
+		if c := key[i]; c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' {
+			_ = buf.WriteByte(c)
+		} else {
+			_ = buf.WriteByte('_')
+		}
+	}
+	_ = buf.WriteByte('=')
+	for i := 0; i < len(value); i++ {
+		switch c := value[i]; c {
+		case '\\', '=':
+			_ = buf.WriteByte('\\')
+			_ = buf.WriteByte(c)
+		case '\n':
+			_, _ = buf.WriteString(`\n`)
+		case '\r':
+			_, _ = buf.WriteString(`\r`)
+		case '\t':
+			_, _ = buf.WriteString(`\t`)
+		default:
+			_ = buf.WriteByte(c)
+		}
+	}
+}
+
+// appendCEFHeader appends a header field, escaping '\' and '|'. Line breaks
+// are not allowed in headers and are replaced by spaces.
+func appendCEFHeader(buf *buffer.Buffer, s string) {
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case '\\', '|':
+			_ = buf.WriteByte('\\')
+			_ = buf.WriteByte(c)
+		case '\n', '\r':
+			_ = buf.WriteByte(' ')
+		default:
+			_ = buf.WriteByte(c)
+		}
+	}
+}
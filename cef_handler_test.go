@@ -0,0 +1,43 @@
+package log
+
+import (
+	"bytes"
+	"regexp"
+	"testing"
+)
+
+func TestCEFHandlerOutput(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New(&buf, CEF(&CEFOptions{
+		HandlerOptions: HandlerOptions{Name: "auth"},
+		Vendor:         "Nexuer",
+		Product:        "api|gw",
+		Version:        "1.0",
+		SignatureID:    "login",
+		Extensions:     map[string]string{"client.ip": "src", NameKey: "dproc"},
+	})).WithGroup("client")
+
+	logger.WarnS("login failed", "ip", "10.0.0.1", "note", "a=b\\c\nd")
+
+	re := regexp.MustCompile(`^` + regexp.QuoteMeta(`CEF:0|Nexuer|api\|gw|1.0|login|login failed|6|rt=`) +
+		`\d+` + regexp.QuoteMeta(` dproc=auth src=10.0.0.1 client_note=a\=b\\c\nd`) + "\n$")
+	if got := buf.String(); !re.MatchString(got) {
+		t.Fatalf("cef output = %q, want match for %s", got, re)
+	}
+}
+
+func TestLEEFHandlerOutput(t *testing.T) {
+	var buf bytes.Buffer
+	New(&buf, CEF(&CEFOptions{
+		Vendor:  "Nexuer",
+		Product: "api",
+		Version: "1.0",
+		LEEF:    true,
+	})).ErrorS("denied", "user", "alice")
+
+	re := regexp.MustCompile(`^` + regexp.QuoteMeta(`LEEF:2.0|Nexuer|api|1.0|log|devTime=`) +
+		`\d+` + regexp.QuoteMeta("\tsev=8\tmsg=denied\tuser=alice") + "\n$")
+	if got := buf.String(); !re.MatchString(got) {
+		t.Fatalf("leef output = %q, want match for %s", got, re)
+	}
+}
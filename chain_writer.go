@@ -0,0 +1,146 @@
+package log
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// maxChainLine bounds the lines VerifyChain reads.
+const maxChainLine = 64 << 20
+
+// ChainOptions configures the writer returned by ChainWriter.
+type ChainOptions struct {
+	// Key is the HMAC-SHA256 key.
+	Key []byte
+	// Prev is the hash the chain continues from, as returned by VerifyChain
+	// for the records already in a file. The default starts a new chain.
+	Prev []byte
+}
+
+// ChainedWriter appends a hash chain to records. It is returned by
+// ChainWriter.
+type ChainedWriter struct {
+	w   io.Writer
+	key []byte
+
+	mu   sync.Mutex
+	prev []byte
+	buf  []byte
+}
+
+// ChainWriter returns a writer that makes a log tamper-evident: it appends to
+// each line a tab and the hex HMAC-SHA256 of the previous line's hash followed
+// by the line, so changing, removing or reordering a line breaks the chain.
+// VerifyChain checks the lines with the same key.
+func ChainWriter(w io.Writer, opts ChainOptions) *ChainedWriter {
+	prev := opts.Prev
+	if prev == nil {
+		prev = make([]byte, sha256.Size)
+	}
+	return &ChainedWriter{w: w, key: opts.Key, prev: bytes.Clone(prev)}
+}
+
+// Write chains each line of p. A last line without a newline is ended with
+// one. If the write fails, the chain continues from the last line written
+// before p.
+func (w *ChainedWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.buf = w.buf[:0]
+	prev := w.prev
+	for rest := p; len(rest) > 0; {
+		line, tail, _ := bytes.Cut(rest, []byte{'\n'})
+		rest = tail
+		prev = chainHash(w.key, prev, line)
+		w.buf = append(w.buf, line...)
+		w.buf = append(w.buf, '\t')
+		w.buf = fmt.Appendf(w.buf, "%x", prev)
+		w.buf = append(w.buf, '\n')
+	}
+	if err := writeFull(w.w, w.buf); err != nil {
+		return 0, err
+	}
+	w.prev = prev
+	return len(p), nil
+}
+
+// Hash returns the hash of the last line written, to continue the chain with
+// ChainOptions.Prev.
+func (w *ChainedWriter) Hash() []byte {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return bytes.Clone(w.prev)
+}
+
+// Sync syncs the underlying writer.
+func (w *ChainedWriter) Sync() error {
+	return syncWriter(w.w)
+}
+
+// Close closes the underlying writer if it is an io.Closer.
+func (w *ChainedWriter) Close() error {
+	if c, ok := w.w.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+func chainHash(key, prev, line []byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(prev)
+	mac.Write(line)
+	return mac.Sum(nil)
+}
+
+// ChainError is a line of a chained log that VerifyChain rejected.
+type ChainError struct {
+	// Line is the 1-based line number.
+	Line int
+	Err  error
+}
+
+func (e *ChainError) Error() string {
+	return fmt.Sprintf("log: chain broken at line %d: %v", e.Line, e.Err)
+}
+
+func (e *ChainError) Unwrap() error {
+	return e.Err
+}
+
+var errChainMismatch = errors.New("hash mismatch")
+
+// VerifyChain checks the lines that ChainWriter wrote to r with key, starting
+// from prev, or from a new chain if prev is nil. It returns the hash of the
+// last line, or a *ChainError for the first line whose hash does not match,
+// which is the first line changed, removed or inserted. Removing lines at the
+// end is detected by comparing the returned hash with ChainedWriter.Hash.
+func VerifyChain(r io.Reader, key, prev []byte) ([]byte, error) {
+	if prev == nil {
+		prev = make([]byte, sha256.Size)
+	}
+	sc := bufio.NewScanner(r)
+	sc.Buffer(nil, maxChainLine)
+	for n := 1; sc.Scan(); n++ {
+		line := sc.Bytes()
+		i := bytes.LastIndexByte(line, '\t')
+		if i < 0 {
+			return nil, &ChainError{Line: n, Err: errors.New("missing hash")}
+		}
+		want := chainHash(key, prev, line[:i])
+		if !hmac.Equal(fmt.Appendf(nil, "%x", want), line[i+1:]) {
+			return nil, &ChainError{Line: n, Err: errChainMismatch}
+		}
+		prev = want
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	return prev, nil
+}
@@ -0,0 +1,69 @@
+package log
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestChainWriter(t *testing.T) {
+	key := []byte("audit key")
+	var buf bytes.Buffer
+	w := ChainWriter(&buf, ChainOptions{Key: key})
+	logger := New(w)
+	logger.Info("login")
+	logger.Info("transfer")
+	logger.Info("logout")
+
+	last, err := VerifyChain(bytes.NewReader(buf.Bytes()), key, nil)
+	if err != nil || !bytes.Equal(last, w.Hash()) {
+		t.Fatalf("VerifyChain = %x, %v, want %x", last, err, w.Hash())
+	}
+
+	// The chain continues in a new writer.
+	next := ChainWriter(&buf, ChainOptions{Key: key, Prev: last})
+	New(next).Info("login again")
+	if _, err := VerifyChain(bytes.NewReader(buf.Bytes()), key, nil); err != nil {
+		t.Fatalf("continued chain: %v", err)
+	}
+
+	lines := strings.SplitAfter(buf.String(), "\n")
+	for name, tampered := range map[string]string{
+		"changed":   strings.Replace(buf.String(), "transfer", "transfex", 1),
+		"removed":   lines[0] + lines[2] + lines[3],
+		"reordered": lines[1] + lines[0] + lines[2] + lines[3],
+	} {
+		_, err := VerifyChain(strings.NewReader(tampered), key, nil)
+		var cerr *ChainError
+		if !errors.As(err, &cerr) || !errors.Is(err, errChainMismatch) {
+			t.Errorf("%s: VerifyChain returned %v", name, err)
+		}
+	}
+	if _, err := VerifyChain(bytes.NewReader(buf.Bytes()), []byte("other key"), nil); err == nil {
+		t.Error("VerifyChain accepted another key")
+	}
+}
+
+func TestChainWriterFailedWrite(t *testing.T) {
+	key := []byte("audit key")
+	out := &failingWriter{}
+	w := ChainWriter(out, ChainOptions{Key: key})
+
+	if _, err := w.Write([]byte("login\n")); err != nil {
+		t.Fatal(err)
+	}
+	out.fail = true
+	if _, err := w.Write([]byte("lost\nalso lost\n")); err == nil {
+		t.Fatal("Write succeeded on a failing writer")
+	}
+	out.fail = false
+	if _, err := w.Write([]byte("logout\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	last, err := VerifyChain(bytes.NewReader(out.Bytes()), key, nil)
+	if err != nil || !bytes.Equal(last, w.Hash()) {
+		t.Fatalf("VerifyChain = %x, %v, want %x", last, err, w.Hash())
+	}
+}
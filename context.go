@@ -0,0 +1,111 @@
+package log
+
+import (
+	"context"
+	"fmt"
+)
+
+type loggerKey struct{}
+
+// NewContext returns a copy of ctx that carries l, so that request-scoped
+// loggers can be passed through middleware. FromContext retrieves it.
+func NewContext(ctx context.Context, l *Logger) context.Context {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return context.WithValue(ctx, loggerKey{}, l)
+}
+
+// FromContext returns the Logger stored by NewContext, or the default Logger
+// if ctx carries none.
+func FromContext(ctx context.Context) *Logger {
+	if ctx != nil {
+		if l, ok := ctx.Value(loggerKey{}).(*Logger); ok && l != nil {
+			return l
+		}
+	}
+	return Default()
+}
+
+type contextFieldsKey struct{}
+
+// ContextWithFields returns a copy of ctx that carries the fields of kvs in
+// addition to those already in ctx. Handlers add them to every record logged
+// with the returned context, before the fields of the call, so middleware can
+// attach fields such as a request ID once for all downstream logging.
+func ContextWithFields(ctx context.Context, kvs ...any) context.Context {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if len(kvs) == 0 {
+		return ctx
+	}
+	fields := FieldsFromContext(ctx)
+	fields = append(fields[:len(fields):len(fields)], kvsToFieldSlice(kvs)...)
+	return context.WithValue(ctx, contextFieldsKey{}, fields)
+}
+
+// contextFields returns the fields of ctx followed by the fields extract
+// returns for it.
+func contextFields(ctx context.Context, extract func(context.Context) []Field) []Field {
+	fields := FieldsFromContext(ctx)
+	if extract == nil || ctx == nil {
+		return fields
+	}
+	extracted := extract(ctx)
+	if len(fields) == 0 {
+		return extracted
+	}
+	return append(fields[:len(fields):len(fields)], extracted...)
+}
+
+// FieldsFromContext returns the fields stored by ContextWithFields. Callers
+// must not modify the returned slice.
+func FieldsFromContext(ctx context.Context) []Field {
+	if ctx == nil {
+		return nil
+	}
+	fields, _ := ctx.Value(contextFieldsKey{}).([]Field)
+	return fields
+}
+
+// RequestIDKey is the conventional key of the field of RequestID.
+const RequestIDKey = "request_id"
+
+// RequestIDFunc extracts a request identifier from ctx, such as one stored by
+// an HTTP or gRPC framework.
+type RequestIDFunc func(ctx context.Context) (string, bool)
+
+// RequestID returns a Valuer that resolves to the request identifier of the
+// record context. Extractors are tried in order, followed by ctx.Value(key)
+// if key is not nil; a string or fmt.Stringer value is logged as a string
+// and other values as they are. Without an identifier the Valuer resolves to
+// an empty group, so the field is omitted:
+//
+//	logger = logger.WithFields(log.Dynamic(log.RequestIDKey, log.RequestID(requestIDKey{})))
+//	logger.WithContext(ctx).InfoS("handled")
+func RequestID(key any, extractors ...RequestIDFunc) Valuer {
+	return func(ctx context.Context) Value {
+		if ctx == nil {
+			return GroupValue()
+		}
+		for _, extract := range extractors {
+			if id, ok := extract(ctx); ok {
+				return StringValue(id)
+			}
+		}
+		if key == nil {
+			return GroupValue()
+		}
+		switch id := ctx.Value(key).(type) {
+		case nil:
+			return GroupValue()
+		case string:
+			return StringValue(id)
+		case fmt.Stringer:
+			return StringValue(id.String())
+		default:
+			return AnyValue(id)
+		}
+	}
+}
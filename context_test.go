@@ -0,0 +1,136 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestLoggerContext(t *testing.T) {
+	if got := FromContext(context.Background()); got != Default() {
+		t.Fatalf("FromContext(empty) = %p, want Default() %p", got, Default())
+	}
+	if got := FromContext(nil); got != Default() {
+		t.Fatalf("FromContext(nil) = %p, want Default() %p", got, Default())
+	}
+
+	var buf bytes.Buffer
+	logger := New(&buf).With("request_id", "r1")
+	ctx := NewContext(context.Background(), logger)
+	FromContext(ctx).Info("handled")
+	if got, want := buf.String(), "INFO request_id=r1 msg=handled\n"; got != want {
+		t.Fatalf("output = %q, want %q", got, want)
+	}
+}
+
+func TestContextWithFields(t *testing.T) {
+	ctx := ContextWithFields(context.Background(), "request_id", "r1")
+	ctx = ContextWithFields(ctx, Int("user_id", 7))
+
+	for _, test := range []struct {
+		name    string
+		handler Handler
+		want    string
+	}{
+		{"Text", Text(), "INFO msg=done request_id=r1 user_id=7 n=1\n"},
+		{"JsonGroup", Json().WithGroup("g"), `{"level":"INFO","msg":"done","g":{"request_id":"r1","user_id":7,"n":1}}` + "\n"},
+		{"SortKeys", Text(&HandlerOptions{SortKeys: true}), "INFO msg=done n=1 request_id=r1 user_id=7\n"},
+		{"CSV", CSV(&CSVOptions{Columns: []string{MessageKey, "request_id", "user_id", "n"}}), "done,r1,7,1\n"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			logger := New(&buf, test.handler)
+			_ = logger.Log(ctx, LevelInfo, "done", "n", 1)
+			if got := buf.String(); got != test.want {
+				t.Fatalf("Log output = %q, want %q", got, test.want)
+			}
+			buf.Reset()
+			logger.WithContext(ctx).InfoAttrs("done", Int("n", 1))
+			if got := buf.String(); got != test.want {
+				t.Fatalf("InfoAttrs output = %q, want %q", got, test.want)
+			}
+		})
+	}
+
+	var buf bytes.Buffer
+	slog.New(NewSlogHandler(New(&buf, Text()))).InfoContext(ctx, "done", "n", 1)
+	if got, want := buf.String(), "INFO msg=done request_id=r1 user_id=7 n=1\n"; got != want {
+		t.Fatalf("slog output = %q, want %q", got, want)
+	}
+}
+
+type tenantKey struct{}
+
+func TestContextExtractor(t *testing.T) {
+	extract := func(ctx context.Context) []Field {
+		if tenant, ok := ctx.Value(tenantKey{}).(string); ok {
+			return []Field{String("tenant", tenant)}
+		}
+		return nil
+	}
+	ctx := context.WithValue(context.Background(), tenantKey{}, "acme")
+	ctx = ContextWithFields(ctx, "request_id", "r1")
+
+	for _, test := range []struct {
+		name    string
+		handler Handler
+		want    string
+	}{
+		{"Json", Json(&HandlerOptions{ContextExtractor: extract}), `{"level":"INFO","msg":"done","request_id":"r1","tenant":"acme","n":1}` + "\n"},
+		{"Syslog", Syslog(&SyslogOptions{HandlerOptions: HandlerOptions{ContextExtractor: extract}, StructuredDataID: "x@1"}), `[x@1 request_id="r1" tenant="acme" n="1"]`},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			logger := New(&buf, test.handler)
+			_ = logger.Log(ctx, LevelInfo, "done", "n", 1)
+			if got := buf.String(); !strings.Contains(got, test.want) {
+				t.Fatalf("output = %q, want %q", got, test.want)
+			}
+			buf.Reset()
+			logger.Info("no context")
+			if strings.Contains(buf.String(), "tenant") {
+				t.Fatalf("output without context = %q", buf.String())
+			}
+		})
+	}
+}
+
+type (
+	requestIDKey struct{}
+	headerIDKey  struct{}
+)
+
+func TestRequestID(t *testing.T) {
+	fromHeader := func(ctx context.Context) (string, bool) {
+		id, ok := ctx.Value(headerIDKey{}).(string)
+		return id, ok
+	}
+	var buf bytes.Buffer
+	logger := New(&buf).WithFields(Dynamic(RequestIDKey, RequestID(requestIDKey{}, fromHeader)))
+
+	for _, test := range []struct {
+		name string
+		ctx  context.Context
+		want string
+	}{
+		{"Key", context.WithValue(context.Background(), requestIDKey{}, "r1"), "INFO request_id=r1 msg=done\n"},
+		{"Extractor", context.WithValue(context.WithValue(context.Background(), requestIDKey{}, "r1"), headerIDKey{}, "h1"), "INFO request_id=h1 msg=done\n"},
+		{"Missing", context.Background(), "INFO msg=done\n"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			buf.Reset()
+			logger.WithContext(test.ctx).Info("done")
+			if got := buf.String(); got != test.want {
+				t.Fatalf("output = %q, want %q", got, test.want)
+			}
+		})
+	}
+
+	buf.Reset()
+	logger.Info("done")
+	if got, want := buf.String(), "INFO msg=done\n"; got != want {
+		t.Fatalf("output without context = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,79 @@
+package log
+
+import "context"
+
+// Sugared is the Logger, whose methods take key values, format arguments and
+// other any values. It is the counterpart of Core.
+type Sugared = Logger
+
+// Core is a Logger restricted to Field arguments. Its methods never box
+// values in an any, for performance-critical code; Sugar returns the Logger
+// with the any-based API. A Core is obtained with Logger.Core and writes
+// through the handler, output and hooks of that Logger.
+type Core struct {
+	logger *Logger
+}
+
+// Core returns l restricted to Field arguments.
+func (l *Logger) Core() *Core {
+	return &Core{logger: l}
+}
+
+// Sugar returns the Logger of c.
+func (c *Core) Sugar() *Sugared {
+	return c.logger
+}
+
+// With returns a Core that includes the given fields in each record.
+func (c *Core) With(fields ...Field) *Core {
+	return &Core{logger: c.logger.WithFields(fields...)}
+}
+
+// WithGroup returns a Core that nests the fields of later records under
+// name.
+func (c *Core) WithGroup(name string) *Core {
+	return &Core{logger: c.logger.WithGroup(name)}
+}
+
+// WithContext returns a Core that logs with ctx.
+func (c *Core) WithContext(ctx context.Context) *Core {
+	return &Core{logger: c.logger.WithContext(ctx)}
+}
+
+// Log logs a message at level with fields and ctx.
+func (c *Core) Log(ctx context.Context, level Level, msg string, fields ...Field) error {
+	return c.logger.logAttrs(ctx, level, msg, fields)
+}
+
+// Debug logs a message at debug level with fields.
+func (c *Core) Debug(msg string, fields ...Field) {
+	err := c.logger.logAttrs(c.logger.ctx, LevelDebug, msg, fields)
+	errorHandler(err)
+}
+
+// Info logs a message at info level with fields.
+func (c *Core) Info(msg string, fields ...Field) {
+	err := c.logger.logAttrs(c.logger.ctx, LevelInfo, msg, fields)
+	errorHandler(err)
+}
+
+// Warn logs a message at warn level with fields.
+func (c *Core) Warn(msg string, fields ...Field) {
+	err := c.logger.logAttrs(c.logger.ctx, LevelWarn, msg, fields)
+	errorHandler(err)
+}
+
+// Error logs a message at error level with fields.
+func (c *Core) Error(msg string, fields ...Field) {
+	err := c.logger.logAttrs(c.logger.ctx, LevelError, msg, fields)
+	errorHandler(err)
+}
+
+// Fatal logs a message at fatal level with fields, then runs the fatal
+// handlers and exits like Logger.Fatal.
+func (c *Core) Fatal(msg string, fields ...Field) {
+	err := c.logger.logAttrs(c.logger.ctx, LevelFatal, msg, fields)
+	errorHandler(err)
+
+	c.logger.fatalExit(msg, fieldsToKVs(fields))
+}
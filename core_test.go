@@ -0,0 +1,28 @@
+package log
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestCore(t *testing.T) {
+	var buf bytes.Buffer
+	core := New(&buf, Json()).Core().With(String("svc", "api")).WithGroup("req")
+	core.Info("ready", Int("id", 7))
+	core.Debug("hidden")
+	core.Sugar().InfoS("sugared", "id", 8)
+	want := `{"level":"INFO","svc":"api","msg":"ready","req":{"id":7}}` + "\n" +
+		`{"level":"INFO","svc":"api","msg":"sugared","req":{"id":8}}` + "\n"
+	if got := buf.String(); got != want {
+		t.Fatalf("output = %q, want %q", got, want)
+	}
+
+	if raceEnabled {
+		return
+	}
+	logger := New(&buf, Json()).Core()
+	fields := []Field{String("k", "v"), Int("n", 1)}
+	if allocs := testing.AllocsPerRun(100, func() { buf.Reset(); logger.Info("msg", fields...) }); allocs > 0 {
+		t.Errorf("allocs = %v, want 0", allocs)
+	}
+}
@@ -0,0 +1,183 @@
+package log
+
+import (
+	"context"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nexuer/log/internal/buffer"
+)
+
+// DefaultCSVColumns is the column schema used when CSVOptions.Columns is empty.
+var DefaultCSVColumns = []string{TimeKey, LevelKey, NameKey, MessageKey}
+
+// CSVOptions configures the handler returned by CSV.
+type CSVOptions struct {
+	HandlerOptions
+	// Columns is the fixed column schema. TimeKey, LevelKey, NameKey and
+	// MessageKey select the built-in values; any other name selects the field
+	// with that key, using dots to address group members. Fields without a
+	// column are dropped and missing fields leave their cell empty. Built-in
+	// values are passed to Replacer; the value it returns fills the cell and
+	// an empty Field leaves it empty.
+	Columns []string
+	// Header writes Columns as the first row before the first record.
+	Header bool
+	// TimeLayout formats the time column. The default is time.RFC3339Nano.
+	// HandlerOptions.TimeEncoding, if set, takes precedence and also applies
+	// to time field values.
+	TimeLayout string
+}
+
+type csvHandler struct {
+	opts    CSVOptions
+	flat    flatFields
+	written *bool
+}
+
+// CSV returns a Handler that writes one RFC 4180 row per record. Every row has
+// the same columns, so the output can be loaded directly by spreadsheet and BI
+// tools.
+func CSV(opts ...*CSVOptions) Handler {
+	opt := new(CSVOptions)
+	if len(opts) > 0 && opts[0] != nil {
+		*opt = *opts[0]
+	}
+	if len(opt.Columns) == 0 {
+		opt.Columns = DefaultCSVColumns
+	}
+	if opt.TimeLayout == "" {
+		opt.TimeLayout = time.RFC3339Nano
+	}
+	return &csvHandler{opts: *opt, flat: newFlatFields(&opt.HandlerOptions), written: new(bool)}
+}
+
+func (h *csvHandler) WithFields(_ context.Context, fields ...Field) Handler {
+	if len(fields) == 0 {
+		return h
+	}
+	return &csvHandler{opts: h.opts, flat: h.flat.withFields(fields), written: h.written}
+}
+
+func (h *csvHandler) WithGroup(name string) Handler {
+	if name == "" {
+		return h
+	}
+	return &csvHandler{opts: h.opts, flat: h.flat.withGroup(name), written: h.written}
+}
+
+func (h *csvHandler) Handle(ctx context.Context, w io.Writer, level Level, msg string, kvs ...any) error {
+	cells := make([]string, len(h.opts.Columns))
+	columns := make(map[string]int, len(h.opts.Columns))
+	for i, column := range h.opts.Columns {
+		switch column {
+		case TimeKey:
+			cells[i] = h.builtIn(ctx, Time(TimeKey, time.Now()))
+		case LevelKey:
+			cells[i] = h.builtIn(ctx, String(LevelKey, level.String()))
+		case NameKey:
+			if name := recordName(ctx, h.opts.Name); name != "" {
+				cells[i] = h.builtIn(ctx, String(NameKey, name))
+			}
+		case MessageKey:
+			if msg != "" {
+				cells[i] = h.builtIn(ctx, String(MessageKey, msg))
+			}
+		default:
+			columns[column] = i
+		}
+	}
+
+	if len(columns) > 0 {
+		h.flat.walk(ctx, kvs, func(groups []string, key string, v Value) {
+			if len(groups) > 0 {
+				key = strings.Join(groups, string(keyComponentSep)) + string(keyComponentSep) + key
+			}
+			if i, ok := columns[key]; ok {
+				if v.Kind() == KindTime && h.opts.TimeEncoding != TimeDefault {
+					cells[i] = h.formatTime(v.time())
+				} else {
+					cells[i] = plainValueString(v)
+				}
+			}
+		})
+	}
+
+	buf := buffer.New()
+	defer buf.Free()
+	appendCSVRow(buf, cells)
+
+	if w == nil || w == io.Discard || w == Discard {
+		discarded(ctx, buf.Len())
+		return nil
+	}
+	h.flat.mu.Lock()
+	defer h.flat.mu.Unlock()
+	if h.opts.Header && !*h.written {
+		header := buffer.New()
+		defer header.Free()
+		appendCSVRow(header, h.opts.Columns)
+		if err := writeRecordLocked(w, header); err != nil {
+			return err
+		}
+	}
+	*h.written = true
+	return writeRecordLocked(w, buf)
+}
+
+// builtIn returns the cell of a built-in field after the Replacer.
+func (h *csvHandler) builtIn(ctx context.Context, field Field) string {
+	if h.flat.replacer != nil {
+		field = h.flat.replacer(ctx, nil, field)
+	}
+	if field.isEmpty() {
+		return ""
+	}
+	v := field.Value.Resolve(ctx)
+	if v.Kind() == KindTime {
+		return h.formatTime(v.time())
+	}
+	return plainValueString(v)
+}
+
+func (h *csvHandler) formatTime(t time.Time) string {
+	switch h.opts.TimeEncoding {
+	case TimeUnix:
+		return strconv.FormatInt(t.Unix(), 10)
+	case TimeUnixMilli:
+		return strconv.FormatInt(t.UnixMilli(), 10)
+	case TimeRFC3339Nano:
+		return t.Format(time.RFC3339Nano)
+	default:
+		return t.Format(h.opts.TimeLayout)
+	}
+}
+
+func appendCSVRow(buf *buffer.Buffer, cells []string) {
+	for i, cell := range cells {
+		if i > 0 {
+			_ = buf.WriteByte(',')
+		}
+		appendCSVField(buf, cell)
+	}
+	_ = buf.WriteByte('\n')
+}
+
+// appendCSVField appends s, quoting it when it contains a separator, quote,
+// line break or leading space.
+func appendCSVField(buf *buffer.Buffer, s string) {
+	if s == "" || (s[0] != ' ' && s[0] != '\t' && !strings.ContainsAny(s, ",\"\r\n")) {
+		_, _ = buf.WriteString(s)
+		return
+	}
+	_ = buf.WriteByte('"')
+	for i := 0; i < len(s); i++ {
+		if s[i] == '"' {
+			_ = buf.WriteByte('"')
+		}
+		_ = buf.WriteByte(s[i])
+	}
+	_ = buf.WriteByte('"')
+}
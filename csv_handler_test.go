@@ -0,0 +1,90 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCSVHandlerColumns(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New(&buf, CSV(&CSVOptions{
+		HandlerOptions: HandlerOptions{Name: "server"},
+		Columns:        []string{LevelKey, NameKey, MessageKey, "user", "req.id", "missing"},
+		Header:         true,
+	})).With("user", "alice", "ignored", 1).WithGroup("req")
+
+	logger.InfoS(`said "hi", left`, "id", 7)
+	logger.Warn("line\nbreak")
+
+	want := "level,logger,msg,user,req.id,missing\n" +
+		`INFO,server,"said ""hi"", left",alice,7,` + "\n" +
+		"WARN,server,\"line\nbreak\",alice,,\n"
+	if got := buf.String(); got != want {
+		t.Fatalf("csv output = %q, want %q", got, want)
+	}
+
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := records[1], []string{"INFO", "server", `said "hi", left`, "alice", "7", ""}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("parsed row = %q, want %q", got, want)
+	}
+}
+
+func TestCSVHandlerDefaultColumns(t *testing.T) {
+	var buf bytes.Buffer
+	New(&buf, CSV()).InfoS("ready", "id", 1)
+
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 1 || len(records[0]) != len(DefaultCSVColumns) {
+		t.Fatalf("records = %q, want one row with %d columns", records, len(DefaultCSVColumns))
+	}
+	if got := records[0][1:]; !reflect.DeepEqual(got, []string{"INFO", "", "ready"}) {
+		t.Fatalf("row = %q", records[0])
+	}
+}
+
+func TestCSVHandlerReplacesBuiltIns(t *testing.T) {
+	var buf bytes.Buffer
+	New(&buf, CSV(&CSVOptions{
+		HandlerOptions: HandlerOptions{Name: "server", Replacer: func(_ context.Context, groups []string, field Field) Field {
+			switch {
+			case groups != nil:
+				return field
+			case field.Key == LevelKey:
+				return String("severity", "notice")
+			case field.Key == NameKey:
+				return Field{}
+			case field.Key == TimeKey:
+				return Time(TimeKey, time.Date(2026, 6, 26, 9, 30, 0, 0, time.UTC))
+			}
+			return field
+		}},
+		TimeLayout: time.DateOnly,
+	})).Info("ready")
+
+	if got, want := buf.String(), "2026-06-26,notice,,ready\n"; got != want {
+		t.Fatalf("csv output = %q, want %q", got, want)
+	}
+}
+
+func TestCSVHandlerTimeEncoding(t *testing.T) {
+	var buf bytes.Buffer
+	at := time.UnixMilli(1782466200123)
+	New(&buf, CSV(&CSVOptions{
+		HandlerOptions: HandlerOptions{TimeEncoding: TimeUnixMilli},
+		Columns:        []string{MessageKey, "at"},
+	})).InfoS("ready", "at", at)
+
+	if got, want := buf.String(), "ready,1782466200123\n"; got != want {
+		t.Fatalf("csv output = %q, want %q", got, want)
+	}
+}
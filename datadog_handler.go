@@ -0,0 +1,180 @@
+package log
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Datadog reserved attributes written by the handler returned by Datadog.
+const (
+	DatadogStatusKey       = "status"
+	DatadogTimestampKey    = "timestamp"
+	DatadogMessageKey      = "message"
+	DatadogLoggerNameKey   = "logger.name"
+	DatadogErrorMessageKey = "error.message"
+	DatadogErrorKindKey    = "error.kind"
+	DatadogErrorStackKey   = "error.stack"
+	DatadogTraceIDKey      = "dd.trace_id"
+	DatadogSpanIDKey       = "dd.span_id"
+)
+
+type datadogHandler struct {
+	handler *commonHandler
+}
+
+// Datadog returns a JSON handler that writes Datadog reserved attributes, so
+// the status facet and logs-to-traces correlation work without pipeline
+// remapping:
+//
+//   - level is written as status, in lower case
+//   - msg is written as message and logger as logger.name
+//   - the built-in time field of HandlerOptions.AddTime is written as timestamp
+//   - a top-level err field is written as error.message, with error.kind when
+//     its value is an error and error.stack for Error and Fatal records
+//   - top-level trace_id and span_id fields, such as those from TraceContext,
+//     are written as dd.trace_id and dd.span_id in Datadog's decimal form
+//
+// The mapping runs before opts.Replacer, which sees the Datadog keys.
+func Datadog(opts ...*HandlerOptions) Handler {
+	opt := new(HandlerOptions)
+	if len(opts) > 0 && opts[0] != nil {
+		*opt = *opts[0]
+	}
+	opt.Replacer = datadogReplacer(opt.Replacer)
+	return &datadogHandler{
+		handler: newCommonHandler(true, *opt),
+	}
+}
+
+func (d *datadogHandler) WithFields(ctx context.Context, fields ...Field) Handler {
+	return &datadogHandler{
+		handler: d.handler.withFields(ctx, fields),
+	}
+}
+
+func (d *datadogHandler) WithGroup(name string) Handler {
+	return &datadogHandler{
+		handler: d.handler.withGroup(name),
+	}
+}
+
+type datadogStackKey struct{}
+
+func (d *datadogHandler) Handle(ctx context.Context, w io.Writer, level Level, msg string, kvs ...any) error {
+	if level >= LevelError {
+		if ctx == nil {
+			ctx = context.Background()
+		}
+		ctx = context.WithValue(ctx, datadogStackKey{}, true)
+	}
+	return d.handler.handle(ctx, w, level, msg, kvs...)
+}
+
+func (d *datadogHandler) HandleFields(ctx context.Context, w io.Writer, level Level, msg string, fields []Field) error {
+	if level >= LevelError {
+		if ctx == nil {
+			ctx = context.Background()
+		}
+		ctx = context.WithValue(ctx, datadogStackKey{}, true)
+	}
+	return d.handler.handleFields(ctx, w, level, msg, fields)
+}
+
+func datadogReplacer(next Replacer) Replacer {
+	return func(ctx context.Context, groups []string, field Field) Field {
+		if groups == nil && field.Key == TimeKey {
+			field.Key = DatadogTimestampKey
+		} else if len(groups) == 0 {
+			field = datadogField(ctx, field)
+		}
+		// A mapped err field becomes an inline group whose members are passed
+		// to the Replacer again.
+		if next == nil || field.Value.Kind() == KindGroup {
+			return field
+		}
+		return next(ctx, groups, field)
+	}
+}
+
+func datadogField(ctx context.Context, field Field) Field {
+	switch field.Key {
+	case LevelKey:
+		if field.Value.Kind() == KindString {
+			return String(DatadogStatusKey, datadogStatus(ParseLevel(field.Value.str())))
+		}
+		field.Key = DatadogStatusKey
+	case MessageKey:
+		field.Key = DatadogMessageKey
+	case NameKey:
+		field.Key = DatadogLoggerNameKey
+	case ErrKey:
+		if field.Value.Kind() != KindValuer {
+			return datadogError(ctx, field.Value)
+		}
+	case TraceIDKey:
+		// Datadog trace IDs are the low 64 bits of the W3C trace ID.
+		if id, ok := datadogID(field.Value, 32); ok {
+			return String(DatadogTraceIDKey, id)
+		}
+	case SpanIDKey:
+		if id, ok := datadogID(field.Value, 16); ok {
+			return String(DatadogSpanIDKey, id)
+		}
+	}
+	return field
+}
+
+func datadogStatus(level Level) string {
+	switch {
+	case level < LevelInfo:
+		return "debug"
+	case level < LevelWarn:
+		return "info"
+	case level < LevelError:
+		return "warn"
+	case level < LevelFatal:
+		return "error"
+	default:
+		return "critical"
+	}
+}
+
+func datadogError(ctx context.Context, v Value) Field {
+	fields := make([]Field, 0, 3)
+	if err, ok := v.Any().(error); ok && err != nil {
+		fields = append(fields,
+			String(DatadogErrorMessageKey, err.Error()),
+			String(DatadogErrorKindKey, fmt.Sprintf("%T", err)),
+		)
+	} else {
+		fields = append(fields, String(DatadogErrorMessageKey, plainValueString(v)))
+	}
+	if ctx != nil && ctx.Value(datadogStackKey{}) != nil {
+		var b strings.Builder
+		for _, frame := range callerStack() {
+			fmt.Fprintf(&b, "%s\n\t%s:%d\n", frame.Function, frame.File, frame.Line)
+		}
+		fields = append(fields, String(DatadogErrorStackKey, b.String()))
+	}
+	return Field{Value: GroupValue(fields...)}
+}
+
+// datadogID converts a hex ID of n digits to the decimal form of its low 64
+// bits.
+func datadogID(v Value, n int) (string, bool) {
+	if v.Kind() != KindString {
+		return "", false
+	}
+	s := v.str()
+	if len(s) != n {
+		return "", false
+	}
+	id, err := strconv.ParseUint(s[n-16:], 16, 64)
+	if err != nil {
+		return "", false
+	}
+	return strconv.FormatUint(id, 10), true
+}
@@ -0,0 +1,66 @@
+package log_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/nexuer/log"
+)
+
+func TestDatadogReservedAttributes(t *testing.T) {
+	sc, err := log.ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := log.ContextWithSpanContext(context.Background(), sc)
+
+	var buf bytes.Buffer
+	logger := log.New(&buf, log.Datadog(&log.HandlerOptions{Name: "billing"})).
+		WithFields(log.Dynamic("", log.TraceContext())).
+		WithContext(ctx)
+
+	logger.InfoS("charging", "id", 1)
+	want := `{"logger.name":"billing","status":"info","dd.trace_id":"11803532876627986230","dd.span_id":"67667974448284343","trace_flags":"01","message":"charging","id":1}` + "\n"
+	if got := buf.String(); got != want {
+		t.Fatalf("output = %q, want %q", got, want)
+	}
+
+	buf.Reset()
+	logger.ErrorS("charge failed", "err", errors.New("card declined"))
+	var record map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatal(err)
+	}
+	if record["status"] != "error" || record["error.message"] != "card declined" || record["error.kind"] != "*errors.errorString" {
+		t.Fatalf("record = %v", record)
+	}
+	if stack, _ := record["error.stack"].(string); !strings.HasPrefix(stack, "github.com/nexuer/log_test.TestDatadogReservedAttributes\n\t") {
+		t.Fatalf("error.stack = %q, want the test function first", stack)
+	}
+}
+
+func TestDatadogWarnErrorHasNoStack(t *testing.T) {
+	var buf bytes.Buffer
+	log.New(&buf, log.Datadog()).WarnS("retrying", log.Err(errors.New("timeout")))
+	want := `{"status":"warn","message":"retrying","error.message":"timeout","error.kind":"*errors.errorString"}` + "\n"
+	if got := buf.String(); got != want {
+		t.Fatalf("output = %q, want %q", got, want)
+	}
+}
+
+func TestDatadogTimestamp(t *testing.T) {
+	var buf bytes.Buffer
+	log.New(&buf, log.Datadog(&log.HandlerOptions{AddTime: true})).InfoS("ready", log.TimeKey, "user")
+
+	var record map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := record[log.DatadogTimestampKey].(string); !ok || record[log.TimeKey] != "user" {
+		t.Fatalf("record = %v, want the built-in time as %s", record, log.DatadogTimestampKey)
+	}
+}
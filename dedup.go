@@ -0,0 +1,179 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/nexuer/log/internal/buffer"
+)
+
+// DedupOptions configures the handler returned by Dedup.
+type DedupOptions struct {
+	// Timeout writes the summary of a run of repeated records that is still
+	// going after this long. The default is 30 seconds.
+	Timeout time.Duration
+}
+
+// DedupHandler collapses consecutive identical records. It is created by
+// Dedup.
+type DedupHandler struct {
+	next  Handler
+	dedup *dedup
+}
+
+type dedup struct {
+	timeout time.Duration
+
+	mu sync.Mutex
+	// handler and key identify the last record: the handler it came through
+	// and its level, message and resolved fields.
+	handler  *DedupHandler
+	key      []byte
+	repeated uint64
+	last     dedupRecord
+	timer    *time.Timer
+
+	dropped atomic.Uint64
+}
+
+// dedupRecord is what is needed to write the summary of a run.
+type dedupRecord struct {
+	ctx   context.Context
+	next  Handler
+	w     io.Writer
+	level Level
+}
+
+// Dedup wraps next so that a record identical to the previous one is not
+// written. Records are identical when they come through the same logger with
+// the same level, message and fields, compared by their resolved values, with
+// pointers compared by what they point to. When a different record arrives,
+// or the run lasts longer than opts.Timeout, a "last message repeated N times"
+// record is written at the level of the repeated record, like syslogd does.
+func Dedup(next Handler, opts ...*DedupOptions) *DedupHandler {
+	opt := new(DedupOptions)
+	if len(opts) > 0 && opts[0] != nil {
+		*opt = *opts[0]
+	}
+	if opt.Timeout <= 0 {
+		opt.Timeout = 30 * time.Second
+	}
+	return &DedupHandler{next: next, dedup: &dedup{timeout: opt.Timeout}}
+}
+
+func (h *DedupHandler) WithFields(ctx context.Context, fields ...Field) Handler {
+	return &DedupHandler{next: h.next.WithFields(ctx, fields...), dedup: h.dedup}
+}
+
+func (h *DedupHandler) WithGroup(name string) Handler {
+	return &DedupHandler{next: h.next.WithGroup(name), dedup: h.dedup}
+}
+
+func (h *DedupHandler) Handle(ctx context.Context, w io.Writer, level Level, msg string, kvs ...any) error {
+	d := h.dedup
+	key := buffer.New()
+	defer key.Free()
+	*key = appendDedupKey(*key, ctx, level, msg, kvs)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if h == d.handler && bytes.Equal(*key, d.key) {
+		d.repeated++
+		d.dropped.Add(1)
+		if d.timer == nil {
+			d.timer = time.AfterFunc(d.timeout, d.timeoutFlush)
+		}
+		return nil
+	}
+	if err := d.flushLocked(); err != nil {
+		errorHandler(err)
+	}
+	d.handler = h
+	d.key = append(d.key[:0], *key...)
+	d.last = dedupRecord{ctx: ctx, next: h.next, w: w, level: level}
+	return h.next.Handle(AddCallerDepth(ctx, 1), w, level, msg, kvs...)
+}
+
+// Dropped returns the number of repeated records that were not written.
+func (h *DedupHandler) Dropped() uint64 {
+	return h.dedup.dropped.Load()
+}
+
+// Close writes the summary of the current run, if any.
+func (h *DedupHandler) Close() error {
+	h.dedup.mu.Lock()
+	defer h.dedup.mu.Unlock()
+	err := h.dedup.flushLocked()
+	h.dedup.handler = nil
+	return err
+}
+
+func (d *dedup) timeoutFlush() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.timer = nil
+	errorHandler(d.flushLocked())
+}
+
+// flushLocked writes the summary of the current run. The run itself goes on,
+// so later repeats are counted again.
+func (d *dedup) flushLocked() error {
+	if d.timer != nil {
+		d.timer.Stop()
+		d.timer = nil
+	}
+	if d.repeated == 0 {
+		return nil
+	}
+	n := d.repeated
+	d.repeated = 0
+	msg := fmt.Sprintf("last message repeated %d times", n)
+	return d.last.next.Handle(d.last.ctx, d.last.w, d.last.level, msg, "repeated", n)
+}
+
+// appendDedupKey appends the level, message and resolved fields of a record.
+func appendDedupKey(dst []byte, ctx context.Context, level Level, msg string, kvs []any) []byte {
+	dst = strconv.AppendInt(dst, int64(level), 10)
+	dst = append(dst, 0)
+	dst = append(dst, msg...)
+	var field Field
+	for len(kvs) > 0 {
+		field, kvs = kvsToField(kvs)
+		dst = appendDedupField(dst, ctx, field)
+	}
+	return dst
+}
+
+func appendDedupField(dst []byte, ctx context.Context, field Field) []byte {
+	dst = append(dst, 0)
+	dst = append(dst, field.Key...)
+	v := field.Value
+	if v.Kind() == KindValuer {
+		if ctx == nil {
+			ctx = context.Background()
+		}
+		v = v.Resolve(ctx)
+	}
+	dst = append(dst, 0, byte(v.Kind()))
+	switch v.Kind() {
+	case KindGroup:
+		for _, child := range v.group() {
+			dst = appendDedupField(dst, ctx, child)
+		}
+		return append(dst, 0)
+	case KindAny:
+		// Compare what a pointer points to rather than its address, so
+		// records with fresh pointers to equal values are identical.
+		if rv := reflect.ValueOf(v.any); rv.Kind() == reflect.Pointer && !rv.IsNil() {
+			return fmt.Appendf(dst, "%+v", rv.Elem().Interface())
+		}
+	}
+	return v.append(dst)
+}
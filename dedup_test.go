@@ -0,0 +1,94 @@
+package log
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestDedupCollapsesRepeats(t *testing.T) {
+	var buf bytes.Buffer
+	h := Dedup(Text())
+	logger := New(&buf, h)
+
+	for i := 0; i < 4; i++ {
+		logger.WarnS("disk full", "path", fmt.Sprint("/var/", "log"))
+	}
+	logger.InfoS("recovered")
+	logger.InfoS("recovered")
+	if err := logger.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := "WARN msg=\"disk full\" path=/var/log\n" +
+		"WARN msg=\"last message repeated 3 times\" repeated=3\n" +
+		"INFO msg=recovered\n" +
+		"INFO msg=\"last message repeated 1 times\" repeated=1\n"
+	if got := buf.String(); got != want {
+		t.Fatalf("output = %q, want %q", got, want)
+	}
+	if got := h.Dropped(); got != 4 {
+		t.Fatalf("Dropped() = %d, want 4", got)
+	}
+}
+
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestDedupTimeout(t *testing.T) {
+	var buf lockedBuffer
+	logger := New(&buf, Dedup(Text(), &DedupOptions{Timeout: 10 * time.Millisecond}))
+	logger.Info("again")
+	logger.Info("again")
+
+	want := "INFO msg=again\nINFO msg=\"last message repeated 1 times\" repeated=1\n"
+	deadline := time.Now().Add(5 * time.Second)
+	for buf.String() != want {
+		if time.Now().After(deadline) {
+			t.Fatalf("output = %q, want %q", buf.String(), want)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestDedupComparesValues(t *testing.T) {
+	type point struct{ X, Y int }
+	var buf bytes.Buffer
+	logger := New(&buf, Dedup(Text()))
+	for i := 0; i < 3; i++ {
+		logger.WarnS("moved", "to", &point{1, 2})
+	}
+	if err := logger.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Count(buf.String(), "\n"); got != 2 {
+		t.Fatalf("output = %q, want the record and its summary", buf.String())
+	}
+
+	if raceEnabled {
+		return
+	}
+	// Building the key of a record does not allocate.
+	key := make([]byte, 0, 64)
+	kvs := []any{"n", 1, String("s", "v"), Group("g", "b", true)}
+	if allocs := testing.AllocsPerRun(100, func() { key = appendDedupKey(key[:0], nil, LevelInfo, "msg", kvs) }); allocs > 0 {
+		t.Errorf("allocs = %v, want 0", allocs)
+	}
+}
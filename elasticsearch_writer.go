@@ -0,0 +1,167 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// DailyIndex returns an index name function for ElasticsearchOptions that
+// appends the UTC date of the record, as in logs-2006.01.02, so that index
+// templates and lifecycle policies can match one index per day.
+func DailyIndex(prefix string) func(time.Time) string {
+	return func(t time.Time) string {
+		return prefix + "-" + t.UTC().Format("2006.01.02")
+	}
+}
+
+// ElasticsearchOptions configures the writer returned by ElasticsearchWriter.
+type ElasticsearchOptions struct {
+	// URL is the cluster address, such as http://localhost:9200.
+	URL string
+	// Index names the target index or data stream for a record written at the
+	// given time. The default is DailyIndex("logs").
+	Index func(time.Time) string
+	// TimestampKey is added to records that do not have it, with the time the
+	// record was written. The default is @timestamp.
+	TimestampKey string
+	// Header is sent with every request, for example Authorization.
+	Header http.Header
+	// Client sends the requests. The default is http.DefaultClient.
+	Client *http.Client
+
+	// BatchSize is the maximum number of records per bulk request. The default
+	// is 1000.
+	BatchSize int
+	// BatchBytes is the maximum size of the records in a bulk request. The
+	// default is 1 MiB.
+	BatchBytes int
+	// MaxBufferedBytes bounds the records waiting to be indexed, including
+	// rejected records being retried. The default is 8 times BatchBytes.
+	MaxBufferedBytes int
+	// FlushInterval sends a partial batch after this long. The default is 1
+	// second.
+	FlushInterval time.Duration
+	// Backoff retries requests that fail with a transport error, 429 or 5xx,
+	// and records the cluster rejects with 429.
+	Backoff Backoff
+}
+
+// ElasticsearchWriter returns a writer that indexes records through the
+// Elasticsearch _bulk API. JSON records are indexed as they are; other records
+// are stored in a message field.
+func ElasticsearchWriter(opts ElasticsearchOptions) (*BatchWriter, error) {
+	if err := checkHTTPURL(opts.URL); err != nil {
+		return nil, err
+	}
+	if opts.Index == nil {
+		opts.Index = DailyIndex("logs")
+	}
+	if opts.TimestampKey == "" {
+		opts.TimestampKey = "@timestamp"
+	}
+	endpoint := strings.TrimSuffix(opts.URL, "/") + "/_bulk?filter_path=errors,items.*.status,items.*.error.reason"
+	return newBatchWriter(batchConfig{
+		maxRecords:  opts.BatchSize,
+		maxBytes:    opts.BatchBytes,
+		maxBuffered: opts.MaxBufferedBytes,
+		interval:    opts.FlushInterval,
+		backoff:     opts.Backoff,
+		send: func(batch []batchRecord) ([]batchRecord, error) {
+			resp, err := httpPost(opts.Client, endpoint, opts.Header, "application/x-ndjson", bulkBody(opts, batch))
+			if err != nil {
+				return batch, err
+			}
+			return bulkRejected(batch, resp)
+		},
+	}), nil
+}
+
+func bulkBody(opts ElasticsearchOptions, batch []batchRecord) []byte {
+	var b bytes.Buffer
+	for _, r := range batch {
+		b.WriteString(`{"create":{"_index":"`)
+		b.Write(appendEscapedJSONString(nil, opts.Index(r.time), 0))
+		b.WriteString("\"}}\n")
+		b.Write(bulkDocument(opts.TimestampKey, r))
+		b.WriteByte('\n')
+	}
+	return b.Bytes()
+}
+
+// bulkDocument returns r as a single-line JSON document with a timestamp.
+// Records that are not JSON objects, including null, are wrapped in a
+// message field.
+func bulkDocument(timestampKey string, r batchRecord) []byte {
+	data := bytes.TrimSpace(r.data)
+	doc := append([]byte{'{', '"'}, appendEscapedJSONString(nil, timestampKey, 0)...)
+	doc = append(doc, `":"`...)
+	doc = r.time.AppendFormat(doc, time.RFC3339Nano)
+	doc = append(doc, '"')
+
+	var fields map[string]json.RawMessage
+	if json.Unmarshal(data, &fields) != nil || fields == nil {
+		doc = append(doc, `,"message":"`...)
+		doc = appendEscapedJSONString(doc, string(data), 0)
+		return append(doc, '"', '}')
+	}
+	// Documents must fit on one line of the bulk body.
+	if bytes.ContainsAny(data, "\n\r") {
+		var compact bytes.Buffer
+		_ = json.Compact(&compact, data)
+		data = compact.Bytes()
+	}
+	if _, ok := fields[timestampKey]; ok {
+		return data
+	}
+	if len(fields) > 0 {
+		doc = append(doc, ',')
+	}
+	return append(doc, data[1:]...)
+}
+
+type bulkResponse struct {
+	Errors bool `json:"errors"`
+	Items  []map[string]struct {
+		Status int `json:"status"`
+		Error  struct {
+			Reason string `json:"reason"`
+		} `json:"error"`
+	} `json:"items"`
+}
+
+// bulkRejected returns the records Elasticsearch rejected with 429, to be
+// retried, and reports other rejections to ErrorHandler.
+func bulkRejected(batch []batchRecord, body []byte) ([]batchRecord, error) {
+	var resp bulkResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, permanentError{fmt.Errorf("log: elasticsearch bulk response: %w", err)}
+	}
+	if !resp.Errors {
+		return nil, nil
+	}
+	var retry []batchRecord
+	failed := 0
+	reason := ""
+	for i, item := range resp.Items {
+		for _, result := range item {
+			switch {
+			case result.Status == http.StatusTooManyRequests && i < len(batch):
+				retry = append(retry, batch[i])
+			case result.Status >= 300:
+				failed++
+				reason = result.Error.Reason
+			}
+		}
+	}
+	if failed > 0 {
+		errorHandler(fmt.Errorf("log: elasticsearch rejected %d records: %s", failed, reason))
+	}
+	if len(retry) > 0 {
+		return retry, fmt.Errorf("log: elasticsearch rejected %d records with 429", len(retry))
+	}
+	return nil, nil
+}
@@ -0,0 +1,98 @@
+package log
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestElasticsearchWriterBulk(t *testing.T) {
+	var (
+		mu     sync.Mutex
+		bodies []string
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if r.URL.Path != "/_bulk" || r.Header.Get("Content-Type") != "application/x-ndjson" {
+			t.Errorf("request = %s %s", r.URL.Path, r.Header.Get("Content-Type"))
+		}
+		body, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+		if len(bodies) == 1 {
+			// Reject the second record as if the write queue were full.
+			_, _ = io.WriteString(w, `{"errors":true,"items":[{"create":{"status":201}},{"create":{"status":429,"error":{"reason":"busy"}}}]}`)
+			return
+		}
+		_, _ = io.WriteString(w, `{"errors":false}`)
+	}))
+	defer srv.Close()
+
+	w, err := ElasticsearchWriter(ElasticsearchOptions{
+		URL:           srv.URL + "/",
+		Index:         func(time.Time) string { return "logs-test" },
+		FlushInterval: time.Hour,
+		Backoff:       Backoff{Min: time.Millisecond},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	New(w, Json()).InfoS("first", "n", 1)
+	New(w, Text()).InfoS("second")
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(bodies) != 2 {
+		t.Fatalf("got %d bulk requests, want 2", len(bodies))
+	}
+	lines := readLines(bodies[0])
+	if len(lines) != 4 || lines[0] != `{"create":{"_index":"logs-test"}}` ||
+		!strings.HasPrefix(lines[1], `{"@timestamp":"`) || !strings.HasSuffix(lines[1], `","level":"INFO","msg":"first","n":1}`) ||
+		!strings.HasSuffix(lines[3], `","message":"INFO msg=second"}`) {
+		t.Fatalf("first bulk body = %q", bodies[0])
+	}
+	if retried := readLines(bodies[1]); len(retried) != 2 || retried[1] != lines[3] {
+		t.Fatalf("retried bulk body = %q, want only the rejected record", bodies[1])
+	}
+}
+
+func TestBulkDocumentWrapsNonObjects(t *testing.T) {
+	ts := time.Date(2024, 3, 9, 12, 0, 0, 0, time.UTC)
+	for data, want := range map[string]string{
+		"null\n":    `{"@timestamp":"2024-03-09T12:00:00Z","message":"null"}`,
+		`[1,2]`:     `{"@timestamp":"2024-03-09T12:00:00Z","message":"[1,2]"}`,
+		`{}`:        `{"@timestamp":"2024-03-09T12:00:00Z"}`,
+		`{"a":"b"}`: `{"@timestamp":"2024-03-09T12:00:00Z","a":"b"}`,
+	} {
+		got := string(bulkDocument("@timestamp", batchRecord{data: []byte(data), time: ts}))
+		if got != want {
+			t.Errorf("bulkDocument(%q) = %s, want %s", data, got, want)
+		}
+		if !json.Valid([]byte(got)) {
+			t.Errorf("bulkDocument(%q) = %s is not valid JSON", data, got)
+		}
+	}
+}
+
+func TestDailyIndex(t *testing.T) {
+	ts := time.Date(2024, 3, 9, 23, 30, 0, 0, time.FixedZone("UTC-2", -2*60*60))
+	if got := DailyIndex("logs")(ts); got != "logs-2024.03.10" {
+		t.Fatalf("DailyIndex = %q, want logs-2024.03.10", got)
+	}
+}
+
+func readLines(s string) []string {
+	var lines []string
+	scanner := bufio.NewScanner(strings.NewReader(s))
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	return lines
+}
@@ -0,0 +1,244 @@
+package log
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+// maxEncryptedChunk bounds the chunks DecryptReader reads, so a corrupt length
+// does not allocate without limit.
+const maxEncryptedChunk = 64 << 20
+
+// EncryptOptions configures the writer returned by EncryptWriter.
+type EncryptOptions struct {
+	// Key returns the AES key of 16, 24 or 32 bytes. It is called once by
+	// EncryptWriter, so it can fetch the key from a KMS. See KeyFromEnv.
+	Key func() ([]byte, error)
+	// ChunkSize is the number of bytes encrypted together. Records are
+	// buffered until ChunkSize bytes are pending, and the pending records are
+	// lost in a crash. A chunk that fails to be written stays pending and is
+	// written with the next flush. The default, 0, encrypts each record on
+	// its own.
+	ChunkSize int
+}
+
+// KeyFromEnv returns a Key function for EncryptOptions that reads a base64
+// encoded key from the environment variable name.
+func KeyFromEnv(name string) func() ([]byte, error) {
+	return func() ([]byte, error) {
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			return nil, fmt.Errorf("log: key variable %s is not set", name)
+		}
+		key, err := base64.StdEncoding.DecodeString(value)
+		if err != nil {
+			return nil, fmt.Errorf("log: key variable %s: %w", name, err)
+		}
+		return key, nil
+	}
+}
+
+// EncryptedWriter encrypts records with AES-GCM. It is returned by
+// EncryptWriter.
+type EncryptedWriter struct {
+	w    io.Writer
+	aead cipher.AEAD
+	size int
+
+	mu      sync.Mutex
+	pending []byte
+	prev    []byte // tag of the last chunk written
+	ad      []byte
+}
+
+// EncryptWriter returns a writer that encrypts records with AES-GCM and writes
+// them to w in chunks, so logs with regulated data are stored encrypted. Each
+// chunk is its 4-byte big-endian length, a random nonce and the sealed
+// records, authenticated with the length and the tag of the previous chunk of
+// the writer, so chunks that were removed, reordered or replaced are detected.
+// A file can be appended to by successive writers with the same key: the
+// first chunk of a writer starts a new chain, so removing all the chunks of a
+// writer, or the last chunks of one, is not detected. Read the chunks back
+// with DecryptReader.
+//
+// Flush encrypts the pending records of a ChunkSize; Close flushes them and
+// closes w if it is an io.Closer.
+func EncryptWriter(w io.Writer, opts EncryptOptions) (*EncryptedWriter, error) {
+	if opts.Key == nil {
+		return nil, errors.New("log: EncryptWriter: no Key")
+	}
+	key, err := opts.Key()
+	if err != nil {
+		return nil, fmt.Errorf("log: EncryptWriter: %w", err)
+	}
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, fmt.Errorf("log: EncryptWriter: %w", err)
+	}
+	return &EncryptedWriter{w: w, aead: aead, size: opts.ChunkSize}, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func (w *EncryptedWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.size <= 0 {
+		if err := w.seal(p); err != nil {
+			return 0, err
+		}
+		return len(p), nil
+	}
+	w.pending = append(w.pending, p...)
+	if len(w.pending) >= w.size {
+		// p stays pending if the flush fails.
+		return len(p), w.flushLocked()
+	}
+	return len(p), nil
+}
+
+// Flush encrypts and writes the pending records.
+func (w *EncryptedWriter) Flush() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.flushLocked()
+}
+
+// flushLocked writes the pending records as one chunk. They are kept for the
+// next flush if the write fails, unless the chunk would grow too large for
+// DecryptReader.
+func (w *EncryptedWriter) flushLocked() error {
+	if len(w.pending) == 0 {
+		return nil
+	}
+	err := w.seal(w.pending)
+	if err == nil {
+		w.pending = w.pending[:0]
+		return nil
+	}
+	if w.aead.NonceSize()+len(w.pending)+w.aead.Overhead() < maxEncryptedChunk {
+		return err
+	}
+	dropped := len(w.pending)
+	w.pending = w.pending[:0]
+	return fmt.Errorf("log: EncryptWriter: %d pending bytes dropped: %w", dropped, err)
+}
+
+// seal writes one chunk with the records of p, chained to the previous chunk.
+func (w *EncryptedWriter) seal(p []byte) error {
+	nonceSize := w.aead.NonceSize()
+	n := nonceSize + len(p) + w.aead.Overhead()
+	chunk := make([]byte, 4+n)
+	binary.BigEndian.PutUint32(chunk, uint32(n))
+	nonce := chunk[4 : 4+nonceSize]
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+	w.ad = append(append(w.ad[:0], chunk[:4]...), w.prev...)
+	w.aead.Seal(chunk[:4+nonceSize], nonce, p, w.ad)
+	if err := writeFull(w.w, chunk); err != nil {
+		return err
+	}
+	w.prev = append(w.prev[:0], chunk[len(chunk)-w.aead.Overhead():]...)
+	return nil
+}
+
+// Sync flushes the pending records and syncs the underlying writer.
+func (w *EncryptedWriter) Sync() error {
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	return syncWriter(w.w)
+}
+
+// Close flushes the pending records and closes the underlying writer if it is
+// an io.Closer.
+func (w *EncryptedWriter) Close() error {
+	err := w.Flush()
+	if c, ok := w.w.(io.Closer); ok {
+		if cerr := c.Close(); err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+// DecryptReader returns a reader of the records that EncryptWriter wrote to r
+// with key. Reading fails if a chunk was changed, removed or reordered, or
+// does not decrypt with key, and returns io.ErrUnexpectedEOF if the last chunk
+// is truncated.
+func DecryptReader(r io.Reader, key []byte) (io.Reader, error) {
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, fmt.Errorf("log: DecryptReader: %w", err)
+	}
+	return &decryptReader{r: r, aead: aead}, nil
+}
+
+type decryptReader struct {
+	r    io.Reader
+	aead cipher.AEAD
+	buf  []byte
+	err  error
+	prev []byte // tag of the last chunk read
+	ad   []byte
+}
+
+func (d *decryptReader) Read(p []byte) (int, error) {
+	for len(d.buf) == 0 {
+		if d.err != nil {
+			return 0, d.err
+		}
+		d.buf, d.err = d.next()
+	}
+	n := copy(p, d.buf)
+	d.buf = d.buf[n:]
+	return n, nil
+}
+
+// next reads and opens the next chunk.
+func (d *decryptReader) next() ([]byte, error) {
+	var header [4]byte
+	if _, err := io.ReadFull(d.r, header[:]); err != nil {
+		return nil, err
+	}
+	n := binary.BigEndian.Uint32(header[:])
+	nonceSize := d.aead.NonceSize()
+	if n < uint32(nonceSize+d.aead.Overhead()) || n > maxEncryptedChunk {
+		return nil, fmt.Errorf("log: DecryptReader: invalid chunk length %d", n)
+	}
+	chunk := make([]byte, n)
+	if _, err := io.ReadFull(d.r, chunk); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	// A chunk follows the previous one, or starts the chain of a writer that
+	// appended to the file. Open is given no dst so a failed attempt leaves
+	// chunk intact for the next.
+	d.ad = append(append(d.ad[:0], header[:]...), d.prev...)
+	plain, err := d.aead.Open(nil, chunk[:nonceSize], chunk[nonceSize:], d.ad)
+	if err != nil && len(d.prev) > 0 {
+		plain, err = d.aead.Open(nil, chunk[:nonceSize], chunk[nonceSize:], header[:])
+	}
+	if err != nil {
+		return nil, fmt.Errorf("log: DecryptReader: %w", err)
+	}
+	d.prev = append(d.prev[:0], chunk[n-uint32(d.aead.Overhead()):]...)
+	return plain, nil
+}
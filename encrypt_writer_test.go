@@ -0,0 +1,118 @@
+package log
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestEncryptWriter(t *testing.T) {
+	key := bytes.Repeat([]byte{7}, 32)
+	t.Setenv("LOG_TEST_KEY", base64.StdEncoding.EncodeToString(key))
+
+	for _, size := range []int{0, 64} {
+		var buf bytes.Buffer
+		w, err := EncryptWriter(&buf, EncryptOptions{Key: KeyFromEnv("LOG_TEST_KEY"), ChunkSize: size})
+		if err != nil {
+			t.Fatal(err)
+		}
+		logger := New(w, Json(&HandlerOptions{}))
+		for i := 0; i < 10; i++ {
+			logger.InfoS("card charged", "card", "4111-1111-1111-1111")
+		}
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if strings.Contains(buf.String(), "card charged") {
+			t.Fatalf("ChunkSize %d: output has plaintext", size)
+		}
+
+		r, err := DecryptReader(bytes.NewReader(buf.Bytes()), key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		plain, err := io.ReadAll(r)
+		if err != nil {
+			t.Fatalf("ChunkSize %d: %v", size, err)
+		}
+		if n := strings.Count(string(plain), `"msg":"card charged"`); n != 10 {
+			t.Fatalf("ChunkSize %d: decrypted %d records: %s", size, n, plain)
+		}
+
+		tampered := bytes.Clone(buf.Bytes())
+		tampered[len(tampered)/2] ^= 1
+		r, _ = DecryptReader(bytes.NewReader(tampered), key)
+		if _, err := io.ReadAll(r); err == nil {
+			t.Errorf("ChunkSize %d: tampered output decrypted", size)
+		}
+		r, _ = DecryptReader(bytes.NewReader(buf.Bytes()[:buf.Len()-1]), key)
+		if _, err := io.ReadAll(r); !errors.Is(err, io.ErrUnexpectedEOF) {
+			t.Errorf("ChunkSize %d: truncated output returned %v", size, err)
+		}
+	}
+
+	if _, err := EncryptWriter(io.Discard, EncryptOptions{Key: KeyFromEnv("LOG_TEST_MISSING_KEY")}); err == nil {
+		t.Error("EncryptWriter accepted a missing key")
+	}
+	if _, err := EncryptWriter(io.Discard, EncryptOptions{Key: func() ([]byte, error) { return []byte("short"), nil }}); err == nil {
+		t.Error("EncryptWriter accepted a 5-byte key")
+	}
+}
+
+func TestEncryptWriterChainsChunks(t *testing.T) {
+	key := bytes.Repeat([]byte{7}, 32)
+	keyFunc := func() ([]byte, error) { return key, nil }
+	var buf bytes.Buffer
+	var chunks [][]byte
+	for _, records := range [][]string{{"a\n", "b\n", "c\n"}, {"d\n"}} {
+		// The second writer appends to the output of the first.
+		w, err := EncryptWriter(&buf, EncryptOptions{Key: keyFunc})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, r := range records {
+			start := buf.Len()
+			_, _ = io.WriteString(w, r)
+			chunks = append(chunks, bytes.Clone(buf.Bytes()[start:]))
+		}
+	}
+	decrypt := func(chunks ...[]byte) (string, error) {
+		r, _ := DecryptReader(bytes.NewReader(bytes.Join(chunks, nil)), key)
+		plain, err := io.ReadAll(r)
+		return string(plain), err
+	}
+
+	if plain, err := decrypt(chunks...); err != nil || plain != "a\nb\nc\nd\n" {
+		t.Fatalf("decrypted %q, %v", plain, err)
+	}
+	if _, err := decrypt(chunks[0], chunks[2], chunks[3]); err == nil {
+		t.Error("removed chunk not detected")
+	}
+	if _, err := decrypt(chunks[0], chunks[2], chunks[1], chunks[3]); err == nil {
+		t.Error("reordered chunks not detected")
+	}
+}
+
+func TestEncryptWriterKeepsPendingOnFailure(t *testing.T) {
+	key := bytes.Repeat([]byte{7}, 32)
+	out := &failingWriter{fail: true}
+	w, err := EncryptWriter(out, EncryptOptions{Key: func() ([]byte, error) { return key, nil }, ChunkSize: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, err := io.WriteString(w, "lost?\n"); n != 6 || err == nil {
+		t.Fatalf("Write = %d, %v, want the flush error", n, err)
+	}
+	out.fail = false
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	r, _ := DecryptReader(bytes.NewReader(out.Bytes()), key)
+	if plain, err := io.ReadAll(r); err != nil || string(plain) != "lost?\n" {
+		t.Fatalf("decrypted %q, %v", plain, err)
+	}
+}
@@ -0,0 +1,74 @@
+package log
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// maxErrChainDepth bounds the causes ErrChain expands, for cyclic or very
+// deep chains.
+const maxErrChainDepth = 32
+
+// ErrChain returns a Field for an error under the standard error key that
+// expands the chain of errors.Unwrap into nested groups, so each cause can be
+// queried: msg is the message of the error, type its Go type, stack its stack
+// trace if it has a StackTrace method, like the errors of
+// github.com/pkg/errors, and cause the group of the error it wraps.
+// Wrappers with the same message as the error they wrap, such as those that
+// only record a stack trace, are folded into it. A nil error returns an empty
+// field and is not emitted.
+//
+//	logger.ErrorS("save failed", log.ErrChain(err))
+//	// ERROR msg="save failed" err.msg="save: ..." err.type=*fmt.wrapError err.cause.msg=...
+func ErrChain(err error) Field {
+	if err == nil {
+		return Field{}
+	}
+	return Field{ErrKey, errChainValue(err, 0)}
+}
+
+func errChainValue(err error, depth int) Value {
+	var stack string
+	cause := unwrapOne(err)
+	// Fold wrappers that do not change the message.
+	for {
+		if s, ok := errorStack(err); ok && stack == "" {
+			stack = s
+		}
+		if cause == nil || cause.Error() != err.Error() {
+			break
+		}
+		err, cause = cause, unwrapOne(cause)
+	}
+
+	fields := make([]Field, 0, 4)
+	fields = append(fields, String("msg", err.Error()), String("type", fmt.Sprintf("%T", err)))
+	if stack != "" {
+		fields = append(fields, String("stack", stack))
+	}
+	if cause != nil && depth < maxErrChainDepth {
+		fields = append(fields, Field{"cause", errChainValue(cause, depth+1)})
+	}
+	return GroupValue(fields...)
+}
+
+// unwrapOne returns the error err wraps with an Unwrap() error method, or nil.
+func unwrapOne(err error) error {
+	if u, ok := err.(interface{ Unwrap() error }); ok {
+		return u.Unwrap()
+	}
+	return nil
+}
+
+// errorStack returns the stack trace of an error with a StackTrace method,
+// formatted by %+v. The result type of StackTrace differs between packages,
+// so the method is found by name.
+func errorStack(err error) (string, bool) {
+	m := reflect.ValueOf(err).MethodByName("StackTrace")
+	if !m.IsValid() || m.Type().NumIn() != 0 || m.Type().NumOut() != 1 {
+		return "", false
+	}
+	stack := strings.TrimPrefix(fmt.Sprintf("%+v", m.Call(nil)[0].Interface()), "\n")
+	return stack, stack != ""
+}
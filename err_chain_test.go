@@ -0,0 +1,57 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"strings"
+	"testing"
+)
+
+// stackError records a stack trace like the errors of github.com/pkg/errors.
+type stackError struct{ err error }
+
+type stackTrace []string
+
+func (e *stackError) Error() string { return e.err.Error() }
+
+func (e *stackError) Unwrap() error { return e.err }
+
+func (e *stackError) StackTrace() stackTrace { return stackTrace{"main.save", "main.main"} }
+
+func (s stackTrace) Format(f fmt.State, verb rune) {
+	for _, frame := range s {
+		fmt.Fprintf(f, "\n%s", frame)
+	}
+}
+
+func TestErrChain(t *testing.T) {
+	if f := ErrChain(nil); f.Key != "" {
+		t.Fatalf("ErrChain(nil) = %v", f)
+	}
+
+	pathErr := &fs.PathError{Op: "open", Path: "a.txt", Err: fs.ErrNotExist}
+	err := fmt.Errorf("save: %w", &stackError{pathErr})
+
+	var buf bytes.Buffer
+	New(&buf, Json()).ErrorS("failed", ErrChain(err))
+	want := `{"level":"ERROR","msg":"failed","err":{` +
+		`"msg":"save: open a.txt: file does not exist","type":"*fmt.wrapError","cause":{` +
+		`"msg":"open a.txt: file does not exist","type":"*fs.PathError","stack":"main.save\nmain.main","cause":{` +
+		`"msg":"file does not exist","type":"*errors.errorString"}}}}` + "\n"
+	if got := buf.String(); got != want {
+		t.Fatalf("output = %s, want %s", got, want)
+	}
+	var record map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatal(err)
+	}
+
+	buf.Reset()
+	New(&buf, Text()).ErrorS("failed", ErrChain(errors.New("boom")))
+	if got := buf.String(); !strings.Contains(got, `err.msg=boom err.type=*errors.errorString`) {
+		t.Fatalf("text output = %q", got)
+	}
+}
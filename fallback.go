@@ -0,0 +1,116 @@
+package log
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// FallbackOptions configures the writer returned by Fallback.
+type FallbackOptions struct {
+	// Failures is the number of consecutive failed writes to the primary
+	// writer after which writes go to the secondary one. The default is 3.
+	Failures int
+	// ProbeInterval is how often a record is tried on the primary writer
+	// again while writing to the secondary one. The default is 30 seconds.
+	ProbeInterval time.Duration
+}
+
+// FallbackWriter writes to a primary writer and falls back to a secondary one
+// while the primary fails. It is returned by Fallback.
+type FallbackWriter struct {
+	primary   io.Writer
+	secondary io.Writer
+	opts      FallbackOptions
+	now       func() time.Time
+
+	mu         sync.Mutex
+	failures   int
+	fallenBack bool
+	probeAt    time.Time
+}
+
+// Fallback returns a writer that writes to primary, such as a file, and
+// switches to secondary, such as os.Stderr, after opts.Failures consecutive
+// failed writes, so records are not lost when the disk fills up. A record
+// whose write to primary fails is written to secondary. While falling back,
+// the first record after each opts.ProbeInterval is tried on primary, and
+// writes go to primary again once it succeeds. The switch to secondary is
+// reported to ErrorHandler.
+func Fallback(primary, secondary io.Writer, opts ...*FallbackOptions) *FallbackWriter {
+	opt := new(FallbackOptions)
+	if len(opts) > 0 && opts[0] != nil {
+		*opt = *opts[0]
+	}
+	if opt.Failures <= 0 {
+		opt.Failures = 3
+	}
+	if opt.ProbeInterval <= 0 {
+		opt.ProbeInterval = 30 * time.Second
+	}
+	return &FallbackWriter{primary: primary, secondary: secondary, opts: *opt, now: time.Now}
+}
+
+// Write writes p to the current writer.
+func (w *FallbackWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.fallenBack && w.now().Before(w.probeAt) {
+		return w.secondary.Write(p)
+	}
+	err := writeFull(w.primary, p)
+	if err == nil {
+		w.failures = 0
+		w.fallenBack = false
+		return len(p), nil
+	}
+	w.failures++
+	if w.fallenBack {
+		w.probeAt = w.now().Add(w.opts.ProbeInterval)
+	} else if w.failures >= w.opts.Failures {
+		w.fallenBack = true
+		w.probeAt = w.now().Add(w.opts.ProbeInterval)
+		errorHandler(fmt.Errorf("log: fallback: writing to secondary writer after %d failed writes: %w", w.failures, err))
+	}
+	return w.secondary.Write(p)
+}
+
+// FallenBack reports whether w writes to the secondary writer.
+func (w *FallbackWriter) FallenBack() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.fallenBack
+}
+
+// Sync syncs the primary and secondary writers.
+func (w *FallbackWriter) Sync() error {
+	return errors.Join(syncWriter(w.primary), syncWriter(w.secondary))
+}
+
+// Close closes the primary and secondary writers that are io.Closers, other
+// than os.Stdout and os.Stderr.
+func (w *FallbackWriter) Close() error {
+	var errs []error
+	for _, wr := range []io.Writer{w.primary, w.secondary} {
+		if wr == os.Stdout || wr == os.Stderr {
+			continue
+		}
+		if c, ok := wr.(io.Closer); ok {
+			errs = append(errs, c.Close())
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// writeFull writes p to w, reporting a short write as an error.
+func writeFull(w io.Writer, p []byte) error {
+	n, err := w.Write(p)
+	if err == nil && n != len(p) {
+		err = io.ErrShortWrite
+	}
+	return err
+}
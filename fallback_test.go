@@ -0,0 +1,62 @@
+package log
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+)
+
+type failingWriter struct {
+	bytes.Buffer
+	fail bool
+}
+
+func (w *failingWriter) Write(p []byte) (int, error) {
+	if w.fail {
+		return 0, errors.New("no space left on device")
+	}
+	return w.Buffer.Write(p)
+}
+
+func TestFallbackWriter(t *testing.T) {
+	prevHandler := ErrorHandler
+	var reported []error
+	ErrorHandler = func(err error) { reported = append(reported, err) }
+	t.Cleanup(func() { ErrorHandler = prevHandler })
+
+	primary := &failingWriter{fail: true}
+	var secondary bytes.Buffer
+	now := time.Unix(0, 0)
+	w := Fallback(primary, &secondary, &FallbackOptions{Failures: 2, ProbeInterval: time.Minute})
+	w.now = func() time.Time { return now }
+
+	for _, record := range []string{"a\n", "b\n", "c\n"} {
+		if _, err := w.Write([]byte(record)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := secondary.String(); got != "a\nb\nc\n" || !w.FallenBack() || len(reported) != 1 {
+		t.Fatalf("secondary = %q, fallen back = %v, reported %v", got, w.FallenBack(), reported)
+	}
+
+	// The primary is probed once per interval.
+	primary.fail = false
+	w.Write([]byte("d\n"))
+	now = now.Add(time.Minute)
+	w.Write([]byte("e\n"))
+	if primary.String() != "e\n" || secondary.String() != "a\nb\nc\nd\n" || w.FallenBack() {
+		t.Fatalf("primary = %q, secondary = %q, fallen back = %v", primary.String(), secondary.String(), w.FallenBack())
+	}
+
+	// A failed probe waits for the next interval.
+	primary.fail = true
+	w.Write([]byte("f\n"))
+	w.Write([]byte("g\n"))
+	primary.fail = false
+	now = now.Add(time.Second)
+	w.Write([]byte("h\n"))
+	if primary.String() != "e\n" || secondary.String() != "a\nb\nc\nd\nf\ng\nh\n" {
+		t.Fatalf("primary = %q, secondary = %q", primary.String(), secondary.String())
+	}
+}
@@ -0,0 +1,85 @@
+package log
+
+import (
+	"errors"
+	"io"
+	"os"
+	"sync"
+)
+
+var (
+	fatalMu       sync.Mutex
+	fatalHandlers []func(Record)
+	exitFunc      = os.Exit
+	exitCode      = 1
+)
+
+// OnFatal registers f to run after a Fatal, Fatalf, FatalS, FatalT or
+// FatalAttrs record has been logged and its logger closed, and before the
+// process exits, in the order of registration. Use it to flush async writers
+// or notify other systems; f must not log at fatal level.
+func OnFatal(f func(Record)) {
+	fatalMu.Lock()
+	defer fatalMu.Unlock()
+	fatalHandlers = append(fatalHandlers, f)
+}
+
+// SetExitFunc sets the function that Fatal, Fatalf, FatalS, FatalT and
+// FatalAttrs call to exit the process, which is os.Exit by default. Tests can
+// replace it to observe the exit code without stopping the test binary. The
+// logger is closed before f is called even when f returns, so records logged
+// to it afterwards fail to be written unless its output is a standard stream.
+// A nil f restores os.Exit.
+func SetExitFunc(f func(code int)) {
+	if f == nil {
+		f = os.Exit
+	}
+	fatalMu.Lock()
+	defer fatalMu.Unlock()
+	exitFunc = f
+}
+
+// SetExitCode sets the status code that Fatal, Fatalf, FatalS, FatalT and
+// FatalAttrs exit with. The default is 1.
+func SetExitCode(code int) {
+	fatalMu.Lock()
+	defer fatalMu.Unlock()
+	exitCode = code
+}
+
+// fatalExit closes l so that its final record is delivered, runs the OnFatal
+// handlers with the record of the fatal call and exits. It backs Fatal,
+// Fatalf, FatalS, FatalT and FatalAttrs. l is closed even when SetExitFunc has
+// replaced os.Exit, so that tests observe the flushed output.
+func (l *Logger) fatalExit(msg string, kvs []any) {
+	fatalMu.Lock()
+	handlers := fatalHandlers[:len(fatalHandlers):len(fatalHandlers)]
+	exit, code := exitFunc, exitCode
+	fatalMu.Unlock()
+
+	errorHandler(l.closeForExit())
+
+	if len(handlers) > 0 {
+		r := Record{Level: LevelFatal, Message: msg, Fields: kvsToFieldSlice(kvs)}
+		for _, f := range handlers {
+			f(r)
+		}
+	}
+	exit(code)
+}
+
+// closeForExit closes the handler and output of l, which flushes async and
+// batching writers, but leaves the standard streams open for the OnFatal
+// handlers.
+func (l *Logger) closeForExit() error {
+	var errs []error
+	if c, ok := l.Handler().(io.Closer); ok {
+		errs = append(errs, c.Close())
+	}
+	if w := l.Writer(); w == os.Stdout || w == os.Stderr {
+		errs = append(errs, syncWriter(w))
+	} else if c, ok := w.(io.Closer); ok {
+		errs = append(errs, c.Close())
+	}
+	return errors.Join(errs...)
+}
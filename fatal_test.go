@@ -0,0 +1,77 @@
+package log
+
+import (
+	"bytes"
+	"testing"
+)
+
+func resetFatal() {
+	SetExitFunc(nil)
+	SetExitCode(1)
+	fatalHandlers = nil
+}
+
+func TestOnFatalAndSetExitFunc(t *testing.T) {
+	t.Cleanup(resetFatal)
+	var buf bytes.Buffer
+	var records []Record
+	var code int
+	OnFatal(func(r Record) {
+		if buf.Len() == 0 {
+			t.Error("OnFatal ran before the record was written")
+		}
+		records = append(records, r)
+	})
+	SetExitFunc(func(c int) { code = c })
+
+	New(&buf).FatalS("shutting down", "reason", "disk full")
+
+	if code != 1 {
+		t.Fatalf("exit code = %d, want 1", code)
+	}
+	if len(records) != 1 || records[0].Message != "shutting down" || records[0].Level != LevelFatal ||
+		len(records[0].Fields) != 1 || records[0].Fields[0].Key != "reason" {
+		t.Fatalf("records = %+v", records)
+	}
+}
+
+func TestFatalVariantsRunOnFatal(t *testing.T) {
+	t.Cleanup(resetFatal)
+	var messages []string
+	OnFatal(func(r Record) { messages = append(messages, r.Message) })
+	SetExitFunc(func(int) {})
+
+	out := &closeBuffer{}
+	logger := New(out)
+	logger.FatalT("disk {path} full", "path", "/var")
+	logger.FatalAttrs("stopping")
+
+	if len(messages) != 2 || messages[1] != "stopping" {
+		t.Fatalf("OnFatal messages = %q", messages)
+	}
+	if !out.closed {
+		t.Fatal("output not closed with a replaced exit func")
+	}
+}
+
+func TestFatalFlushesAsyncWriterBeforeExit(t *testing.T) {
+	t.Cleanup(resetFatal)
+	out := &gateWriter{open: make(chan struct{})}
+	close(out.open)
+	var written string
+	var code int
+	SetExitCode(3)
+	SetExitFunc(func(c int) {
+		code = c
+		written = out.buf.String()
+	})
+
+	New(AsyncWriter(out)).Fatal("last words")
+
+	if code != 3 {
+		t.Fatalf("exit code = %d, want 3", code)
+	}
+	if written != "FATAL msg=\"last words\"\n" {
+		t.Fatalf("output at exit = %q", written)
+	}
+}
@@ -1,7 +1,10 @@
 package log
 
 import (
+	"encoding/base64"
 	"log/slog"
+	"net/netip"
+	"sync"
 	"time"
 )
 
@@ -38,18 +41,60 @@ func Float64(key string, v float64) Field {
 	return Field{key, Float64Value(v)}
 }
 
+// Float32 returns a Field for a 32-bit floating-point number, encoded with
+// float32 precision.
+func Float32(key string, v float32) Field {
+	return Field{key, Float32Value(v)}
+}
+
+// Bytes returns a Field for a []byte. Handlers encode it as set by
+// HandlerOptions.BytesEncoding. The caller must not subsequently mutate the
+// slice.
+func Bytes(key string, v []byte) Field {
+	return Field{key, BytesValue(v)}
+}
+
+// Hex returns a Field for the lowercase hexadecimal encoding of v, such as
+// a digest. The string is the only allocation.
+func Hex(key string, v []byte) Field {
+	return String(key, bytesToString(appendHex(make([]byte, 0, 2*len(v)), v)))
+}
+
+// Base64 returns a Field for the standard base64 encoding of v, such as a
+// binary token. The string is the only allocation.
+func Base64(key string, v []byte) Field {
+	return String(key, bytesToString(appendBase64(make([]byte, 0, base64.StdEncoding.EncodedLen(len(v))), v)))
+}
+
+// IPAddr returns a Field for an IP address. Handlers format it without
+// allocating.
+func IPAddr(key string, v netip.Addr) Field {
+	return Field{key, AnyValue(v)}
+}
+
+// Prefix returns a Field for an IP network prefix, such as 10.0.0.0/8.
+// Handlers format it without allocating.
+func Prefix(key string, v netip.Prefix) Field {
+	return Field{key, AnyValue(v)}
+}
+
 // Bool returns an Attr for a bool.
 func Bool(key string, v bool) Field {
 	return Field{key, BoolValue(v)}
 }
 
 // Err returns a Field for an error using the standard error key.
-// A nil error returns an empty field and is not emitted.
+// A nil error returns an empty field and is not emitted. An error of
+// errors.Join returns a Field under ErrsKey with the list of the joined
+// errors, each encoded as set by HandlerOptions.ErrorFormat.
 func Err(err error) Field {
 	if err == nil {
 		return Field{}
 	}
-	return String(ErrKey, err.Error())
+	if errs := joinedErrors(err); errs != nil {
+		return Field{ErrsKey, AnyValue(errs)}
+	}
+	return Field{ErrKey, ErrValue(err)}
 }
 
 // Time returns an Attr for a [time.Time].
@@ -63,6 +108,39 @@ func Duration(key string, v time.Duration) Field {
 	return Field{key, DurationValue(v)}
 }
 
+// Ints returns a Field for a []int, encoded as an array.
+func Ints(key string, v []int) Field {
+	return Field{key, AnyValue(v)}
+}
+
+// Strings returns a Field for a []string, encoded as an array.
+func Strings(key string, v []string) Field {
+	return Field{key, AnyValue(v)}
+}
+
+// Bools returns a Field for a []bool, encoded as an array.
+func Bools(key string, v []bool) Field {
+	return Field{key, AnyValue(v)}
+}
+
+// Durations returns a Field for a []time.Duration, encoded as an array of
+// nanoseconds in JSON.
+func Durations(key string, v []time.Duration) Field {
+	return Field{key, AnyValue(v)}
+}
+
+// Times returns a Field for a []time.Time, encoded as an array.
+func Times(key string, v []time.Time) Field {
+	return Field{key, AnyValue(v)}
+}
+
+// Errs returns a Field for a []error, encoded as an array of errors
+// formatted as set by HandlerOptions.ErrorFormat in JSON and of their
+// messages in text.
+func Errs(key string, v []error) Field {
+	return Field{key, AnyValue(v)}
+}
+
 // Group returns an Attr for a Group [Value].
 // The first argument is the key; the remaining arguments
 // are converted to Attrs as in [Logger.Log].
@@ -74,6 +152,12 @@ func Group(key string, kvs ...any) Field {
 	return Field{key, GroupValue(kvsToFieldSlice(kvs)...)}
 }
 
+// Dict returns a Field for a group of fields. Unlike Group, it takes Fields
+// only, so no key-value parsing is done.
+func Dict(key string, fields ...Field) Field {
+	return Field{key, GroupValue(fields...)}
+}
+
 // Dynamic returns a Field whose value is evaluated for each log record.
 func Dynamic(key string, v Valuer) Field {
 	return Field{Key: key, Value: ValuerValue(v)}
@@ -94,10 +178,15 @@ func Fields(kvs ...any) []Field {
 const badKey = "<BAD_KEY>"
 
 func kvsToFieldSlice(kvs []any) []Field {
-	var (
-		field  Field
-		fields []Field
-	)
+	if len(kvs) == 0 {
+		return nil
+	}
+	return appendKVFields(make([]Field, 0, countFields(kvs)), kvs)
+}
+
+// appendKVFields appends the fields of kvs to fields.
+func appendKVFields(fields []Field, kvs []any) []Field {
+	var field Field
 	for len(kvs) > 0 {
 		field, kvs = kvsToField(kvs)
 		fields = append(fields, field)
@@ -105,6 +194,43 @@ func kvsToFieldSlice(kvs []any) []Field {
 	return fields
 }
 
+// countFields returns the number of fields in kvs, so slices for them can be
+// allocated once.
+func countFields(kvs []any) int {
+	n := 0
+	for i := 0; i < len(kvs); i++ {
+		if _, ok := kvs[i].(string); ok {
+			i++
+		}
+		n++
+	}
+	return n
+}
+
+// maxPooledFields is the capacity above which a field slice is not returned
+// to fieldSlicePool.
+const maxPooledFields = 256
+
+// fieldSlicePool holds slices for the fields of a record that are converted
+// from key values and not kept after the record is written.
+var fieldSlicePool = sync.Pool{New: func() any {
+	fields := make([]Field, 0, 16)
+	return &fields
+}}
+
+func newFieldSlice() *[]Field {
+	return fieldSlicePool.Get().(*[]Field)
+}
+
+func freeFieldSlice(fields *[]Field) {
+	if cap(*fields) > maxPooledFields {
+		return
+	}
+	clear(*fields)
+	*fields = (*fields)[:0]
+	fieldSlicePool.Put(fields)
+}
+
 // kvsToField turns a prefix of the nonempty args slice into an Attr
 // and returns the unconsumed portion of the slice.
 // If args[0] is an Attr, it returns it.
@@ -0,0 +1,114 @@
+package log
+
+import (
+	"sync"
+	"time"
+)
+
+// maxBuilderFields is the capacity above which a FieldBuilder is not
+// returned to the pool, so that one large record does not pin memory.
+const maxBuilderFields = 64
+
+var fieldBuilderPool = sync.Pool{New: func() any { return &FieldBuilder{fields: make([]Field, 0, 8)} }}
+
+// FieldBuilder assembles Fields without the []any boxing of key-value pairs.
+// Builders come from a pool:
+//
+//	fb := log.NewFields().Str("user", "alice").Int("attempt", 1)
+//	logger.InfoAttrs("login", fb.Done()...)
+//	fb.Free()
+//
+// A FieldBuilder is not safe for concurrent use.
+type FieldBuilder struct {
+	fields []Field
+}
+
+// NewFields returns an empty FieldBuilder from the pool. Call Free when the
+// fields are no longer used.
+func NewFields() *FieldBuilder {
+	return fieldBuilderPool.Get().(*FieldBuilder)
+}
+
+// Field adds fields.
+func (b *FieldBuilder) Field(fields ...Field) *FieldBuilder {
+	b.fields = append(b.fields, fields...)
+	return b
+}
+
+// Str adds a string field.
+func (b *FieldBuilder) Str(key, v string) *FieldBuilder {
+	b.fields = append(b.fields, String(key, v))
+	return b
+}
+
+// Int adds an int field.
+func (b *FieldBuilder) Int(key string, v int) *FieldBuilder {
+	b.fields = append(b.fields, Int(key, v))
+	return b
+}
+
+// Int64 adds an int64 field.
+func (b *FieldBuilder) Int64(key string, v int64) *FieldBuilder {
+	b.fields = append(b.fields, Int64(key, v))
+	return b
+}
+
+// Uint64 adds a uint64 field.
+func (b *FieldBuilder) Uint64(key string, v uint64) *FieldBuilder {
+	b.fields = append(b.fields, Uint64(key, v))
+	return b
+}
+
+// Float64 adds a float64 field.
+func (b *FieldBuilder) Float64(key string, v float64) *FieldBuilder {
+	b.fields = append(b.fields, Float64(key, v))
+	return b
+}
+
+// Bool adds a bool field.
+func (b *FieldBuilder) Bool(key string, v bool) *FieldBuilder {
+	b.fields = append(b.fields, Bool(key, v))
+	return b
+}
+
+// Duration adds a time.Duration field.
+func (b *FieldBuilder) Duration(key string, v time.Duration) *FieldBuilder {
+	b.fields = append(b.fields, Duration(key, v))
+	return b
+}
+
+// Time adds a time.Time field.
+func (b *FieldBuilder) Time(key string, v time.Time) *FieldBuilder {
+	b.fields = append(b.fields, Time(key, v))
+	return b
+}
+
+// Err adds err under ErrKey. A nil error adds nothing.
+func (b *FieldBuilder) Err(err error) *FieldBuilder {
+	if err != nil {
+		b.fields = append(b.fields, Err(err))
+	}
+	return b
+}
+
+// Any adds a field for any value, as Any does.
+func (b *FieldBuilder) Any(key string, v any) *FieldBuilder {
+	b.fields = append(b.fields, Any(key, v))
+	return b
+}
+
+// Done returns the fields added so far. The slice is valid until Free.
+func (b *FieldBuilder) Done() []Field {
+	return b.fields
+}
+
+// Free returns b to the pool. Neither b nor the slice returned by Done may be
+// used afterwards.
+func (b *FieldBuilder) Free() {
+	if cap(b.fields) > maxBuilderFields {
+		return
+	}
+	clear(b.fields)
+	b.fields = b.fields[:0]
+	fieldBuilderPool.Put(b)
+}
@@ -0,0 +1,35 @@
+package log
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestFieldBuilder(t *testing.T) {
+	fb := NewFields().Str("user", "alice").Int("attempt", 1).Bool("ok", true).
+		Duration("took", time.Second).Err(nil).Err(errors.New("slow")).Field(Float64("score", 0.5))
+	var buf bytes.Buffer
+	New(&buf, Json()).WithFields(fb.Done()...).Info("login")
+	fb.Free()
+	want := `{"level":"INFO","user":"alice","attempt":1,"ok":true,"took":1000000000,"err":"slow","score":0.5,"msg":"login"}` + "\n"
+	if got := buf.String(); got != want {
+		t.Fatalf("output = %q, want %q", got, want)
+	}
+
+	if fields := NewFields().Done(); len(fields) != 0 {
+		t.Fatalf("pooled builder has %d fields, want 0", len(fields))
+	}
+}
+
+func TestFieldBuilderAllocs(t *testing.T) {
+	allocs := testing.AllocsPerRun(100, func() {
+		fb := NewFields().Str("k", "v").Int("n", 1).Bool("b", true)
+		_ = fb.Done()
+		fb.Free()
+	})
+	if allocs > 0 {
+		t.Fatalf("allocs = %v, want 0", allocs)
+	}
+}
@@ -0,0 +1,120 @@
+package log
+
+import (
+	"context"
+	"io"
+	"sync"
+
+	"github.com/nexuer/log/internal/buffer"
+)
+
+// flatFields holds the state shared by handlers whose encodings have no
+// nesting, such as syslog structured data, CSV cells and CEF extensions.
+// Fields are kept unencoded and flattened when a record is written.
+type flatFields struct {
+	replacer Replacer
+	extract  func(context.Context) []Field
+	fields   []Field
+	groups   []string
+	mu       *sync.Mutex
+}
+
+func newFlatFields(opts *HandlerOptions) flatFields {
+	if opts == nil {
+		return flatFields{mu: &sync.Mutex{}}
+	}
+	return flatFields{replacer: opts.replacer(), extract: opts.ContextExtractor, mu: &sync.Mutex{}}
+}
+
+func (f flatFields) withFields(fields []Field) flatFields {
+	f.fields = append(f.fields[:len(f.fields):len(f.fields)], nestFields(f.groups, fields)...)
+	return f
+}
+
+func (f flatFields) withGroup(name string) flatFields {
+	f.groups = append(f.groups[:len(f.groups):len(f.groups)], name)
+	return f
+}
+
+// nestFields wraps fields in the given groups, outermost first.
+func nestFields(groups []string, fields []Field) []Field {
+	for i := len(groups) - 1; i >= 0; i-- {
+		fields = []Field{{Key: groups[i], Value: GroupValue(fields...)}}
+	}
+	return fields
+}
+
+// topLevel is the groups of a field outside any group. It is empty rather
+// than nil, which Replacer reserves for built-in fields.
+var topLevel = []string{}
+
+// walk calls fn for each accumulated field, each field of ctx and each field
+// in kvs, in order. Groups are flattened, Valuers are resolved and the
+// Replacer is applied, so fn only sees leaf values.
+func (f *flatFields) walk(ctx context.Context, kvs []any, fn func(groups []string, key string, v Value)) {
+	for _, field := range f.fields {
+		f.walkField(ctx, topLevel, field, fn)
+	}
+	if ctxFields := contextFields(ctx, f.extract); len(ctxFields) > 0 {
+		for _, field := range nestFields(f.groups, ctxFields) {
+			f.walkField(ctx, topLevel, field, fn)
+		}
+	}
+	if len(kvs) == 0 {
+		return
+	}
+	if len(f.groups) == 0 {
+		var field Field
+		for len(kvs) > 0 {
+			field, kvs = kvsToField(kvs)
+			f.walkField(ctx, topLevel, field, fn)
+		}
+		return
+	}
+	// fn only sees leaf values, so the fields need not outlive the walk.
+	fields := newFieldSlice()
+	defer freeFieldSlice(fields)
+	*fields = appendKVFields(*fields, kvs)
+	for _, field := range nestFields(f.groups, *fields) {
+		f.walkField(ctx, topLevel, field, fn)
+	}
+}
+
+func (f *flatFields) walkField(ctx context.Context, groups []string, field Field, fn func(groups []string, key string, v Value)) {
+	if f.replacer != nil && field.Value.Kind() != KindGroup {
+		field = f.replacer(ctx, groups, field)
+	}
+	if field.isEmpty() {
+		return
+	}
+	v := field.Value.Resolve(ctx)
+	if v.Kind() != KindGroup {
+		fn(groups, field.Key, v)
+		return
+	}
+	if field.Key != "" {
+		groups = append(groups, field.Key)
+	}
+	for _, child := range v.group() {
+		f.walkField(ctx, groups, child, fn)
+	}
+}
+
+// write writes buf to w as a single record.
+func (f *flatFields) write(ctx context.Context, w io.Writer, buf *buffer.Buffer) error {
+	if w == nil || w == io.Discard || w == Discard {
+		discarded(ctx, buf.Len())
+		return nil
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return writeRecordLocked(w, buf)
+}
+
+func writeRecordLocked(w io.Writer, buf *buffer.Buffer) error {
+	n, err := w.Write(*buf)
+	if err == nil && n != buf.Len() {
+		return io.ErrShortWrite
+	}
+	return err
+}
@@ -0,0 +1,81 @@
+package log
+
+import (
+	"errors"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+)
+
+var (
+	flushMu    sync.Mutex
+	flushFuncs []func() error
+)
+
+// OnSignalFlush registers f to run when FlushOnSignal catches a signal, in the
+// order of registration and before the default logger is closed. The logmgr
+// package registers the Close of its default Manager.
+func OnSignalFlush(f func() error) {
+	flushMu.Lock()
+	defer flushMu.Unlock()
+	flushFuncs = append(flushFuncs, f)
+}
+
+// FlushOnSignal makes the process flush its logs before it exits on one of
+// sigs, SIGINT and SIGTERM by default: when a signal arrives, it runs the
+// functions of OnSignalFlush, closes the default logger, which delivers the
+// records of async and batching writers and leaves the standard streams open,
+// and exits with status 128 plus the signal number through the function of
+// SetExitFunc. Errors are reported to ErrorHandler. Calling the returned
+// function stops catching the signals.
+//
+//	func main() {
+//		defer log.FlushOnSignal()()
+//		...
+//	}
+func FlushOnSignal(sigs ...os.Signal) (stop func()) {
+	if len(sigs) == 0 {
+		sigs = []os.Signal{os.Interrupt, syscall.SIGTERM}
+	}
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, sigs...)
+	done := make(chan struct{})
+	go func() {
+		select {
+		case sig := <-ch:
+			signal.Stop(ch)
+			errorHandler(flushForExit())
+			code := 1
+			if s, ok := sig.(syscall.Signal); ok {
+				code = 128 + int(s)
+			}
+			fatalMu.Lock()
+			exit := exitFunc
+			fatalMu.Unlock()
+			exit(code)
+		case <-done:
+		}
+	}()
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			signal.Stop(ch)
+			close(done)
+		})
+	}
+}
+
+// flushForExit runs the OnSignalFlush functions and closes the default logger.
+func flushForExit() error {
+	flushMu.Lock()
+	funcs := flushFuncs[:len(flushFuncs):len(flushFuncs)]
+	flushMu.Unlock()
+
+	var errs []error
+	for _, f := range funcs {
+		errs = append(errs, f())
+	}
+	errs = append(errs, Default().closeForExit())
+	return errors.Join(errs...)
+}
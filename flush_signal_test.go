@@ -0,0 +1,68 @@
+package log
+
+import (
+	"errors"
+	"os"
+	"runtime"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestFlushOnSignal(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("SIGHUP cannot be sent on windows")
+	}
+	t.Cleanup(resetFatal)
+	prevDefault := Default()
+	t.Cleanup(func() {
+		SetDefault(prevDefault)
+		flushFuncs = nil
+	})
+
+	out := &gateWriter{open: make(chan struct{})}
+	q := AsyncWriter(out)
+	SetDefault(New(q))
+	var flushed []string
+	OnSignalFlush(func() error {
+		flushed = append(flushed, "manager")
+		return nil
+	})
+	OnSignalFlush(func() error { return errors.New("flush failed") })
+	prevHandler := ErrorHandler
+	var reported error
+	ErrorHandler = func(err error) { reported = err }
+	t.Cleanup(func() { ErrorHandler = prevHandler })
+
+	codes := make(chan int, 1)
+	SetExitFunc(func(code int) { codes <- code })
+	stop := FlushOnSignal(syscall.SIGHUP)
+	defer stop()
+
+	Info("queued before the signal")
+	close(out.open)
+	p, err := os.FindProcess(os.Getpid())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Signal(syscall.SIGHUP); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case code := <-codes:
+		if code != 128+int(syscall.SIGHUP) {
+			t.Errorf("exit code = %d", code)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no exit after the signal")
+	}
+	out.mu.Lock()
+	defer out.mu.Unlock()
+	if !strings.Contains(out.buf.String(), "queued before the signal") {
+		t.Errorf("output = %q, want the queued record", out.buf.String())
+	}
+	if len(flushed) != 1 || reported == nil {
+		t.Errorf("flushed = %v, reported = %v", flushed, reported)
+	}
+}
@@ -17,9 +17,10 @@ var (
 )
 
 var (
-	// DefaultCaller returns the call site for standard Logger APIs.
-	// Logging wrappers should adjust it with AddCallerDepth.
-	DefaultCaller = Caller(9)
+	// DefaultCaller returns the call site for standard Logger APIs. It is
+	// captured by the Logger, so handler wrappers need not adjust it; logging
+	// wrappers around the Logger methods should use AddCallerDepth.
+	DefaultCaller = CallSite()
 
 	// DefaultTimestamp is a Valuer that returns the current wallclock time.
 	DefaultTimestamp = Timestamp(time.RFC3339)
@@ -30,6 +31,16 @@ var (
 		Dynamic("ts", DefaultTimestamp),
 		Dynamic("caller", DefaultCaller),
 	}
+
+	// ServerFields is DefaultFields with the host name and process ID, for
+	// attributing the logs of a fleet. It is a read-only template like
+	// DefaultFields.
+	ServerFields = []Field{
+		Dynamic("ts", DefaultTimestamp),
+		Dynamic("caller", DefaultCaller),
+		Dynamic("host", Hostname()),
+		Dynamic("pid", PID()),
+	}
 )
 
 type defaultLoggerState struct {
@@ -51,7 +62,7 @@ func SetDefault(l *Logger) {
 	}
 	defaultLogger.Store(&defaultLoggerState{
 		logger: l,
-		global: l.WithContext(AddCallerDepth(l.ctx, 1)),
+		global: l.AddCallerSkip(1),
 	})
 }
 
@@ -79,6 +90,12 @@ func DebugS(msg string, kvs ...any) {
 	defaultLogger.Load().global.DebugS(msg, kvs...)
 }
 
+// DebugT logs a message at debug level with key vals, replacing each {key} in
+// the template with the value of key.
+func DebugT(template string, kvs ...any) {
+	defaultLogger.Load().global.DebugT(template, kvs...)
+}
+
 // Info logs a message at info level.
 func Info(args ...any) {
 	defaultLogger.Load().global.Info(args...)
@@ -94,6 +111,12 @@ func InfoS(msg string, kvs ...any) {
 	defaultLogger.Load().global.InfoS(msg, kvs...)
 }
 
+// InfoT logs a message at info level with key vals, replacing each {key} in
+// the template with the value of key.
+func InfoT(template string, kvs ...any) {
+	defaultLogger.Load().global.InfoT(template, kvs...)
+}
+
 // Warn logs a message at warn level.
 func Warn(args ...any) {
 	defaultLogger.Load().global.Warn(args...)
@@ -109,6 +132,12 @@ func WarnS(msg string, kvs ...any) {
 	defaultLogger.Load().global.WarnS(msg, kvs...)
 }
 
+// WarnT logs a message at warn level with key vals, replacing each {key} in
+// the template with the value of key.
+func WarnT(template string, kvs ...any) {
+	defaultLogger.Load().global.WarnT(template, kvs...)
+}
+
 // Error logs a message at error level.
 func Error(args ...any) {
 	defaultLogger.Load().global.Error(args...)
@@ -124,6 +153,12 @@ func ErrorS(msg string, kvs ...any) {
 	defaultLogger.Load().global.ErrorS(msg, kvs...)
 }
 
+// ErrorT logs a message at error level with key vals, replacing each {key} in
+// the template with the value of key.
+func ErrorT(template string, kvs ...any) {
+	defaultLogger.Load().global.ErrorT(template, kvs...)
+}
+
 // Fatal logs a message at fatal level.
 func Fatal(args ...any) {
 	defaultLogger.Load().global.Fatal(args...)
@@ -138,3 +173,9 @@ func Fatalf(format string, args ...any) {
 func FatalS(msg string, kvs ...any) {
 	defaultLogger.Load().global.FatalS(msg, kvs...)
 }
+
+// FatalT logs a message at fatal level with key vals, replacing each {key} in
+// the template with the value of key.
+func FatalT(template string, kvs ...any) {
+	defaultLogger.Load().global.FatalT(template, kvs...)
+}
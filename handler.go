@@ -2,12 +2,15 @@ package log
 
 import (
 	"context"
+	"encoding/base64"
 	"fmt"
 	"io"
 	"log/slog"
+	"net/netip"
 	"reflect"
 	"slices"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 	"unicode"
@@ -16,18 +19,168 @@ import (
 	"github.com/nexuer/log/internal/buffer"
 )
 
+// TimeEncoding selects how handlers encode time values.
+type TimeEncoding int
+
+const (
+	// TimeDefault encodes times in RFC 3339, with nanoseconds in JSON and
+	// milliseconds in text.
+	TimeDefault TimeEncoding = iota
+	// TimeRFC3339Nano encodes times in RFC 3339 with nanoseconds.
+	TimeRFC3339Nano
+	// TimeUnix encodes times as integer seconds since the Unix epoch.
+	TimeUnix
+	// TimeUnixMilli encodes times as integer milliseconds since the Unix
+	// epoch.
+	TimeUnixMilli
+)
+
+// BytesEncoding selects how handlers encode Bytes values.
+type BytesEncoding int
+
+const (
+	// BytesDefault encodes bytes as a quoted string in text and as standard
+	// base64 in JSON, like encoding/json.
+	BytesDefault BytesEncoding = iota
+	// BytesHex encodes bytes as lowercase hexadecimal.
+	BytesHex
+	// BytesBase64 encodes bytes as standard base64 with padding.
+	BytesBase64
+	// BytesString encodes bytes as a quoted string, with invalid UTF-8
+	// escaped.
+	BytesString
+)
+
+// ErrorFormat selects how handlers encode error values.
+type ErrorFormat int
+
+const (
+	// ErrorMessage encodes an error as its Error message.
+	ErrorMessage ErrorFormat = iota
+	// ErrorVerbose encodes an error as formatted by %+v, which includes the
+	// stack trace of errors that record one, such as those of
+	// github.com/pkg/errors.
+	ErrorVerbose
+	// ErrorChain encodes an error as the list of the messages of the error
+	// and of the errors it wraps, in errors.Unwrap order.
+	ErrorChain
+)
+
+// InvalidUTF8Mode selects how JSON handlers encode strings that are not valid
+// UTF-8. The output is valid JSON in every mode.
+type InvalidUTF8Mode int
+
+const (
+	// InvalidUTF8Replace replaces each invalid byte with U+FFFD, like
+	// encoding/json.
+	InvalidUTF8Replace InvalidUTF8Mode = iota
+	// InvalidUTF8Escape writes each invalid byte as the text \xNN, so the
+	// original bytes can be recovered from the decoded string.
+	InvalidUTF8Escape
+	// InvalidUTF8Base64 writes a string with invalid bytes as the standard
+	// base64 encoding of the whole string.
+	InvalidUTF8Base64
+)
+
+// MultilineMode selects how text handlers write messages and string values
+// that span several lines, such as stack traces and SQL. JSON handlers escape
+// newlines in every mode, since JSON strings cannot hold them.
+type MultilineMode int
+
+const (
+	// MultilineEscape quotes such strings on one line, with newlines and tabs
+	// escaped as \n and \t.
+	MultilineEscape MultilineMode = iota
+	// MultilineIndent quotes such strings with their newlines and tabs as
+	// is, and indents each continuation line by two spaces, so it stands out
+	// from the next record.
+	MultilineIndent
+	// MultilineRaw quotes such strings with their newlines and tabs as is.
+	MultilineRaw
+)
+
+// preformattedAttr is a segment of fields encoded by withFields. A Valuer
+// cannot be encoded in advance, so it ends the segment and keeps the key and
+// text group prefix it was added under; the field is encoded when the record is
+// written.
 type preformattedAttr struct {
 	bytes  []byte
 	valuer Valuer
+	key    string
+	prefix string
 }
 
 type HandlerOptions struct {
 	// Name identifies the logger. JSON handlers emit it as the built-in logger
 	// field; text handlers render it as a [name] prefix.
 	Name string
-	// Replacer can transform or remove user fields and the built-in level, msg,
-	// and logger fields.
+	// Replacer can transform or remove user fields and the built-in level,
+	// time, msg, and logger fields. The key and value it returns for a
+	// built-in field are the ones written.
 	Replacer Replacer
+	// LevelEncoding selects how the Json and Datadog handlers encode the
+	// built-in level field. The default is the level name.
+	LevelEncoding LevelEncoding
+	// TimeEncoding selects how time values, including the built-in time
+	// field, are encoded. It does not apply to Timestamp values, which have
+	// their own layout.
+	TimeEncoding TimeEncoding
+	// BytesEncoding selects how Bytes values, and other values whose
+	// underlying type is []byte, are encoded.
+	BytesEncoding BytesEncoding
+	// MaxBytes, if positive, encodes only the first MaxBytes bytes of longer
+	// Bytes values, followed by "...".
+	MaxBytes int
+	// ErrorFormat selects how error values, such as the one of Err, are
+	// encoded. The default is the Error message.
+	ErrorFormat ErrorFormat
+	// Multiline selects how text handlers write messages and string values
+	// with newlines. The default escapes them.
+	Multiline MultilineMode
+	// SkipStringer makes text handlers format values without calling their
+	// String methods, which they otherwise call directly, for types whose
+	// String is expensive. Such values are printed from their fields, like
+	// %+v prints types without methods.
+	SkipStringer bool
+	// SortKeys writes the fields of each log call, of each With or
+	// WithFields call, and of each group in lexicographic key order. Fields
+	// with equal keys keep their order.
+	SortKeys bool
+	// EscapeHTML escapes <, > and & in the strings of JSON handlers, for logs
+	// displayed in browsers.
+	EscapeHTML bool
+	// EscapeUnicode escapes runes outside ASCII in the strings of JSON
+	// handlers as \uXXXX, so the output is plain ASCII.
+	EscapeUnicode bool
+	// InvalidUTF8 selects how JSON handlers encode strings with invalid
+	// UTF-8. The default replaces the invalid bytes with U+FFFD.
+	InvalidUTF8 InvalidUTF8Mode
+	// AddTime adds the time of the log call as the built-in TimeKey field,
+	// after the level.
+	AddTime bool
+	// AddSource adds the file and line of the log call as the built-in
+	// SourceKey field, after the time. The Logger captures the call site once
+	// per record, so unlike Caller it needs no depth for the standard Logger
+	// methods; wrappers that call them still use AddCallerDepth. It is
+	// supported by the Text, Json and Datadog handlers.
+	AddSource bool
+	// Redactor masks sensitive text in the message and string field values.
+	// It runs before Replacer.
+	Redactor *Redactor
+	// DropKeys removes the fields whose key matches one of these path.Match
+	// patterns, such as "password" or "*_secret". Inside groups, the path of
+	// the field with dots joining the group names, such as "http.header",
+	// matches as well. Matching ignores case. The built-in fields are kept.
+	DropKeys []string
+	// AllowKeys, if not empty, removes the fields whose key matches none of
+	// these patterns. Patterns match like DropKeys, and a group whose fields
+	// are all removed is omitted.
+	AllowKeys []string
+	// ContextExtractor, if set, returns fields taken from the context of each
+	// record, such as a tenant or a deadline. They are added after the fields
+	// of ContextWithFields and before the fields of the call. It is only
+	// called with a non-nil context.
+	ContextExtractor func(ctx context.Context) []Field
 }
 
 type commonHandler struct {
@@ -37,15 +190,21 @@ type commonHandler struct {
 	groupPrefix       string
 	groups            []string
 	nOpenGroups       int
+	callSite          bool // a preformatted Valuer is a CallSite
+	escape            jsonEscape
 	mu                *sync.Mutex
 }
 
 func newCommonHandler(json bool, opts HandlerOptions) *commonHandler {
+	opts.Replacer = opts.replacer()
 	ch := &commonHandler{
 		mu:   &sync.Mutex{},
 		json: json,
 		opts: opts,
 	}
+	if json {
+		ch.escape = opts.jsonEscape()
+	}
 	return ch
 }
 
@@ -58,6 +217,8 @@ func (h *commonHandler) clone() *commonHandler {
 		groupPrefix:       h.groupPrefix,
 		groups:            slices.Clip(h.groups),
 		nOpenGroups:       h.nOpenGroups,
+		callSite:          h.callSite,
+		escape:            h.escape,
 		mu:                h.mu, // mutex shared among all clones of this handler
 	}
 }
@@ -68,6 +229,9 @@ func (h *commonHandler) withFields(ctx context.Context, fields []Field) *commonH
 	if countEmptyGroups(fields) == len(fields) {
 		return h
 	}
+	if h.opts.SortKeys {
+		fields = sortedFields(fields)
+	}
 	h2 := h.clone()
 	var buf buffer.Buffer
 	state := h2.newHandleState(&buf, false, h.attrSep())
@@ -142,21 +306,26 @@ func (s *handleState) appendFieldValue(ctx context.Context, field Field, isPrefo
 	}
 	// Valuer
 	if v := field.Value; v.Kind() == KindValuer {
-		s.appendKey(field.Key)
-		if isPreformat {
-			valuer := v.valuer()
-			if valuer == nil {
-				valuer = nilValuer
-			}
-			s.h.preformattedAttrs = append(s.h.preformattedAttrs, preformattedAttr{
-				bytes:  *s.buf,
-				valuer: valuer,
-			})
-			// Keep the stored bytes owned by the segment and reuse the slice header.
-			*s.buf = nil
-		} else {
-			s.appendValue(v.Resolve(ctx))
+		if !isPreformat {
+			field.Value = v.Resolve(ctx)
+			return s.appendFieldValue(ctx, field, false)
+		}
+		valuer := v.valuer()
+		if valuer == nil {
+			valuer = nilValuer
 		}
+		if isCallSite(valuer) {
+			s.h.callSite = true
+		}
+		s.h.preformattedAttrs = append(s.h.preformattedAttrs, preformattedAttr{
+			bytes:  *s.buf,
+			valuer: valuer,
+			key:    field.Key,
+			prefix: s.prefix.String(),
+		})
+		// Keep the stored bytes owned by the segment and reuse the slice header.
+		*s.buf = nil
+		s.sep = s.h.attrSep()
 		return true
 	}
 
@@ -166,6 +335,9 @@ func (s *handleState) appendFieldValue(ctx context.Context, field Field, isPrefo
 		if len(fs) == 0 {
 			return false
 		}
+		if s.h.opts.SortKeys {
+			fs = sortedFields(fs)
+		}
 		pos := s.buf.Len()
 		sep := s.sep
 		prefixLen := len(*s.prefix)
@@ -200,6 +372,21 @@ func (s *handleState) appendFieldValue(ctx context.Context, field Field, isPrefo
 	return true
 }
 
+// sortedFields returns fields sorted by key. Fields with equal keys keep their
+// order. fields is not modified.
+func sortedFields(fields []Field) []Field {
+	if slices.IsSortedFunc(fields, compareFieldKeys) {
+		return fields
+	}
+	fields = slices.Clone(fields)
+	slices.SortStableFunc(fields, compareFieldKeys)
+	return fields
+}
+
+func compareFieldKeys(a, b Field) int {
+	return strings.Compare(a.Key, b.Key)
+}
+
 // attrSep returns the separator between attributes.
 func (h *commonHandler) attrSep() string {
 	if h.json {
@@ -293,6 +480,9 @@ func (s *handleState) closeGroup(name string) {
 }
 
 func (s *handleState) appendKey(key string) {
+	if s.h.json && s.buf.Len() > 0 && (*s.buf)[s.buf.Len()-1] == '{' {
+		s.sep = ""
+	}
 	_, _ = s.buf.WriteString(s.sep)
 	if s.h.json {
 		s.appendString(key)
@@ -312,11 +502,17 @@ func (s *handleState) appendKey(key string) {
 func (s *handleState) appendString(str string) {
 	if s.h.json {
 		_ = s.buf.WriteByte('"')
-		*s.buf = appendEscapedJSONString(*s.buf, str)
+		if s.h.opts.InvalidUTF8 == InvalidUTF8Base64 && !utf8.ValidString(str) {
+			*s.buf = appendBase64(*s.buf, []byte(str))
+		} else {
+			*s.buf = appendEscapedJSONString(*s.buf, str, s.h.escape)
+		}
 		_ = s.buf.WriteByte('"')
 	} else {
 		// text
-		if needsQuoting(str) {
+		if s.h.opts.Multiline != MultilineEscape && strings.IndexByte(str, '\n') >= 0 {
+			*s.buf = appendMultiline(*s.buf, str, s.h.opts.Multiline == MultilineIndent)
+		} else if needsQuoting(str) {
 			*s.buf = strconv.AppendQuote(*s.buf, str)
 		} else {
 			_, _ = s.buf.WriteString(str)
@@ -324,6 +520,33 @@ func (s *handleState) appendString(str string) {
 	}
 }
 
+// appendMultiline appends s quoted, with its newlines and tabs unescaped and,
+// if indent is set, two spaces after each newline.
+func appendMultiline(buf []byte, s string, indent bool) []byte {
+	buf = append(buf, '"')
+	for len(s) > 0 {
+		i := strings.IndexAny(s, "\n\t")
+		if i < 0 {
+			i = len(s)
+		}
+		if i > 0 {
+			// Quote the segment and drop its quotation marks.
+			n := len(buf)
+			buf = strconv.AppendQuote(buf, s[:i])
+			buf = append(buf[:n], buf[n+1:len(buf)-1]...)
+		}
+		if i == len(s) {
+			break
+		}
+		buf = append(buf, s[i])
+		if s[i] == '\n' && indent {
+			buf = append(buf, "  "...)
+		}
+		s = s[i+1:]
+	}
+	return append(buf, '"')
+}
+
 func needsQuoting(s string) bool {
 	if len(s) == 0 {
 		return true
@@ -377,18 +600,178 @@ func (s *handleState) appendValue(v Value) {
 	}
 }
 
+// appendErr encodes err as set by HandlerOptions.ErrorFormat.
+func (s *handleState) appendErr(err error) {
+	if err == nil {
+		s.appendString("<nil>")
+		return
+	}
+	switch s.h.opts.ErrorFormat {
+	case ErrorVerbose:
+		formatted := buffer.New()
+		defer formatted.Free()
+		*formatted = fmt.Appendf(*formatted, "%+v", err)
+		s.appendString(bytesToString(*formatted))
+	case ErrorChain:
+		chain := errorChain(nil, err)
+		if s.h.json {
+			appendJSONSlice(s, chain)
+		} else {
+			appendTextSlice(s, chain)
+		}
+	default:
+		if errs := joinedErrors(err); errs != nil {
+			if s.h.json {
+				appendJSONSlice(s, errs)
+			} else {
+				appendTextSlice(s, errs)
+			}
+			return
+		}
+		s.appendString(err.Error())
+	}
+}
+
+// joinedErrors returns the errors joined by errors.Join into err, or nil for
+// other errors. Errors with an Unwrap() []error method whose message adds to
+// the messages of the errors, like those of fmt.Errorf with several %w, are
+// not expanded, since the list would lose that text.
+func joinedErrors(err error) []error {
+	u, ok := err.(interface{ Unwrap() []error })
+	if !ok {
+		return nil
+	}
+	errs := u.Unwrap()
+	if len(errs) == 0 {
+		return nil
+	}
+	msg := err.Error()
+	for i, e := range errs {
+		if i > 0 {
+			if !strings.HasPrefix(msg, "\n") {
+				return nil
+			}
+			msg = msg[1:]
+		}
+		if e == nil {
+			return nil
+		}
+		m := e.Error()
+		if !strings.HasPrefix(msg, m) {
+			return nil
+		}
+		msg = msg[len(m):]
+	}
+	if msg != "" {
+		return nil
+	}
+	return errs
+}
+
+// errorChain appends the messages of err and of the errors it wraps to
+// chain. The errors joined by errors.Join are walked depth first.
+func errorChain(chain []string, err error) []string {
+	for err != nil {
+		chain = append(chain, err.Error())
+		switch u := err.(type) {
+		case interface{ Unwrap() error }:
+			err = u.Unwrap()
+		case interface{ Unwrap() []error }:
+			for _, err := range u.Unwrap() {
+				chain = errorChain(chain, err)
+			}
+			return chain
+		default:
+			return chain
+		}
+	}
+	return chain
+}
+
 func (s *handleState) appendError(err error) {
 	s.appendString(fmt.Sprintf("!ERROR:%v", err))
 }
 
 func (s *handleState) appendTime(t time.Time) {
-	if s.h.json {
-		appendJSONTime(s, t)
-	} else {
-		*s.buf = appendRFC3339Millis(*s.buf, t)
+	switch s.h.opts.TimeEncoding {
+	case TimeUnix:
+		*s.buf = strconv.AppendInt(*s.buf, t.Unix(), 10)
+	case TimeUnixMilli:
+		*s.buf = strconv.AppendInt(*s.buf, t.UnixMilli(), 10)
+	case TimeRFC3339Nano:
+		if s.h.json {
+			appendJSONTime(s, t)
+		} else {
+			*s.buf = t.AppendFormat(*s.buf, time.RFC3339Nano)
+		}
+	default:
+		if s.h.json {
+			appendJSONTime(s, t)
+		} else {
+			*s.buf = appendRFC3339Millis(*s.buf, t)
+		}
 	}
 }
 
+// appendBytes encodes bs as set by HandlerOptions.BytesEncoding and MaxBytes.
+func (s *handleState) appendBytes(bs []byte) {
+	truncated := false
+	if max := s.h.opts.MaxBytes; max > 0 && len(bs) > max {
+		bs, truncated = bs[:max], true
+	}
+	enc := s.h.opts.BytesEncoding
+	if enc == BytesDefault {
+		enc = BytesString
+		if s.h.json {
+			enc = BytesBase64
+		}
+	}
+	formatted := buffer.New()
+	defer formatted.Free()
+	switch enc {
+	case BytesHex:
+		*formatted = appendHex(*formatted, bs)
+	case BytesBase64:
+		*formatted = appendBase64(*formatted, bs)
+	default:
+		*formatted = append(*formatted, bs...)
+	}
+	if truncated {
+		*formatted = append(*formatted, "..."...)
+	}
+	if enc == BytesString && !s.h.json {
+		// Text always quotes bytes, so they cannot be mistaken for a string.
+		*s.buf = strconv.AppendQuote(*s.buf, bytesToString(*formatted))
+		return
+	}
+	s.appendString(bytesToString(*formatted))
+}
+
+// appendNetIP writes a netip.Addr, netip.Prefix or netip.AddrPort through
+// its AppendTo method, whose output matches MarshalText without allocating,
+// and reports whether a is one of them.
+func (s *handleState) appendNetIP(a any) bool {
+	switch a.(type) {
+	case netip.Addr, netip.Prefix, netip.AddrPort:
+	default:
+		return false
+	}
+	formatted := buffer.New()
+	defer formatted.Free()
+	*formatted = a.(interface{ AppendTo([]byte) []byte }).AppendTo(*formatted)
+	s.appendString(bytesToString(*formatted))
+	return true
+}
+
+// appendBase64 appends the standard base64 encoding of src to dst.
+func appendBase64(dst, src []byte) []byte {
+	n := len(dst)
+	dst = slices.Grow(dst, base64.StdEncoding.EncodedLen(len(src)))
+	dst = dst[:n+base64.StdEncoding.EncodedLen(len(src))]
+	base64.StdEncoding.Encode(dst[n:], src)
+	return dst
+}
+
 func (s *handleState) appendTimestamp(t time.Time, layout string) {
 	if layout == time.RFC3339 || layout == time.RFC3339Nano {
 		if s.h.json {
@@ -419,6 +802,26 @@ func appendRFC3339Millis(b []byte, t time.Time) []byte {
 	return b
 }
 
+// plainValueString returns an unquoted text form of a resolved value, for
+// handlers whose encoding does its own escaping.
+func plainValueString(v Value) string {
+	switch v.Kind() {
+	case KindString:
+		return v.str()
+	case KindTime:
+		return v.time().Format(time.RFC3339Nano)
+	case KindError:
+		if err := v.err(); err != nil {
+			return err.Error()
+		}
+	case KindAny:
+		if masked, ok := maskStruct(v.any); ok {
+			return fmt.Sprint(masked)
+		}
+	}
+	return v.String()
+}
+
 func (s *handleState) appendByte(c byte) {
 	_ = s.buf.WriteByte(c)
 }
@@ -428,17 +831,42 @@ func (s *handleState) appendPreformattedAttrs(ctx context.Context) bool {
 		return false
 	}
 	for _, attr := range s.h.preformattedAttrs {
-		if len(attr.bytes) > 0 {
-			_, _ = s.buf.Write(attr.bytes)
+		if bs := attr.bytes; len(bs) > 0 {
+			// The segment was encoded assuming the previous field was written.
+			// Drop its leading separator if that field was elided.
+			if s.atFieldStart() && bs[0] == s.h.attrSep()[0] {
+				bs = bs[1:]
+			}
+			_, _ = s.buf.Write(bs)
 		}
 		if attr.valuer != nil {
-			s.appendValue(resolvePreformattedValuer(ctx, attr.valuer))
+			// Resolve here rather than in a helper: Caller, and CallSite without
+			// a captured call site, depend on the number of frames between the
+			// Logger method and the Valuer.
+			field := Field{Key: attr.key, Value: resolvePreformattedValuer(ctx, attr.valuer)}
+			if s.atFieldStart() {
+				s.sep = ""
+			} else {
+				s.sep = s.h.attrSep()
+			}
+			_, _ = s.prefix.WriteString(attr.prefix)
+			// A Valuer that resolves to a group is encoded like any other group,
+			// and an empty group is elided.
+			s.appendFieldValue(ctx, field, false)
+			s.prefix.SetLen(0)
 		}
 	}
 	s.sep = s.h.attrSep()
 	return true
 }
 
+// atFieldStart reports whether the next field is the first one in the record
+// or, for JSON, in the current object, so it needs no separator.
+func (s *handleState) atFieldStart() bool {
+	n := s.buf.Len()
+	return n == 0 || s.h.json && (*s.buf)[n-1] == '{'
+}
+
 var nilValuer = Valuer(func(context.Context) Value { return AnyValue(nil) })
 
 func resolvePreformattedValuer(ctx context.Context, valuer Valuer) (rv Value) {
@@ -447,10 +875,22 @@ func resolvePreformattedValuer(ctx context.Context, valuer Valuer) (rv Value) {
 			rv = AnyValue(fmt.Errorf("valuer panicked\n%s", stack(3, 5)))
 		}
 	}()
-	return valuer(ctx).Resolve(ctx)
+	c := valuerCacheFor(ctx, valuer)
+	if c == nil {
+		return valuer(ctx).Resolve(ctx)
+	}
+	if v, ok := c.load(valuer); ok {
+		return v.Resolve(ctx)
+	}
+	rv = valuer(ctx).Resolve(ctx)
+	c.store(valuer, rv)
+	return rv
 }
 
-func (s *handleState) appendNonBuiltIns(ctx context.Context, kvs []any) {
+// appendNonBuiltIns appends the preformatted fields, the message and the
+// fields of the record, which are the fields of ctx followed by either kvs or
+// fields.
+func (s *handleState) appendNonBuiltIns(ctx context.Context, kvs []any, fields []Field) {
 	nOpenGroups := s.h.nOpenGroups
 	s.appendPreformattedAttrs(ctx)
 	messageAppended := !s.h.json || s.h.nOpenGroups == 0
@@ -458,13 +898,34 @@ func (s *handleState) appendNonBuiltIns(ctx context.Context, kvs []any) {
 		s.appendMessage(ctx)
 	}
 
-	if len(kvs) > 0 {
+	ctxFields := contextFields(ctx, s.h.opts.ContextExtractor)
+	if len(kvs) > 0 || len(fields) > 0 || len(ctxFields) > 0 {
 		_, _ = s.prefix.WriteString(s.h.groupPrefix)
 		pos := s.buf.Len()
 		s.openGroups()
 		nOpenGroups = len(s.h.groups)
 
 		nonEmpty := false
+		if s.h.opts.SortKeys {
+			if len(kvs) > 0 || len(ctxFields) > 0 {
+				// The merged fields are only read here, so they are sorted in a
+				// pooled slice.
+				pooled := newFieldSlice()
+				defer freeFieldSlice(pooled)
+				*pooled = append(append(*pooled, ctxFields...), fields...)
+				*pooled = appendKVFields(*pooled, kvs)
+				fields, kvs, ctxFields = *pooled, nil, nil
+				slices.SortStableFunc(fields, compareFieldKeys)
+			} else {
+				fields = sortedFields(fields)
+			}
+		}
+		nonEmpty = s.appendContextFields(ctx, ctxFields)
+		for _, field := range fields {
+			if s.appendField(ctx, field, false) {
+				nonEmpty = true
+			}
+		}
 		var a Field
 		for len(kvs) > 0 {
 			if attr, ok := kvs[0].(slog.Attr); ok {
@@ -496,6 +957,21 @@ func (s *handleState) appendNonBuiltIns(ctx context.Context, kvs []any) {
 	}
 }
 
+// appendContextFields appends the fields carried by the context of a record
+// and reports whether any was written.
+func (s *handleState) appendContextFields(ctx context.Context, fields []Field) bool {
+	if s.h.opts.SortKeys {
+		fields = sortedFields(fields)
+	}
+	nonEmpty := false
+	for _, field := range fields {
+		if s.appendField(ctx, field, false) {
+			nonEmpty = true
+		}
+	}
+	return nonEmpty
+}
+
 func (s *handleState) appendMessage(ctx context.Context) {
 	if s.message.isEmpty() {
 		return
@@ -507,7 +983,7 @@ func (s *handleState) appendMessage(ctx context.Context) {
 	s.message = Field{}
 }
 
-func (h *commonHandler) newRecordState(ctx context.Context, level, msg string) handleState {
+func (h *commonHandler) newRecordState(ctx context.Context, level Level, msg string) handleState {
 	state := h.newHandleState(buffer.New(), true, "")
 
 	if h.json {
@@ -517,14 +993,24 @@ func (h *commonHandler) newRecordState(ctx context.Context, level, msg string) h
 	// Built-in attributes. They are not in a group.
 	stateGroups := state.groups
 	state.groups = nil // Built-in fields are always outside user groups.
-	levelField := h.replaceBuiltIn(ctx, String(LevelKey, level))
+	levelField := h.replaceBuiltIn(ctx, Field{Key: LevelKey, Value: h.levelValue(level)})
 	msgField := Field{}
 	if msg != "" {
 		msgField = h.replaceBuiltIn(ctx, String(MessageKey, msg))
 	}
+	timeField := Field{}
+	if h.opts.AddTime {
+		timeField = h.replaceBuiltIn(ctx, Time(TimeKey, time.Now()))
+	}
+	sourceField := Field{}
+	if h.opts.AddSource {
+		if pc := pcFromContext(ctx); pc != 0 {
+			sourceField = h.replaceBuiltIn(ctx, Field{Key: SourceKey, Value: sourceValue(pc)})
+		}
+	}
 	nameField := Field{}
-	if h.opts.Name != "" {
-		nameField = h.replaceBuiltIn(ctx, String(NameKey, h.opts.Name))
+	if name := recordName(ctx, h.opts.Name); name != "" {
+		nameField = h.replaceBuiltIn(ctx, String(NameKey, name))
 	}
 
 	// Preserve the text handler's [name] prefix when logger remains a string.
@@ -551,12 +1037,32 @@ func (h *commonHandler) newRecordState(ctx context.Context, level, msg string) h
 			state.appendFieldValue(ctx, levelField, false)
 		}
 	}
+	if !timeField.isEmpty() {
+		state.appendFieldValue(ctx, timeField, false)
+	}
+	if !sourceField.isEmpty() {
+		state.appendFieldValue(ctx, sourceField, false)
+	}
 	state.message = msgField
 
 	state.groups = stateGroups // Restore groups passed to Replacer.
 	return state
 }
 
+// levelValue returns the value of the built-in level field. LevelEncoding
+// applies to JSON only.
+func (h *commonHandler) levelValue(level Level) Value {
+	if h.json {
+		switch h.opts.LevelEncoding {
+		case LevelNumber:
+			return Int64Value(int64(level))
+		case LevelSyslogSeverity:
+			return Int64Value(int64(SyslogSeverity(level)))
+		}
+	}
+	return StringValue(level.String())
+}
+
 func (h *commonHandler) replaceBuiltIn(ctx context.Context, field Field) Field {
 	if h.opts.Replacer == nil {
 		return field
@@ -564,10 +1070,11 @@ func (h *commonHandler) replaceBuiltIn(ctx context.Context, field Field) Field {
 	return h.opts.Replacer(ctx, nil, field)
 }
 
-func (h *commonHandler) writeRecord(w io.Writer, state *handleState) error {
+func (h *commonHandler) writeRecord(ctx context.Context, w io.Writer, state *handleState) error {
 	state.appendByte('\n')
 
 	if w == nil || w == io.Discard || w == Discard {
+		discarded(ctx, state.buf.Len())
 		return nil
 	}
 
@@ -581,9 +1088,17 @@ func (h *commonHandler) writeRecord(w io.Writer, state *handleState) error {
 }
 
 func (h *commonHandler) handle(ctx context.Context, w io.Writer, level Level, msg string, kvs ...any) error {
-	state := h.newRecordState(ctx, level.String(), msg)
+	state := h.newRecordState(ctx, level, msg)
+	defer state.free()
+
+	state.appendNonBuiltIns(ctx, kvs, nil)
+	return h.writeRecord(ctx, w, &state)
+}
+
+func (h *commonHandler) handleFields(ctx context.Context, w io.Writer, level Level, msg string, fields []Field) error {
+	state := h.newRecordState(ctx, level, msg)
 	defer state.free()
 
-	state.appendNonBuiltIns(ctx, kvs)
-	return h.writeRecord(w, &state)
+	state.appendNonBuiltIns(ctx, nil, fields)
+	return h.writeRecord(ctx, w, &state)
 }
@@ -0,0 +1,283 @@
+// Package handlertest checks that a Handler follows the behavior the Logger
+// and the built-in handlers rely on, so that third-party handlers can
+// validate themselves:
+//
+//	func TestMyHandler(t *testing.T) {
+//		handlertest.TestHandler(t, func(opts *log.HandlerOptions) log.Handler {
+//			return myhandler.New(opts)
+//		}, handlertest.ParseJSON)
+//	}
+package handlertest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/nexuer/log"
+)
+
+// ParseFunc parses one line of handler output into a map. The fields of a
+// group are in a nested map[string]any under the group key.
+type ParseFunc func(line []byte) (map[string]any, error)
+
+// ParseJSON parses a record of the Json handler, or of a handler with the
+// same encoding.
+func ParseJSON(line []byte) (map[string]any, error) {
+	var m map[string]any
+	err := json.Unmarshal(line, &m)
+	return m, err
+}
+
+// ParseText parses a record of the Text handler: the level, then key=value
+// pairs whose keys and values are Go-quoted if needed. Dotted keys are nested
+// as groups.
+func ParseText(line []byte) (map[string]any, error) {
+	m := make(map[string]any)
+	s := string(line)
+	if i := strings.IndexByte(s, ' '); i > 0 && !strings.Contains(s[:i], "=") {
+		m[log.LevelKey], s = s[:i], s[i+1:]
+	} else if i < 0 && s != "" && !strings.Contains(s, "=") {
+		m[log.LevelKey] = s
+		return m, nil
+	}
+	for s != "" {
+		key, rest, err := textToken(s, '=')
+		if err != nil {
+			return nil, err
+		}
+		if rest == "" || rest[0] != '=' {
+			return nil, fmt.Errorf("missing = after key %q", key)
+		}
+		value, rest, err := textToken(rest[1:], ' ')
+		if err != nil {
+			return nil, err
+		}
+		s = strings.TrimPrefix(rest, " ")
+
+		group := m
+		path := strings.Split(key, ".")
+		for _, name := range path[:len(path)-1] {
+			g, ok := group[name].(map[string]any)
+			if !ok {
+				g = make(map[string]any)
+				group[name] = g
+			}
+			group = g
+		}
+		group[path[len(path)-1]] = value
+	}
+	return m, nil
+}
+
+// textToken returns the leading, possibly quoted, token of s that ends at end
+// and the rest of s.
+func textToken(s string, end byte) (string, string, error) {
+	if strings.HasPrefix(s, `"`) {
+		quoted, err := strconv.QuotedPrefix(s)
+		if err != nil {
+			return "", "", err
+		}
+		token, err := strconv.Unquote(quoted)
+		return token, s[len(quoted):], err
+	}
+	if i := strings.IndexByte(s, end); i >= 0 {
+		return s[:i], s[i:], nil
+	}
+	return s, "", nil
+}
+
+type testCase struct {
+	name string
+	opts log.HandlerOptions
+	log  func(l *log.Logger)
+	// want holds the fields the record must have, besides the level, as
+	// strings formatted with %v, or nested maps for groups.
+	want map[string]any
+	// absent holds keys the record must not have.
+	absent []string
+}
+
+var cases = []testCase{
+	{
+		name: "built-ins",
+		log:  func(l *log.Logger) { l.Info("message") },
+		want: map[string]any{log.MessageKey: "message"},
+	},
+	{
+		name: "fields",
+		log:  func(l *log.Logger) { l.InfoS("message", "a", 1, "b", "two", log.Bool("c", true)) },
+		want: map[string]any{log.MessageKey: "message", "a": "1", "b": "two", "c": "true"},
+	},
+	{
+		name: "escaping",
+		log: func(l *log.Logger) {
+			l.InfoS("say \"hi\"\n\tand=leave", "key with space", "a=b c", "quote", `"\`, "unicode", "héllo, 世界")
+		},
+		want: map[string]any{
+			log.MessageKey:   "say \"hi\"\n\tand=leave",
+			"key with space": "a=b c",
+			"quote":          `"\`,
+			"unicode":        "héllo, 世界",
+		},
+	},
+	{
+		name: "With",
+		log:  func(l *log.Logger) { l.With("a", 1).WithFields(log.String("b", "two")).InfoS("message", "c", 3) },
+		want: map[string]any{log.MessageKey: "message", "a": "1", "b": "two", "c": "3"},
+	},
+	{
+		name: "WithGroup",
+		log:  func(l *log.Logger) { l.WithGroup("g").InfoS("message", "a", 1) },
+		want: map[string]any{log.MessageKey: "message", "g": map[string]any{"a": "1"}},
+	},
+	{
+		name: "nested groups",
+		log: func(l *log.Logger) {
+			l.With("a", 1).WithGroup("g").With("b", 2).WithGroup("h").InfoS("message", "c", 3, log.Group("i", "d", 4))
+		},
+		want: map[string]any{
+			log.MessageKey: "message",
+			"a":            "1",
+			"g": map[string]any{
+				"b": "2",
+				"h": map[string]any{"c": "3", "i": map[string]any{"d": "4"}},
+			},
+		},
+	},
+	{
+		name:   "empty group elided",
+		log:    func(l *log.Logger) { l.WithGroup("g").InfoS("message", log.Group("e")) },
+		want:   map[string]any{log.MessageKey: "message"},
+		absent: []string{"g", "e"},
+	},
+	{
+		name:   "empty field elided",
+		log:    func(l *log.Logger) { l.InfoS("message", log.Field{}, "a", 1) },
+		want:   map[string]any{log.MessageKey: "message", "a": "1"},
+		absent: []string{""},
+	},
+	{
+		name: "inline group",
+		log:  func(l *log.Logger) { l.InfoS("message", log.Group("", "a", 1)) },
+		want: map[string]any{log.MessageKey: "message", "a": "1"},
+	},
+	{
+		name: "Replacer",
+		opts: log.HandlerOptions{Replacer: func(_ context.Context, groups []string, field log.Field) log.Field {
+			switch {
+			case groups == nil:
+				return field
+			case field.Key == "drop":
+				return log.Field{}
+			case field.Key == "rename":
+				return log.String("renamed", field.Value.String())
+			case field.Key == "path":
+				return log.String("path", strings.Join(groups, "/"))
+			}
+			return field
+		}},
+		log: func(l *log.Logger) {
+			l.With("drop", 1).WithGroup("g").InfoS("message", "rename", "v", "drop", 2, log.Group("h", "path", ""))
+		},
+		want: map[string]any{
+			log.MessageKey: "message",
+			"g": map[string]any{
+				"renamed": "v",
+				"h":       map[string]any{"path": "g/h"},
+			},
+		},
+		absent: []string{"drop", "rename"},
+	},
+	{
+		name: "Valuer",
+		log: func(l *log.Logger) {
+			l.With(log.Dynamic("with", func(context.Context) log.Value { return log.StringValue("resolved") })).
+				InfoS("message",
+					log.Dynamic("call", func(context.Context) log.Value { return log.IntValue(7) }),
+					log.Dynamic("group", func(context.Context) log.Value { return log.GroupValue(log.Int("a", 1)) }),
+				)
+		},
+		want: map[string]any{
+			log.MessageKey: "message",
+			"with":         "resolved",
+			"call":         "7",
+			"group":        map[string]any{"a": "1"},
+		},
+	},
+}
+
+// TestHandler runs the conformance suite against the handlers returned by
+// newHandler, whose output is parsed with parse. Each record must be written
+// to the writer passed to Handle, followed by a newline. The suite checks
+// the built-in msg field and the level, escaping, With and WithGroup, the
+// elision of empty fields and groups, inline groups, Replacer and Valuer
+// resolution.
+func TestHandler(t *testing.T, newHandler func(opts *log.HandlerOptions) log.Handler, parse ParseFunc) {
+	t.Helper()
+	for _, c := range cases {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			opts := c.opts
+			c.log(log.New(&buf, newHandler(&opts)))
+
+			line, ok := bytes.CutSuffix(buf.Bytes(), []byte("\n"))
+			if !ok || bytes.Contains(line, []byte("\n")) {
+				t.Fatalf("output is not one line ending with a newline: %q", buf.Bytes())
+			}
+			got, err := parse(line)
+			if err != nil {
+				t.Fatalf("parsing %q: %v", line, err)
+			}
+			if level := fmt.Sprint(got[log.LevelKey]); level != log.LevelInfo.String() {
+				t.Errorf("level = %q, want %q in %q", level, log.LevelInfo.String(), line)
+			}
+			delete(got, log.LevelKey)
+			if diff := compare("", got, c.want); diff != "" {
+				t.Errorf("%s\noutput: %s", diff, line)
+			}
+			for _, key := range c.absent {
+				if _, ok := got[key]; ok {
+					t.Errorf("key %q should be absent from %s", key, line)
+				}
+			}
+		})
+	}
+}
+
+// compare returns a description of the first difference between got and the
+// fields of want, or "".
+func compare(prefix string, got map[string]any, want map[string]any) string {
+	keys := make([]string, 0, len(want))
+	for key := range want {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	for _, key := range keys {
+		path := prefix + key
+		g, ok := got[key]
+		if !ok {
+			return fmt.Sprintf("missing %q", path)
+		}
+		if w, ok := want[key].(map[string]any); ok {
+			gm, ok := g.(map[string]any)
+			if !ok {
+				return fmt.Sprintf("%q = %v, want a group", path, g)
+			}
+			if diff := compare(path+".", gm, w); diff != "" {
+				return diff
+			}
+			continue
+		}
+		if s := fmt.Sprint(g); s != want[key] {
+			return fmt.Sprintf("%q = %q, want %q", path, s, want[key])
+		}
+	}
+	return ""
+}
@@ -0,0 +1,27 @@
+package handlertest
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/nexuer/log"
+)
+
+func TestJSON(t *testing.T) {
+	TestHandler(t, func(opts *log.HandlerOptions) log.Handler { return log.Json(opts) }, ParseJSON)
+}
+
+func TestText(t *testing.T) {
+	TestHandler(t, func(opts *log.HandlerOptions) log.Handler { return log.Text(opts) }, ParseText)
+}
+
+func TestParseText(t *testing.T) {
+	got, err := ParseText([]byte(`WARN msg="a \"b\"" g.a=1 "k v"=x`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]any{"level": "WARN", "msg": `a "b"`, "g": map[string]any{"a": "1"}, "k v": "x"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("ParseText = %v, want %v", got, want)
+	}
+}
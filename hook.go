@@ -2,7 +2,57 @@ package log
 
 import "context"
 
-// Replacer transforms user fields and the built-in level, msg, and logger
-// fields. It can change a field's key or value; returning an empty Field removes
+// Replacer transforms user fields and the built-in level, time, msg, and
+// logger fields. It can change a field's key or value; returning an empty Field removes
 // the field. Groups is nil for built-in fields.
 type Replacer func(ctx context.Context, groups []string, field Field) Field
+
+// Record is a log record as seen by a Hook.
+type Record struct {
+	Level   Level
+	Message string
+	// Fields holds the fields passed with the log call, with Valuers,
+	// including those inside groups, already resolved. Fields added with
+	// With or WithFields are encoded when the logger is derived, so they are
+	// not included and a hook can neither see nor change them.
+	Fields []Field
+}
+
+// Hook inspects every record of a Logger before it reaches the handler.
+// Unlike a Replacer, which sees one field at a time, a hook sees the whole
+// record and can change its level, message and fields.
+type Hook interface {
+	// Run is called with the record of each enabled log call. Returning
+	// false drops the record.
+	Run(ctx context.Context, r *Record) bool
+}
+
+// HookFunc adapts a function to a Hook.
+type HookFunc func(ctx context.Context, r *Record) bool
+
+func (f HookFunc) Run(ctx context.Context, r *Record) bool {
+	return f(ctx, r)
+}
+
+// runHooks passes a record through the hooks of l. It returns false if a hook
+// dropped the record.
+func (l *Logger) runHooks(ctx context.Context, level Level, msg string, kvs []any) (Level, string, []any, bool) {
+	if len(l.hooks) == 0 {
+		return level, msg, kvs, true
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	r := Record{Level: level, Message: msg, Fields: resolveFields(ctx, kvsToFieldSlice(kvs))}
+	for _, h := range l.hooks {
+		if !h.Run(ctx, &r) {
+			l.stats.hookDropped.Add(1)
+			return r.Level, r.Message, nil, false
+		}
+	}
+	kvs = make([]any, len(r.Fields))
+	for i, field := range r.Fields {
+		kvs[i] = field
+	}
+	return r.Level, r.Message, kvs, true
+}
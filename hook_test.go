@@ -0,0 +1,60 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"testing"
+)
+
+func TestLoggerHooks(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New(&buf).AddHook(HookFunc(func(_ context.Context, r *Record) bool {
+		if r.Message == "noisy" {
+			return false
+		}
+		r.Fields = append(r.Fields, String("region", "eu"))
+		return true
+	}))
+	child := logger.With("k", "v").AddHook(HookFunc(func(_ context.Context, r *Record) bool {
+		if r.Level == LevelInfo {
+			r.Level = LevelWarn
+			r.Message += "!"
+		}
+		return true
+	}))
+
+	logger.InfoS("started", "port", 80)
+	logger.Info("noisy")
+	child.Info("child")
+
+	want := "INFO msg=started port=80 region=eu\nWARN k=v msg=child! region=eu\n"
+	if got := buf.String(); got != want {
+		t.Fatalf("output = %q, want %q", got, want)
+	}
+	if got := logger.Stats().Dropped; got != 1 {
+		t.Fatalf("Dropped = %d, want 1", got)
+	}
+}
+
+func TestHookSeesResolvedValuers(t *testing.T) {
+	var buf bytes.Buffer
+	var got []Field
+	logger := New(&buf).WithFields(Dynamic("k", func(context.Context) Value { return StringValue("with") })).
+		AddHook(HookFunc(func(_ context.Context, r *Record) bool {
+			got = r.Fields
+			return true
+		}))
+	valuer := func(context.Context) Value { return IntValue(7) }
+
+	logger.InfoS("msg", Dynamic("n", valuer), Group("g", Dynamic("n", valuer)))
+
+	if len(got) != 2 || got[0].Value.Kind() != KindInt64 || got[0].Value.Int64() != 7 {
+		t.Fatalf("fields = %v", got)
+	}
+	if g := got[1].Value.Group(); len(g) != 1 || g[0].Value.Kind() != KindInt64 {
+		t.Fatalf("group = %v", g)
+	}
+	if want := "INFO k=with msg=msg n=7 g.n=7\n"; buf.String() != want {
+		t.Fatalf("output = %q, want %q", buf.String(), want)
+	}
+}
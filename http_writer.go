@@ -0,0 +1,93 @@
+package log
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"time"
+)
+
+// HTTPOptions configures the writer returned by HTTPWriter.
+type HTTPOptions struct {
+	// URL is the ingestion endpoint.
+	URL string
+	// Header is sent with every request, for example the Authorization header
+	// of a Splunk HEC token.
+	Header http.Header
+	// ContentType is the request content type. The default is
+	// application/x-ndjson.
+	ContentType string
+	// Gzip compresses request bodies.
+	Gzip bool
+	// Client sends the requests. The default is http.DefaultClient.
+	Client *http.Client
+
+	// BatchSize is the maximum number of records per request. The default is
+	// 1000.
+	BatchSize int
+	// BatchBytes is the maximum size of the records in a request, before
+	// compression. The default is 1 MiB.
+	BatchBytes int
+	// MaxBufferedBytes bounds the records waiting to be sent, including
+	// requests in flight. The default is 8 times BatchBytes.
+	MaxBufferedBytes int
+	// FlushInterval sends a partial batch after this long. The default is 1
+	// second.
+	FlushInterval time.Duration
+	// MaxInFlight is the number of requests sent concurrently. Batches may
+	// arrive out of order when it is above 1. The default is 1.
+	MaxInFlight int
+	// Backoff retries requests that fail with a transport error, 429 or 5xx.
+	Backoff Backoff
+}
+
+// HTTPWriter returns a writer that POSTs batches of records to an HTTP
+// endpoint, one record per line.
+func HTTPWriter(opts HTTPOptions) (*BatchWriter, error) {
+	if err := checkHTTPURL(opts.URL); err != nil {
+		return nil, err
+	}
+	if opts.ContentType == "" {
+		opts.ContentType = "application/x-ndjson"
+	}
+	header := opts.Header.Clone()
+	if opts.Gzip {
+		if header == nil {
+			header = make(http.Header)
+		}
+		header.Set("Content-Encoding", "gzip")
+	}
+	return newBatchWriter(batchConfig{
+		maxRecords:  opts.BatchSize,
+		maxBytes:    opts.BatchBytes,
+		maxBuffered: opts.MaxBufferedBytes,
+		interval:    opts.FlushInterval,
+		maxInFlight: opts.MaxInFlight,
+		backoff:     opts.Backoff,
+		send: func(batch []batchRecord) ([]batchRecord, error) {
+			_, err := httpPost(opts.Client, opts.URL, header, opts.ContentType, httpBody(batch, opts.Gzip))
+			return batch, err
+		},
+	}), nil
+}
+
+func httpBody(batch []batchRecord, compress bool) []byte {
+	var b bytes.Buffer
+	var w io.Writer = &b
+	var zw *gzip.Writer
+	if compress {
+		zw = gzip.NewWriter(&b)
+		w = zw
+	}
+	for _, r := range batch {
+		_, _ = w.Write(r.data)
+		if len(r.data) == 0 || r.data[len(r.data)-1] != '\n' {
+			_, _ = w.Write([]byte{'\n'})
+		}
+	}
+	if zw != nil {
+		_ = zw.Close()
+	}
+	return b.Bytes()
+}
@@ -0,0 +1,78 @@
+package log
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestHTTPWriterSendsGzipNDJSON(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		lines    []string
+		active   int
+		maxInUse int
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		active++
+		if active > maxInUse {
+			maxInUse = active
+		}
+		mu.Unlock()
+		time.Sleep(20 * time.Millisecond)
+
+		if r.Header.Get("Content-Encoding") != "gzip" || r.Header.Get("Authorization") != "Splunk token" {
+			t.Errorf("headers = %v", r.Header)
+		}
+		zr, err := gzip.NewReader(r.Body)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		body, _ := io.ReadAll(zr)
+
+		mu.Lock()
+		defer mu.Unlock()
+		active--
+		lines = append(lines, strings.SplitAfter(string(body), "\n")...)
+	}))
+	defer srv.Close()
+
+	w, err := HTTPWriter(HTTPOptions{
+		URL:           srv.URL,
+		Header:        http.Header{"Authorization": {"Splunk token"}},
+		Gzip:          true,
+		BatchSize:     1,
+		MaxInFlight:   2,
+		FlushInterval: time.Hour,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger := New(w, Json())
+	for i := 0; i < 6; i++ {
+		logger.InfoS("event", "i", i)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	records := 0
+	for _, line := range lines {
+		if strings.HasPrefix(line, `{"level":"INFO","msg":"event","i":`) {
+			records++
+		}
+	}
+	if records != 6 {
+		t.Fatalf("received %d records, want 6: %q", records, lines)
+	}
+	if maxInUse != 2 {
+		t.Fatalf("max concurrent requests = %d, want 2", maxInUse)
+	}
+}
@@ -6,8 +6,10 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"math"
 	"strconv"
 	"time"
+	"unicode/utf16"
 	"unicode/utf8"
 
 	"github.com/nexuer/log/internal/buffer"
@@ -43,6 +45,10 @@ func (j *jsonHandler) Handle(ctx context.Context, w io.Writer, level Level, msg
 	return j.handler.handle(ctx, w, level, msg, kvs...)
 }
 
+func (j *jsonHandler) HandleFields(ctx context.Context, w io.Writer, level Level, msg string, fields []Field) error {
+	return j.handler.handleFields(ctx, w, level, msg, fields)
+}
+
 // Adapted from time.Time.MarshalJSON to avoid allocation.
 func appendJSONTime(s *handleState, t time.Time) {
 	if y := t.Year(); y < 0 || y >= 10000 {
@@ -84,7 +90,11 @@ func appendJSONValue(s *handleState, v Value) error {
 		// json.Marshal is funny about floats; it doesn't
 		// always match strconv.AppendFloat. So just call it.
 		// That's expensive, but floats are rare.
-		if err := appendJSONMarshal(s.buf, v.Float64()); err != nil {
+		if err := appendJSONMarshal(s.buf, v.Float64(), 0); err != nil {
+			return err
+		}
+	case KindFloat32:
+		if err := appendJSONMarshal(s.buf, v.Float32(), 0); err != nil {
 			return err
 		}
 	case KindBool:
@@ -94,15 +104,30 @@ func appendJSONValue(s *handleState, v Value) error {
 		*s.buf = strconv.AppendInt(*s.buf, int64(v.Duration()), 10)
 	case KindTime:
 		s.appendTime(v.Time())
+	case KindBytes:
+		s.appendBytes(v.bytes())
+	case KindError:
+		err := v.err()
+		if masked, ok := maskStruct(err); ok {
+			return appendJSONMarshal(s.buf, masked, s.h.escape)
+		}
+		if _, ok := err.(json.Marshaler); ok {
+			return appendJSONMarshal(s.buf, err, s.h.escape)
+		}
+		s.appendErr(err)
 	case KindAny:
 		a := v.any
+		if masked, ok := maskStruct(a); ok {
+			a = masked
+		}
+		if c, bitSize, ok := complexValue(a); ok {
+			return appendJSONComplex(s, c, bitSize)
+		}
 		_, jm := a.(json.Marshaler)
-		if err, ok := a.(error); ok && !jm {
-			s.appendString(err.Error())
-		} else if appendJSONSlice(s, a) {
+		if appendJSONSlice(s, a) || s.appendNetIP(a) || !jm && s.appendUUID(a) || s.appendBig(a) {
 			return nil
 		} else {
-			return appendJSONMarshal(s.buf, a)
+			return appendJSONMarshal(s.buf, a, s.h.escape)
 		}
 	default:
 		panic(fmt.Sprintf("bad kind: %s", v.Kind()))
@@ -110,6 +135,29 @@ func appendJSONValue(s *handleState, v Value) error {
 	return nil
 }
 
+// appendJSONComplex writes c as an object with "real" and "imag" members.
+func appendJSONComplex(s *handleState, c complex128, bitSize int) error {
+	re, im := real(c), imag(c)
+	if math.IsNaN(re) || math.IsInf(re, 0) || math.IsNaN(im) || math.IsInf(im, 0) {
+		return fmt.Errorf("json: unsupported value: %v", c)
+	}
+	_, _ = s.buf.WriteString(`{"real":`)
+	appendJSONFloat(s, re, bitSize)
+	_, _ = s.buf.WriteString(`,"imag":`)
+	appendJSONFloat(s, im, bitSize)
+	_ = s.buf.WriteByte('}')
+	return nil
+}
+
+// appendJSONFloat writes a finite f like encoding/json.
+func appendJSONFloat(s *handleState, f float64, bitSize int) {
+	if bitSize == 32 {
+		_ = appendJSONMarshal(s.buf, float32(f), 0)
+	} else {
+		_ = appendJSONMarshal(s.buf, f, 0)
+	}
+}
+
 func appendJSONSource(s *handleState, source *Source) {
 	_ = s.buf.WriteByte('{')
 	s.sep = ""
@@ -139,6 +187,53 @@ func appendJSONSlice(s *handleState, value any) bool {
 		}
 		s.appendByte(']')
 		return true
+	case []bool:
+		if values == nil {
+			_, _ = s.buf.WriteString("null")
+			return true
+		}
+		s.appendByte('[')
+		for i, value := range values {
+			if i > 0 {
+				s.appendByte(',')
+			}
+			*s.buf = strconv.AppendBool(*s.buf, value)
+		}
+		s.appendByte(']')
+		return true
+	case []time.Duration:
+		if values == nil {
+			_, _ = s.buf.WriteString("null")
+			return true
+		}
+		s.appendByte('[')
+		for i, value := range values {
+			if i > 0 {
+				s.appendByte(',')
+			}
+			// Do what json.Marshal does.
+			*s.buf = strconv.AppendInt(*s.buf, int64(value), 10)
+		}
+		s.appendByte(']')
+		return true
+	case []error:
+		if values == nil {
+			_, _ = s.buf.WriteString("null")
+			return true
+		}
+		s.appendByte('[')
+		for i, value := range values {
+			if i > 0 {
+				s.appendByte(',')
+			}
+			if value == nil {
+				_, _ = s.buf.WriteString("null")
+			} else {
+				s.appendErr(value)
+			}
+		}
+		s.appendByte(']')
+		return true
 	case []string:
 		if values == nil {
 			_, _ = s.buf.WriteString("null")
@@ -172,7 +267,7 @@ func appendJSONSlice(s *handleState, value any) bool {
 			if i > 0 {
 				s.appendByte(',')
 			}
-			appendJSONTime(s, value)
+			s.appendTime(value)
 		}
 		s.appendByte(']')
 		return true
@@ -181,31 +276,84 @@ func appendJSONSlice(s *handleState, value any) bool {
 	}
 }
 
-func appendJSONMarshal(buf *buffer.Buffer, v any) error {
+func appendJSONMarshal(buf *buffer.Buffer, v any, esc jsonEscape) error {
 	start := buf.Len()
 	enc := json.NewEncoder(buf)
-	enc.SetEscapeHTML(false)
+	enc.SetEscapeHTML(esc&escapeHTML != 0)
 	if err := enc.Encode(v); err != nil {
 		buf.SetLen(start)
 		return err
 	}
 	buf.SetLen(buf.Len() - 1) // remove Encoder.Encode's final newline
+	if esc&escapeUnicode != 0 {
+		if encoded := (*buf)[start:]; !isASCII(encoded) {
+			*buf = appendASCIIJSON((*buf)[:start], string(encoded))
+		}
+	}
 	return nil
 }
 
+// appendASCIIJSON appends the encoded JSON s with its runes outside ASCII
+// escaped. They only occur inside strings.
+func appendASCIIJSON(buf []byte, s string) []byte {
+	start := 0
+	for i, c := range s {
+		if c < utf8.RuneSelf {
+			continue
+		}
+		buf = append(buf, s[start:i]...)
+		buf = appendUnicodeEscapes(buf, c)
+		start = i + utf8.RuneLen(c)
+	}
+	return append(buf, s[start:]...)
+}
+
+// jsonEscape selects the optional escapes of JSON strings.
+type jsonEscape uint8
+
+const (
+	escapeHTML       jsonEscape = 1 << iota // <, > and &
+	escapeUnicode                           // runes outside ASCII
+	escapeInvalidHex                        // invalid UTF-8 as \\xNN rather than U+FFFD
+)
+
+func (o *HandlerOptions) jsonEscape() jsonEscape {
+	var esc jsonEscape
+	if o.EscapeHTML {
+		esc |= escapeHTML
+	}
+	if o.EscapeUnicode {
+		esc |= escapeUnicode
+	}
+	if o.InvalidUTF8 == InvalidUTF8Escape {
+		esc |= escapeInvalidHex
+	}
+	return esc
+}
+
+func isASCII(b []byte) bool {
+	for _, c := range b {
+		if c >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
+
 // appendEscapedJSONString escapes s for JSON and appends it to buf.
-// It does not surround the string in quotation marks.
+// It does not surround the string in quotation marks. esc adds escapes for
+// HTML characters and non-ASCII runes, and selects how invalid UTF-8 is
+// written.
 //
-// Modified from encoding/json/encode.go:encodeState.string,
-// with escapeHTML set to false.
-func appendEscapedJSONString(buf []byte, s string) []byte {
+// Modified from encoding/json/encode.go:encodeState.string.
+func appendEscapedJSONString(buf []byte, s string, esc jsonEscape) []byte {
 	char := func(b byte) { buf = append(buf, b) }
 	str := func(s string) { buf = append(buf, s...) }
 
 	start := 0
 	for i := 0; i < len(s); {
 		if b := s[i]; b < utf8.RuneSelf {
-			if safeSet[b] {
+			if safeSet[b] && (esc&escapeHTML == 0 || b != '<' && b != '>' && b != '&') {
 				i++
 				continue
 			}
@@ -237,7 +385,13 @@ func appendEscapedJSONString(buf []byte, s string) []byte {
 			if start < i {
 				str(s[start:i])
 			}
-			str(`\ufffd`)
+			if esc&escapeInvalidHex != 0 {
+				str(`\\x`)
+				char(hex[s[i]>>4])
+				char(hex[s[i]&0xF])
+			} else {
+				str(`\ufffd`)
+			}
 			i += size
 			start = i
 			continue
@@ -259,6 +413,15 @@ func appendEscapedJSONString(buf []byte, s string) []byte {
 			start = i
 			continue
 		}
+		if esc&escapeUnicode != 0 {
+			if start < i {
+				str(s[start:i])
+			}
+			buf = appendUnicodeEscapes(buf, c)
+			i += size
+			start = i
+			continue
+		}
 		i += size
 	}
 	if start < len(s) {
@@ -269,6 +432,16 @@ func appendEscapedJSONString(buf []byte, s string) []byte {
 
 const hex = "0123456789abcdef"
 
+// appendUnicodeEscapes appends r as \uXXXX, or as a surrogate pair outside
+// the Basic Multilingual Plane.
+func appendUnicodeEscapes(buf []byte, r rune) []byte {
+	if r > 0xFFFF {
+		r1, r2 := utf16.EncodeRune(r)
+		return appendUnicodeEscapes(appendUnicodeEscapes(buf, r1), r2)
+	}
+	return append(buf, '\\', 'u', hex[r>>12&0xF], hex[r>>8&0xF], hex[r>>4&0xF], hex[r&0xF])
+}
+
 // Copied from encoding/json/tables.go.
 //
 // safeSet holds the value true if the ASCII character with the given array
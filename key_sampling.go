@@ -0,0 +1,115 @@
+package log
+
+import (
+	"context"
+	"io"
+	"math"
+	"sync/atomic"
+)
+
+// KeySamplingOptions configures the handler returned by KeySample.
+type KeySamplingOptions struct {
+	// Key is the field whose value selects the records to keep, such as
+	// user_id or request_id.
+	Key string
+	// Rate is the fraction of key values whose records are kept, from 0 to 1.
+	Rate float64
+}
+
+// KeySamplingHandler keeps the records of a fraction of key values. It is
+// created by KeySample.
+type KeySamplingHandler struct {
+	next     Handler
+	key      string
+	limit    uint64
+	value    Value // the key field added by WithFields, if hasValue
+	hasValue bool
+	dropped  *atomic.Uint64
+}
+
+// KeySample wraps next so that only records whose opts.Key field hashes into
+// the opts.Rate fraction are written. Unlike sampling individual records, all
+// records of a kept user or request are written, so its story stays complete.
+// The decision only depends on the value, so every process keeps the same
+// keys. Records without the field are always written.
+//
+// The field can be added with WithFields or passed with the record; a field
+// inside a group is not considered.
+func KeySample(next Handler, opts KeySamplingOptions) *KeySamplingHandler {
+	var limit uint64
+	switch {
+	case opts.Rate >= 1:
+		limit = math.MaxUint64
+	case opts.Rate > 0:
+		limit = uint64(opts.Rate * math.MaxUint64)
+	}
+	return &KeySamplingHandler{next: next, key: opts.Key, limit: limit, dropped: new(atomic.Uint64)}
+}
+
+func (h *KeySamplingHandler) WithFields(ctx context.Context, fields ...Field) Handler {
+	h2 := *h
+	h2.next = h.next.WithFields(ctx, fields...)
+	for i := range fields {
+		if fields[i].Key == h.key {
+			h2.value, h2.hasValue = fields[i].Value, true
+		}
+	}
+	return &h2
+}
+
+func (h *KeySamplingHandler) WithGroup(name string) Handler {
+	h2 := *h
+	h2.next = h.next.WithGroup(name)
+	return &h2
+}
+
+func (h *KeySamplingHandler) Handle(ctx context.Context, w io.Writer, level Level, msg string, kvs ...any) error {
+	value, ok := h.keyValue(kvs)
+	if ok && !h.keep(ctx, value) {
+		h.dropped.Add(1)
+		return nil
+	}
+	return h.next.Handle(AddCallerDepth(ctx, 1), w, level, msg, kvs...)
+}
+
+// Dropped returns the number of records of key values that are not kept.
+func (h *KeySamplingHandler) Dropped() uint64 {
+	return h.dropped.Load()
+}
+
+func (h *KeySamplingHandler) keyValue(kvs []any) (Value, bool) {
+	var field Field
+	for len(kvs) > 0 {
+		field, kvs = kvsToField(kvs)
+		if field.Key == h.key {
+			return field.Value, true
+		}
+	}
+	if h.hasValue {
+		return h.value, true
+	}
+	return Value{}, false
+}
+
+func (h *KeySamplingHandler) keep(ctx context.Context, v Value) bool {
+	if h.limit == math.MaxUint64 {
+		return true
+	}
+	if v.Kind() == KindValuer {
+		v = v.Resolve(ctx)
+	}
+	return keyHash(v.String()) < h.limit
+}
+
+// keyHash hashes s with 64-bit FNV-1a and mixes the result, so that keys that
+// differ only in their last characters are spread over the whole range.
+func keyHash(s string) uint64 {
+	h := uint64(14695981039346656037)
+	for i := 0; i < len(s); i++ {
+		h = (h ^ uint64(s[i])) * 1099511628211
+	}
+	h ^= h >> 33
+	h *= 0xff51afd7ed558ccd
+	h ^= h >> 33
+	return h
+}
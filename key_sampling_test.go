@@ -0,0 +1,56 @@
+package log
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestKeySampleKeepsWholeKeys(t *testing.T) {
+	var buf bytes.Buffer
+	h := KeySample(Text(), KeySamplingOptions{Key: "user", Rate: 0.25})
+	logger := New(&buf, h)
+
+	kept := 0
+	for i := 0; i < 1000; i++ {
+		user := strconv.Itoa(i)
+		if h.keep(nil, StringValue(user)) {
+			kept++
+		}
+		logger.InfoS("start", "user", user)
+		logger.With("user", user).InfoS("end")
+	}
+	logger.InfoS("no key")
+
+	if kept < 200 || kept > 300 {
+		t.Fatalf("kept %d of 1000 keys, want about 250", kept)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2*kept+1 || lines[len(lines)-1] != `INFO msg="no key"` {
+		t.Fatalf("wrote %d records for %d kept keys", len(lines), kept)
+	}
+	if got := h.Dropped(); got != uint64(2*(1000-kept)) {
+		t.Fatalf("Dropped() = %d, want %d", got, 2*(1000-kept))
+	}
+}
+
+func TestKeySampleCopiesWithFieldsValue(t *testing.T) {
+	h := KeySample(Text(), KeySamplingOptions{Key: "user", Rate: 0.5})
+	var kept, dropped string
+	for i := 0; kept == "" || dropped == ""; i++ {
+		if user := strconv.Itoa(i); h.keep(nil, StringValue(user)) {
+			kept = user
+		} else {
+			dropped = user
+		}
+	}
+	fields := []Field{String("user", kept)}
+	var buf bytes.Buffer
+	logger := New(&buf, h).WithFields(fields...)
+	fields[0] = String("user", dropped)
+	logger.Info("kept")
+	if buf.Len() == 0 {
+		t.Fatal("changing the fields passed to WithFields changed the sampling key")
+	}
+}
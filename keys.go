@@ -0,0 +1,85 @@
+package log
+
+import (
+	"context"
+	"path"
+	"strings"
+	"unicode"
+)
+
+// keyMatcher matches field keys against path.Match patterns, ignoring case.
+type keyMatcher struct {
+	keys  []string // lowercase keys without pattern characters
+	globs []string // lowercase patterns
+}
+
+func newKeyMatcher(patterns []string) keyMatcher {
+	var m keyMatcher
+	for _, pattern := range patterns {
+		pattern = strings.ToLower(pattern)
+		if strings.ContainsAny(pattern, `*?[\`) {
+			m.globs = append(m.globs, pattern)
+		} else {
+			m.keys = append(m.keys, pattern)
+		}
+	}
+	return m
+}
+
+func (m keyMatcher) empty() bool {
+	return len(m.keys) == 0 && len(m.globs) == 0
+}
+
+func (m keyMatcher) match(key string) bool {
+	for _, k := range m.keys {
+		if strings.EqualFold(k, key) {
+			return true
+		}
+	}
+	if len(m.globs) == 0 {
+		return false
+	}
+	if strings.ContainsFunc(key, unicode.IsUpper) {
+		key = strings.ToLower(key)
+	}
+	for _, pattern := range m.globs {
+		if ok, _ := path.Match(pattern, key); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// matchField matches the key of a field and, inside groups, its path with
+// dots joining the group names, such as "http.status".
+func (m keyMatcher) matchField(groups []string, key string) bool {
+	if m.match(key) {
+		return true
+	}
+	if len(groups) == 0 {
+		return false
+	}
+	return m.match(strings.Join(groups, ".") + "." + key)
+}
+
+// replacer returns the Replacer the handlers apply: DropKeys and AllowKeys
+// first, then the Redactor and then the user's Replacer.
+func (o *HandlerOptions) replacer() Replacer {
+	next := o.Redactor.replacer(o.Replacer)
+	drop, allow := newKeyMatcher(o.DropKeys), newKeyMatcher(o.AllowKeys)
+	if drop.empty() && allow.empty() {
+		return next
+	}
+	return func(ctx context.Context, groups []string, field Field) Field {
+		// Built-in fields are never dropped.
+		if groups != nil {
+			if drop.matchField(groups, field.Key) || !allow.empty() && !allow.matchField(groups, field.Key) {
+				return Field{}
+			}
+		}
+		if next != nil {
+			field = next(ctx, groups, field)
+		}
+		return field
+	}
+}
@@ -0,0 +1,43 @@
+package log
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestDropAndAllowKeys(t *testing.T) {
+	for _, test := range []struct {
+		name string
+		opts HandlerOptions
+		want string
+	}{
+		{
+			name: "drop",
+			opts: HandlerOptions{DropKeys: []string{"password", "*_TOKEN", "http.header"}},
+			want: `{"level":"INFO","msg":"login","user":"alice","http":{"status":200}}`,
+		},
+		{
+			name: "allow",
+			opts: HandlerOptions{AllowKeys: []string{"user", "http.s*"}},
+			want: `{"level":"INFO","msg":"login","user":"alice","http":{"status":200}}`,
+		},
+		{
+			name: "allow removes empty groups",
+			opts: HandlerOptions{AllowKeys: []string{"user"}},
+			want: `{"level":"INFO","msg":"login","user":"alice"}`,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			New(&buf, Json(&test.opts)).InfoS("login",
+				"user", "alice",
+				"password", "hunter2",
+				"api_token", "t",
+				Group("http", "status", 200, "header", "x"),
+			)
+			if got := buf.String(); got != test.want+"\n" {
+				t.Fatalf("output = %s, want %s", got, test.want)
+			}
+		})
+	}
+}
@@ -17,6 +17,20 @@ const (
 	LevelFatal Level = 12
 )
 
+// LevelEncoding selects how handlers encode the built-in level field.
+type LevelEncoding int
+
+const (
+	// LevelName encodes the level as its name, such as "INFO" or "WARN+2".
+	LevelName LevelEncoding = iota
+	// LevelNumber encodes the level as its integer value, such as 0 for
+	// LevelInfo.
+	LevelNumber
+	// LevelSyslogSeverity encodes the level as its RFC 5424 severity code from
+	// SyslogSeverity, such as 6 for LevelInfo.
+	LevelSyslogSeverity
+)
+
 func (l Level) Enable(level Level) bool {
 	return level >= l
 }
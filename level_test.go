@@ -1,6 +1,8 @@
 package log
 
 import (
+	"bytes"
+	"context"
 	"strconv"
 	"testing"
 )
@@ -192,3 +194,42 @@ func TestParseLevel(t *testing.T) {
 		}
 	}
 }
+
+func TestLevelEncoding(t *testing.T) {
+	tests := []struct {
+		encoding LevelEncoding
+		want     string
+	}{
+		{LevelName, `{"level":"WARN+2","msg":"disk"}`},
+		{LevelNumber, `{"level":6,"msg":"disk"}`},
+		{LevelSyslogSeverity, `{"level":4,"msg":"disk"}`},
+	}
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		logger := New(&buf, Json(&HandlerOptions{LevelEncoding: tt.encoding}))
+		_ = logger.Log(context.Background(), LevelWarn+2, "disk")
+		if got := buf.String(); got != tt.want+"\n" {
+			t.Errorf("encoding %d: output = %q, want %q", tt.encoding, got, tt.want)
+		}
+	}
+
+	var buf bytes.Buffer
+	New(&buf, Text(&HandlerOptions{LevelEncoding: LevelNumber})).Info("text")
+	if got, want := buf.String(), "INFO msg=text\n"; got != want {
+		t.Fatalf("text output = %q, want %q", got, want)
+	}
+}
+
+func TestLoggerEnabled(t *testing.T) {
+	logger := NewWithOptions(Discard, WithLevel(LevelWarn))
+	if logger.Level() != LevelWarn {
+		t.Fatalf("Level() = %v, want WARN", logger.Level())
+	}
+	if logger.Enabled(LevelInfo) || !logger.Enabled(LevelWarn) || !logger.Enabled(LevelError) {
+		t.Fatal("Enabled does not follow the level")
+	}
+	logger.SetLevel(LevelDebug)
+	if !logger.Enabled(LevelDebug) || logger.With("k", "v").Level() != LevelDebug {
+		t.Fatal("Enabled does not follow SetLevel")
+	}
+}
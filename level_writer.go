@@ -0,0 +1,79 @@
+package log
+
+import (
+	"bytes"
+	"io"
+	"sync"
+)
+
+// maxWriterLevelLine bounds the bytes a writer returned by WriterLevel holds
+// for a line without a newline.
+const maxWriterLevelLine = 64 << 10
+
+// WriterLevel returns a writer that logs each line written to it as a record
+// of l at level, without the line ending. A line without a trailing newline
+// is held until the rest arrives or the writer is closed, or logged as is
+// once it reaches 64 KiB; empty lines are dropped. Use it for the stderr of
+// an exec.Cmd or a library that takes an io.Writer:
+//
+//	cmd.Stderr = logger.WriterLevel(log.LevelWarn)
+//
+// If a line fails to log, Write returns the error and the number of bytes of p
+// before that line; a held part of the line is kept. Close logs the held
+// line, if any, and does not close l. The writer is safe for concurrent use.
+func (l *Logger) WriterLevel(level Level) io.WriteCloser {
+	return &levelWriter{logger: l, level: level}
+}
+
+type levelWriter struct {
+	logger *Logger
+	level  Level
+
+	mu  sync.Mutex
+	buf []byte
+}
+
+func (w *levelWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	held := len(w.buf)
+	w.buf = append(w.buf, p...)
+	// start is the start of the first line not logged yet.
+	start := 0
+	for {
+		rest := w.buf[start:]
+		end := bytes.IndexByte(rest, '\n')
+		next := end + 1
+		if end < 0 {
+			if len(rest) < maxWriterLevelLine {
+				break
+			}
+			// Log a long line without a newline rather than hold it.
+			end, next = len(rest), len(rest)
+		}
+		if line := bytes.TrimSuffix(rest[:end], []byte{'\r'}); len(line) > 0 {
+			if err := w.logger.log(w.level, string(line), nil); err != nil {
+				// Keep the held bytes that were not logged; the rest of p
+				// is not consumed.
+				n := max(start-held, 0)
+				w.buf = append(w.buf[:0], w.buf[start:max(start, held)]...)
+				return n, err
+			}
+		}
+		start += next
+	}
+	// Keep the partial line at the start of the buffer.
+	w.buf = append(w.buf[:0], w.buf[start:]...)
+	return len(p), nil
+}
+
+func (w *levelWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	line := bytes.TrimSuffix(w.buf, []byte{'\r'})
+	w.buf = nil
+	if len(line) == 0 {
+		return nil
+	}
+	return w.logger.log(w.level, string(line), nil)
+}
@@ -0,0 +1,120 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func TestWriterLevel(t *testing.T) {
+	var buf bytes.Buffer
+	w := New(&buf, Text()).WriterLevel(LevelWarn)
+	for _, p := range []string{"first line\r\nsec", "ond\n\n", "partial"} {
+		if n, err := w.Write([]byte(p)); n != len(p) || err != nil {
+			t.Fatalf("Write(%q) = %d, %v", p, n, err)
+		}
+	}
+	want := "WARN msg=\"first line\"\nWARN msg=second\n"
+	if got := buf.String(); got != want {
+		t.Fatalf("output = %q, want %q", got, want)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	want += "WARN msg=partial\n"
+	if got := buf.String(); got != want {
+		t.Fatalf("output after Close = %q, want %q", got, want)
+	}
+
+	buf.Reset()
+	NewWithOptions(&buf, WithLevel(LevelError)).WriterLevel(LevelWarn).Write([]byte("hidden\n"))
+	if buf.Len() != 0 {
+		t.Fatalf("output below the level = %q", buf.String())
+	}
+}
+
+func TestWriterLevelCaller(t *testing.T) {
+	var buf bytes.Buffer
+	w := New(&buf, Json()).WithFields(DefaultFields...).WriterLevel(LevelError)
+	w.Write([]byte("direct\n"))
+	var record struct {
+		Caller Source `json:"caller"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(record.Caller.Function, "TestWriterLevelCaller") {
+		t.Fatalf("caller = %+v", record.Caller)
+	}
+}
+
+func TestWriterLevelCmd(t *testing.T) {
+	sh, err := exec.LookPath("sh")
+	if err != nil {
+		t.Skip(err)
+	}
+	var buf lockedBuffer
+	w := New(&buf, Text()).WriterLevel(LevelError)
+	cmd := exec.Command(sh, "-c", "echo one >&2; printf two >&2")
+	cmd.Stderr = w
+	if err := cmd.Run(); err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+	want := "ERROR msg=one\nERROR msg=two\n"
+	if got := buf.String(); got != want {
+		t.Fatalf("output = %q, want %q", got, want)
+	}
+}
+
+func TestWriterLevelFailedLine(t *testing.T) {
+	out := &failingWriter{}
+	w := New(out, Text()).WriterLevel(LevelWarn)
+	if _, err := w.Write([]byte("held")); err != nil {
+		t.Fatal(err)
+	}
+	out.fail = true
+	p := []byte(" line\nsecond\nthird\n")
+	if n, err := w.Write(p); n != 0 || err == nil {
+		t.Fatalf("Write = %d, %v, want 0 and an error", n, err)
+	}
+	out.fail = false
+	if n, err := w.Write(p); n != len(p) || err != nil {
+		t.Fatalf("Write = %d, %v", n, err)
+	}
+	want := "WARN msg=\"held line\"\nWARN msg=second\nWARN msg=third\n"
+	if got := out.String(); got != want {
+		t.Fatalf("output = %q, want %q", got, want)
+	}
+
+	// A failure after the first line consumes only that line.
+	out = &failingWriter{}
+	w = New(out, Text()).AddHook(HookFunc(func(_ context.Context, r *Record) bool {
+		out.fail = r.Message == "second"
+		return true
+	})).WriterLevel(LevelWarn)
+	if n, err := w.Write([]byte("first\nsecond\n")); n != len("first\n") || err == nil {
+		t.Fatalf("Write = %d, %v, want %d and an error", n, err, len("first\n"))
+	}
+}
+
+func TestWriterLevelLongLine(t *testing.T) {
+	var buf bytes.Buffer
+	w := New(&buf, Text()).WriterLevel(LevelWarn)
+	long := strings.Repeat("x", maxWriterLevelLine)
+	if _, err := w.Write([]byte(long[:100])); err != nil {
+		t.Fatal(err)
+	}
+	if buf.Len() != 0 {
+		t.Fatalf("short partial line logged: %q", buf.String())
+	}
+	if _, err := w.Write([]byte(long[100:] + "tail")); err != nil {
+		t.Fatal(err)
+	}
+	if want := "WARN msg=" + long + "tail\n"; buf.String() != want {
+		t.Fatalf("output has %d bytes, want %d", buf.Len(), len(want))
+	}
+}
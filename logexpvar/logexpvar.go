@@ -0,0 +1,44 @@
+package logexpvar
+
+import (
+	"expvar"
+	"sync"
+
+	"github.com/nexuer/log"
+)
+
+// Name is the expvar map the stats are published under.
+const Name = "nexuer.log"
+
+var (
+	once sync.Once
+	vars *expvar.Map
+)
+
+// Publish publishes the result of stats under name in the Name expvar map,
+// which the expvar package serves at /debug/vars. stats is usually the Stats
+// method of a log.Logger or a logmgr.Manager. Publishing again under the same
+// name replaces the previous entry.
+func Publish(name string, stats func() log.Stats) {
+	once.Do(func() {
+		vars = expvar.NewMap(Name)
+	})
+	vars.Set(name, expvar.Func(func() any {
+		s := stats()
+		return map[string]any{
+			"records":       s.Records,
+			"bytes":         s.Bytes,
+			"write_errors":  s.WriteErrors,
+			"dropped":       s.Dropped,
+			"queue_dropped": s.QueueDropped,
+			"queue_depth":   s.Queued,
+			"levels": map[string]uint64{
+				"debug": s.Levels.Debug,
+				"info":  s.Levels.Info,
+				"warn":  s.Levels.Warn,
+				"error": s.Levels.Error,
+				"fatal": s.Levels.Fatal,
+			},
+		}
+	}))
+}
@@ -0,0 +1,31 @@
+package logexpvar
+
+import (
+	"bytes"
+	"encoding/json"
+	"expvar"
+	"testing"
+
+	"github.com/nexuer/log"
+)
+
+func TestPublish(t *testing.T) {
+	var buf bytes.Buffer
+	logger := log.New(&buf)
+	Publish("app", logger.Stats)
+	logger.Info("one")
+	logger.With("k", "v").ErrorS("two")
+
+	var got struct {
+		Records    uint64            `json:"records"`
+		QueueDepth uint64            `json:"queue_depth"`
+		Levels     map[string]uint64 `json:"levels"`
+	}
+	v := expvar.Get(Name).(*expvar.Map).Get("app")
+	if err := json.Unmarshal([]byte(v.String()), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Records != 2 || got.Levels["info"] != 1 || got.Levels["error"] != 1 || got.Levels["debug"] != 0 {
+		t.Fatalf("published stats = %s", v)
+	}
+}
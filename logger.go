@@ -2,13 +2,12 @@ package log
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
-	"os"
+	"sync/atomic"
 )
 
-var exitFunc = os.Exit
-
 type Handler interface {
 	WithFields(ctx context.Context, fields ...Field) Handler
 	WithGroup(name string) Handler
@@ -17,6 +16,13 @@ type Handler interface {
 	Handle(ctx context.Context, w io.Writer, level Level, msg string, kvs ...any) error
 }
 
+// FieldHandler is implemented by handlers that can handle a record whose
+// fields are all Fields without the []any of Handle. The Attrs methods of
+// Logger use it when the Logger has no hooks.
+type FieldHandler interface {
+	HandleFields(ctx context.Context, w io.Writer, level Level, msg string, fields []Field) error
+}
+
 // Keys for "built-in" attributes.
 const (
 	// LevelKey is the key used by the built-in handlers for the level
@@ -29,55 +35,159 @@ const (
 	NameKey = "logger"
 	// ErrKey is the key used by the built-in handlers for the error message.
 	ErrKey = "err"
+	// ErrsKey is the key used by Err for the errors of a joined error.
+	ErrsKey = "errs"
+	// TimeKey is the key used by handlers that record the time of the log call.
+	TimeKey = "time"
+	// SourceKey is the key used by the built-in handlers for the source file
+	// and line of the log call. The associated value is a *Source.
+	SourceKey = "source"
 )
 
 type Logger struct {
-	ctx     context.Context
-	level   Level
-	handler Handler
-	w       io.WriteCloser
+	ctx   context.Context
+	level Level
+	name  string
+	// callerSkip is the number of wrapper frames added by AddCallerSkip. It
+	// is included in the caller depth of ctx.
+	callerSkip int
+	// handler and output are replaced as a whole by SetHandler and
+	// SetOutput, so they can be changed while other goroutines log.
+	handler atomic.Pointer[loggerHandler]
+	output  atomic.Pointer[loggerOutput]
+	stats   *loggerStats
+	hooks   []Hook
+	// valuers is whether fields added by With or WithFields have Valuers,
+	// whose results are then cached for each record.
+	valuers bool
+}
+
+// loggerHandler is the handler of a Logger.
+type loggerHandler struct {
+	Handler
+	// capturePC is whether the handler uses the call site of records.
+	capturePC bool
+}
+
+// loggerOutput is the output of a Logger.
+type loggerOutput struct {
+	w   io.WriteCloser
+	out io.Writer // w, counting into stats
+}
+
+// New returns a Logger that writes to w with h, or with the Text handler if h
+// is omitted. NewWithOptions also sets the level, fields and name.
+func New(w io.Writer, h ...Handler) *Logger {
+	if len(h) > 0 && h[0] != nil {
+		return NewWithOptions(w, WithHandler(h[0]))
+	}
+	return NewWithOptions(w)
 }
 
-func New(w io.Writer, h ...Handler) *Logger {
+// NewWithOptions returns a Logger that writes to w, with the Text handler
+// unless opts set another:
+//
+//	logger := log.NewWithOptions(os.Stdout, log.WithHandler(log.Json()), log.WithLevel(log.LevelDebug), log.WithName("api"))
+func NewWithOptions(w io.Writer, opts ...Option) *Logger {
 	if w == nil {
 		w = io.Discard
 	}
 	l := &Logger{
-		w: addWriteCloser(w),
-	}
-	if len(h) > 0 && h[0] != nil {
-		l.handler = h[0]
-	} else {
-		l.handler = Text()
+		stats: new(loggerStats),
 	}
+	l.setOutput(w)
+	l.setHandler(Text(), false)
+	l.applyOptions(newLoggerOptions(opts))
 	return l
 }
 
 func (l *Logger) clone() *Logger {
-	return &Logger{
-		ctx:     l.ctx,
-		w:       l.w,
-		level:   l.level,
-		handler: l.handler,
+	l2 := &Logger{
+		ctx:        l.ctx,
+		stats:      l.stats,
+		level:      l.level,
+		name:       l.name,
+		callerSkip: l.callerSkip,
+		hooks:      l.hooks,
+		valuers:    l.valuers,
 	}
+	l2.handler.Store(l.handler.Load())
+	l2.output.Store(l.output.Load())
+	return l2
+}
+
+func (l *Logger) setHandler(h Handler, capturePC bool) {
+	l.handler.Store(&loggerHandler{Handler: h, capturePC: capturePC})
 }
 
+// Close closes the output and, if it implements io.Closer, the handler, such
+// as a handler that delivers records in the background.
 func (l *Logger) Close() error {
-	if l.w == nil {
-		return nil
+	var errs []error
+	if c, ok := l.Handler().(io.Closer); ok {
+		errs = append(errs, c.Close())
+	}
+	if w := l.output.Load().w; w != nil {
+		errs = append(errs, w.Close())
+	}
+	return errors.Join(errs...)
+}
+
+// Sync flushes the records buffered by the output of l, such as the queue of
+// an AsyncWriter or the batches of an exporter, and commits them to stable
+// storage if the output is a WriteSyncer, such as an *os.File, or a file of
+// FileWriter. Standard streams that cannot be synced, such as terminals and
+// pipes, are skipped.
+func (l *Logger) Sync() error {
+	return syncWriter(l.Writer())
+}
+
+// CloseContext is Close that waits for the output and handler to deliver the
+// records they hold, such as the queue of an AsyncWriter or the batches of an
+// exporter, only until ctx is done, rather than without bound. Outputs with a
+// CloseContext method discard what they still hold at that point and count it
+// in Stats().QueueDropped. CloseContext returns the number of records
+// discarded that way, and ctx.Err() if ctx was done first.
+func (l *Logger) CloseContext(ctx context.Context) (uint64, error) {
+	before := writerDropped(l.Writer())
+	var errs []error
+	if c, ok := l.Handler().(io.Closer); ok {
+		errs = append(errs, closeContext(ctx, c))
+	}
+	if w := l.output.Load().w; w != nil {
+		errs = append(errs, closeContext(ctx, w))
 	}
-	return l.w.Close()
+	return writerDropped(l.Writer()) - before, errors.Join(errs...)
+}
+
+// closeContext closes c with its CloseContext method if it has one.
+func closeContext(ctx context.Context, c io.Closer) error {
+	if cc, ok := c.(interface {
+		CloseContext(ctx context.Context) error
+	}); ok {
+		return cc.CloseContext(ctx)
+	}
+	return c.Close()
 }
 
 func (l *Logger) Writer() io.Writer {
-	switch w := l.w.(type) {
+	switch w := l.output.Load().w.(type) {
 	case writerWrapper:
 		return w.Writer
 	default:
-		return l.w
+		return w
 	}
 }
 
+// Handler returns the current Handler of l, including the fields and groups
+// added by With, WithFields and WithGroup. It is nil after SetHandler(nil).
+// Wrap it to add middleware:
+//
+//	logger.SetHandler(log.Dedup(logger.Handler()))
+func (l *Logger) Handler() Handler {
+	return l.handler.Load().Handler
+}
+
 func (l *Logger) Context() context.Context {
 	return l.ctx
 }
@@ -92,16 +202,39 @@ func (l *Logger) SetLevel(level Level) *Logger {
 	return l
 }
 
-// SetOutput set the current io.Writer
-// Note: This is not concurrency-safe.
+// Level returns the current minimum severity level of l.
+func (l *Logger) Level() Level {
+	return l.level
+}
+
+// Enabled reports whether l logs records of level, so callers can skip
+// computing the fields of records that would be dropped:
+//
+//	if logger.Enabled(log.LevelDebug) {
+//		logger.DebugS("state", "dump", expensiveDump())
+//	}
+func (l *Logger) Enabled(level Level) bool {
+	return l.level.Enable(level)
+}
+
+// SetOutput set the current io.Writer. It is safe to call while other
+// goroutines log with l: each record is written to either the old or the new
+// writer.
 func (l *Logger) SetOutput(w io.Writer) *Logger {
 	if l.Writer() == w {
 		return l
 	}
-	l.w = addWriteCloser(w)
+	l.setOutput(w)
 	return l
 }
 
+func (l *Logger) setOutput(w io.Writer) {
+	wc := addWriteCloser(w)
+	l.output.Store(&loggerOutput{w: wc, out: countedWriter(wc, l.stats)})
+}
+
+// Write logs p as one record at LevelInfo. WriterLevel returns a writer that
+// logs each line at a chosen level.
 func (l *Logger) Write(p []byte) (n int, err error) {
 	err = l.log(LevelInfo, string(p), nil)
 	if err != nil {
@@ -110,13 +243,22 @@ func (l *Logger) Write(p []byte) (n int, err error) {
 	return len(p), nil
 }
 
-// SetHandler set the current Handler
-// Note: This is not concurrency-safe.
+// SetHandler set the current Handler. It is safe to call while other
+// goroutines log with l: each record is handled by either the old or the new
+// handler.
 func (l *Logger) SetHandler(h Handler) *Logger {
-	if l.handler == h {
+	if l.Handler() == h {
 		return l
 	}
-	l.handler = h
+	l.setHandler(h, handlerNeedsPC(h))
+	return l
+}
+
+// AddHook adds a hook that runs for every record of l and of the loggers
+// later derived from it, in the order the hooks were added.
+// Note: This is not concurrency-safe.
+func (l *Logger) AddHook(h Hook) *Logger {
+	l.hooks = append(l.hooks[:len(l.hooks):len(l.hooks)], h)
 	return l
 }
 
@@ -125,9 +267,15 @@ func (l *Logger) log(level Level, template string, fmtArgs []any, kvs ...any) er
 		return nil
 	}
 
-	if l.handler != nil {
+	if h := l.handler.Load(); h.Handler != nil {
+		l.stats.countLevel(level)
+		ctx := l.ctx
+		if h.capturePC {
+			// Skip the level method, such as Info.
+			ctx = contextWithPC(ctx, callerPC(2+callerDepth(ctx)))
+		}
 		msg := getMessage(template, fmtArgs)
-		return l.Handle(l.ctx, l.w, level, msg, kvs...)
+		return l.Handle(ctx, l.output.Load().out, level, msg, kvs...)
 	}
 	return nil
 }
@@ -137,50 +285,179 @@ func (l *Logger) Log(ctx context.Context, level Level, msg string, kvs ...any) e
 		return nil
 	}
 
-	if l.handler != nil {
+	if h := l.handler.Load(); h.Handler != nil {
+		l.stats.countLevel(level)
+		if h.capturePC {
+			ctx = contextWithPC(ctx, callerPC(1+l.callerSkip+callerDepth(ctx)))
+		}
 		// Log has one fewer wrapper frame than the level-specific methods.
-		return l.Handle(AddCallerDepth(ctx, -1), l.w, level, msg, kvs...)
+		return l.Handle(AddCallerDepth(l.nameContext(ctx), l.callerSkip-1), l.output.Load().out, level, msg, kvs...)
+	}
+	return nil
+}
+
+// LogAttrs is a more efficient version of Log that accepts only Fields, like
+// slog.Logger.LogAttrs.
+func (l *Logger) LogAttrs(ctx context.Context, level Level, msg string, fields ...Field) error {
+	if !l.level.Enable(level) {
+		return nil
+	}
+
+	if h := l.handler.Load(); h.Handler != nil {
+		l.stats.countLevel(level)
+		if h.capturePC {
+			ctx = contextWithPC(ctx, callerPC(1+l.callerSkip+callerDepth(ctx)))
+		}
+		// LogAttrs has one fewer wrapper frame than the level-specific methods.
+		return l.handleFields(AddCallerDepth(l.nameContext(ctx), l.callerSkip-1), level, msg, fields)
 	}
 	return nil
 }
 
+func (l *Logger) logAttrs(ctx context.Context, level Level, msg string, fields []Field) error {
+	if !l.level.Enable(level) {
+		return nil
+	}
+
+	if h := l.handler.Load(); h.Handler != nil {
+		l.stats.countLevel(level)
+		if h.capturePC {
+			// Skip the level method, such as InfoAttrs.
+			ctx = contextWithPC(ctx, callerPC(2+callerDepth(ctx)))
+		}
+		return l.handleFields(ctx, level, msg, fields)
+	}
+	return nil
+}
+
+// handleFields is Handle for Fields. It has the same depth as Handle, so the
+// Caller depths of both paths match.
+func (l *Logger) handleFields(ctx context.Context, level Level, msg string, fields []Field) error {
+	if l.valuers {
+		ctx = withValuerCache(ctx)
+	}
+	fields = nestNamespaceFields(fields)
+	h := l.Handler()
+	if h == nil {
+		return nil
+	}
+	out := l.output.Load().out
+	if fh, ok := h.(FieldHandler); ok && len(l.hooks) == 0 {
+		return fh.HandleFields(ctx, out, level, msg, fields)
+	}
+	level, msg, kvs, ok := l.runHooks(ctx, level, msg, fieldsToKVs(fields))
+	if !ok {
+		return nil
+	}
+	return h.Handle(ctx, out, level, msg, kvs...)
+}
+
 func (l *Logger) Handle(ctx context.Context, w io.Writer, level Level, msg string, kvs ...any) error {
-	return l.handler.Handle(ctx, w, level, msg, kvs...)
+	if l.valuers {
+		ctx = withValuerCache(ctx)
+	}
+	h := l.Handler()
+	if h == nil {
+		return nil
+	}
+	level, msg, kvs, ok := l.runHooks(ctx, level, msg, nestNamespaces(kvs))
+	if !ok {
+		return nil
+	}
+	return h.Handle(ctx, w, level, msg, kvs...)
 }
 
 func (l *Logger) With(kvs ...any) *Logger {
-	if len(kvs) == 0 || l.handler == nil {
+	if len(kvs) == 0 || l.Handler() == nil {
 		return l
 	}
-	l2 := l.clone()
-	l2.handler = l.handler.WithFields(l.ctx, kvsToFieldSlice(kvs)...)
-	return l2
+	return l.withFields(kvsToFieldSlice(kvs))
 }
 
 func (l *Logger) WithFields(fields ...Field) *Logger {
-	if len(fields) == 0 || l.handler == nil {
+	if len(fields) == 0 || l.Handler() == nil {
 		return l
 	}
+	return l.withFields(fields)
+}
+
+func (l *Logger) withFields(fields []Field) *Logger {
 	l2 := l.clone()
-	l2.handler = l.handler.WithFields(l.ctx, fields...)
+	l2.addFields(fields)
 	return l2
 }
 
+// addFields adds fields to the handler of l.
+func (l *Logger) addFields(fields []Field) {
+	h := l.handler.Load()
+	next := withFields(l.ctx, h.Handler, fields)
+	l.setHandler(next, h.capturePC || hasCallSite(fields) || handlerNeedsPC(next))
+	l.valuers = l.valuers || hasValuer(fields)
+}
+
 func (l *Logger) WithGroup(name string) *Logger {
-	if name == "" || l.handler == nil {
+	h := l.handler.Load()
+	if name == "" || h.Handler == nil {
 		return l
 	}
 	l2 := l.clone()
-	l2.handler = l.handler.WithGroup(name)
+	l2.setHandler(h.WithGroup(name), h.capturePC)
 	return l2
 }
 
 func (l *Logger) WithContext(ctx context.Context) *Logger {
 	l2 := l.clone()
 	l2.ctx = ctx
+	if l.callerSkip != 0 {
+		l2.ctx = AddCallerDepth(ctx, l.callerSkip)
+	}
+	if l.name != "" {
+		l2.setName(l.name)
+	}
 	return l2
 }
 
+// AddCallerSkip returns a logger derived from l that skips n more frames when
+// it reports the call site, for the CallSite and Caller fields and
+// HandlerOptions.AddSource, so a package wrapping the methods of l reports
+// the caller of the wrapper:
+//
+//	func Warn(msg string) {
+//		logger.AddCallerSkip(1).Warn(msg)
+//	}
+//
+// The skip applies to the contexts of Log and LogAttrs and is kept by
+// WithContext.
+func (l *Logger) AddCallerSkip(n int) *Logger {
+	if n == 0 {
+		return l
+	}
+	l2 := l.clone()
+	l2.callerSkip += n
+	l2.ctx = AddCallerDepth(l.ctx, n)
+	return l2
+}
+
+// Named returns a logger derived from l whose name is the name of l and name
+// joined with a dot, so New(w).Named("db").Named("pool") is named "db.pool".
+// The built-in handlers emit it as the NameKey field.
+func (l *Logger) Named(name string) *Logger {
+	if name == "" {
+		return l
+	}
+	if l.name != "" {
+		name = l.name + "." + name
+	}
+	l2 := l.clone()
+	l2.setName(name)
+	return l2
+}
+
+// Name returns the name of l set by WithName or Named.
+func (l *Logger) Name() string {
+	return l.name
+}
+
 // Debug logs a message at debug level.
 func (l *Logger) Debug(args ...any) {
 	err := l.log(LevelDebug, "", args)
@@ -199,6 +476,13 @@ func (l *Logger) DebugS(msg string, kvs ...any) {
 	errorHandler(err)
 }
 
+// DebugT logs a message at debug level with key vals, replacing each {key} in
+// the template with the value of key.
+func (l *Logger) DebugT(template string, kvs ...any) {
+	err := l.logT(LevelDebug, template, kvs)
+	errorHandler(err)
+}
+
 // Info logs a message at info level.
 func (l *Logger) Info(args ...any) {
 	err := l.log(LevelInfo, "", args)
@@ -217,6 +501,13 @@ func (l *Logger) InfoS(msg string, kvs ...any) {
 	errorHandler(err)
 }
 
+// InfoT logs a message at info level with key vals, replacing each {key} in
+// the template with the value of key.
+func (l *Logger) InfoT(template string, kvs ...any) {
+	err := l.logT(LevelInfo, template, kvs)
+	errorHandler(err)
+}
+
 // Warn logs a message at warn level.
 func (l *Logger) Warn(args ...any) {
 	err := l.log(LevelWarn, "", args)
@@ -235,6 +526,13 @@ func (l *Logger) WarnS(msg string, kvs ...any) {
 	errorHandler(err)
 }
 
+// WarnT logs a message at warn level with key vals, replacing each {key} in
+// the template with the value of key.
+func (l *Logger) WarnT(template string, kvs ...any) {
+	err := l.logT(LevelWarn, template, kvs)
+	errorHandler(err)
+}
+
 // Error logs a message at error level.
 func (l *Logger) Error(args ...any) {
 	err := l.log(LevelError, "", args)
@@ -253,12 +551,19 @@ func (l *Logger) ErrorS(msg string, kvs ...any) {
 	errorHandler(err)
 }
 
+// ErrorT logs a message at error level with key vals, replacing each {key} in
+// the template with the value of key.
+func (l *Logger) ErrorT(template string, kvs ...any) {
+	err := l.logT(LevelError, template, kvs)
+	errorHandler(err)
+}
+
 // Fatal logs a message at fatal level.
 func (l *Logger) Fatal(args ...any) {
 	err := l.log(LevelFatal, "", args)
 	errorHandler(err)
 
-	exitFunc(1)
+	l.fatalExit(getMessage("", args), nil)
 }
 
 // Fatalf logs a formatted message at fatal level.
@@ -266,7 +571,7 @@ func (l *Logger) Fatalf(format string, args ...any) {
 	err := l.log(LevelFatal, format, args)
 	errorHandler(err)
 
-	exitFunc(1)
+	l.fatalExit(getMessage(format, args), nil)
 }
 
 // FatalS logs a message at fatal level with key vals.
@@ -274,7 +579,56 @@ func (l *Logger) FatalS(msg string, kvs ...any) {
 	err := l.log(LevelFatal, msg, nil, kvs...)
 	errorHandler(err)
 
-	exitFunc(1)
+	l.fatalExit(msg, kvs)
+}
+
+// FatalT logs a message at fatal level with key vals, replacing each {key} in
+// the template with the value of key.
+func (l *Logger) FatalT(template string, kvs ...any) {
+	err := l.logT(LevelFatal, template, kvs)
+	errorHandler(err)
+
+	l.fatalExit(formatTemplate(l.ctx, template, kvs), kvs)
+}
+
+// DebugAttrs logs a message at debug level with fields.
+func (l *Logger) DebugAttrs(msg string, fields ...Field) {
+	err := l.logAttrs(l.ctx, LevelDebug, msg, fields)
+	errorHandler(err)
+}
+
+// InfoAttrs logs a message at info level with fields.
+func (l *Logger) InfoAttrs(msg string, fields ...Field) {
+	err := l.logAttrs(l.ctx, LevelInfo, msg, fields)
+	errorHandler(err)
+}
+
+// WarnAttrs logs a message at warn level with fields.
+func (l *Logger) WarnAttrs(msg string, fields ...Field) {
+	err := l.logAttrs(l.ctx, LevelWarn, msg, fields)
+	errorHandler(err)
+}
+
+// ErrorAttrs logs a message at error level with fields.
+func (l *Logger) ErrorAttrs(msg string, fields ...Field) {
+	err := l.logAttrs(l.ctx, LevelError, msg, fields)
+	errorHandler(err)
+}
+
+// FatalAttrs logs a message at fatal level with fields.
+func (l *Logger) FatalAttrs(msg string, fields ...Field) {
+	err := l.logAttrs(l.ctx, LevelFatal, msg, fields)
+	errorHandler(err)
+
+	l.fatalExit(msg, fieldsToKVs(fields))
+}
+
+func fieldsToKVs(fields []Field) []any {
+	kvs := make([]any, len(fields))
+	for i, field := range fields {
+		kvs[i] = field
+	}
+	return kvs
 }
 
 // getMessage format with Sprint, Sprintf, or neither.
@@ -5,11 +5,18 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
 	"log/slog"
+	"math"
+	"math/big"
+	"net/netip"
 	"reflect"
 	"strings"
+	"sync"
 	"testing"
+	"time"
+	"unicode/utf8"
 )
 
 func TestLoggerTextOutput(t *testing.T) {
@@ -73,7 +80,7 @@ func TestLoggerBuiltInAndFieldOrder(t *testing.T) {
 
 func TestLoggerWithFieldsUsesZeroCapacityBuffer(t *testing.T) {
 	logger := New(io.Discard, Json()).With("k", "v")
-	handler := logger.handler.(*jsonHandler).handler
+	handler := logger.Handler().(*jsonHandler).handler
 	if len(handler.preformattedAttrs) != 1 {
 		t.Fatalf("preformatted segments = %d, want 1", len(handler.preformattedAttrs))
 	}
@@ -222,6 +229,64 @@ func TestReplacerCanDeleteBuiltInFields(t *testing.T) {
 	}
 }
 
+func TestReplacerCanModifyBuiltInTime(t *testing.T) {
+	at := time.Date(2026, 6, 26, 9, 30, 0, 0, time.UTC)
+	replacer := func(_ context.Context, groups []string, field Field) Field {
+		if groups == nil && field.Key == TimeKey {
+			if field.Value.Kind() != KindTime {
+				t.Errorf("time kind = %v, want KindTime", field.Value.Kind())
+			}
+			return Time("ts", at)
+		}
+		return field
+	}
+
+	var jsonBuf, textBuf bytes.Buffer
+	New(&jsonBuf, Json(&HandlerOptions{AddTime: true, Replacer: replacer})).InfoS("ready", "time", "user")
+	New(&textBuf, Text(&HandlerOptions{AddTime: true, Replacer: replacer})).InfoS("ready")
+	if got, want := jsonBuf.String(), `{"level":"INFO","ts":"2026-06-26T09:30:00Z","msg":"ready","time":"user"}`+"\n"; got != want {
+		t.Fatalf("json output = %q, want %q", got, want)
+	}
+	if got, want := textBuf.String(), "INFO ts=2026-06-26T09:30:00.000Z msg=ready\n"; got != want {
+		t.Fatalf("text output = %q, want %q", got, want)
+	}
+}
+
+func TestReplacerCanDeleteBuiltInTime(t *testing.T) {
+	replacer := func(_ context.Context, groups []string, field Field) Field {
+		if groups == nil && field.Key == TimeKey {
+			return Field{}
+		}
+		return field
+	}
+	var buf bytes.Buffer
+	New(&buf, Json(&HandlerOptions{AddTime: true, Replacer: replacer})).InfoS("ready")
+	if got, want := buf.String(), `{"level":"INFO","msg":"ready"}`+"\n"; got != want {
+		t.Fatalf("json output = %q, want %q", got, want)
+	}
+}
+
+func TestReplacerDeletedLevelBeforeWithFields(t *testing.T) {
+	replacer := func(_ context.Context, _ []string, field Field) Field {
+		if field.Key == LevelKey {
+			return Field{}
+		}
+		return field
+	}
+
+	var buf bytes.Buffer
+	New(&buf, Json(&HandlerOptions{Replacer: replacer})).With("a", 1).InfoS("ready")
+	if got, want := buf.String(), `{"a":1,"msg":"ready"}`+"\n"; got != want {
+		t.Fatalf("json output = %q, want %q", got, want)
+	}
+
+	buf.Reset()
+	New(&buf, Text(&HandlerOptions{Replacer: replacer})).With("a", 1).InfoS("ready")
+	if got, want := buf.String(), "a=1 msg=ready\n"; got != want {
+		t.Fatalf("text output = %q, want %q", got, want)
+	}
+}
+
 func TestLoggerNameAllowsDuplicateLoggerField(t *testing.T) {
 	var buf bytes.Buffer
 	New(&buf, Json(&HandlerOptions{Name: "server"})).InfoS("ready", NameKey, "worker")
@@ -460,3 +525,477 @@ func TestLoggerFatalSExit(t *testing.T) {
 		})
 	}
 }
+
+func TestTimeEncoding(t *testing.T) {
+	at := time.Date(2026, 6, 26, 9, 30, 0, 123456789, time.UTC)
+	tests := []struct {
+		encoding TimeEncoding
+		json     string
+		text     string
+	}{
+		{TimeDefault, `"2026-06-26T09:30:00.123456789Z"`, "2026-06-26T09:30:00.123Z"},
+		{TimeRFC3339Nano, `"2026-06-26T09:30:00.123456789Z"`, "2026-06-26T09:30:00.123456789Z"},
+		{TimeUnix, `1782466200`, "1782466200"},
+		{TimeUnixMilli, `1782466200123`, "1782466200123"},
+	}
+	for _, tt := range tests {
+		var jsonBuf, textBuf bytes.Buffer
+		opts := &HandlerOptions{TimeEncoding: tt.encoding}
+		New(&jsonBuf, Json(opts)).InfoS("ready", "at", at, "ats", []time.Time{at})
+		New(&textBuf, Text(opts)).InfoS("ready", "at", at)
+		if got, want := jsonBuf.String(), `{"level":"INFO","msg":"ready","at":`+tt.json+`,"ats":[`+tt.json+`]}`+"\n"; got != want {
+			t.Errorf("encoding %d: json output = %q, want %q", tt.encoding, got, want)
+		}
+		if got, want := textBuf.String(), "INFO msg=ready at="+tt.text+"\n"; got != want {
+			t.Errorf("encoding %d: text output = %q, want %q", tt.encoding, got, want)
+		}
+	}
+}
+
+func TestBytesEncoding(t *testing.T) {
+	data := []byte("hi\x00there")
+	tests := []struct {
+		encoding BytesEncoding
+		maxBytes int
+		json     string
+		text     string
+	}{
+		{BytesDefault, 0, `"aGkAdGhlcmU="`, `"hi\x00there"`},
+		{BytesHex, 0, `"6869007468657265"`, "6869007468657265"},
+		{BytesBase64, 0, `"aGkAdGhlcmU="`, `"aGkAdGhlcmU="`},
+		{BytesString, 0, `"hi\u0000there"`, `"hi\x00there"`},
+		{BytesHex, 3, `"686900..."`, "686900..."},
+		{BytesString, 2, `"hi..."`, `"hi..."`},
+	}
+	for _, tt := range tests {
+		var jsonBuf, textBuf bytes.Buffer
+		opts := &HandlerOptions{BytesEncoding: tt.encoding, MaxBytes: tt.maxBytes}
+		New(&jsonBuf, Json(opts)).InfoS("read", Bytes("data", data))
+		New(&textBuf, Text(opts)).InfoS("read", "data", data)
+		if got, want := jsonBuf.String(), `{"level":"INFO","msg":"read","data":`+tt.json+"}\n"; got != want {
+			t.Errorf("encoding %d, max %d: json output = %q, want %q", tt.encoding, tt.maxBytes, got, want)
+		}
+		if got, want := textBuf.String(), "INFO msg=read data="+tt.text+"\n"; got != want {
+			t.Errorf("encoding %d, max %d: text output = %q, want %q", tt.encoding, tt.maxBytes, got, want)
+		}
+	}
+}
+
+func TestSortKeys(t *testing.T) {
+	var buf bytes.Buffer
+	opts := &HandlerOptions{SortKeys: true}
+	logger := New(&buf, Json(opts)).With("z", 1, "a", 2)
+	logger.InfoS("ready", "b", 1, "a", 2, Group("g", "y", 1, "x", 2), "a", 3)
+	want := `{"level":"INFO","a":2,"z":1,"msg":"ready","a":2,"a":3,"b":1,"g":{"x":2,"y":1}}` + "\n"
+	if got := buf.String(); got != want {
+		t.Fatalf("json output = %q, want %q", got, want)
+	}
+
+	buf.Reset()
+	slog.New(NewSlogHandler(New(&buf, Text(opts)))).Info("ready", "b", 1, slog.Group("g", "y", 1, "x", 2), "a", 2)
+	if got, want := buf.String(), "INFO msg=ready a=2 b=1 g.x=2 g.y=1\n"; got != want {
+		t.Fatalf("slog text output = %q, want %q", got, want)
+	}
+}
+
+func TestJSONEscaping(t *testing.T) {
+	type payload struct {
+		Note string `json:"note"`
+	}
+	tests := []struct {
+		opts HandlerOptions
+		want string
+	}{
+		{HandlerOptions{}, `{"level":"INFO","msg":"<b>&</b> 日本 😀","p":{"note":"<i>é</i>"}}`},
+		{HandlerOptions{EscapeHTML: true}, `{"level":"INFO","msg":"\u003cb\u003e\u0026\u003c/b\u003e 日本 😀","p":{"note":"\u003ci\u003eé\u003c/i\u003e"}}`},
+		{HandlerOptions{EscapeUnicode: true}, `{"level":"INFO","msg":"<b>&</b> \u65e5\u672c \ud83d\ude00","p":{"note":"<i>\u00e9</i>"}}`},
+	}
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		New(&buf, Json(&tt.opts)).InfoS("<b>&</b> 日本 😀", "p", payload{Note: "<i>é</i>"})
+		if got := buf.String(); got != tt.want+"\n" {
+			t.Errorf("%+v: json output = %q, want %q", tt.opts, got, tt.want)
+		}
+		var record map[string]any
+		if err := json.Unmarshal(buf.Bytes(), &record); err != nil || record["msg"] != "<b>&</b> 日本 😀" {
+			t.Errorf("%+v: decoded = %v, %v", tt.opts, record, err)
+		}
+	}
+}
+
+func TestJSONInvalidUTF8(t *testing.T) {
+	tests := []struct {
+		mode InvalidUTF8Mode
+		want string
+	}{
+		{InvalidUTF8Replace, `{"level":"INFO","msg":"a\ufffdb","k\ufffd":"v\ufffd","ok":"é"}`},
+		{InvalidUTF8Escape, `{"level":"INFO","msg":"a\\xffb","k\\xfe":"v\\xc3","ok":"é"}`},
+		{InvalidUTF8Base64, `{"level":"INFO","msg":"Yf9i","a/4=":"dsM=","ok":"é"}`},
+	}
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		New(&buf, Json(&HandlerOptions{InvalidUTF8: tt.mode})).InfoS("a\xffb", "k\xfe", "v\xc3", "ok", "é")
+		if got := buf.String(); got != tt.want+"\n" {
+			t.Errorf("mode %d: json output = %q, want %q", tt.mode, got, tt.want)
+		}
+	}
+}
+
+func TestMultiline(t *testing.T) {
+	const stack = "panic: boom\n\nmain.main()\n\t/app/main.go:5"
+	tests := []struct {
+		mode MultilineMode
+		want string
+	}{
+		{MultilineEscape, `INFO msg="query failed" stack="panic: boom\n\nmain.main()\n\t/app/main.go:5" sql="SELECT \"a\"\nFROM t"`},
+		{MultilineIndent, "INFO msg=\"query failed\" stack=\"panic: boom\n  \n  main.main()\n  \t/app/main.go:5\" sql=\"SELECT \\\"a\\\"\n  FROM t\""},
+		{MultilineRaw, "INFO msg=\"query failed\" stack=\"panic: boom\n\nmain.main()\n\t/app/main.go:5\" sql=\"SELECT \\\"a\\\"\nFROM t\""},
+	}
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		New(&buf, Text(&HandlerOptions{Multiline: tt.mode})).InfoS("query failed", "stack", stack, "sql", "SELECT \"a\"\nFROM t")
+		if got := buf.String(); got != tt.want+"\n" {
+			t.Errorf("mode %d: text output = %q, want %q", tt.mode, got, tt.want)
+		}
+	}
+
+	var buf bytes.Buffer
+	New(&buf, Json(&HandlerOptions{Multiline: MultilineRaw})).Info("a\nb")
+	if got := buf.String(); got != `{"level":"INFO","msg":"a\nb"}`+"\n" {
+		t.Errorf("json output = %q", got)
+	}
+}
+
+func FuzzJSONInvalidUTF8(f *testing.F) {
+	f.Add([]byte("plain"))
+	f.Add([]byte("a\xffb\xc3"))
+	f.Add([]byte("\xed\xa0\x80 \u2028 <&> \"\\\x00\x7f"))
+	f.Fuzz(func(t *testing.T, b []byte) {
+		s := string(b)
+		for _, mode := range []InvalidUTF8Mode{InvalidUTF8Replace, InvalidUTF8Escape, InvalidUTF8Base64} {
+			for _, opts := range []HandlerOptions{
+				{InvalidUTF8: mode},
+				{InvalidUTF8: mode, EscapeHTML: true, EscapeUnicode: true},
+			} {
+				var buf bytes.Buffer
+				New(&buf, Json(&opts)).InfoS(s, s, s, Group("g", "k", s), "b", b)
+				out := buf.Bytes()
+				if !utf8.Valid(out) || !json.Valid(out) {
+					t.Fatalf("%+v: invalid JSON output for %q: %q", opts, b, out)
+				}
+			}
+		}
+	})
+}
+
+func TestNetIPFields(t *testing.T) {
+	addr := netip.MustParseAddr("fe80::1%eth0")
+	prefix := netip.MustParsePrefix("10.0.0.0/8")
+	peer := netip.MustParseAddrPort("192.0.2.1:443")
+
+	var jsonBuf, textBuf bytes.Buffer
+	New(&jsonBuf, Json()).InfoS("conn", IPAddr("addr", addr), Prefix("net", prefix), "peer", peer, IPAddr("none", netip.Addr{}))
+	New(&textBuf, Text()).InfoS("conn", IPAddr("addr", addr), Prefix("net", prefix), "peer", peer)
+	if got, want := jsonBuf.String(), `{"level":"INFO","msg":"conn","addr":"fe80::1%eth0","net":"10.0.0.0/8","peer":"192.0.2.1:443","none":""}`+"\n"; got != want {
+		t.Errorf("json output = %q, want %q", got, want)
+	}
+	if got, want := textBuf.String(), "INFO msg=conn addr=fe80::1%eth0 net=10.0.0.0/8 peer=192.0.2.1:443\n"; got != want {
+		t.Errorf("text output = %q, want %q", got, want)
+	}
+
+	// Formatting an address allocates no more than writing a string field.
+	if raceEnabled {
+		t.Skip("allocation counts are unreliable under the race detector")
+	}
+	logger := New(&jsonBuf, Json())
+	ipField, strField := IPAddr("addr", addr), String("addr", "fe80::1%eth0")
+	ipAllocs := testing.AllocsPerRun(10, func() { jsonBuf.Reset(); logger.InfoS("conn", ipField) })
+	strAllocs := testing.AllocsPerRun(10, func() { jsonBuf.Reset(); logger.InfoS("conn", strField) })
+	if ipAllocs > strAllocs {
+		t.Errorf("allocs = %v, want at most %v", ipAllocs, strAllocs)
+	}
+}
+
+// namedUUID returns b as a type named UUID, like the UUID types of UUID
+// packages.
+func namedUUID(b [16]byte) any {
+	type UUID [16]byte
+	return UUID(b)
+}
+
+func TestUUIDFields(t *testing.T) {
+	id := [16]byte{0x12, 0x3e, 0x45, 0x67, 0xe8, 0x9b, 0x12, 0xd3, 0xa4, 0x56, 0x42, 0x66, 0x14, 0x17, 0x40, 0x00}
+	const canonical = "123e4567-e89b-12d3-a456-426614174000"
+
+	var jsonBuf, textBuf bytes.Buffer
+	New(&jsonBuf, Json()).InfoS("req", UUID("id", id), "named", namedUUID(id), "digest", id)
+	New(&textBuf, Text()).InfoS("req", UUID("id", id), "named", namedUUID(id))
+	if got, want := jsonBuf.String(), `{"level":"INFO","msg":"req","id":"`+canonical+`","named":"`+canonical+`","digest":[18,62,69,103,232,155,18,211,164,86,66,102,20,23,64,0]}`+"\n"; got != want {
+		t.Errorf("json output = %q, want %q", got, want)
+	}
+	if got, want := textBuf.String(), "INFO msg=req id="+canonical+" named="+canonical+"\n"; got != want {
+		t.Errorf("text output = %q, want %q", got, want)
+	}
+	if got := UUID("id", id).Value.String(); got != canonical {
+		t.Errorf("String() = %q, want %q", got, canonical)
+	}
+}
+
+func TestBigValues(t *testing.T) {
+	i, _ := new(big.Int).SetString("-123456789012345678901234567890", 10)
+	f := new(big.Float).SetPrec(100).SetFloat64(1.5)
+	r := big.NewRat(-1, 3)
+
+	var jsonBuf, textBuf bytes.Buffer
+	New(&jsonBuf, Json()).InfoS("big", "i", i, "iv", *i, "f", f, "r", r, "n", big.NewRat(4, 2), "nil", (*big.Int)(nil))
+	New(&textBuf, Text()).InfoS("big", "i", i, "iv", *i, "f", f, "r", r)
+	if got, want := jsonBuf.String(), `{"level":"INFO","msg":"big","i":-123456789012345678901234567890,"iv":-123456789012345678901234567890,"f":"1.5","r":"-1/3","n":"2","nil":null}`+"\n"; got != want {
+		t.Errorf("json output = %q, want %q", got, want)
+	}
+	if got, want := textBuf.String(), "INFO msg=big i=-123456789012345678901234567890 iv=-123456789012345678901234567890 f=1.5 r=-1/3\n"; got != want {
+		t.Errorf("text output = %q, want %q", got, want)
+	}
+}
+
+// verboseError is an error that prints a trace with %+v, like the errors of
+// github.com/pkg/errors.
+type verboseError struct{ msg string }
+
+func (e verboseError) Error() string { return e.msg }
+
+func (e verboseError) Format(f fmt.State, verb rune) {
+	if verb == 'v' && f.Flag('+') {
+		fmt.Fprintf(f, "%s\ntrace", e.msg)
+		return
+	}
+	fmt.Fprint(f, e.msg)
+}
+
+func TestErrorFormat(t *testing.T) {
+	err := fmt.Errorf("save: %w", errors.Join(verboseError{"disk full"}, errors.New("retry failed")))
+	tests := []struct {
+		format ErrorFormat
+		err    error
+		json   string
+		text   string
+	}{
+		{ErrorMessage, err, `"save: disk full\nretry failed"`, `"save: disk full\nretry failed"`},
+		{ErrorVerbose, verboseError{"disk full"}, `"disk full\ntrace"`, `"disk full\ntrace"`},
+		{ErrorChain, err, `["save: disk full\nretry failed","disk full\nretry failed","disk full","retry failed"]`, `"[save: disk full\nretry failed disk full\nretry failed disk full retry failed]"`},
+	}
+	for _, tt := range tests {
+		var jsonBuf, textBuf bytes.Buffer
+		opts := &HandlerOptions{ErrorFormat: tt.format}
+		New(&jsonBuf, Json(opts)).InfoS("failed", Err(tt.err))
+		New(&textBuf, Text(opts)).InfoS("failed", "err", tt.err)
+		if got, want := jsonBuf.String(), `{"level":"INFO","msg":"failed","err":`+tt.json+"}\n"; got != want {
+			t.Errorf("format %d: json output = %q, want %q", tt.format, got, want)
+		}
+		if got, want := textBuf.String(), "INFO msg=failed err="+tt.text+"\n"; got != want {
+			t.Errorf("format %d: text output = %q, want %q", tt.format, got, want)
+		}
+	}
+	if v := AnyValue(err); v.Kind() != KindError || v.Err() != err {
+		t.Errorf("AnyValue(err) = %v (kind %s), want an error value", v, v.Kind())
+	}
+}
+
+func TestErrJoined(t *testing.T) {
+	err := errors.Join(errors.New("disk full"), errors.Join(errors.New("retry failed"), errors.New("timeout")))
+	var jsonBuf, textBuf bytes.Buffer
+	New(&jsonBuf, Json()).ErrorS("failed", Err(err), "cause", err)
+	New(&textBuf, Text()).ErrorS("failed", Err(err))
+	want := `{"level":"ERROR","msg":"failed","errs":["disk full",["retry failed","timeout"]],"cause":["disk full",["retry failed","timeout"]]}` + "\n"
+	if got := jsonBuf.String(); got != want {
+		t.Errorf("json output = %q, want %q", got, want)
+	}
+	if got, want := textBuf.String(), "ERROR msg=failed errs=\"[disk full [retry failed timeout]]\"\n"; got != want {
+		t.Errorf("text output = %q, want %q", got, want)
+	}
+
+	// The message of fmt.Errorf with several %w adds text, which is kept.
+	wrapped := fmt.Errorf("save: %w, %w", errors.New("a"), errors.New("b"))
+	if f := Err(wrapped); f.Key != ErrKey || f.Value.Err() != wrapped {
+		t.Errorf("Err(%q) = %v", wrapped, f)
+	}
+}
+
+func TestFloat32(t *testing.T) {
+	var jsonBuf, textBuf bytes.Buffer
+	New(&jsonBuf, Json()).InfoS("ratio", Float32("f", 0.1), "any", float32(1e-7), "wide", float64(float32(0.1)))
+	New(&textBuf, Text()).InfoS("ratio", Float32("f", 0.1), "any", float32(1e-7))
+	if got, want := jsonBuf.String(), `{"level":"INFO","msg":"ratio","f":0.1,"any":1e-7,"wide":0.10000000149011612}`+"\n"; got != want {
+		t.Errorf("json output = %q, want %q", got, want)
+	}
+	if got, want := textBuf.String(), "INFO msg=ratio f=0.1 any=1e-07\n"; got != want {
+		t.Errorf("text output = %q, want %q", got, want)
+	}
+}
+
+func TestComplex(t *testing.T) {
+	var jsonBuf, textBuf bytes.Buffer
+	New(&jsonBuf, Json()).InfoS("fft", "c", complex(1.5, -2), "c64", complex64(complex(0.1, 0)), "nan", complex(math.NaN(), 0))
+	New(&textBuf, Text()).InfoS("fft", "c", complex(1.5, -2), "c64", complex64(complex(0.1, 0)), "inf", complex(0, math.Inf(1)))
+	if got, want := jsonBuf.String(), `{"level":"INFO","msg":"fft","c":{"real":1.5,"imag":-2},"c64":{"real":0.1,"imag":0},"nan":"!ERROR:json: unsupported value: (NaN+0i)"}`+"\n"; got != want {
+		t.Errorf("json output = %q, want %q", got, want)
+	}
+	want := fmt.Sprintf("INFO msg=fft c=%v c64=%v inf=%v\n", complex(1.5, -2), complex64(complex(0.1, 0)), complex(0, math.Inf(1)))
+	if got := textBuf.String(); got != want {
+		t.Errorf("text output = %q, want %q", got, want)
+	}
+}
+
+func TestTypedSlices(t *testing.T) {
+	at := time.Date(2026, 6, 26, 9, 30, 0, 0, time.UTC)
+	fields := []any{
+		Ints("ints", []int{1, -2}),
+		Strings("strs", []string{"a", "b c"}),
+		Bools("bools", []bool{true, false}),
+		Durations("durs", []time.Duration{time.Second}),
+		Times("times", []time.Time{at}),
+		Errs("errs", []error{errors.New("boom"), nil}),
+		Ints("none", nil),
+	}
+	var buf bytes.Buffer
+	New(&buf, Json()).InfoS("slices", fields...)
+	want := `{"level":"INFO","msg":"slices","ints":[1,-2],"strs":["a","b c"],"bools":[true,false],"durs":[1000000000],"times":["2026-06-26T09:30:00Z"],"errs":["boom",null],"none":null}` + "\n"
+	if got := buf.String(); got != want {
+		t.Errorf("json output = %q, want %q", got, want)
+	}
+
+	if raceEnabled {
+		return
+	}
+	logger := New(&buf, Json())
+	if allocs := testing.AllocsPerRun(10, func() { buf.Reset(); logger.InfoS("slices", fields...) }); allocs > 0 {
+		t.Errorf("allocs = %v, want 0", allocs)
+	}
+}
+
+func TestDictAndNamespace(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New(&buf, Json()).With("svc", "api", Namespace("req"), "id", 7)
+	logger.InfoS("done", Dict("user", String("name", "alice"), Int("age", 30)), Namespace("resp"), "status", 200, "bytes", 512)
+	want := `{"level":"INFO","svc":"api","req":{"id":7,"user":{"name":"alice","age":30},"resp":{"status":200,"bytes":512}},"msg":"done"}` + "\n"
+	if got := buf.String(); got != want {
+		t.Errorf("json output = %q, want %q", got, want)
+	}
+
+	buf.Reset()
+	New(&buf, Text()).InfoS("done", "a", 1, Namespace("ns"), "b", 2)
+	if got, want := buf.String(), "INFO msg=done a=1 ns.b=2\n"; got != want {
+		t.Errorf("text output = %q, want %q", got, want)
+	}
+}
+
+func TestLogAttrs(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New(&buf, Json()).With("svc", "api")
+	logger.InfoAttrs("ready", Int("port", 8080), Namespace("tls"), Bool("on", true))
+	_ = logger.LogAttrs(context.Background(), LevelWarn, "slow", Duration("took", time.Second))
+	logger.DebugAttrs("hidden")
+	want := `{"level":"INFO","svc":"api","msg":"ready","port":8080,"tls":{"on":true}}` + "\n" +
+		`{"level":"WARN","svc":"api","msg":"slow","took":1000000000}` + "\n"
+	if got := buf.String(); got != want {
+		t.Fatalf("output = %q, want %q", got, want)
+	}
+
+	// Hooks see the fields through the Handle path.
+	buf.Reset()
+	hooked := New(&buf, Text()).AddHook(HookFunc(func(ctx context.Context, r *Record) bool {
+		r.Fields = append(r.Fields, String("hooked", "yes"))
+		return true
+	}))
+	hooked.ErrorAttrs("failed", Int("n", 1))
+	if got, want := buf.String(), "ERROR msg=failed n=1 hooked=yes\n"; got != want {
+		t.Fatalf("hooked output = %q, want %q", got, want)
+	}
+
+	if raceEnabled {
+		return
+	}
+	logger = New(&buf, Json())
+	fields := []Field{String("k", "v"), Int("n", 1), Bool("b", true)}
+	if allocs := testing.AllocsPerRun(100, func() { buf.Reset(); logger.InfoAttrs("msg", fields...) }); allocs > 0 {
+		t.Errorf("allocs = %v, want 0", allocs)
+	}
+}
+
+func TestKeyValueConversionAllocs(t *testing.T) {
+	if raceEnabled {
+		t.Skip("allocation counts are unreliable under the race detector")
+	}
+	kvs := []any{"b", 1, "a", "x", String("c", "y"), "d", true}
+	for _, tt := range []struct {
+		name    string
+		handler Handler
+		allocs  float64 // those of the CSV cells
+	}{
+		{"SortKeys", Json(&HandlerOptions{SortKeys: true}), 0},
+		{"CSV", CSV(&CSVOptions{Columns: []string{MessageKey, "a"}}), 2},
+		{"CSVGroup", CSV(&CSVOptions{Columns: []string{MessageKey, "g.a"}}).WithGroup("g"), 2},
+	} {
+		var buf bytes.Buffer
+		logger := New(&buf, tt.handler)
+		if allocs := testing.AllocsPerRun(100, func() { buf.Reset(); logger.InfoS("msg", kvs...) }); allocs > tt.allocs {
+			t.Errorf("%s: allocs = %v, want at most %v", tt.name, allocs, tt.allocs)
+		}
+	}
+}
+
+func TestSetOutputWhileLogging(t *testing.T) {
+	var a, b, last lockedBuffer
+	logger := New(&a)
+	derived := logger.With("id", 1)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				logger.Info("record")
+				logger.InfoAttrs("record")
+			}
+		}()
+	}
+	for i := 0; i < 100; i++ {
+		if i%2 == 0 {
+			logger.SetOutput(&b).SetHandler(Json())
+		} else {
+			logger.SetOutput(&a).SetHandler(Text())
+		}
+	}
+	wg.Wait()
+
+	logger.SetOutput(&last).SetHandler(Json())
+	logger.Info("last")
+	if got := last.String(); !strings.HasPrefix(got, "{") {
+		t.Fatalf("output after SetHandler = %q", got)
+	}
+	if derived.Writer() != &a {
+		t.Fatal("SetOutput changed the writer of a derived logger")
+	}
+}
+
+func TestLoggerHandler(t *testing.T) {
+	var buf bytes.Buffer
+	h := Json()
+	logger := New(&buf, h)
+	if logger.Handler() != h {
+		t.Fatal("Handler() is not the handler passed to New")
+	}
+	derived := logger.With("id", 1)
+	if derived.Handler() == h {
+		t.Fatal("Handler() of a derived logger does not include its fields")
+	}
+	derived.SetHandler(Dedup(derived.Handler()))
+	derived.Info("once")
+	derived.Info("once")
+	if got := buf.String(); got != `{"level":"INFO","id":1,"msg":"once"}`+"\n" {
+		t.Fatalf("output = %q", got)
+	}
+	if logger.SetHandler(nil).Handler() != nil {
+		t.Fatal("Handler() after SetHandler(nil) is not nil")
+	}
+}
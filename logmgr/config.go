@@ -1,15 +1,19 @@
 package logmgr
 
 import (
+	"errors"
 	"fmt"
 	"io"
+	"maps"
 	"os"
-	"path/filepath"
+	"path"
+	"slices"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/nexuer/log"
-	"gopkg.in/natefinch/lumberjack.v2"
 )
 
 // Format controls the handler output encoding.
@@ -21,6 +25,10 @@ func (f Format) String() string {
 		return "text"
 	case JsonFormat:
 		return "json"
+	case SyslogFormat:
+		return "syslog"
+	case DatadogFormat:
+		return "datadog"
 	}
 	return ""
 }
@@ -30,6 +38,11 @@ const (
 	TextFormat Format = iota
 	// JsonFormat writes JSON records.
 	JsonFormat
+	// SyslogFormat writes RFC 5424 syslog messages with the logger name as
+	// APP-NAME.
+	SyslogFormat
+	// DatadogFormat writes JSON records with Datadog reserved attributes.
+	DatadogFormat
 )
 
 // Output controls where log records are written.
@@ -43,6 +56,8 @@ func (o Output) String() string {
 		return "stdout"
 	case FileOutput:
 		return "file"
+	case SplitFileOutput:
+		return "split-file"
 	}
 	return ""
 }
@@ -54,41 +69,332 @@ const (
 	StdoutOutput
 	// FileOutput writes records to rotating log files.
 	FileOutput
+	// SplitFileOutput writes records to rotating log files like FileOutput,
+	// and also writes the records of some levels to files of their own, as
+	// set by WithLevelFiles. By default, warnings and errors also go to
+	// <name>.error.log.
+	SplitFileOutput
 )
 
+// Config is the resolved configuration of a scope, as returned by
+// Manager.Config and Scope.Config.
+type Config struct {
+	// Scope is the name of the scope.
+	Scope    string
+	Level    log.Level
+	Format   Format
+	Output   Output
+	File     FileConfig
+	Sampling SamplingConfig
+	// DropKeys and AllowKeys are the key patterns of WithDropKeys and
+	// WithAllowKeys.
+	DropKeys  []string
+	AllowKeys []string
+	Fields    []log.Field
+	// Levels are the levels of WithLevels by printer name.
+	Levels map[string]log.Level
+}
+
+// Validate reports the invalid values of c, each as a *ConfigError, joined
+// with errors.Join. With file output, it checks that a file can be created in
+// File.Dir, creating the directory as file output would.
+func (c Config) Validate() error {
+	var errs []error
+	invalid := func(key string, value any, err error) {
+		errs = append(errs, &ConfigError{Scope: c.Scope, Key: key, Value: value, Err: err})
+	}
+	if c.Format.String() == "" {
+		invalid("format", int(c.Format), errors.New("unknown format"))
+	}
+	if c.Output.String() == "" {
+		invalid("output", int(c.Output), errors.New("unknown output"))
+	}
+	if c.File.Size < 0 {
+		invalid("file-size", c.File.Size, errNegative)
+	}
+	if c.File.Backups < 0 {
+		invalid("file-backups", c.File.Backups, errNegative)
+	}
+	if c.Sampling.First < 0 {
+		invalid("sampling-first", c.Sampling.First, errNegative)
+	}
+	if c.Sampling.Thereafter < 0 {
+		invalid("sampling-thereafter", c.Sampling.Thereafter, errNegative)
+	}
+	if c.Sampling.First > 0 && c.Sampling.Tick <= 0 {
+		invalid("sampling-tick", c.Sampling.Tick, errors.New("must be positive"))
+	}
+	for _, keys := range []struct {
+		name     string
+		patterns []string
+	}{{"drop-keys", c.DropKeys}, {"allow-keys", c.AllowKeys}} {
+		for _, pattern := range keys.patterns {
+			if _, err := path.Match(pattern, ""); err != nil {
+				invalid(keys.name, pattern, err)
+			}
+		}
+	}
+	if err := validateFilePattern(c.File.Pattern); err != nil {
+		invalid("file-pattern", c.File.Pattern, err)
+	}
+	if c.File.Sync.Records < 0 {
+		invalid("file-sync", c.File.Sync.Records, errNegative)
+	}
+	if c.File.Sync.Interval < 0 {
+		invalid("file-sync", c.File.Sync.Interval, errNegative)
+	}
+	suffixes := make([]string, 0, len(c.File.Levels))
+	for suffix := range c.File.Levels {
+		suffixes = append(suffixes, suffix)
+	}
+	sort.Strings(suffixes)
+	for _, suffix := range suffixes {
+		if err := validateLevelFileSuffix(suffix); err != nil {
+			invalid("file-levels", suffix, err)
+		}
+	}
+	names := make([]string, 0, len(c.Levels))
+	for name := range c.Levels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if err := validatePrinterName(name); err != nil {
+			invalid("levels", name, err)
+		}
+	}
+	if c.Output == FileOutput || c.Output == SplitFileOutput {
+		if err := checkDirWritable(c.File.Dir); err != nil {
+			invalid("file-dir", c.File.Dir, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+var errNegative = errors.New("must not be negative")
+
+// validatePrinterName checks that name is a printer name: dot-separated
+// non-empty parts.
+func validatePrinterName(name string) error {
+	for _, part := range strings.Split(name, ".") {
+		if part == "" {
+			return errors.New("empty name part")
+		}
+	}
+	return nil
+}
+
+// checkDirWritable creates dir if needed and checks that a file can be
+// created in it.
+func checkDirWritable(dir string) error {
+	if dir == "" {
+		dir = "."
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(dir, ".logmgr-*")
+	if err != nil {
+		return err
+	}
+	f.Close()
+	return os.Remove(f.Name())
+}
+
+// ConfigError is an invalid configuration value, as reported by
+// Config.Validate, Apply and AddScope.
+type ConfigError struct {
+	// Scope is the name of the scope, or of the printer for the options of
+	// WithPrinter.
+	Scope string
+	// Key is the configuration key, as in --log-set, such as "file-size".
+	Key   string
+	Value any
+	Err   error
+}
+
+func (e *ConfigError) Error() string {
+	return fmt.Sprintf("logmgr: scope %q: invalid %s %v: %v", e.Scope, e.Key, e.Value, e.Err)
+}
+
+func (e *ConfigError) Unwrap() error {
+	return e.Err
+}
+
+// FileConfig configures file output.
+type FileConfig struct {
+	Dir string
+	// Pattern names the file of each printer. See WithFilePattern.
+	Pattern string
+	// Size is the rotation size in MB.
+	Size     int64
+	Backups  int64
+	Compress bool
+	// Link keeps a <name>.log symlink to the current file. See WithFileLink.
+	Link bool
+	// Levels maps the suffixes of the level files of SplitFileOutput to the
+	// lowest level they have. See WithLevelFiles.
+	Levels map[string]log.Level
+	// Sync is when files are synced to stable storage. See WithFileSync.
+	Sync log.SyncOptions
+}
+
+// SamplingConfig configures sampling. Sampling is disabled when First is 0.
+type SamplingConfig struct {
+	First      int
+	Thereafter int
+	Tick       time.Duration
+}
+
 type config struct {
 	// flags
-	Format *Format
-	Level  *log.Level
-	Output *Output
-	File   fileConfig
+	Format   *Format
+	Level    *log.Level
+	Output   *Output
+	File     fileConfig
+	Sampling samplingConfig
+
+	// DropKeys and AllowKeys are set when not nil; an empty slice clears
+	// them.
+	DropKeys  []string
+	AllowKeys []string
 
 	Replacer log.Replacer
 	Fields   []log.Field
+
+	// Levels is set when not nil; an empty map clears it.
+	Levels map[string]log.Level
+	// Printers holds the options of WithPrinter by printer name.
+	Printers map[string][]Option
+}
+
+// export returns the Config of the scope name.
+func (c *config) export(name string) Config {
+	return Config{
+		Scope:  name,
+		Level:  *c.Level,
+		Format: *c.Format,
+		Output: *c.Output,
+		File: FileConfig{
+			Dir:      *c.File.Dir,
+			Pattern:  *c.File.Pattern,
+			Size:     *c.File.Size,
+			Backups:  *c.File.Backups,
+			Compress: *c.File.Compress,
+			Link:     *c.File.Link,
+			Levels:   maps.Clone(c.File.Levels),
+			Sync:     *c.File.Sync,
+		},
+		Sampling: SamplingConfig{
+			First:      *c.Sampling.First,
+			Thereafter: *c.Sampling.Thereafter,
+			Tick:       *c.Sampling.Tick,
+		},
+		DropKeys:  append([]string(nil), c.DropKeys...),
+		AllowKeys: append([]string(nil), c.AllowKeys...),
+		Fields:    append([]log.Field(nil), c.Fields...),
+		Levels:    maps.Clone(c.Levels),
+	}
+}
+
+// clone returns a copy of c that options can change without changing c.
+func (c *config) clone() *config {
+	next := *c
+	next.Fields = c.Fields[:len(c.Fields):len(c.Fields)]
+	return &next
+}
+
+// validate validates the config of the scope name and of its printers.
+func (c *config) validate(name string) error {
+	errs := []error{c.export(name).Validate()}
+	printers := make([]string, 0, len(c.Printers))
+	for printer := range c.Printers {
+		printers = append(printers, printer)
+	}
+	sort.Strings(printers)
+	for _, printer := range printers {
+		errs = append(errs, c.printer(printer).export(name+"."+printer).Validate())
+	}
+	return errors.Join(errs...)
 }
 
-func (c *config) handler(name string) log.Handler {
-	opts := &log.HandlerOptions{Name: name, Replacer: c.Replacer}
-	if *c.Format == JsonFormat {
-		return log.Json(opts)
+// printer returns the config of the printer name, relative to the scope: c
+// with the options of WithPrinter and the levels of WithLevels applied, or c
+// itself. Those of the dot-separated ancestors of name, such as "db" for
+// "db.pool", apply first, so the most specific name wins.
+func (c *config) printer(name string) *config {
+	var opts []Option
+	for i := 0; ; i++ {
+		j := strings.IndexByte(name[i:], '.')
+		prefix := name
+		if j >= 0 {
+			i += j
+			prefix = name[:i]
+		}
+		opts = append(opts, c.Printers[prefix]...)
+		if level, ok := c.Levels[prefix]; ok {
+			opts = append(opts, WithLevel(level))
+		}
+		if j < 0 {
+			break
+		}
+	}
+	if len(opts) == 0 {
+		return c
+	}
+	next := c.clone()
+	next.Printers = nil
+	return applyConfig(next, opts)
+}
+
+// handler returns the handler of the printer name that writes to w.
+func (c *config) handler(name string, w io.Writer) log.Handler {
+	opts := &log.HandlerOptions{
+		Name:      name,
+		Replacer:  c.Replacer,
+		DropKeys:  c.DropKeys,
+		AllowKeys: c.AllowKeys,
+	}
+	var h log.Handler
+	switch *c.Format {
+	case JsonFormat:
+		h = log.Json(opts)
+	case SyslogFormat:
+		h = log.Syslog(&log.SyslogOptions{HandlerOptions: *opts})
+	case DatadogFormat:
+		h = log.Datadog(opts)
+	default:
+		h = log.Text(opts)
+	}
+	if split, ok := w.(*splitFiles); ok {
+		h = split.handler(h)
 	}
-	return log.Text(opts)
+	if first := c.Sampling.First; first != nil && *first > 0 {
+		h = log.Sample(h, log.SamplingOptions{
+			First:      *first,
+			Thereafter: *c.Sampling.Thereafter,
+			Tick:       *c.Sampling.Tick,
+		})
+	}
+	return h
 }
 
 func (c *config) writer(name string, current io.Writer) (io.Writer, string) {
 	switch *c.Output {
-	case FileOutput:
-		path := filepath.Join(*c.File.Dir, name+".log")
-		newPath := path
-		if f, ok := current.(*lumberjack.Logger); ok && f.Filename == path {
-			if f.MaxSize == int(*c.File.Size) &&
-				f.MaxBackups == int(*c.File.Backups) &&
-				f.Compress == *c.File.Compress {
-				return f, ""
+	case FileOutput, SplitFileOutput:
+		next := c.fileOutput(name, "")
+		var levels []levelFile
+		if *c.Output == SplitFileOutput {
+			levels = c.levelFiles(name)
+		}
+		newPath := next.currentPath()
+		if cur, ok := fileOutputOf(current); ok && cur.path == next.path {
+			if cur == next && slices.Equal(levelFilesOf(current), levels) {
+				return current, ""
 			}
 			newPath = ""
 		}
-		return log.FileWriter(path, *c.File.Size, *c.File.Backups, *c.File.Compress), newPath
+		return openFiles(next, levels), newPath
 	case StdoutOutput:
 		return os.Stdout, ""
 	default:
@@ -98,9 +404,21 @@ func (c *config) writer(name string, current io.Writer) (io.Writer, string) {
 
 type fileConfig struct {
 	Dir      *string
+	Pattern  *string
 	Size     *int64
 	Backups  *int64
 	Compress *bool
+	Link     *bool
+	// Levels is set when not nil; an empty map clears it.
+	Levels map[string]log.Level
+	Sync   *log.SyncOptions
+}
+
+// samplingConfig enables log.Sample when First is positive.
+type samplingConfig struct {
+	First      *int
+	Thereafter *int
+	Tick       *time.Duration
 }
 
 // Option changes manager or scope configuration.
@@ -164,6 +482,18 @@ func WithFileDir(v string) Option {
 	}}
 }
 
+// WithFilePattern sets the name of the file of each printer, in the file
+// directory. The pattern can have the placeholders {name}, the printer name,
+// which is required, {date}, the current date as 2006-01-02, {pid} and
+// {host}. With {date}, a new file is started each day. Rotated files are
+// named after the current file, with a timestamp before the extension. The
+// default, or an empty pattern, is "{name}.log".
+func WithFilePattern(v string) Option {
+	return Option{apply: func(c *config) {
+		c.File.Pattern = &v
+	}}
+}
+
 // WithFileSize sets the file rotation size in MB.
 func WithFileSize(v int64) Option {
 	return Option{apply: func(c *config) {
@@ -185,6 +515,100 @@ func WithFileCompress(v bool) Option {
 	}}
 }
 
+// WithFileLink sets whether a <name>.log symlink in the file directory is kept
+// pointing at the current file of each printer, so tail -F and log shippers
+// can follow a stable path when the pattern of WithFilePattern names the file
+// otherwise, such as by date.
+func WithFileLink(v bool) Option {
+	return Option{apply: func(c *config) {
+		c.File.Link = &v
+	}}
+}
+
+// WithLevels sets the levels of printers by name, relative to the scope as in
+// Scope.Printer. A level also applies to the printers below the name, unless
+// they have a level of their own, so
+//
+//	logmgr.WithLevels(map[string]log.Level{"db": log.LevelWarn, "db.migrations": log.LevelDebug})
+//
+// logs the printer "db.pool" at warn and "db.migrations" at debug. The levels
+// override those of WithPrinter for the same name.
+func WithLevels(v map[string]log.Level) Option {
+	v = maps.Clone(v)
+	if v == nil {
+		v = map[string]log.Level{}
+	}
+	return Option{apply: func(c *config) {
+		c.Levels = v
+	}}
+}
+
+// WithLevelFiles sets the level files of SplitFileOutput by file suffix: each
+// printer also writes the records at or above the level of a suffix to a file
+// named like its own with the suffix before the extension, such as
+// <name>.error.log for the suffix "error". The default is
+//
+//	logmgr.WithLevelFiles(map[string]log.Level{"error": log.LevelWarn})
+func WithLevelFiles(v map[string]log.Level) Option {
+	v = maps.Clone(v)
+	if v == nil {
+		v = map[string]log.Level{}
+	}
+	return Option{apply: func(c *config) {
+		c.File.Levels = v
+	}}
+}
+
+// WithFileSync sets when files are synced to stable storage with
+// log.SyncWriter, for channels such as audit logs that must not lose their
+// last records in a crash. The zero value, the default, leaves it to the
+// operating system; log.SyncOptions{Records: 1} syncs after every record.
+func WithFileSync(v log.SyncOptions) Option {
+	return Option{apply: func(c *config) {
+		c.File.Sync = &v
+	}}
+}
+
+// WithSampling samples records with the same level and message: within each
+// tick, the first records are written and then every thereafter-th one. A
+// first of 0 disables sampling.
+func WithSampling(first, thereafter int, tick time.Duration) Option {
+	return Option{apply: func(c *config) {
+		c.Sampling = samplingConfig{First: &first, Thereafter: &thereafter, Tick: &tick}
+	}}
+}
+
+// WithDropKeys removes the fields whose key matches one of the patterns. See
+// log.HandlerOptions.DropKeys.
+func WithDropKeys(patterns ...string) Option {
+	return Option{apply: func(c *config) {
+		c.DropKeys = append([]string{}, patterns...)
+	}}
+}
+
+// WithAllowKeys removes the fields whose key matches none of the patterns. No
+// patterns allow every field. See log.HandlerOptions.AllowKeys.
+func WithAllowKeys(patterns ...string) Option {
+	return Option{apply: func(c *config) {
+		c.AllowKeys = append([]string{}, patterns...)
+	}}
+}
+
+// WithPrinter applies opts to the printer name of the scope, over the
+// configuration of the scope. Printers can so write to their own outputs, such
+// as access logs to a file while the other printers of the scope write to
+// stderr. The name is relative to the scope, as in Scope.Printer.
+func WithPrinter(name string, opts ...Option) Option {
+	return Option{apply: func(c *config) {
+		printers := make(map[string][]Option, len(c.Printers)+1)
+		for k, v := range c.Printers {
+			printers[k] = v
+		}
+		printers[name] = append(printers[name][:len(printers[name]):len(printers[name])], opts...)
+		c.Printers = printers
+	}}
+}
+
 // WithReplacer sets the field replacer.
 func WithReplacer(v log.Replacer) Option {
 	return Option{apply: func(c *config) {
@@ -200,9 +624,18 @@ func applyConfig(next *config, opts []Option, flagsConfigs ...*config) *config {
 			Output: &defaultOutput,
 			File: fileConfig{
 				Dir:      &defaultFileDir,
+				Pattern:  &defaultFilePattern,
 				Size:     &defaultFileSize,
 				Backups:  &defaultFileBackups,
 				Compress: &defaultFileCompress,
+				Link:     &defaultFileLink,
+				Levels:   defaultFileLevels,
+				Sync:     &defaultFileSync,
+			},
+			Sampling: samplingConfig{
+				First:      &defaultSamplingFirst,
+				Thereafter: &defaultSamplingThereafter,
+				Tick:       &defaultSamplingTick,
 			},
 		}
 	}
@@ -235,6 +668,9 @@ func mergeConfig(next *config, flagsConfig *config) {
 	if flagsConfig.File.Dir != nil {
 		next.File.Dir = flagsConfig.File.Dir
 	}
+	if flagsConfig.File.Pattern != nil {
+		next.File.Pattern = flagsConfig.File.Pattern
+	}
 	if flagsConfig.File.Size != nil {
 		next.File.Size = flagsConfig.File.Size
 	}
@@ -244,9 +680,36 @@ func mergeConfig(next *config, flagsConfig *config) {
 	if flagsConfig.File.Compress != nil {
 		next.File.Compress = flagsConfig.File.Compress
 	}
+	if flagsConfig.File.Link != nil {
+		next.File.Link = flagsConfig.File.Link
+	}
+	if flagsConfig.File.Levels != nil {
+		next.File.Levels = flagsConfig.File.Levels
+	}
+	if flagsConfig.File.Sync != nil {
+		next.File.Sync = flagsConfig.File.Sync
+	}
+	if flagsConfig.Sampling.First != nil {
+		next.Sampling.First = flagsConfig.Sampling.First
+	}
+	if flagsConfig.Sampling.Thereafter != nil {
+		next.Sampling.Thereafter = flagsConfig.Sampling.Thereafter
+	}
+	if flagsConfig.Sampling.Tick != nil {
+		next.Sampling.Tick = flagsConfig.Sampling.Tick
+	}
+	if flagsConfig.DropKeys != nil {
+		next.DropKeys = flagsConfig.DropKeys
+	}
+	if flagsConfig.AllowKeys != nil {
+		next.AllowKeys = flagsConfig.AllowKeys
+	}
 	if flagsConfig.Replacer != nil {
 		next.Replacer = flagsConfig.Replacer
 	}
+	if flagsConfig.Levels != nil {
+		next.Levels = flagsConfig.Levels
+	}
 	if len(flagsConfig.Fields) > 0 {
 		next.Fields = append(next.Fields, flagsConfig.Fields...)
 	}
@@ -257,9 +720,17 @@ var (
 	defaultFormat       = TextFormat
 	defaultOutput       = StderrOutput
 	defaultFileDir      = "log"
+	defaultFilePattern  = "{name}.log"
 	defaultFileSize     = int64(512)
 	defaultFileBackups  = int64(0)
 	defaultFileCompress = false
+	defaultFileLink     = false
+	defaultFileLevels   = map[string]log.Level{"error": log.LevelWarn}
+	defaultFileSync     = log.SyncOptions{}
+
+	defaultSamplingFirst      = 0
+	defaultSamplingThereafter = 0
+	defaultSamplingTick       = time.Second
 )
 
 // ParseFormat parses a log output format.
@@ -269,6 +740,10 @@ func ParseFormat(s string) (Format, error) {
 		return TextFormat, nil
 	case "json":
 		return JsonFormat, nil
+	case "syslog":
+		return SyslogFormat, nil
+	case "datadog":
+		return DatadogFormat, nil
 	default:
 		return TextFormat, fmt.Errorf("unknown log format %q", s)
 	}
@@ -283,6 +758,8 @@ func ParseOutput(s string) (Output, error) {
 		return StdoutOutput, nil
 	case "file":
 		return FileOutput, nil
+	case "split-file":
+		return SplitFileOutput, nil
 	default:
 		return StderrOutput, fmt.Errorf("unknown log output %q", s)
 	}
@@ -291,7 +768,10 @@ func ParseOutput(s string) (Output, error) {
 func parseConfigField(cfg *config, key, value string) error {
 	switch key {
 	case "level":
-		v := log.ParseLevel(value)
+		v, err := parseLevel(value)
+		if err != nil {
+			return err
+		}
 		cfg.Level = &v
 	case "format":
 		v, err := ParseFormat(value)
@@ -307,17 +787,28 @@ func parseConfigField(cfg *config, key, value string) error {
 		cfg.Output = &v
 	case "file-dir":
 		cfg.File.Dir = &value
+	case "file-pattern":
+		if err := validateFilePattern(value); err != nil {
+			return fmt.Errorf("invalid log file pattern %q: %w", value, err)
+		}
+		cfg.File.Pattern = &value
 	case "file-size":
 		v, err := strconv.ParseInt(value, 10, 64)
 		if err != nil {
 			return fmt.Errorf("invalid log file size %q: %w", value, err)
 		}
+		if v < 0 {
+			return fmt.Errorf("invalid log file size %q: %w", value, errNegative)
+		}
 		cfg.File.Size = &v
 	case "file-backups":
 		v, err := strconv.ParseInt(value, 10, 64)
 		if err != nil {
 			return fmt.Errorf("invalid log file backups %q: %w", value, err)
 		}
+		if v < 0 {
+			return fmt.Errorf("invalid log file backups %q: %w", value, errNegative)
+		}
 		cfg.File.Backups = &v
 	case "file-compress":
 		v, err := strconv.ParseBool(value)
@@ -325,8 +816,150 @@ func parseConfigField(cfg *config, key, value string) error {
 			return fmt.Errorf("invalid log file compress %q: %w", value, err)
 		}
 		cfg.File.Compress = &v
+	case "file-link":
+		v, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid log file link %q: %w", value, err)
+		}
+		cfg.File.Link = &v
+	case "file-sync":
+		v, err := parseFileSync(value)
+		if err != nil {
+			return fmt.Errorf("invalid log file sync %q: %w", value, err)
+		}
+		cfg.File.Sync = &v
+	case "levels":
+		v, err := parseLevels(value)
+		if err != nil {
+			return fmt.Errorf("invalid log levels %q: %w", value, err)
+		}
+		cfg.Levels = v
+	case "file-levels":
+		v, err := parseLevelFiles(value)
+		if err != nil {
+			return fmt.Errorf("invalid log file levels %q: %w", value, err)
+		}
+		cfg.File.Levels = v
+	case "sampling-first":
+		v, err := strconv.Atoi(value)
+		if err != nil || v < 0 {
+			return fmt.Errorf("invalid log sampling first %q", value)
+		}
+		cfg.Sampling.First = &v
+	case "sampling-thereafter":
+		v, err := strconv.Atoi(value)
+		if err != nil || v < 0 {
+			return fmt.Errorf("invalid log sampling thereafter %q", value)
+		}
+		cfg.Sampling.Thereafter = &v
+	case "sampling-tick":
+		v, err := time.ParseDuration(value)
+		if err != nil || v <= 0 {
+			return fmt.Errorf("invalid log sampling tick %q", value)
+		}
+		cfg.Sampling.Tick = &v
+	case "drop-keys":
+		cfg.DropKeys = splitKeys(value)
+	case "allow-keys":
+		cfg.AllowKeys = splitKeys(value)
 	default:
 		return fmt.Errorf("unknown log config key %q", key)
 	}
 	return nil
 }
+
+// parseLevel parses a level as log.ParseLevel does, but rejects unknown level
+// names instead of returning log.LevelInfo.
+func parseLevel(s string) (log.Level, error) {
+	name, offset := s, ""
+	if i := strings.IndexAny(s, "+-"); i >= 0 {
+		name, offset = s[:i], s[i:]
+	}
+	switch strings.ToLower(name) {
+	case "debug", "info", "warn", "error", "fatal":
+	default:
+		return log.LevelInfo, fmt.Errorf("unknown log level %q", s)
+	}
+	if offset != "" {
+		if _, err := strconv.Atoi(offset); err != nil {
+			return log.LevelInfo, fmt.Errorf("invalid log level %q: %w", s, err)
+		}
+	}
+	return log.ParseLevel(s), nil
+}
+
+// parseFileSync parses a sync policy: "never", "always", a number of records
+// such as "100", or an interval such as "1s".
+func parseFileSync(s string) (log.SyncOptions, error) {
+	switch s = strings.ToLower(strings.TrimSpace(s)); s {
+	case "never", "":
+		return log.SyncOptions{}, nil
+	case "always":
+		return log.SyncOptions{Records: 1}, nil
+	}
+	if n, err := strconv.Atoi(s); err == nil {
+		if n < 0 {
+			return log.SyncOptions{}, errNegative
+		}
+		return log.SyncOptions{Records: n}, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return log.SyncOptions{}, errors.New("want never, always, a record count or an interval")
+	}
+	if d < 0 {
+		return log.SyncOptions{}, errNegative
+	}
+	return log.SyncOptions{Interval: d}, nil
+}
+
+// parseLevels parses printer levels such as "db=warn,db.migrations=debug".
+func parseLevels(s string) (map[string]log.Level, error) {
+	levels := make(map[string]log.Level)
+	for _, item := range splitKeys(s) {
+		name, value, ok := strings.Cut(item, "=")
+		if !ok {
+			return nil, fmt.Errorf("want name=level, got %q", item)
+		}
+		if err := validatePrinterName(name); err != nil {
+			return nil, fmt.Errorf("name %q: %w", name, err)
+		}
+		level, err := parseLevel(value)
+		if err != nil {
+			return nil, err
+		}
+		levels[name] = level
+	}
+	return levels, nil
+}
+
+// parseLevelFiles parses level files such as "error=warn,fatal=fatal".
+func parseLevelFiles(s string) (map[string]log.Level, error) {
+	files := make(map[string]log.Level)
+	for _, item := range splitKeys(s) {
+		suffix, name, ok := strings.Cut(item, "=")
+		if !ok {
+			return nil, fmt.Errorf("want suffix=level, got %q", item)
+		}
+		if err := validateLevelFileSuffix(suffix); err != nil {
+			return nil, fmt.Errorf("suffix %q: %w", suffix, err)
+		}
+		level, err := parseLevel(name)
+		if err != nil {
+			return nil, err
+		}
+		files[suffix] = level
+	}
+	return files, nil
+}
+
+// splitKeys splits a comma-separated list of key patterns.
+func splitKeys(s string) []string {
+	keys := []string{}
+	for _, key := range strings.Split(s, ",") {
+		if key = strings.TrimSpace(key); key != "" {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
@@ -0,0 +1,157 @@
+package logmgr
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// ApplyFrom applies a configuration decoded from v, which is a map with string
+// keys, such as viper's AllSettings or koanf's Raw, or a struct. Its keys are
+// those of WatchFile: top-level keys configure the default scope and the keys
+// under scopes.<name> configure that scope, which is added if it does not
+// exist. Struct fields are named by their mapstructure tag, or else their json
+// tag; a mapstructure tag with ",squash" embeds the fields of the struct.
+// Fields with the zero value are skipped, so use pointers to set a value such
+// as false. Keys are matched ignoring case, and _ matches -.
+//
+// The values are applied like the options of Apply, so flags and the config
+// file of WatchFile take precedence. ApplyFrom returns an error, and applies
+// nothing, if v cannot be decoded. Scopes whose resulting configuration is
+// invalid keep their configuration, and their *ConfigError values are
+// returned joined.
+func (m *Manager) ApplyFrom(v any) error {
+	values := make(map[string]string)
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer || rv.Kind() == reflect.Interface {
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Map && rv.Kind() != reflect.Struct {
+		return fmt.Errorf("logmgr: ApplyFrom: want a map or struct, got %T", v)
+	}
+	if err := flattenValue(values, "", rv); err != nil {
+		return fmt.Errorf("logmgr: ApplyFrom: %w", err)
+	}
+	cfgs, err := configsFromValues(values)
+	if err != nil {
+		return fmt.Errorf("logmgr: ApplyFrom: %w", err)
+	}
+
+	names := make([]string, 0, len(cfgs))
+	for name := range cfgs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var errs []error
+	for _, name := range names {
+		cfg := cfgs[name]
+		opt := Option{apply: func(c *config) { mergeConfig(c, cfg) }}
+		if name == "" {
+			name = m.name
+		}
+		m.mu.Lock()
+		scope, ok := m.scopes.load(name)
+		if !ok {
+			_, err = m.addScopeLocked(name, opt)
+		}
+		m.mu.Unlock()
+		if ok {
+			err = scope.Apply(opt)
+		}
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// flattenValue adds the scalars of v to values under dotted keys starting
+// with key. Sequences are joined with commas.
+func flattenValue(values map[string]string, key string, v reflect.Value) error {
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	prefix := key
+	if prefix != "" {
+		prefix += "."
+	}
+	switch v.Kind() {
+	case reflect.Map:
+		iter := v.MapRange()
+		for iter.Next() {
+			if err := flattenValue(values, prefix+fmt.Sprint(iter.Key().Interface()), iter.Value()); err != nil {
+				return err
+			}
+		}
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if !f.IsExported() || v.Field(i).IsZero() {
+				continue
+			}
+			name, squash := structFieldName(f)
+			switch {
+			case name == "-":
+				continue
+			case squash:
+				if err := flattenValue(values, key, v.Field(i)); err != nil {
+					return err
+				}
+				continue
+			}
+			if err := flattenValue(values, prefix+name, v.Field(i)); err != nil {
+				return err
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		items := make([]string, v.Len())
+		for i := range items {
+			item := v.Index(i)
+			for item.Kind() == reflect.Interface && !item.IsNil() {
+				item = item.Elem()
+			}
+			switch item.Kind() {
+			case reflect.Map, reflect.Struct, reflect.Slice, reflect.Array:
+				return fmt.Errorf("%s: sequences of %s are not supported", key, item.Kind())
+			}
+			items[i] = fmt.Sprint(item.Interface())
+		}
+		values[key] = strings.Join(items, ",")
+	case reflect.Func, reflect.Chan, reflect.UnsafePointer:
+		return fmt.Errorf("%s: unsupported %s value", key, v.Kind())
+	default:
+		if key == "" {
+			return errors.New("want a map or struct")
+		}
+		values[key] = fmt.Sprint(v.Interface())
+	}
+	return nil
+}
+
+// structFieldName returns the config key of f, and whether its fields are
+// squashed into the parent.
+func structFieldName(f reflect.StructField) (string, bool) {
+	for _, tag := range []string{"mapstructure", "json"} {
+		value, ok := f.Tag.Lookup(tag)
+		if !ok {
+			continue
+		}
+		name, opts, _ := strings.Cut(value, ",")
+		if tag == "mapstructure" && strings.Contains(","+opts+",", ",squash,") {
+			return "", true
+		}
+		if name != "" {
+			return name, false
+		}
+	}
+	if f.Anonymous && f.Type.Kind() == reflect.Struct {
+		return "", true
+	}
+	return f.Name, false
+}
@@ -2,12 +2,15 @@ package logmgr
 
 import (
 	"errors"
-	"flag"
+	"sync"
 	"sync/atomic"
+
+	"github.com/nexuer/log"
 )
 
 var defaultManager atomic.Pointer[Manager]
-var defaultFlags = newFlags()
+var defaultFlags = NewFlagBinder()
+var onFatalOnce sync.Once
 
 func checkInit() {
 	if defaultManager.Load() == nil {
@@ -15,7 +18,9 @@ func checkInit() {
 	}
 }
 
-// Init creates and installs the singleton manager.
+// Init creates and installs the singleton manager. A Fatal log call, or a
+// signal caught by log.FlushOnSignal, closes the manager before the process
+// exits, so no final record is lost.
 //
 // Calling Init again panics.
 func Init(name string, opts ...Option) *Manager {
@@ -25,21 +30,37 @@ func Init(name string, opts ...Option) *Manager {
 	if name == "" {
 		panic(errors.New("logmgr: default scope name is empty"))
 	}
-	m := newManager(name, opts...)
+	m := newManager(name, defaultFlags, true, opts...)
 	defaultManager.Store(m)
+	onFatalOnce.Do(func() {
+		log.OnFatal(closeDefaultManager)
+		log.OnSignalFlush(func() error {
+			if m := defaultManager.Load(); m != nil {
+				return m.Close()
+			}
+			return nil
+		})
+	})
 	return m
 }
 
+func closeDefaultManager(log.Record) {
+	if m := defaultManager.Load(); m != nil {
+		reportCloseError(m.Close())
+	}
+}
+
 // M returns the singleton manager installed by Init.
 func M() *Manager {
 	checkInit()
 	return defaultManager.Load()
 }
 
-// AddFlags registers log manager flags on fs.
+// AddFlags registers log manager flags on fs for the manager installed by
+// Init. See FlagBinder.AddFlags.
 //
 // Call AddFlags and parse the flag set before Init so the default scope can
 // apply parsed flag values when it is created.
-func AddFlags(fs *flag.FlagSet) {
-	defaultFlags.AddFlags(fs)
+func AddFlags(fs FlagSet, prefix ...string) {
+	defaultFlags.AddFlags(fs, prefix...)
 }
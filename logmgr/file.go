@@ -0,0 +1,355 @@
+package logmgr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nexuer/log"
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+// dateLayout formats the {date} of file patterns.
+const dateLayout = "2006-01-02"
+
+// now returns the current time for dated files; tests replace it.
+var now = time.Now
+
+var (
+	filePlaceholder = regexp.MustCompile(`\{[^{}]*\}`)
+	hostname        = sync.OnceValue(func() string {
+		host, _ := os.Hostname()
+		return host
+	})
+)
+
+// validateFilePattern checks that a file pattern only has known placeholders
+// and names a file per printer. An empty pattern is the default.
+func validateFilePattern(pattern string) error {
+	if pattern == "" {
+		return nil
+	}
+	for _, p := range filePlaceholder.FindAllString(pattern, -1) {
+		switch p {
+		case "{name}", "{date}", "{pid}", "{host}":
+		default:
+			return fmt.Errorf("unknown placeholder %s", p)
+		}
+	}
+	if !strings.Contains(pattern, "{name}") {
+		return errors.New("missing {name}")
+	}
+	return nil
+}
+
+// fileOutput holds the settings of a file output.
+type fileOutput struct {
+	// path is the file path, with {date} if the file changes daily.
+	path     string
+	size     int64
+	backups  int64
+	compress bool
+	// link is the path of the symlink to the current file, or "".
+	link string
+	sync log.SyncOptions
+}
+
+// fileOutput returns the file output settings of the printer name, or of its
+// level file with suffix.
+func (c *config) fileOutput(name, suffix string) fileOutput {
+	pattern := *c.File.Pattern
+	if pattern == "" {
+		pattern = defaultFilePattern
+	}
+	linkName := name + ".log"
+	if suffix != "" {
+		ext := path.Ext(pattern)
+		pattern = strings.TrimSuffix(pattern, ext) + "." + suffix + ext
+		linkName = name + "." + suffix + ".log"
+	}
+	file := strings.NewReplacer(
+		"{name}", name,
+		"{pid}", strconv.Itoa(os.Getpid()),
+		"{host}", hostname(),
+	).Replace(pattern)
+	o := fileOutput{
+		path:     filepath.Join(*c.File.Dir, file),
+		size:     *c.File.Size,
+		backups:  *c.File.Backups,
+		compress: *c.File.Compress,
+		sync:     *c.File.Sync,
+	}
+	if link := filepath.Join(*c.File.Dir, linkName); *c.File.Link && link != o.path {
+		o.link = link
+	}
+	return o
+}
+
+// levelFile is a file that has the records at or above a level.
+type levelFile struct {
+	level  log.Level
+	output fileOutput
+}
+
+// levelFiles returns the level files of the printer name, ordered by suffix.
+func (c *config) levelFiles(name string) []levelFile {
+	suffixes := make([]string, 0, len(c.File.Levels))
+	for suffix := range c.File.Levels {
+		suffixes = append(suffixes, suffix)
+	}
+	sort.Strings(suffixes)
+	files := make([]levelFile, len(suffixes))
+	for i, suffix := range suffixes {
+		files[i] = levelFile{level: c.File.Levels[suffix], output: c.fileOutput(name, suffix)}
+	}
+	return files
+}
+
+// validateLevelFileSuffix checks that a level file suffix can be put in a file
+// name.
+func validateLevelFileSuffix(suffix string) error {
+	switch {
+	case suffix == "":
+		return errors.New("empty suffix")
+	case strings.ContainsAny(suffix, `/\{}`):
+		return errors.New("suffix has a path separator or a brace")
+	}
+	return nil
+}
+
+// currentPath returns the path of the file written now.
+func (o fileOutput) currentPath() string {
+	return strings.ReplaceAll(o.path, "{date}", now().Format(dateLayout))
+}
+
+func (o fileOutput) open() io.Writer {
+	if o.link != "" || o.sync.Enabled() || strings.Contains(o.path, "{date}") {
+		return &patternFile{output: o}
+	}
+	return log.FileWriter(o.path, o.size, o.backups, o.compress)
+}
+
+// openFiles opens a file output and its level files.
+func openFiles(o fileOutput, levels []levelFile) io.Writer {
+	if len(levels) == 0 {
+		return o.open()
+	}
+	w := &splitFiles{main: o.open(), levels: levels, files: make([]io.Writer, len(levels))}
+	for i, level := range levels {
+		w.files[i] = level.output.open()
+	}
+	return w
+}
+
+// fileOutputOf returns the settings of w if it is a file output.
+func fileOutputOf(w io.Writer) (fileOutput, bool) {
+	switch f := w.(type) {
+	case *lumberjack.Logger:
+		return fileOutput{path: f.Filename, size: int64(f.MaxSize), backups: int64(f.MaxBackups), compress: f.Compress}, true
+	case *patternFile:
+		return f.output, true
+	case *splitFiles:
+		return fileOutputOf(f.main)
+	}
+	return fileOutput{}, false
+}
+
+// levelFilesOf returns the level files of w, or nil.
+func levelFilesOf(w io.Writer) []levelFile {
+	if f, ok := w.(*splitFiles); ok {
+		return f.levels
+	}
+	return nil
+}
+
+// rotatingFiles returns the rotating files that w writes to.
+func rotatingFiles(w io.Writer) []*lumberjack.Logger {
+	switch f := w.(type) {
+	case *lumberjack.Logger:
+		return []*lumberjack.Logger{f}
+	case *patternFile:
+		if file := f.current(); file != nil {
+			return []*lumberjack.Logger{file}
+		}
+	case *splitFiles:
+		files := rotatingFiles(f.main)
+		for _, w := range f.files {
+			files = append(files, rotatingFiles(w)...)
+		}
+		return files
+	}
+	return nil
+}
+
+// splitFiles is the writer of SplitFileOutput. Writes go to the main file; the
+// handler returned by handler also writes the records of the level files to
+// them.
+type splitFiles struct {
+	main   io.Writer
+	levels []levelFile
+	files  []io.Writer
+}
+
+func (f *splitFiles) Write(p []byte) (int, error) {
+	return f.main.Write(p)
+}
+
+func (f *splitFiles) Sync() error {
+	errs := []error{syncFile(f.main)}
+	for _, w := range f.files {
+		errs = append(errs, syncFile(w))
+	}
+	return errors.Join(errs...)
+}
+
+func (f *splitFiles) Close() error {
+	errs := []error{closeWriter(f.main)}
+	for _, w := range f.files {
+		errs = append(errs, closeWriter(w))
+	}
+	return errors.Join(errs...)
+}
+
+// handler wraps h to also write each record to the level files that have its
+// level.
+func (f *splitFiles) handler(h log.Handler) log.Handler {
+	return &splitFilesHandler{next: h, files: f}
+}
+
+type splitFilesHandler struct {
+	next  log.Handler
+	files *splitFiles
+}
+
+func (h *splitFilesHandler) WithFields(ctx context.Context, fields ...log.Field) log.Handler {
+	return &splitFilesHandler{next: h.next.WithFields(ctx, fields...), files: h.files}
+}
+
+func (h *splitFilesHandler) WithGroup(name string) log.Handler {
+	return &splitFilesHandler{next: h.next.WithGroup(name), files: h.files}
+}
+
+func (h *splitFilesHandler) Handle(ctx context.Context, w io.Writer, level log.Level, msg string, kvs ...any) error {
+	var writers []io.Writer
+	for i, file := range h.files.levels {
+		if level >= file.level {
+			if writers == nil {
+				writers = append(make([]io.Writer, 0, len(h.files.files)+1), w)
+			}
+			writers = append(writers, h.files.files[i])
+		}
+	}
+	if writers != nil {
+		w = io.MultiWriter(writers...)
+	}
+	return h.next.Handle(log.AddCallerDepth(ctx, 1), w, level, msg, kvs...)
+}
+
+// patternFile is a rotating file whose path has a {date}, that keeps a link
+// to the current file or that is synced. It moves to the file of the new date
+// at the first write of each day, and then points the link at it.
+type patternFile struct {
+	output fileOutput
+
+	mu   sync.Mutex
+	path string
+	file *lumberjack.Logger
+	// out writes to file, syncing it as set by output.
+	out io.Writer
+}
+
+func (f *patternFile) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	path := f.output.currentPath()
+	moved := f.file == nil || path != f.path
+	if moved {
+		if f.out != nil {
+			reportCloseError(closeWriter(f.out))
+		}
+		f.path = path
+		f.file = log.FileWriter(path, f.output.size, f.output.backups, f.output.compress).(*lumberjack.Logger)
+		f.out = f.file
+		if f.output.sync.Enabled() {
+			f.out = log.SyncWriter(f.file, &f.output.sync)
+		}
+	}
+	n, err := f.out.Write(p)
+	// The link is updated after the first write, which creates the file and
+	// its directory.
+	if moved && err == nil && f.output.link != "" {
+		if err := updateLink(f.output.link, path); err != nil && log.ErrorHandler != nil {
+			log.ErrorHandler(err)
+		}
+	}
+	return n, err
+}
+
+// current returns the file written to, or nil before the first write.
+func (f *patternFile) current() *lumberjack.Logger {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.file
+}
+
+func (f *patternFile) Sync() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.out == nil {
+		return nil
+	}
+	return syncFile(f.out)
+}
+
+func (f *patternFile) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.out == nil {
+		return nil
+	}
+	return closeWriter(f.out)
+}
+
+// syncFile syncs the writer of a file output.
+func syncFile(w io.Writer) error {
+	switch f := w.(type) {
+	case *lumberjack.Logger:
+		return log.SyncWriter(f).Sync()
+	case log.WriteSyncer:
+		return f.Sync()
+	}
+	return nil
+}
+
+// updateLink points the symlink at link to target. The link is replaced with
+// a rename, so readers never see it missing. Its target is relative when
+// possible, so the directory can be moved.
+func updateLink(link, target string) error {
+	if rel, err := filepath.Rel(filepath.Dir(link), target); err == nil {
+		target = rel
+	}
+	if current, err := os.Readlink(link); err == nil && current == target {
+		return nil
+	}
+	tmp := link + ".tmp"
+	_ = os.Remove(tmp)
+	if err := os.Symlink(target, tmp); err != nil {
+		return fmt.Errorf("logmgr: link %s: %w", link, err)
+	}
+	if err := os.Rename(tmp, link); err != nil {
+		_ = os.Remove(tmp)
+		return fmt.Errorf("logmgr: link %s: %w", link, err)
+	}
+	return nil
+}
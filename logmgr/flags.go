@@ -7,6 +7,29 @@ import (
 	"strings"
 )
 
+// FlagSet registers flags. *flag.FlagSet implements it.
+//
+// The values passed to Var also implement the Value interface of
+// github.com/spf13/pflag, so a pflag FlagSet can be used with FlagSetFunc:
+//
+//	logmgr.AddFlags(logmgr.FlagSetFunc(func(v flag.Value, name, usage string) {
+//		f := pfs.VarPF(v.(pflag.Value), name, "", usage)
+//		if b, ok := v.(interface{ IsBoolFlag() bool }); ok && b.IsBoolFlag() {
+//			f.NoOptDefVal = "true"
+//		}
+//	}))
+type FlagSet interface {
+	Var(value flag.Value, name, usage string)
+}
+
+// FlagSetFunc adapts a function to FlagSet.
+type FlagSetFunc func(value flag.Value, name, usage string)
+
+// Var calls f.
+func (f FlagSetFunc) Var(value flag.Value, name, usage string) {
+	f(value, name, usage)
+}
+
 type flagValue struct {
 	typ string
 	set func(string) error
@@ -44,18 +67,34 @@ func (v boolFlagValue) IsBoolFlag() bool {
 	return true
 }
 
-type flags struct {
+// FlagBinder holds the values of the log flags registered by its AddFlags,
+// for the managers created with it. Each binder has its own values, so two
+// managers, or tests, do not share flag state. The zero FlagBinder is ready
+// to use.
+type FlagBinder struct {
 	config *config
 	set    map[string]*config
 }
 
-func newFlags() *flags {
-	return &flags{
+// NewFlagBinder returns an empty FlagBinder.
+func NewFlagBinder() *FlagBinder {
+	return &FlagBinder{
 		config: new(config),
 		set:    make(map[string]*config),
 	}
 }
 
+// configs returns the flag configs of the scope name, nil-safe.
+func (f *FlagBinder) configs(name string, defaultScope bool) []*config {
+	if f == nil {
+		return nil
+	}
+	if defaultScope {
+		return []*config{f.config, f.set[""], f.set[name]}
+	}
+	return []*config{f.set[name]}
+}
+
 func parseBool(s string) (bool, error) {
 	switch strings.ToLower(s) {
 	case "1", "t", "true", "y", "yes", "on":
@@ -67,11 +106,21 @@ func parseBool(s string) (bool, error) {
 	}
 }
 
-// AddFlags registers global log flags and dynamic log-set flags.
-func (f *flags) AddFlags(fs *flag.FlagSet) {
+// AddFlags registers the default-scope log flags and the log-set flag on fs.
+// An optional prefix is prepended to the flag names, so AddFlags(fs,
+// "server-") registers --server-log-level, which avoids collisions with the
+// flags of other components.
+func (f *FlagBinder) AddFlags(fs FlagSet, prefix ...string) {
 	if f.config == nil {
 		f.config = &config{}
 	}
+	if f.set == nil {
+		f.set = make(map[string]*config)
+	}
+	p := ""
+	if len(prefix) > 0 {
+		p = prefix[0]
+	}
 	fs.Var(
 		flagValue{
 			typ: "level",
@@ -79,10 +128,20 @@ func (f *flags) AddFlags(fs *flag.FlagSet) {
 				return parseConfigField(f.config, "level", s)
 			},
 		},
-		"log-level",
+		p+"log-level",
 		fmt.Sprintf("Set log `level`. One of: debug, info, warn, error, fatal (default %q)",
 			strings.ToLower(defaultLevel.String())),
 	)
+	fs.Var(
+		flagValue{
+			typ: "levels",
+			set: func(s string) error {
+				return parseConfigField(f.config, "levels", s)
+			},
+		},
+		p+"log-levels",
+		"Comma-separated name=level levels of printers and the printers below them, such as db=warn,db.migrations=debug",
+	)
 	fs.Var(
 		flagValue{
 			typ: "output",
@@ -90,8 +149,8 @@ func (f *flags) AddFlags(fs *flag.FlagSet) {
 				return parseConfigField(f.config, "output", s)
 			},
 		},
-		"log-output",
-		fmt.Sprintf("Set log `output`. One of: stderr, stdout, file (default %q)", defaultOutput),
+		p+"log-output",
+		fmt.Sprintf("Set log `output`. One of: stderr, stdout, file, split-file (default %q)", defaultOutput),
 	)
 	fs.Var(
 		flagValue{
@@ -100,9 +159,39 @@ func (f *flags) AddFlags(fs *flag.FlagSet) {
 				return parseConfigField(f.config, "file-dir", s)
 			},
 		},
-		"log-file-dir",
+		p+"log-file-dir",
 		fmt.Sprintf("Directory `dir` to store log files (default %q)", defaultFileDir),
 	)
+	fs.Var(
+		flagValue{
+			typ: "pattern",
+			set: func(s string) error {
+				return parseConfigField(f.config, "file-pattern", s)
+			},
+		},
+		p+"log-file-pattern",
+		fmt.Sprintf("Log file name `pattern` with {name}, {date}, {pid} and {host} placeholders (default %q)", defaultFilePattern),
+	)
+	fs.Var(
+		flagValue{
+			typ: "policy",
+			set: func(s string) error {
+				return parseConfigField(f.config, "file-sync", s)
+			},
+		},
+		p+"log-file-sync",
+		"Sync log files to disk: never, always, every N records, or every interval such as 1s (default \"never\")",
+	)
+	fs.Var(
+		flagValue{
+			typ: "levels",
+			set: func(s string) error {
+				return parseConfigField(f.config, "file-levels", s)
+			},
+		},
+		p+"log-file-levels",
+		fmt.Sprintf("Comma-separated suffix=level files of the split-file output (default %q)", "error=warn"),
+	)
 	fs.Var(
 		flagValue{
 			typ: "format",
@@ -110,8 +199,8 @@ func (f *flags) AddFlags(fs *flag.FlagSet) {
 				return parseConfigField(f.config, "format", s)
 			},
 		},
-		"log-format",
-		fmt.Sprintf("Set log `format`. One of: text, json (default %q)", defaultFormat),
+		p+"log-format",
+		fmt.Sprintf("Set log `format`. One of: text, json, syslog, datadog (default %q)", defaultFormat),
 	)
 	fs.Var(
 		flagValue{
@@ -123,7 +212,7 @@ func (f *flags) AddFlags(fs *flag.FlagSet) {
 				return parseConfigField(f.config, "file-size", s)
 			},
 		},
-		"log-file-size",
+		p+"log-file-size",
 		fmt.Sprintf("Maximum log file size in `MB`, 0 means the default value (default %d MB)", defaultFileSize),
 	)
 	fs.Var(
@@ -136,7 +225,7 @@ func (f *flags) AddFlags(fs *flag.FlagSet) {
 				return parseConfigField(f.config, "file-backups", s)
 			},
 		},
-		"log-file-backups",
+		p+"log-file-backups",
 		fmt.Sprintf("Maximum backup `count` to retain, 0 means unlimited (default %d)", defaultFileBackups),
 	)
 	fs.Var(
@@ -148,9 +237,71 @@ func (f *flags) AddFlags(fs *flag.FlagSet) {
 				return parseConfigField(f.config, "file-compress", s)
 			},
 		},
-		"log-file-compress",
+		p+"log-file-compress",
 		fmt.Sprintf("Enable gzip compression for rotated log files (default %t)", defaultFileCompress),
 	)
+	fs.Var(
+		boolFlagValue{
+			set: func(s string) error {
+				if _, err := parseBool(s); err != nil {
+					return err
+				}
+				return parseConfigField(f.config, "file-link", s)
+			},
+		},
+		p+"log-file-link",
+		fmt.Sprintf("Keep a <name>.log symlink to the current log file (default %t)", defaultFileLink),
+	)
+	fs.Var(
+		flagValue{
+			typ: "count",
+			set: func(s string) error {
+				return parseConfigField(f.config, "sampling-first", s)
+			},
+		},
+		p+"log-sampling-first",
+		"Write the first `count` records with the same level and message in each sampling tick, 0 disables sampling (default 0)",
+	)
+	fs.Var(
+		flagValue{
+			typ: "M",
+			set: func(s string) error {
+				return parseConfigField(f.config, "sampling-thereafter", s)
+			},
+		},
+		p+"log-sampling-thereafter",
+		"After the first records, write every `M`th one in each sampling tick, 0 drops them (default 0)",
+	)
+	fs.Var(
+		flagValue{
+			typ: "duration",
+			set: func(s string) error {
+				return parseConfigField(f.config, "sampling-tick", s)
+			},
+		},
+		p+"log-sampling-tick",
+		fmt.Sprintf("Sampling window `duration` (default %s)", defaultSamplingTick),
+	)
+	fs.Var(
+		flagValue{
+			typ: "keys",
+			set: func(s string) error {
+				return parseConfigField(f.config, "drop-keys", s)
+			},
+		},
+		p+"log-drop-keys",
+		"Comma-separated field `keys` to remove from records; globs such as *_token are supported",
+	)
+	fs.Var(
+		flagValue{
+			typ: "keys",
+			set: func(s string) error {
+				return parseConfigField(f.config, "allow-keys", s)
+			},
+		},
+		p+"log-allow-keys",
+		"Comma-separated field `keys` to keep in records, removing all others; globs are supported",
+	)
 	fs.Var(
 		flagValue{
 			typ: "key=value",
@@ -170,7 +321,7 @@ func (f *flags) AddFlags(fs *flag.FlagSet) {
 				return nil
 			},
 		},
-		"log-set",
+		p+"log-set",
 		"Set log config `key=value` or `scope.key=value`. Example: --log-set=db.level=warn",
 	)
 }
@@ -1,32 +1,59 @@
 package logmgr
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io"
 	"os"
 	"sort"
+	"strings"
 	"sync"
 
 	"github.com/nexuer/log"
 )
 
-// Manager manages logger scopes and shared configuration.
+// Manager manages logger scopes and shared configuration. Looking up scopes
+// and printers does not lock; mu serializes changes.
 type Manager struct {
 	mu *sync.RWMutex
 
 	initOptions []Option
 	name        string
-	scopes      map[string]*Scope
+	flags       *FlagBinder
+	// global is set for the manager installed by Init, whose default printer
+	// is the default logger of the log package.
+	global bool
+	scopes registry[*Scope]
+	// fileConfigs holds the configs of the file watched by WatchFile, by
+	// scope name.
+	fileConfigs map[string]*config
+	// stop is closed by Close to stop watching.
+	stop chan struct{}
+	// subscribers receive the configs of changed scopes.
+	subscribers []chan Config
+}
+
+// NewManager creates a Manager with a default scope named name, independent
+// of the one installed by Init. Its default printer does not replace the
+// default logger of the log package. Flags registered with flags configure
+// it; flags may be nil.
+func NewManager(name string, flags *FlagBinder, opts ...Option) *Manager {
+	if name == "" {
+		panic(errors.New("logmgr: default scope name is empty"))
+	}
+	return newManager(name, flags, false, opts...)
 }
 
 // newManager creates a Manager with a default scope named after name.
-func newManager(name string, opts ...Option) *Manager {
+func newManager(name string, flags *FlagBinder, global bool, opts ...Option) *Manager {
 	m := &Manager{
 		name:        name,
+		flags:       flags,
+		global:      global,
 		initOptions: opts,
 		mu:          new(sync.RWMutex),
-		scopes:      make(map[string]*Scope),
+		stop:        make(chan struct{}),
 	}
 	// add default scope
 	_ = m.addScope(name)
@@ -38,17 +65,33 @@ func (m *Manager) isDefaultScope(name string) bool {
 }
 
 func (m *Manager) flagConfigs(name string) []*config {
+	return m.flags.configs(name, m.isDefaultScope(name))
+}
+
+// overrides returns the configs merged over the options of a scope: flags,
+// then the watched config file.
+func (m *Manager) overrides(name string) []*config {
+	return m.overridesWith(name, m.fileConfigs)
+}
+
+// overridesWith is overrides with the configs of a config file.
+func (m *Manager) overridesWith(name string, fileConfigs map[string]*config) []*config {
+	cfgs := m.flagConfigs(name)
 	if m.isDefaultScope(name) {
-		return []*config{defaultFlags.config, defaultFlags.set[""], defaultFlags.set[name]}
+		cfgs = append(cfgs, fileConfigs[""])
 	}
-	return []*config{defaultFlags.set[name]}
+	return append(cfgs, fileConfigs[name])
 }
 
 func (m *Manager) addScope(name string, opts ...Option) *Scope {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	return m.addScopeLocked(name, opts...)
+	scope, err := m.addScopeLocked(name, opts...)
+	if err != nil {
+		panic(err)
+	}
+	return scope
 }
 
 func (m *Manager) scopeOpts(opts ...Option) []Option {
@@ -66,29 +109,30 @@ func (m *Manager) scopeOpts(opts ...Option) []Option {
 	return scopeOpts
 }
 
-func (m *Manager) addScopeLocked(name string, opts ...Option) *Scope {
+func (m *Manager) addScopeLocked(name string, opts ...Option) (*Scope, error) {
 	scope := &Scope{
 		name:    name,
 		manager: m,
-		config:  applyConfig(nil, m.scopeOpts(opts...), m.flagConfigs(name)...),
-		entries: make(map[string]*entry),
+		config:  applyConfig(nil, m.scopeOpts(opts...), m.overrides(name)...),
+	}
+	if err := scope.config.validate(name); err != nil {
+		return nil, err
 	}
 
 	scope.upsertEntryLocked(name)
-	m.scopes[name] = scope
-	return scope
+	m.scopes.store(name, scope)
+	m.notifyLocked(scope)
+	return scope, nil
 }
 
 func (m *Manager) getScope(name string) (*Scope, bool) {
-	m.mu.RLock()
-	defer m.mu.RUnlock()
-	scope, ok := m.scopes[name]
-	return scope, ok
+	return m.scopes.load(name)
 }
 
 // AddScope registers a named scope.
 //
-// It returns an error if the scope already exists.
+// It returns an error if the scope already exists, or a *ConfigError if its
+// configuration is invalid.
 func (m *Manager) AddScope(name string, opts ...Option) (*Scope, error) {
 	if name == "" {
 		return nil, errors.New("logmgr: scope name is empty")
@@ -97,13 +141,13 @@ func (m *Manager) AddScope(name string, opts ...Option) (*Scope, error) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	if _, ok := m.scopes[name]; ok {
+	if _, ok := m.scopes.load(name); ok {
 		return nil, fmt.Errorf(`logmgr: %q scope already exists`, name)
 	}
-	return m.addScopeLocked(name, opts...), nil
+	return m.addScopeLocked(name, opts...)
 }
 
-// MustAddScope is like AddScope but panics if the scope already exists.
+// MustAddScope is like AddScope but panics on error.
 func (m *Manager) MustAddScope(name string, opts ...Option) *Scope {
 	s, err := m.AddScope(name, opts...)
 	if err != nil {
@@ -112,13 +156,75 @@ func (m *Manager) MustAddScope(name string, opts ...Option) *Scope {
 	return s
 }
 
+// Add registers a named scope and returns its default printer. args holds
+// Options, which override the options passed to Init, and key-value pairs,
+// which are appended to the fields of every record of the scope:
+//
+//	db, err := m.Add("db", logmgr.WithLevel(log.LevelDebug), logmgr.WithOutput(logmgr.FileOutput), "component", "db")
+//
+// So a noisy subsystem can log at debug to its own file while the default
+// scope stays at info. Add returns an error if the scope already exists.
+func (m *Manager) Add(name string, args ...any) (log.Printer, error) {
+	scope, err := m.AddScope(name, argOptions(args)...)
+	if err != nil {
+		return nil, err
+	}
+	return scope.Printer(), nil
+}
+
+// GetOrCreate returns the default printer of the named scope, registering the
+// scope as Add does if it does not exist. args is only used when the scope is
+// created, so concurrent initialization paths can call GetOrCreate without
+// handling the error of Add. It panics if the configuration of the new scope
+// is invalid.
+func (m *Manager) GetOrCreate(name string, args ...any) log.Printer {
+	if name == "" {
+		panic(errors.New("logmgr: scope name is empty"))
+	}
+	scope, ok := m.getScope(name)
+	if !ok {
+		m.mu.Lock()
+		var err error
+		if scope, ok = m.scopes.load(name); !ok {
+			scope, err = m.addScopeLocked(name, argOptions(args)...)
+		}
+		m.mu.Unlock()
+		if err != nil {
+			panic(err)
+		}
+	}
+	return scope.Printer()
+}
+
+// argOptions returns the Options of args followed by an option appending
+// their other elements as key-value pairs.
+func argOptions(args []any) []Option {
+	var (
+		opts []Option
+		kvs  []any
+	)
+	for _, arg := range args {
+		if opt, ok := arg.(Option); ok {
+			opts = append(opts, opt)
+		} else {
+			kvs = append(kvs, arg)
+		}
+	}
+	if len(kvs) > 0 {
+		opts = append(opts, AppendKeyValues(kvs...))
+	}
+	return opts
+}
+
 // Apply applies options to the default scope.
 //
 // It does not update other scopes; use Scope.Apply for named scopes.
 //
-// If opts is empty, Apply is a no-op.
-func (m *Manager) Apply(opts ...Option) {
-	m.Scope(m.name).Apply(opts...)
+// If opts is empty, Apply is a no-op. If the resulting configuration is
+// invalid, Apply returns the *ConfigError values joined and the scope keeps
+// its configuration.
+func (m *Manager) Apply(opts ...Option) error {
+	return m.Scope(m.name).Apply(opts...)
 }
 
 // Printer returns a printer from the default scope.
@@ -130,6 +236,51 @@ func (m *Manager) Printer(name ...string) log.Printer {
 	return m.Scope(m.name).Printer(name...)
 }
 
+// Lookup returns the existing printer with the hierarchical name, the full
+// name it logs with, such as "app.db.pool" for the printer "db.pool" of the
+// scope "app".
+func (m *Manager) Lookup(name string) (log.Printer, bool) {
+	scope, ok := m.scopeOf(name)
+	if !ok {
+		return nil, false
+	}
+	e, ok := scope.entries.load(name)
+	if !ok {
+		return nil, false
+	}
+	return e.printer, true
+}
+
+// ApplyTo applies options to the scope or printer with the hierarchical name,
+// as Lookup resolves it. For a printer, the options apply as WithPrinter
+// options of its scope, so they also apply to the printers below it: options
+// for "app.db" apply to "app.db.pool". The printer need not exist yet.
+func (m *Manager) ApplyTo(name string, opts ...Option) error {
+	scope, ok := m.scopeOf(name)
+	if !ok {
+		return fmt.Errorf("logmgr: no scope for %q", name)
+	}
+	if name == scope.name {
+		return scope.Apply(opts...)
+	}
+	return scope.Apply(WithPrinter(strings.TrimPrefix(name, scope.name+"."), opts...))
+}
+
+// scopeOf returns the scope of the hierarchical name: the scope with the
+// longest name that is name or a dot-separated prefix of it.
+func (m *Manager) scopeOf(name string) (*Scope, bool) {
+	var found *Scope
+	for scopeName, scope := range m.scopes.all() {
+		if scopeName != name && !strings.HasPrefix(name, scopeName+".") {
+			continue
+		}
+		if found == nil || len(scopeName) > len(found.name) {
+			found = scope
+		}
+	}
+	return found, found != nil
+}
+
 // DefaultScope returns the manager's default scope.
 func (m *Manager) DefaultScope() *Scope {
 	return m.Scope(m.name)
@@ -148,11 +299,9 @@ func (m *Manager) Scope(name string) *Scope {
 
 // Scopes returns a snapshot of all registered scopes sorted by name.
 func (m *Manager) Scopes() []*Scope {
-	m.mu.RLock()
-	defer m.mu.RUnlock()
-
-	scopes := make([]*Scope, 0, len(m.scopes))
-	for _, scope := range m.scopes {
+	all := m.scopes.all()
+	scopes := make([]*Scope, 0, len(all))
+	for _, scope := range all {
 		scopes = append(scopes, scope)
 	}
 	sort.Slice(scopes, func(i, j int) bool {
@@ -161,52 +310,160 @@ func (m *Manager) Scopes() []*Scope {
 	return scopes
 }
 
-// Close closes all printers managed by m.
-func (m *Manager) Close() error {
+// Config returns the resolved configuration of the default scope.
+func (m *Manager) Config() Config {
+	return m.DefaultScope().Config()
+}
+
+// Subscribe returns a channel that receives the configuration of a scope
+// each time it is added or changed, such as by Apply or WatchFile. If the
+// receiver falls behind, the oldest pending configurations are dropped. The
+// channel is closed when m is closed.
+func (m *Manager) Subscribe() <-chan Config {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
+	ch := make(chan Config, subscribeBuffer)
+	select {
+	case <-m.stop:
+		close(ch)
+	default:
+		m.subscribers = append(m.subscribers, ch)
+	}
+	return ch
+}
+
+// subscribeBuffer is the number of configurations a subscriber can fall
+// behind before the oldest are dropped.
+const subscribeBuffer = 16
+
+func (m *Manager) notifyLocked(scope *Scope) {
+	if len(m.subscribers) == 0 {
+		return
+	}
+	cfg := scope.config.export(scope.name)
+	for _, ch := range m.subscribers {
+		select {
+		case ch <- cfg:
+			continue
+		default:
+		}
+		// Only notifyLocked sends, under m.mu, so once the oldest config is
+		// dropped the send does not block.
+		select {
+		case <-ch:
+		default:
+		}
+		ch <- cfg
+	}
+}
+
+// Sync flushes the outputs of all printers managed by m, as log.Logger.Sync
+// does, including their log files.
+func (m *Manager) Sync() error {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
 	var errs []error
-	for _, scope := range m.scopes {
-		for name, v := range scope.entries {
-			makeDefault := scope.manager.isDefaultScope(scope.name) && name == scope.name
-			if err := v.close(makeDefault); err != nil && !errors.Is(err, os.ErrClosed) {
+	for _, scope := range m.scopes.all() {
+		for _, e := range scope.entries.all() {
+			errs = append(errs, e.logger.Sync())
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Close closes all printers managed by m, stops watching its config file and
+// closes the channels of Subscribe.
+func (m *Manager) Close() error {
+	_, err := m.CloseContext(context.Background())
+	return err
+}
+
+// CloseContext is Close that waits for the outputs of the printers to deliver
+// the records they hold only until ctx is done, as log.Logger.CloseContext
+// does. It returns the number of records discarded at that point, and
+// ctx.Err() if ctx was done first.
+func (m *Manager) CloseContext(ctx context.Context) (uint64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	select {
+	case <-m.stop:
+	default:
+		close(m.stop)
+		for _, ch := range m.subscribers {
+			close(ch)
+		}
+		m.subscribers = nil
+	}
+
+	var (
+		dropped uint64
+		errs    []error
+	)
+	for _, scope := range m.scopes.all() {
+		for name, v := range scope.entries.all() {
+			n, err := v.close(ctx, scope.isDefaultEntry(name))
+			dropped += n
+			if err != nil && !errors.Is(err, os.ErrClosed) {
 				errs = append(errs, err)
 			}
 		}
 	}
-	return errors.Join(errs...)
+	return dropped, errors.Join(errs...)
+}
+
+// Stats returns the sum of the stats of all printers managed by m, including
+// the loggers they used before configuration changes.
+func (m *Manager) Stats() log.Stats {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var stats log.Stats
+	for _, scope := range m.scopes.all() {
+		for _, e := range scope.entries.all() {
+			stats = stats.Add(e.stats())
+		}
+	}
+	return stats
 }
 
 type entry struct {
 	logger  *log.Logger
 	printer *managedPrinter
+	// retired holds the stats of the loggers replaced by apply and close.
+	retired log.Stats
 }
 
-func (e *entry) apply(name string, cfg *config, makeDefault bool) {
-	h := cfg.handler(name)
-	if len(cfg.Fields) > 0 {
-		h = h.WithFields(e.logger.Context(), cfg.Fields...)
-	}
+func (e *entry) stats() log.Stats {
+	return e.retired.Add(e.logger.Stats())
+}
 
+func (e *entry) apply(name string, cfg *config, makeDefault bool) {
 	if e.printer != nil {
 		e.printer.mu.Lock()
 		defer e.printer.mu.Unlock()
 	}
 	oldWriter := e.logger.Writer()
 	w, newPath := cfg.writer(name, oldWriter)
+	h := cfg.handler(name, w)
+	if len(cfg.Fields) > 0 {
+		h = h.WithFields(e.logger.Context(), cfg.Fields...)
+	}
 	if newPath != "" {
 		log.New(oldWriter, h).SetLevel(*cfg.Level).Infof("log output redirected to %s", newPath)
 	}
 	next := log.New(w, h).SetLevel(*cfg.Level).WithContext(e.logger.Context())
 	// managedPrinter adds one wrapper frame around log.Printer.
-	printerLogger := next.WithContext(log.AddCallerDepth(next.Context(), 1))
+	printerLogger := next.AddCallerSkip(1)
 	nextPrinter := log.NewPrinter(printerLogger)
 	if e.printer == nil {
 		e.printer = &managedPrinter{printer: nextPrinter}
 	} else {
 		e.printer.printer = nextPrinter
 	}
+	e.retired = e.stats()
 	e.logger = next
 	if makeDefault {
 		log.SetDefault(next)
@@ -216,12 +473,15 @@ func (e *entry) apply(name string, cfg *config, makeDefault bool) {
 	}
 }
 
-func (e *entry) close(makeDefault bool) error {
+// close closes the output of e, waiting for it to deliver its records until
+// ctx is done, and returns the number of records it discarded then.
+func (e *entry) close(ctx context.Context, makeDefault bool) (uint64, error) {
 	if e.printer != nil {
 		e.printer.mu.Lock()
 		defer e.printer.mu.Unlock()
 	}
-	oldWriter := e.logger.Writer()
+	old := e.logger
+	before := old.Stats().QueueDropped
 	discard := log.New(io.Discard)
 	e.logger = discard
 	if e.printer == nil {
@@ -232,7 +492,21 @@ func (e *entry) close(makeDefault bool) error {
 	if makeDefault {
 		log.SetDefault(discard)
 	}
-	return closeWriter(oldWriter)
+	err := closeWriterContext(ctx, old.Writer())
+	stats := old.Stats()
+	e.retired = e.retired.Add(stats)
+	return stats.QueueDropped - before, err
+}
+
+// closeWriterContext closes w as closeWriter does, with its CloseContext
+// method if it has one.
+func closeWriterContext(ctx context.Context, w io.Writer) error {
+	if cc, ok := w.(interface {
+		CloseContext(ctx context.Context) error
+	}); ok {
+		return cc.CloseContext(ctx)
+	}
+	return closeWriter(w)
 }
 
 func closeWriter(w io.Writer) error {
@@ -319,7 +593,7 @@ type Scope struct {
 	config *config
 
 	name    string
-	entries map[string]*entry
+	entries registry[*entry]
 }
 
 func (s *Scope) locker() *sync.RWMutex {
@@ -331,26 +605,40 @@ func (s *Scope) Name() string {
 	return s.name
 }
 
+// Config returns the resolved configuration of the scope.
+func (s *Scope) Config() Config {
+	s.locker().RLock()
+	defer s.locker().RUnlock()
+	return s.config.export(s.name)
+}
+
 // Apply applies options to the scope.
 //
-// If opts is empty, Apply is a no-op.
-func (s *Scope) Apply(opts ...Option) {
-	s.apply(false, opts...)
+// If opts is empty, Apply is a no-op. If the resulting configuration is
+// invalid, Apply returns the *ConfigError values joined and the scope keeps
+// its configuration.
+func (s *Scope) Apply(opts ...Option) error {
+	return s.apply(false, opts...)
 }
 
-func (s *Scope) apply(force bool, opts ...Option) {
+func (s *Scope) apply(force bool, opts ...Option) error {
 	if !force && len(opts) == 0 {
-		return
+		return nil
 	}
 	s.locker().Lock()
 	defer s.locker().Unlock()
-	// new config
 
-	s.config = applyConfig(s.config, opts, s.manager.flagConfigs(s.name)...)
+	next := applyConfig(s.config.clone(), opts, s.manager.overrides(s.name)...)
+	if err := next.validate(s.name); err != nil {
+		return err
+	}
+	s.config = next
 
-	for k, v := range s.entries {
-		v.apply(k, s.config, s.isDefaultEntry(k))
+	for k, v := range s.entries.all() {
+		v.apply(k, s.entryConfig(k), s.isDefaultEntry(k))
 	}
+	s.manager.notifyLocked(s)
+	return nil
 }
 
 // Printer returns a printer from the scope.
@@ -364,10 +652,7 @@ func (s *Scope) Printer(name ...string) log.Printer {
 		fullName = s.fullName(name[0])
 	}
 
-	s.locker().RLock()
-	e := s.entries[fullName]
-	s.locker().RUnlock()
-	if e != nil {
+	if e, ok := s.entries.load(fullName); ok {
 		return e.printer
 	}
 
@@ -375,8 +660,8 @@ func (s *Scope) Printer(name ...string) log.Printer {
 	s.locker().Lock()
 	defer s.locker().Unlock()
 
-	e = s.entries[fullName]
-	if e == nil {
+	e, ok := s.entries.load(fullName)
+	if !ok {
 		e = s.upsertEntryLocked(fullName)
 	}
 	return e.printer
@@ -390,26 +675,29 @@ func (s *Scope) fullName(name string) string {
 	return s.name + "." + name
 }
 
-func (s *Scope) getEntry(name string) (*entry, bool) {
-	s.locker().RLock()
-	defer s.locker().RUnlock()
-	e, ok := s.entries[name]
-	return e, ok
-}
-
 func (s *Scope) upsertEntryLocked(name string) *entry {
-	e := s.entries[name]
-	if e == nil {
+	e, ok := s.entries.load(name)
+	if !ok {
 		e = &entry{
 			logger: log.New(os.Stderr),
 		}
 	}
 
-	e.apply(name, s.config, s.isDefaultEntry(name))
-	s.entries[name] = e
+	e.apply(name, s.entryConfig(name), s.isDefaultEntry(name))
+	if !ok {
+		s.entries.store(name, e)
+	}
 	return e
 }
 
+// entryConfig returns the config of the printer with the full name.
+func (s *Scope) entryConfig(fullName string) *config {
+	if fullName == s.name {
+		return s.config
+	}
+	return s.config.printer(strings.TrimPrefix(fullName, s.name+"."))
+}
+
 func (s *Scope) isDefaultEntry(name string) bool {
-	return s.manager.isDefaultScope(s.name) && name == s.name
+	return s.manager.global && s.manager.isDefaultScope(s.name) && name == s.name
 }
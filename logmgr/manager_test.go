@@ -3,13 +3,19 @@ package logmgr
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"flag"
+	"fmt"
 	"io"
 	"os"
+	"path/filepath"
 	"runtime"
+	"strconv"
 	"strings"
 	"sync"
+	"syscall"
 	"testing"
+	"time"
 
 	"github.com/nexuer/log"
 	"gopkg.in/natefinch/lumberjack.v2"
@@ -18,10 +24,10 @@ import (
 func resetDefault(t *testing.T) {
 	t.Helper()
 	defaultManager.Store(nil)
-	defaultFlags = newFlags()
+	defaultFlags = NewFlagBinder()
 	t.Cleanup(func() {
 		defaultManager.Store(nil)
-		defaultFlags = newFlags()
+		defaultFlags = NewFlagBinder()
 	})
 }
 
@@ -172,7 +178,7 @@ func TestApplyUpdatesFileWriterConfiguration(t *testing.T) {
 		WithFileSize(1),
 		WithFileBackups(1),
 	)
-	entry := m.DefaultScope().entries["server"]
+	entry := m.DefaultScope().entries.all()["server"]
 	before := entry.logger.Writer().(*lumberjack.Logger)
 
 	m.Apply(
@@ -370,6 +376,7 @@ func TestFlagHelpMetavars(t *testing.T) {
 		"-log-file-dir dir",
 		"-log-file-size MB",
 		"-log-file-backups count",
+		"-log-drop-keys keys",
 		"-log-set key=value",
 	} {
 		if !strings.Contains(out, want) {
@@ -504,3 +511,1035 @@ func mustPanic(t *testing.T, f func()) {
 	}()
 	f()
 }
+
+func TestSamplingFlags(t *testing.T) {
+	resetDefault(t)
+	dir := t.TempDir()
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	AddFlags(fs)
+	if err := fs.Parse([]string{
+		"--log-sampling-first=2",
+		"--log-sampling-thereafter=0",
+		"--log-sampling-tick=1h",
+	}); err != nil {
+		t.Fatalf("parse flags: %v", err)
+	}
+
+	m := Init("server", WithOutput(FileOutput), WithFileDir(dir))
+	p := m.Printer()
+	for i := 0; i < 5; i++ {
+		p.Info("repeated")
+	}
+	if err := m.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(dir + "/server.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Count(string(data), "repeated"); got != 2 {
+		t.Fatalf("sampled output has %d records, want 2:\n%s", got, data)
+	}
+	if err := fs.Parse([]string{"--log-sampling-tick=0s"}); err == nil {
+		t.Fatal("zero sampling tick was accepted")
+	}
+}
+
+func TestManagerStats(t *testing.T) {
+	resetDefault(t)
+	dir := t.TempDir()
+	m := Init("server",
+		WithOutput(FileOutput),
+		WithFileDir(dir),
+		WithSampling(1, 0, time.Hour),
+	)
+	m.Printer().Info("repeated")
+	m.Printer().Info("repeated")
+	m.Printer("db").Info("query")
+
+	// Reconfiguring replaces the loggers but keeps their counts.
+	m.Apply(WithLevel(log.LevelDebug))
+	m.Printer().Info("after apply")
+
+	stats := m.Stats()
+	if stats.Records != 3 || stats.Dropped != 1 || stats.Bytes == 0 {
+		t.Fatalf("Stats() = %+v, want 3 records and 1 dropped", stats)
+	}
+	if err := m.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if got := m.Stats(); got != stats {
+		t.Fatalf("Stats() after Close = %+v, want %+v", got, stats)
+	}
+}
+
+func TestFatalClosesManager(t *testing.T) {
+	resetDefault(t)
+	t.Cleanup(func() { log.SetExitFunc(nil) })
+	dir := t.TempDir()
+	m := Init("server", WithOutput(FileOutput), WithFileDir(dir))
+	log.SetExitFunc(func(int) {})
+
+	log.Fatal("stopping")
+	m.Printer().Info("after fatal")
+
+	data, err := os.ReadFile(dir + "/server.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "stopping") || strings.Contains(string(data), "after fatal") {
+		t.Fatalf("file output = %q", data)
+	}
+}
+
+func TestDropKeysFlag(t *testing.T) {
+	resetDefault(t)
+	dir := t.TempDir()
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	AddFlags(fs)
+	if err := fs.Parse([]string{"--log-drop-keys=password, *_token"}); err != nil {
+		t.Fatalf("parse flags: %v", err)
+	}
+
+	m := Init("server", WithOutput(FileOutput), WithFileDir(dir))
+	log.InfoS("login", "user", "alice", "password", "hunter2", "api_token", "t")
+	if err := m.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(dir + "/server.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "user=alice") || strings.Contains(string(data), "hunter2") ||
+		strings.Contains(string(data), "api_token") {
+		t.Fatalf("file output = %q", data)
+	}
+}
+
+func TestConfigFileFormats(t *testing.T) {
+	files := map[string]string{
+		"log.json": `{
+	"level": "warn",
+	"drop-keys": ["password", "*_token"],
+	"scopes": {"db": {"level": "debug", "output": "file", "file-size": 64}}
+}`,
+		"log.yaml": `# log config
+level: warn
+drop-keys:
+  - password
+  - "*_token"
+scopes:
+  db:
+    level: debug # noisy
+    output: file
+    file-size: 64
+`,
+		"log.toml": `level = "warn"
+drop-keys = ["password", "*_token"]
+
+[scopes.db]
+level = "debug"
+output = "file"
+file-size = 64
+`,
+	}
+	for name, data := range files {
+		cfgs, err := parseConfigFile(name, []byte(data))
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		top, db := cfgs[""], cfgs["db"]
+		if top == nil || db == nil || len(cfgs) != 2 {
+			t.Fatalf("%s: configs = %v, want top-level and db", name, cfgs)
+		}
+		if *top.Level != log.LevelWarn || strings.Join(top.DropKeys, ",") != "password,*_token" {
+			t.Errorf("%s: top-level level = %v, drop keys = %q", name, *top.Level, top.DropKeys)
+		}
+		if *db.Level != log.LevelDebug || *db.Output != FileOutput || *db.File.Size != 64 {
+			t.Errorf("%s: db level = %v, output = %v, file size = %d", name, *db.Level, *db.Output, *db.File.Size)
+		}
+	}
+
+	for name, data := range map[string]string{
+		"log.json": `{"level": "warn",`,
+		"log.yaml": "level warn\n",
+		"log.toml": `colour = "red"`,
+		"log.ini":  "level=warn",
+	} {
+		if _, err := parseConfigFile(name, []byte(data)); err == nil {
+			t.Errorf("%s: invalid config %q was accepted", name, data)
+		}
+	}
+}
+
+func TestWatchFile(t *testing.T) {
+	resetDefault(t)
+	interval := watchInterval
+	watchInterval = 10 * time.Millisecond
+	t.Cleanup(func() { watchInterval = interval })
+
+	path := t.TempDir() + "/log.yaml"
+	write := func(data string) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("level: warn\nscopes:\n  db:\n    level: error\n")
+
+	m := Init("server", WithLevel(log.LevelInfo))
+	defer m.Close()
+	db := m.MustAddScope("db", WithLevel(log.LevelInfo))
+	if err := m.WatchFile(path); err != nil {
+		t.Fatal(err)
+	}
+	if got := *m.DefaultScope().config.Level; got != log.LevelWarn {
+		t.Fatalf("default scope level = %v, want %v", got, log.LevelWarn)
+	}
+	if got := *db.config.Level; got != log.LevelError {
+		t.Fatalf("db scope level = %v, want %v", got, log.LevelError)
+	}
+	if got := *m.MustAddScope("cache").config.Level; got != log.LevelInfo {
+		t.Fatalf("cache scope level = %v, want %v", got, log.LevelInfo)
+	}
+
+	var (
+		mu     sync.Mutex
+		errs   []error
+		prevEH = log.ErrorHandler
+	)
+	log.ErrorHandler = func(err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		errs = append(errs, err)
+	}
+	t.Cleanup(func() { log.ErrorHandler = prevEH })
+
+	write("level: [\nbad")
+	write("level: debug\nscopes:\n  db:\n    level: warn\n")
+	server := m.DefaultScope()
+	waitFor(t, func() bool {
+		m.mu.RLock()
+		defer m.mu.RUnlock()
+		return *db.config.Level == log.LevelWarn && *server.config.Level == log.LevelDebug
+	})
+
+	write("level: error\nformat: xml\n")
+	waitFor(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(errs) > 0
+	})
+	m.mu.RLock()
+	level := *server.config.Level
+	m.mu.RUnlock()
+	if level != log.LevelDebug {
+		t.Fatalf("default scope level after invalid file = %v, want %v", level, log.LevelDebug)
+	}
+
+	if err := m.WatchFile(t.TempDir() + "/missing.yaml"); err == nil {
+		t.Fatal("WatchFile accepted a missing file")
+	}
+}
+
+func TestWatchFileInvalidScopeKeepsPreviousFile(t *testing.T) {
+	resetDefault(t)
+	path := t.TempDir() + "/log.yaml"
+	if err := os.WriteFile(path, []byte("scopes:\n  db:\n    level: warn\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	m := Init("server")
+	defer m.Close()
+	db := m.MustAddScope("db")
+	if err := m.WatchFile(path); err != nil {
+		t.Fatal(err)
+	}
+
+	var cerr *ConfigError
+	err := m.applyFile(path, []byte("scopes:\n  db:\n    level: error\n    drop-keys: \"[\"\n"))
+	if !errors.As(err, &cerr) || cerr.Key != "drop-keys" {
+		t.Fatalf("applyFile = %v, want a drop-keys *ConfigError", err)
+	}
+	if err := db.Apply(WithLevel(log.LevelDebug)); err != nil {
+		t.Fatalf("Apply after an invalid file = %v", err)
+	}
+	// The previous file still overrides the scope.
+	if got := db.Config().Level; got != log.LevelWarn {
+		t.Fatalf("db scope level = %v, want %v", got, log.LevelWarn)
+	}
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met before the deadline")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestReopenAndRotate(t *testing.T) {
+	resetDefault(t)
+	dir := t.TempDir()
+	m := Init("server", WithOutput(FileOutput), WithFileDir(dir))
+	defer m.Close()
+	path := dir + "/server.log"
+
+	m.Printer().Info("before move")
+	if err := os.Rename(path, dir+"/server.log.1"); err != nil {
+		t.Fatal(err)
+	}
+	m.Printer().Info("still old file")
+	if err := m.Reopen(); err != nil {
+		t.Fatal(err)
+	}
+	m.Printer().Info("after reopen")
+
+	old, err := os.ReadFile(dir + "/server.log.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(old), "still old file") || strings.Contains(string(old), "after reopen") {
+		t.Fatalf("moved file = %q", old)
+	}
+	if !strings.Contains(string(data), "after reopen") || strings.Contains(string(data), "before move") {
+		t.Fatalf("reopened file = %q", data)
+	}
+
+	if err := m.Rotate(); err != nil {
+		t.Fatal(err)
+	}
+	m.Printer().Info("after rotate")
+	if data, err = os.ReadFile(path); err != nil {
+		t.Fatal(err)
+	}
+	if got := string(data); !strings.Contains(got, "after rotate") || strings.Contains(got, "after reopen") {
+		t.Fatalf("rotated file = %q", got)
+	}
+}
+
+func TestHandleSignalsReopens(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("SIGHUP cannot be sent on windows")
+	}
+	resetDefault(t)
+	dir := t.TempDir()
+	m := Init("server", WithOutput(FileOutput), WithFileDir(dir))
+	defer m.Close()
+	m.HandleSignals()
+	path := dir + "/server.log"
+
+	m.Printer().Info("before move")
+	if err := os.Rename(path, dir+"/server.log.1"); err != nil {
+		t.Fatal(err)
+	}
+	p, err := os.FindProcess(os.Getpid())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Signal(syscall.SIGHUP); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, func() bool {
+		m.Printer().Info("after signal")
+		_, err := os.Stat(path)
+		return err == nil
+	})
+}
+
+func TestAddOverridesScopeConfig(t *testing.T) {
+	resetDefault(t)
+	dir := t.TempDir()
+	m := Init("server", WithLevel(log.LevelInfo), WithFileDir(dir))
+	defer m.Close()
+
+	db, err := m.Add("db", WithLevel(log.LevelDebug), WithOutput(FileOutput), "component", "db", log.Int("shard", 2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := m.Scope("db").Printer(); got != db {
+		t.Fatal("Add did not return the default printer of the scope")
+	}
+	db.Debug("query")
+	if got := *m.DefaultScope().config.Level; got != log.LevelInfo {
+		t.Fatalf("default scope level = %v, want %v", got, log.LevelInfo)
+	}
+
+	data, err := os.ReadFile(dir + "/db.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(data); !strings.Contains(got, "DEBUG component=db shard=2 msg=query") {
+		t.Fatalf("db file output = %q", got)
+	}
+	if _, err := os.Stat(dir + "/server.log"); !os.IsNotExist(err) {
+		t.Fatalf("default scope wrote to a file: %v", err)
+	}
+	if _, err := m.Add("db"); err == nil {
+		t.Fatal("Add accepted a duplicate scope")
+	}
+}
+
+func TestConcurrentAddPrinterAndApply(t *testing.T) {
+	resetDefault(t)
+	m := Init("server", WithOutput(StdoutOutput), WithLevel(log.LevelError))
+	defer m.Close()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		i := i
+		wg.Add(3)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				m.Add(fmt.Sprintf("scope%d.%d", i, j), WithLevel(log.LevelError))
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				m.Printer(fmt.Sprint("p", j)).Info("filtered")
+				m.Scopes()
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				m.Apply(WithLevel(log.LevelFatal))
+			}
+		}()
+	}
+	wg.Wait()
+	if got := len(m.Scopes()); got != 81 {
+		t.Fatalf("Scopes returned %d scopes, want 81", got)
+	}
+}
+
+func TestGetOrCreate(t *testing.T) {
+	resetDefault(t)
+	m := Init("server")
+	defer m.Close()
+
+	var wg sync.WaitGroup
+	printers := make(chan log.Printer, 8)
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			printers <- m.GetOrCreate("db", WithLevel(log.LevelWarn), "component", "db")
+		}()
+	}
+	wg.Wait()
+	close(printers)
+	for p := range printers {
+		if p != m.Scope("db").Printer() {
+			t.Fatal("GetOrCreate returned different printers")
+		}
+	}
+	if got := *m.Scope("db").config.Level; got != log.LevelWarn {
+		t.Fatalf("db scope level = %v, want %v", got, log.LevelWarn)
+	}
+
+	// The arguments of later calls are ignored.
+	m.GetOrCreate("db", WithLevel(log.LevelDebug))
+	if got := *m.Scope("db").config.Level; got != log.LevelWarn {
+		t.Fatalf("db scope level after GetOrCreate = %v, want %v", got, log.LevelWarn)
+	}
+	if got := m.GetOrCreate("server"); got != m.Printer() {
+		t.Fatal("GetOrCreate did not return the default printer of the default scope")
+	}
+	mustPanic(t, func() { m.GetOrCreate("") })
+}
+
+func TestWithPrinterOutput(t *testing.T) {
+	resetDefault(t)
+	dir := t.TempDir()
+	m := Init("server",
+		WithFileDir(dir),
+		WithPrinter("access", WithOutput(FileOutput)),
+		WithPrinter("audit", WithOutput(FileOutput), AppendKeyValues("audit", true)),
+	)
+	defer m.Close()
+
+	m.Printer("access").Info("GET /")
+	m.Printer("audit").Info("login")
+	m.Printer("worker").Info("started")
+	m.Apply(WithLevel(log.LevelWarn))
+	m.Printer("access").Info("filtered")
+
+	if _, err := os.Stat(dir + "/server.worker.log"); !os.IsNotExist(err) {
+		t.Fatalf("worker printer wrote to a file: %v", err)
+	}
+	if _, err := os.Stat(dir + "/server.log"); !os.IsNotExist(err) {
+		t.Fatalf("default printer wrote to a file: %v", err)
+	}
+	access, err := os.ReadFile(dir + "/server.access.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(access); !strings.Contains(got, "GET /") || strings.Contains(got, "filtered") {
+		t.Fatalf("access file output = %q", got)
+	}
+	audit, err := os.ReadFile(dir + "/server.audit.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(audit); !strings.Contains(got, "audit=true msg=login") {
+		t.Fatalf("audit file output = %q", got)
+	}
+	if len(m.DefaultScope().config.Fields) != 0 {
+		t.Fatalf("printer fields leaked into the scope: %v", m.DefaultScope().config.Fields)
+	}
+}
+
+func TestConfigAndSubscribe(t *testing.T) {
+	resetDefault(t)
+	m := Init("server", WithFormat(JsonFormat), WithDropKeys("password"), WithFields(log.String("service", "api")))
+
+	cfg := m.Config()
+	if cfg.Scope != "server" || cfg.Level != log.LevelInfo || cfg.Format != JsonFormat || cfg.Output != StderrOutput ||
+		cfg.File.Dir != "log" || cfg.File.Size != 512 || cfg.Sampling.Tick != time.Second {
+		t.Fatalf("Config() = %+v", cfg)
+	}
+	if len(cfg.DropKeys) != 1 || len(cfg.Fields) != 1 {
+		t.Fatalf("Config() keys and fields = %v %v", cfg.DropKeys, cfg.Fields)
+	}
+
+	updates := m.Subscribe()
+	m.Apply(WithLevel(log.LevelDebug))
+	if got := <-updates; got.Scope != "server" || got.Level != log.LevelDebug {
+		t.Fatalf("update after Apply = %+v", got)
+	}
+	m.MustAddScope("db", WithLevel(log.LevelWarn))
+	if got := <-updates; got.Scope != "db" || got.Level != log.LevelWarn {
+		t.Fatalf("update after AddScope = %+v", got)
+	}
+	if got := m.Scope("db").Config(); got.Level != log.LevelWarn || got.Format != JsonFormat {
+		t.Fatalf("db Config() = %+v", got)
+	}
+
+	// A subscriber that falls behind receives the latest configs.
+	for i := 0; i < subscribeBuffer+5; i++ {
+		m.Apply(WithSampling(i, 0, time.Second))
+	}
+	var last Config
+	for i := 0; i < subscribeBuffer; i++ {
+		last = <-updates
+	}
+	if last.Sampling.First != subscribeBuffer+4 {
+		t.Fatalf("last update sampling = %+v, want first %d", last.Sampling, subscribeBuffer+4)
+	}
+
+	if err := m.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := <-updates; ok {
+		t.Fatal("Subscribe channel is open after Close")
+	}
+	if _, ok := <-m.Subscribe(); ok {
+		t.Fatal("Subscribe after Close returned an open channel")
+	}
+}
+
+func TestAddFlagsPrefixAndFlagSetFunc(t *testing.T) {
+	resetDefault(t)
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	AddFlags(fs, "server-")
+	if err := fs.Parse([]string{"--server-log-level=debug", "--server-log-file-compress", "--server-log-set=db.level=error"}); err != nil {
+		t.Fatal(err)
+	}
+	if fs.Lookup("log-level") != nil {
+		t.Fatal("AddFlags with a prefix registered --log-level")
+	}
+
+	type pflagValue interface {
+		flag.Value
+		Type() string
+	}
+	types := make(map[string]string)
+	AddFlags(FlagSetFunc(func(v flag.Value, name, usage string) {
+		types[name] = v.(pflagValue).Type()
+	}), "worker-")
+	if types["worker-log-level"] != "level" || types["worker-log-file-compress"] != "bool" || len(types) != 18 {
+		t.Fatalf("registered flags = %v", types)
+	}
+
+	m := Init("server")
+	defer m.Close()
+	if got := m.Config(); got.Level != log.LevelDebug || !got.File.Compress {
+		t.Fatalf("default scope config = %+v", got)
+	}
+	if got := m.MustAddScope("db").Config().Level; got != log.LevelError {
+		t.Fatalf("db scope level = %v, want %v", got, log.LevelError)
+	}
+}
+
+func TestFlagBindersAreIndependent(t *testing.T) {
+	resetDefault(t)
+	defaultLogger := log.Default()
+
+	apiFlags, jobFlags := NewFlagBinder(), new(FlagBinder)
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	apiFlags.AddFlags(fs, "api-")
+	jobFlags.AddFlags(fs, "job-")
+	if err := fs.Parse([]string{"--api-log-level=debug", "--job-log-level=error", "--job-log-set=db.format=json"}); err != nil {
+		t.Fatal(err)
+	}
+
+	api := NewManager("api", apiFlags)
+	defer api.Close()
+	job := NewManager("job", jobFlags, WithOutput(StdoutOutput))
+	defer job.Close()
+	if got := api.Config().Level; got != log.LevelDebug {
+		t.Fatalf("api level = %v, want %v", got, log.LevelDebug)
+	}
+	if got := job.Config().Level; got != log.LevelError {
+		t.Fatalf("job level = %v, want %v", got, log.LevelError)
+	}
+	if got := api.MustAddScope("db").Config().Format; got != TextFormat {
+		t.Fatalf("api db format = %v, want %v", got, TextFormat)
+	}
+	if got := job.MustAddScope("db").Config().Format; got != JsonFormat {
+		t.Fatalf("job db format = %v, want %v", got, JsonFormat)
+	}
+	if log.Default() != defaultLogger {
+		t.Fatal("NewManager replaced the default logger")
+	}
+
+	unflagged := NewManager("cli", nil)
+	defer unflagged.Close()
+	if got := unflagged.Config().Level; got != log.LevelInfo {
+		t.Fatalf("cli level = %v, want %v", got, log.LevelInfo)
+	}
+	mustPanic(t, func() { NewManager("", nil) })
+}
+
+func TestApplyFrom(t *testing.T) {
+	resetDefault(t)
+	m := Init("server")
+	defer m.Close()
+
+	// As returned by viper's AllSettings.
+	settings := map[string]any{
+		"level":     "warn",
+		"drop_keys": []any{"password", "*_token"},
+		"scopes": map[string]any{
+			"db": map[string]any{"level": "debug", "file-size": 64},
+		},
+	}
+	if err := m.ApplyFrom(settings); err != nil {
+		t.Fatal(err)
+	}
+	if got := m.Config(); got.Level != log.LevelWarn || strings.Join(got.DropKeys, ",") != "password,*_token" {
+		t.Fatalf("default scope config = %+v", got)
+	}
+	if got := m.Scope("db").Config(); got.Level != log.LevelDebug || got.File.Size != 64 {
+		t.Fatalf("db scope config = %+v", got)
+	}
+
+	type Sampling struct {
+		First int           `mapstructure:"sampling-first"`
+		Tick  time.Duration `mapstructure:"sampling-tick"`
+	}
+	type scopeConfig struct {
+		Sampling `mapstructure:",squash"`
+		Level    log.Level `mapstructure:"level"`
+		Format   string    `json:"format"`
+		Ignored  string    `mapstructure:"-"`
+	}
+	type appConfig struct {
+		Output string                  `mapstructure:"output"`
+		Scopes map[string]*scopeConfig `mapstructure:"scopes"`
+	}
+	err := m.ApplyFrom(&appConfig{
+		Output: "stdout",
+		Scopes: map[string]*scopeConfig{
+			"db":    {Level: log.LevelError, Sampling: Sampling{First: 10, Tick: time.Minute}, Ignored: "x"},
+			"cache": {Format: "json"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := m.Config(); got.Output != StdoutOutput || got.Level != log.LevelWarn {
+		t.Fatalf("default scope config = %+v", got)
+	}
+	if got := m.Scope("db").Config(); got.Level != log.LevelError || got.Sampling.First != 10 || got.Sampling.Tick != time.Minute {
+		t.Fatalf("db scope config = %+v", got)
+	}
+	if got := m.Scope("cache").Config(); got.Format != JsonFormat || got.Level != log.LevelInfo {
+		t.Fatalf("cache scope config = %+v", got)
+	}
+
+	for _, v := range []any{
+		"level=debug",
+		map[string]any{"colour": "red"},
+		map[string]any{"level": "warn", "scopes": map[string]any{"db": map[string]any{"format": "xml"}}},
+		map[string]any{"drop-keys": []any{map[string]any{}}},
+	} {
+		if err := m.ApplyFrom(v); err == nil {
+			t.Errorf("ApplyFrom(%v) succeeded", v)
+		}
+	}
+	if got := m.Config().Level; got != log.LevelWarn {
+		t.Fatalf("level after invalid ApplyFrom = %v, want %v", got, log.LevelWarn)
+	}
+}
+
+func TestConfigValidation(t *testing.T) {
+	resetDefault(t)
+	dir := t.TempDir()
+	notDir := dir + "/file"
+	if err := os.WriteFile(notDir, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := Config{Scope: "db", Format: Format(9), File: FileConfig{Size: -1}, DropKeys: []string{"[a"}}
+	err := cfg.Validate()
+	var keys []string
+	for _, err := range err.(interface{ Unwrap() []error }).Unwrap() {
+		var cerr *ConfigError
+		if !errors.As(err, &cerr) || cerr.Scope != "db" {
+			t.Fatalf("Validate error %v is not a *ConfigError of scope db", err)
+		}
+		keys = append(keys, cerr.Key)
+	}
+	if got := strings.Join(keys, ","); got != "format,file-size,drop-keys" {
+		t.Fatalf("Validate reported keys %s, want format,file-size,drop-keys", got)
+	}
+	if !errors.Is(err, errNegative) {
+		t.Fatalf("Validate error %v does not wrap the cause", err)
+	}
+
+	m := Init("server")
+	defer m.Close()
+	if err := m.Apply(WithLevel(log.LevelWarn), WithFileSize(-1)); err == nil || !strings.Contains(err.Error(), "file-size") {
+		t.Fatalf("Apply with a negative file size returned %v", err)
+	}
+	if got := m.Config(); got.Level != log.LevelInfo || got.File.Size != 512 {
+		t.Fatalf("config after invalid Apply = %+v", got)
+	}
+	err = m.Apply(WithOutput(FileOutput), WithFileDir(notDir))
+	var cerr *ConfigError
+	if !errors.As(err, &cerr) || cerr.Key != "file-dir" || cerr.Value != notDir {
+		t.Fatalf("Apply with an unwritable file dir returned %v", err)
+	}
+	if err := m.Apply(WithPrinter("audit", WithOutput(FileOutput), WithFileDir(notDir))); !errors.As(err, &cerr) || cerr.Scope != "server.audit" {
+		t.Fatalf("Apply with an invalid printer returned %v", err)
+	}
+	if err := m.Apply(WithOutput(FileOutput), WithFileDir(dir+"/logs")); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := m.AddScope("db", WithSampling(-1, 0, time.Second)); err == nil {
+		t.Fatal("AddScope accepted a negative sampling first")
+	}
+	if _, ok := m.getScope("db"); ok {
+		t.Fatal("AddScope registered an invalid scope")
+	}
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	AddFlags(fs)
+	for _, arg := range []string{"--log-level=loud", "--log-level=warn+x", "--log-file-size=-1", "--log-file-backups=-2"} {
+		if err := fs.Parse([]string{arg}); err == nil {
+			t.Errorf("%s was accepted", arg)
+		}
+	}
+	if err := fs.Parse([]string{"--log-level=warn+2"}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFilePattern(t *testing.T) {
+	resetDefault(t)
+	day := time.Date(2026, 1, 2, 23, 59, 0, 0, time.Local)
+	prevNow := now
+	now = func() time.Time { return day }
+	t.Cleanup(func() { now = prevNow })
+
+	dir := t.TempDir()
+	m := Init("server", WithOutput(FileOutput), WithFileDir(dir), WithFilePattern("{name}-{date}.log"))
+	defer m.Close()
+	m.Printer().Info("first day")
+	m.Printer("worker").Info("worker first day")
+	day = day.Add(2 * time.Minute)
+	m.Printer().Info("second day")
+
+	for file, want := range map[string]string{
+		"server-2026-01-02.log":        "first day",
+		"server.worker-2026-01-02.log": "worker first day",
+		"server-2026-01-03.log":        "second day",
+	} {
+		data, err := os.ReadFile(dir + "/" + file)
+		if err != nil || strings.Count(string(data), "\n") != 1 || !strings.Contains(string(data), want) {
+			t.Fatalf("%s = %q, %v", file, data, err)
+		}
+	}
+	if err := m.Rotate(); err != nil {
+		t.Fatal(err)
+	}
+	matches, _ := filepath.Glob(dir + "/server-2026-01-03-*.log")
+	if len(matches) != 1 {
+		t.Fatalf("rotated files = %v, want one backup of server-2026-01-03.log", matches)
+	}
+
+	host, _ := os.Hostname()
+	if err := m.Apply(WithFilePattern("{host}/{name}.log")); err != nil {
+		t.Fatal(err)
+	}
+	m.Printer().Info("by host")
+	if data, err := os.ReadFile(filepath.Join(dir, host, "server.log")); err != nil || !strings.Contains(string(data), "by host") {
+		t.Fatalf("host file = %q, %v", data, err)
+	}
+
+	for _, pattern := range []string{"{date}.log", "{name}-{user}.log"} {
+		var cerr *ConfigError
+		if err := m.Apply(WithFilePattern(pattern)); !errors.As(err, &cerr) || cerr.Key != "file-pattern" {
+			t.Errorf("Apply(WithFilePattern(%q)) returned %v", pattern, err)
+		}
+	}
+}
+
+func TestFileLink(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("symlinks need privileges on windows")
+	}
+	resetDefault(t)
+	day := time.Date(2026, 1, 2, 23, 59, 0, 0, time.Local)
+	prevNow := now
+	now = func() time.Time { return day }
+	t.Cleanup(func() { now = prevNow })
+
+	dir := t.TempDir()
+	m := Init("server", WithOutput(FileOutput), WithFileDir(dir), WithFilePattern("{name}-{date}.log"), WithFileLink(true))
+	defer m.Close()
+	link := filepath.Join(dir, "server.log")
+	m.Printer().Info("first day")
+	if target, err := os.Readlink(link); err != nil || target != "server-2026-01-02.log" {
+		t.Fatalf("link = %q, %v", target, err)
+	}
+	day = day.Add(2 * time.Minute)
+	m.Printer().Info("second day")
+	if data, err := os.ReadFile(link); err != nil || !strings.Contains(string(data), "second day") {
+		t.Fatalf("linked file = %q, %v", data, err)
+	}
+	if _, err := os.Lstat(link + ".tmp"); !os.IsNotExist(err) {
+		t.Errorf("temporary link left: %v", err)
+	}
+}
+
+func TestSplitFileOutput(t *testing.T) {
+	resetDefault(t)
+	dir := t.TempDir()
+	m := Init("server", WithOutput(SplitFileOutput), WithFileDir(dir))
+	defer m.Close()
+	p := m.Printer()
+	p.Info("info record")
+	p.Warn("warn record")
+	p.Error("error record")
+
+	read := func(file string) string {
+		t.Helper()
+		data, err := os.ReadFile(filepath.Join(dir, file))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(data)
+	}
+	if all := read("server.log"); strings.Count(all, "\n") != 3 {
+		t.Fatalf("server.log = %q", all)
+	}
+	if errs := read("server.error.log"); strings.Contains(errs, "info record") || !strings.Contains(errs, "warn record") || !strings.Contains(errs, "error record") {
+		t.Fatalf("server.error.log = %q", errs)
+	}
+	if err := m.Rotate(); err != nil {
+		t.Fatal(err)
+	}
+	if matches, _ := filepath.Glob(dir + "/server.error-*.log"); len(matches) != 1 {
+		t.Fatalf("rotated level files = %v", matches)
+	}
+
+	if err := m.Apply(WithLevelFiles(map[string]log.Level{"fatal": log.LevelError}), WithFilePattern("{name}-{pid}.log")); err != nil {
+		t.Fatal(err)
+	}
+	p.Warn("warn again")
+	p.Error("error again")
+	pid := strconv.Itoa(os.Getpid())
+	if errs := read("server-" + pid + ".fatal.log"); strings.Contains(errs, "warn again") || !strings.Contains(errs, "error again") {
+		t.Fatalf("fatal file = %q", errs)
+	}
+	if cfg := m.Config(); cfg.File.Levels["fatal"] != log.LevelError || len(cfg.File.Levels) != 1 {
+		t.Errorf("Config().File.Levels = %v", cfg.File.Levels)
+	}
+
+	var cerr *ConfigError
+	if err := m.Apply(WithLevelFiles(map[string]log.Level{"a/b": log.LevelWarn})); !errors.As(err, &cerr) || cerr.Key != "file-levels" {
+		t.Errorf("Apply with an invalid suffix returned %v", err)
+	}
+	cfg := new(config)
+	if err := parseConfigField(cfg, "file-levels", "error=warn, audit=info"); err != nil || len(cfg.File.Levels) != 2 || cfg.File.Levels["audit"] != log.LevelInfo {
+		t.Errorf("file-levels = %v, %v", cfg.File.Levels, err)
+	}
+	if err := parseConfigField(cfg, "file-levels", "error"); err == nil {
+		t.Error("file-levels without a level was accepted")
+	}
+}
+
+func TestFileSync(t *testing.T) {
+	resetDefault(t)
+	dir := t.TempDir()
+	m := Init("server", WithOutput(FileOutput), WithFileDir(dir), WithFileSync(log.SyncOptions{Records: 1}))
+	defer m.Close()
+	m.Printer().Info("synced")
+
+	w := m.DefaultScope().entries.all()["server"].logger.Writer()
+	f, ok := w.(*patternFile)
+	if !ok {
+		t.Fatalf("writer = %T, want a synced file", w)
+	}
+	if _, ok := f.out.(*log.SyncedWriter); !ok {
+		t.Fatalf("file writer = %T, want *log.SyncedWriter", f.out)
+	}
+	if data, err := os.ReadFile(filepath.Join(dir, "server.log")); err != nil || !strings.Contains(string(data), "synced") {
+		t.Fatalf("server.log = %q, %v", data, err)
+	}
+	if got := m.Config().File.Sync; got != (log.SyncOptions{Records: 1}) {
+		t.Errorf("Config().File.Sync = %+v", got)
+	}
+
+	for value, want := range map[string]log.SyncOptions{
+		"never":  {},
+		"always": {Records: 1},
+		"100":    {Records: 100},
+		"1s":     {Interval: time.Second},
+	} {
+		cfg := new(config)
+		if err := parseConfigField(cfg, "file-sync", value); err != nil || *cfg.File.Sync != want {
+			t.Errorf("file-sync=%s: %+v, %v", value, cfg.File.Sync, err)
+		}
+	}
+	for _, value := range []string{"-1", "sometimes", "-1s"} {
+		if err := parseConfigField(new(config), "file-sync", value); err == nil {
+			t.Errorf("file-sync=%s was accepted", value)
+		}
+	}
+}
+
+func TestManagerSync(t *testing.T) {
+	resetDefault(t)
+	dir := t.TempDir()
+	m := Init("server", WithOutput(FileOutput), WithFileDir(dir), WithPrinter("audit", WithFileLink(true), WithFilePattern("{name}-{pid}.log")))
+	defer m.Close()
+	m.Printer().Info("record")
+	m.Printer("audit").Info("audit record")
+	if err := m.Sync(); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Apply(WithOutput(SplitFileOutput)); err != nil {
+		t.Fatal(err)
+	}
+	m.Printer().Error("error record")
+	if err := m.Sync(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestManagerHierarchicalNames(t *testing.T) {
+	dir := t.TempDir()
+	m := NewManager("app", nil, WithOutput(FileOutput), WithFileDir(dir))
+	defer m.Close()
+	jobs, err := m.AddScope("app.jobs")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m.Printer("db.pool").Info("connected")
+	if p, ok := m.Lookup("app.db.pool"); !ok || p != m.Printer("db.pool") {
+		t.Fatal("Lookup did not find app.db.pool")
+	}
+	if p, ok := m.Lookup("app.jobs"); !ok || p != jobs.Printer() {
+		t.Fatal("Lookup did not find the scope app.jobs")
+	}
+	for _, name := range []string{"app.db", "other", "app.dbx.pool"} {
+		if _, ok := m.Lookup(name); ok {
+			t.Errorf("Lookup found %s", name)
+		}
+	}
+
+	if err := m.ApplyTo("app.db", WithLevel(log.LevelWarn)); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.ApplyTo("app.jobs", WithLevel(log.LevelError)); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.ApplyTo("other", WithLevel(log.LevelError)); err == nil {
+		t.Fatal("ApplyTo accepted a name outside the scopes")
+	}
+	m.Printer("db.pool").Info("filtered")
+	m.Printer("db.pool").Warn("slow")
+	if got := *m.DefaultScope().config.Level; got != log.LevelInfo {
+		t.Fatalf("ApplyTo a printer changed the scope level to %v", got)
+	}
+	if got := *jobs.config.Level; got != log.LevelError {
+		t.Fatalf("jobs level = %v", got)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "app.db.pool.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(data); !strings.Contains(got, "[app.db.pool] INFO msg=connected") ||
+		strings.Contains(got, "filtered") || !strings.Contains(got, "msg=slow") {
+		t.Fatalf("output = %q", got)
+	}
+}
+
+func TestLevelsByName(t *testing.T) {
+	m := NewManager("app", nil,
+		WithPrinter("db", WithLevel(log.LevelError)),
+		WithLevels(map[string]log.Level{"db": log.LevelWarn, "db.migrations": log.LevelDebug}),
+	)
+	defer m.Close()
+	scope := m.DefaultScope()
+	for name, want := range map[string]log.Level{
+		"app":                      log.LevelInfo,
+		"app.db":                   log.LevelWarn,
+		"app.db.pool":              log.LevelWarn,
+		"app.db.migrations":        log.LevelDebug,
+		"app.db.migrations.schema": log.LevelDebug,
+		"app.dbx":                  log.LevelInfo,
+	} {
+		if got := *scope.entryConfig(name).Level; got != want {
+			t.Errorf("level of %s = %v, want %v", name, got, want)
+		}
+	}
+	if got := m.Config().Levels; len(got) != 2 || got["db"] != log.LevelWarn {
+		t.Fatalf("Config().Levels = %v", got)
+	}
+
+	var cerr *ConfigError
+	if err := m.Apply(WithLevels(map[string]log.Level{"db..pool": log.LevelWarn})); !errors.As(err, &cerr) || cerr.Key != "levels" {
+		t.Fatalf("Apply with an invalid name returned %v", err)
+	}
+	cfg := new(config)
+	if err := parseConfigField(cfg, "levels", "db=warn, db.migrations=debug"); err != nil || cfg.Levels["db.migrations"] != log.LevelDebug {
+		t.Fatalf("levels = %v, %v", cfg.Levels, err)
+	}
+	for _, value := range []string{"db", "db=loud", ".db=warn"} {
+		if err := parseConfigField(cfg, "levels", value); err == nil {
+			t.Errorf("levels %q was accepted", value)
+		}
+	}
+}
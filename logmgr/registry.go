@@ -0,0 +1,37 @@
+package logmgr
+
+import "sync/atomic"
+
+// registry is a copy-on-write map of names. Reads are lock-free, so looking
+// up printers does not contend with other readers. Writes copy the map and
+// must be serialized by the Manager mutex.
+type registry[V any] struct {
+	m atomic.Pointer[map[string]V]
+}
+
+func (r *registry[V]) load(name string) (V, bool) {
+	if m := r.m.Load(); m != nil {
+		v, ok := (*m)[name]
+		return v, ok
+	}
+	var zero V
+	return zero, false
+}
+
+// all returns the current map, which must not be modified.
+func (r *registry[V]) all() map[string]V {
+	if m := r.m.Load(); m != nil {
+		return *m
+	}
+	return nil
+}
+
+func (r *registry[V]) store(name string, v V) {
+	old := r.all()
+	m := make(map[string]V, len(old)+1)
+	for k, ov := range old {
+		m[k] = ov
+	}
+	m[name] = v
+	r.m.Store(&m)
+}
@@ -0,0 +1,63 @@
+package logmgr
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/nexuer/log"
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+// HandleSignals reopens the log files of m when the process receives SIGHUP,
+// as logrotate and similar tools expect after moving them. It stops when m is
+// closed. Errors are reported to log.ErrorHandler.
+func (m *Manager) HandleSignals() {
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, syscall.SIGHUP)
+	go func() {
+		defer signal.Stop(ch)
+		for {
+			select {
+			case <-m.stop:
+				return
+			case <-ch:
+				if err := m.Reopen(); err != nil && log.ErrorHandler != nil {
+					log.ErrorHandler(err)
+				}
+			}
+		}
+	}()
+}
+
+// Reopen closes the log files of m. Each file is opened again by its path,
+// following symlinks, at the next record, so records go to a new file once
+// the old one has been moved away.
+func (m *Manager) Reopen() error {
+	return m.eachFile(func(f *lumberjack.Logger) error { return f.Close() })
+}
+
+// Rotate renames the current log files of m with a timestamp and opens new
+// ones, keeping at most the configured number of backups.
+func (m *Manager) Rotate() error {
+	return m.eachFile(func(f *lumberjack.Logger) error { return f.Rotate() })
+}
+
+func (m *Manager) eachFile(fn func(f *lumberjack.Logger) error) error {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var errs []error
+	for _, scope := range m.scopes.all() {
+		for _, e := range scope.entries.all() {
+			for _, f := range rotatingFiles(e.logger.Writer()) {
+				if err := fn(f); err != nil {
+					errs = append(errs, fmt.Errorf("logmgr: %s: %w", f.Filename, err))
+				}
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
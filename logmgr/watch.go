@@ -0,0 +1,328 @@
+package logmgr
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nexuer/log"
+)
+
+// watchInterval is how often WatchFile polls the config file.
+var watchInterval = 2 * time.Second
+
+// WatchFile loads the config file at path, applies it and then applies it
+// again whenever its content changes, so levels and outputs can be changed
+// without a restart. The file is polled; watching stops when m is closed.
+//
+// The file is JSON, YAML or TOML, selected by its extension. Its top-level
+// keys are the keys of --log-set, such as level, format or file-dir, and
+// configure the default scope. The keys under scopes.<name> configure the
+// named scope, including scopes added later:
+//
+//	level: info
+//	scopes:
+//	  db:
+//	    level: debug
+//	    output: file
+//
+// The file is applied after flags. Keys removed from the file keep their last
+// value. WatchFile returns an error if the file cannot be read or is invalid,
+// including the *ConfigError of scopes it would misconfigure; later errors
+// are reported to log.ErrorHandler and keep the previous configuration.
+func (m *Manager) WatchFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("logmgr: %w", err)
+	}
+	if err := m.applyFile(path, data); err != nil {
+		return err
+	}
+	go m.watchFile(path, data, watchInterval)
+	return nil
+}
+
+func (m *Manager) watchFile(path string, last []byte, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-m.stop:
+			return
+		case <-ticker.C:
+		}
+		// A file that cannot be read, such as while it is replaced, is read
+		// again at the next tick.
+		data, err := os.ReadFile(path)
+		if err != nil || bytes.Equal(data, last) {
+			continue
+		}
+		last = data
+		if err := m.applyFile(path, data); err != nil && log.ErrorHandler != nil {
+			log.ErrorHandler(err)
+		}
+	}
+}
+
+// applyFile parses a config file and applies it to every scope. If the
+// resulting configuration of any scope is invalid, the file is not applied.
+func (m *Manager) applyFile(path string, data []byte) error {
+	cfgs, err := parseConfigFile(path, data)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	var errs []error
+	for _, scope := range m.Scopes() {
+		next := applyConfig(scope.config.clone(), nil, m.overridesWith(scope.name, cfgs)...)
+		if err := next.validate(scope.name); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		m.mu.Unlock()
+		return errors.Join(errs...)
+	}
+	m.fileConfigs = cfgs
+	m.mu.Unlock()
+	for _, scope := range m.Scopes() {
+		if err := scope.apply(true); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// parseConfigFile returns the configs of a config file by scope name, with ""
+// for the top-level keys.
+func parseConfigFile(path string, data []byte) (map[string]*config, error) {
+	var (
+		values map[string]string
+		err    error
+	)
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".json":
+		values, err = parseJSONConfig(data)
+	case ".yaml", ".yml":
+		values, err = parseYAMLConfig(data)
+	case ".toml":
+		values, err = parseTOMLConfig(data)
+	default:
+		return nil, fmt.Errorf("logmgr: config file %s: unknown extension %q", path, ext)
+	}
+	if err == nil {
+		var cfgs map[string]*config
+		if cfgs, err = configsFromValues(values); err == nil {
+			return cfgs, nil
+		}
+	}
+	return nil, fmt.Errorf("logmgr: config file %s: %w", path, err)
+}
+
+// configsFromValues returns the configs of flattened config values by scope
+// name, with "" for the top-level keys. Keys are matched ignoring case, and
+// _ matches -.
+func configsFromValues(values map[string]string) (map[string]*config, error) {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	cfgs := make(map[string]*config)
+	for _, key := range keys {
+		scope, value := "", values[key]
+		if rest, ok := strings.CutPrefix(key, "scopes."); ok {
+			i := strings.LastIndexByte(rest, '.')
+			if i <= 0 {
+				return nil, fmt.Errorf("want scopes.<name>.<key>, got %q", key)
+			}
+			scope, key = rest[:i], rest[i+1:]
+		}
+		cfg := cfgs[scope]
+		if cfg == nil {
+			cfg = new(config)
+			cfgs[scope] = cfg
+		}
+		key = strings.ReplaceAll(strings.ToLower(key), "_", "-")
+		if err := parseConfigField(cfg, key, value); err != nil {
+			return nil, err
+		}
+	}
+	return cfgs, nil
+}
+
+// parseJSONConfig flattens a JSON object into dotted keys. Arrays are joined
+// with commas.
+func parseJSONConfig(data []byte) (map[string]string, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var obj map[string]any
+	if err := dec.Decode(&obj); err != nil {
+		return nil, err
+	}
+	values := make(map[string]string)
+	if err := flattenJSON(values, "", obj); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+func flattenJSON(values map[string]string, prefix string, obj map[string]any) error {
+	for key, v := range obj {
+		key = prefix + key
+		switch v := v.(type) {
+		case map[string]any:
+			if err := flattenJSON(values, key+".", v); err != nil {
+				return err
+			}
+		case []any:
+			items := make([]string, len(v))
+			for i, item := range v {
+				if _, ok := item.(map[string]any); ok {
+					return fmt.Errorf("%s: arrays of objects are not supported", key)
+				}
+				items[i] = fmt.Sprint(item)
+			}
+			values[key] = strings.Join(items, ",")
+		case nil:
+		default:
+			values[key] = fmt.Sprint(v)
+		}
+	}
+	return nil
+}
+
+// parseYAMLConfig parses the subset of YAML used by config files: nested
+// mappings of scalars, with flow or block sequences of scalars.
+func parseYAMLConfig(data []byte) (map[string]string, error) {
+	type level struct {
+		indent int
+		prefix string
+	}
+	values := make(map[string]string)
+	stack := []level{{indent: -1}}
+	list := "" // key of the block sequence being read
+	for n, line := range strings.Split(string(data), "\n") {
+		line = stripComment(strings.TrimRight(line, " \t\r"))
+		text := strings.TrimLeft(line, " ")
+		if text == "" || text == "---" {
+			continue
+		}
+		indent := len(line) - len(text)
+		if item, ok := strings.CutPrefix(text, "- "); ok && list != "" {
+			if values[list] != "" {
+				values[list] += ","
+			}
+			values[list] += unquote(strings.TrimSpace(item))
+			continue
+		}
+		list = ""
+		for indent <= stack[len(stack)-1].indent {
+			stack = stack[:len(stack)-1]
+		}
+		key, value, ok := strings.Cut(text, ":")
+		if !ok || strings.TrimSpace(key) == "" {
+			return nil, fmt.Errorf("line %d: want key: value", n+1)
+		}
+		key = stack[len(stack)-1].prefix + unquote(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+		switch {
+		case value == "":
+			// A mapping or a block sequence follows.
+			stack = append(stack, level{indent: indent, prefix: key + "."})
+			list = key
+		case strings.HasPrefix(value, "["):
+			values[key] = parseInlineArray(value)
+		default:
+			values[key] = unquote(value)
+		}
+	}
+	return values, nil
+}
+
+// parseTOMLConfig parses the subset of TOML used by config files: tables of
+// key = value pairs whose values are scalars or arrays of scalars.
+func parseTOMLConfig(data []byte) (map[string]string, error) {
+	values := make(map[string]string)
+	prefix := ""
+	for n, line := range strings.Split(string(data), "\n") {
+		text := strings.TrimSpace(stripComment(line))
+		if text == "" {
+			continue
+		}
+		if strings.HasPrefix(text, "[") {
+			table, ok := strings.CutSuffix(strings.TrimPrefix(text, "["), "]")
+			if !ok || strings.HasPrefix(table, "[") {
+				return nil, fmt.Errorf("line %d: invalid table header %q", n+1, text)
+			}
+			prefix = ""
+			for _, name := range strings.Split(table, ".") {
+				prefix += unquote(strings.TrimSpace(name)) + "."
+			}
+			continue
+		}
+		key, value, ok := strings.Cut(text, "=")
+		if !ok || strings.TrimSpace(key) == "" {
+			return nil, fmt.Errorf("line %d: want key = value", n+1)
+		}
+		key = prefix + unquote(strings.TrimSpace(key))
+		if value = strings.TrimSpace(value); strings.HasPrefix(value, "[") {
+			values[key] = parseInlineArray(value)
+		} else {
+			values[key] = unquote(value)
+		}
+	}
+	return values, nil
+}
+
+// parseInlineArray returns the items of an array such as ["a", "b"] joined
+// with commas.
+func parseInlineArray(s string) string {
+	s = strings.TrimSuffix(strings.TrimPrefix(s, "["), "]")
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, unquote(item))
+		}
+	}
+	return strings.Join(items, ",")
+}
+
+// stripComment removes a # comment that is not inside quotes.
+func stripComment(line string) string {
+	var quote byte
+	for i := 0; i < len(line); i++ {
+		switch c := line[i]; {
+		case quote != 0:
+			if c == '\\' && quote == '"' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '#' && (i == 0 || line[i-1] == ' ' || line[i-1] == '\t'):
+			return line[:i]
+		}
+	}
+	return line
+}
+
+func unquote(s string) string {
+	if len(s) >= 2 && s[0] == '\'' && s[len(s)-1] == '\'' {
+		return strings.ReplaceAll(s[1:len(s)-1], "''", "'")
+	}
+	if len(s) >= 2 && s[0] == '"' {
+		if v, err := strconv.Unquote(s); err == nil {
+			return v
+		}
+	}
+	return s
+}
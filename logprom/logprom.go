@@ -0,0 +1,101 @@
+package logprom
+
+import (
+	"errors"
+
+	"github.com/nexuer/log"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Options configures the handler returned by New.
+type Options struct {
+	// Registerer is where the metrics are registered. The default is
+	// prometheus.DefaultRegisterer.
+	Registerer prometheus.Registerer
+	// Name is the logger label, usually the HandlerOptions.Name of next.
+	Name string
+	// Buckets are the buckets of the record size histogram. The default is
+	// log.PrometheusSizeBuckets.
+	Buckets []float64
+}
+
+// Metrics is a log.PrometheusMetrics backed by a counter and a histogram
+// registered on a prometheus.Registerer.
+type Metrics struct {
+	messages *prometheus.CounterVec
+	sizes    *prometheus.HistogramVec
+}
+
+// NewMetrics registers the log.PrometheusMessagesMetric counter and the
+// log.PrometheusRecordSizeMetric histogram on reg, or on
+// prometheus.DefaultRegisterer if reg is nil. If reg already has them, from
+// an earlier call, they are shared, so several loggers can report to one
+// registry.
+func NewMetrics(reg prometheus.Registerer, buckets []float64) (*Metrics, error) {
+	if reg == nil {
+		reg = prometheus.DefaultRegisterer
+	}
+	if buckets == nil {
+		buckets = log.PrometheusSizeBuckets
+	}
+	labels := []string{"level", "logger"}
+	messages := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: log.PrometheusMessagesMetric,
+		Help: "Number of log records written, by level and logger.",
+	}, labels)
+	sizes := prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    log.PrometheusRecordSizeMetric,
+		Help:    "Size of the log records written, in bytes.",
+		Buckets: buckets,
+	}, labels)
+	var err error
+	if messages, err = register(reg, messages); err != nil {
+		return nil, err
+	}
+	if sizes, err = register(reg, sizes); err != nil {
+		return nil, err
+	}
+	return &Metrics{messages: messages, sizes: sizes}, nil
+}
+
+// register registers c on reg, or returns the collector reg already has.
+func register[C prometheus.Collector](reg prometheus.Registerer, c C) (C, error) {
+	if err := reg.Register(c); err != nil {
+		var are prometheus.AlreadyRegisteredError
+		if !errors.As(err, &are) {
+			return c, err
+		}
+		existing, ok := are.ExistingCollector.(C)
+		if !ok {
+			return c, err
+		}
+		return existing, nil
+	}
+	return c, nil
+}
+
+// IncMessages increments the messages counter.
+func (m *Metrics) IncMessages(level, logger string) {
+	m.messages.WithLabelValues(level, logger).Inc()
+}
+
+// ObserveSize observes size in the record size histogram.
+func (m *Metrics) ObserveSize(level, logger string, size float64) {
+	m.sizes.WithLabelValues(level, logger).Observe(size)
+}
+
+// New wraps next with log.Prometheus, recording to metrics registered on
+// opts.Registerer by NewMetrics.
+//
+//	h, err := logprom.New(log.Json(), logprom.Options{Registerer: registry, Name: "api"})
+//	if err != nil {
+//		return err
+//	}
+//	logger := log.New(os.Stderr, h)
+func New(next log.Handler, opts Options) (*log.PrometheusHandler, error) {
+	metrics, err := NewMetrics(opts.Registerer, opts.Buckets)
+	if err != nil {
+		return nil, err
+	}
+	return log.Prometheus(next, log.PrometheusOptions{Metrics: metrics, Name: opts.Name}), nil
+}
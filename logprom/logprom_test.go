@@ -0,0 +1,52 @@
+package logprom
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/nexuer/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestNew(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	var buf bytes.Buffer
+	h, err := New(log.Text(), Options{Registerer: reg, Name: "api"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger := log.New(&buf, h)
+	logger.Info("one")
+	logger.ErrorS("two")
+
+	// A second logger shares the metrics of the registry.
+	h2, err := New(log.Text(), Options{Registerer: reg, Name: "db"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	log.New(&buf, h2).Info("three")
+
+	want := `
+# HELP log_messages_total Number of log records written, by level and logger.
+# TYPE log_messages_total counter
+log_messages_total{level="error",logger="api"} 1
+log_messages_total{level="info",logger="api"} 1
+log_messages_total{level="info",logger="db"} 1
+`
+	if err := testutil.GatherAndCompare(reg, strings.NewReader(want), log.PrometheusMessagesMetric); err != nil {
+		t.Fatal(err)
+	}
+	if n := testutil.CollectAndCount(reg, log.PrometheusRecordSizeMetric); n != 3 {
+		t.Fatalf("histogram series = %d, want 3", n)
+	}
+}
+
+func TestNewMetricsConflict(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(prometheus.NewGauge(prometheus.GaugeOpts{Name: log.PrometheusMessagesMetric}))
+	if _, err := NewMetrics(reg, nil); err == nil {
+		t.Fatal("NewMetrics succeeded over a conflicting collector")
+	}
+}
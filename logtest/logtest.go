@@ -0,0 +1,118 @@
+// Package logtest helps test handlers against golden files. Records are
+// rendered with a fixed time and source, volatile values in the output are
+// masked, and the result is compared with a file under testdata that is
+// rewritten when LOGTEST_UPDATE is set:
+//
+//	func TestJSONOutput(t *testing.T) {
+//		out := logtest.Render(log.Json(logtest.Options(&log.HandlerOptions{AddTime: true})), func(l *log.Logger) {
+//			l.InfoS("started", "port", 8080)
+//		})
+//		logtest.AssertGolden(t, "testdata/json.golden", logtest.Normalize(out))
+//	}
+package logtest
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/nexuer/log"
+)
+
+// UpdateEnv is the environment variable that makes AssertGolden write the
+// golden files instead of comparing against them.
+const UpdateEnv = "LOGTEST_UPDATE"
+
+var (
+	// Time is the value of the built-in time field set by Replacer.
+	Time = time.Date(2006, time.January, 2, 15, 4, 5, 0, time.UTC)
+	// Source is the value of the source fields set by Replacer.
+	Source = &log.Source{Function: "main.main", File: "main.go", Line: 1}
+)
+
+// Replacer returns a Replacer that sets the built-in time field to Time and
+// every source value, such as the built-in source field and the CallSite
+// fields added by With, to Source, and then calls next if it is not nil.
+func Replacer(next log.Replacer) log.Replacer {
+	return func(ctx context.Context, groups []string, field log.Field) log.Field {
+		switch {
+		case groups == nil && field.Key == log.TimeKey:
+			field.Value = log.TimeValue(Time)
+		case field.Value.Kind() == log.KindSource:
+			field.Value = log.SourceValue(Source)
+		case field.Value.Kind() == log.KindValuer:
+			// Fields added by With are replaced before they are resolved.
+			valuer := field.Value.Valuer()
+			field.Value = log.ValuerValue(func(ctx context.Context) log.Value {
+				if v := valuer(ctx).Resolve(ctx); v.Kind() != log.KindSource {
+					return v
+				}
+				return log.SourceValue(Source)
+			})
+		}
+		if next != nil {
+			field = next(ctx, groups, field)
+		}
+		return field
+	}
+}
+
+// Options returns a copy of opts, which may be nil, whose Replacer is
+// wrapped by Replacer.
+func Options(opts *log.HandlerOptions) *log.HandlerOptions {
+	var o log.HandlerOptions
+	if opts != nil {
+		o = *opts
+	}
+	o.Replacer = Replacer(o.Replacer)
+	return &o
+}
+
+// Render returns the output of h for the records that logs writes with a
+// Logger using h.
+func Render(h log.Handler, logs func(l *log.Logger)) []byte {
+	var buf bytes.Buffer
+	logs(log.New(&buf, h).SetLevel(log.LevelDebug))
+	return buf.Bytes()
+}
+
+var timestampPattern = regexp.MustCompile(`\d{4}-\d{2}-\d{2}[T ]\d{2}:\d{2}:\d{2}(\.\d+)?(Z|[+-]\d{2}:?\d{2})?`)
+
+// Normalize masks the values that vary between runs and that Replacer does
+// not reach, such as Timestamp fields and the timestamps of syslog headers:
+// timestamps become <time>, and matches of the extra patterns become
+// <volatile>.
+func Normalize(out []byte, patterns ...*regexp.Regexp) []byte {
+	out = timestampPattern.ReplaceAll(out, []byte("<time>"))
+	for _, p := range patterns {
+		out = p.ReplaceAll(out, []byte("<volatile>"))
+	}
+	return out
+}
+
+// AssertGolden compares got with the contents of the golden file at path
+// and reports a difference with t.Errorf. If the UpdateEnv environment
+// variable is set, it writes got to path instead.
+func AssertGolden(t testing.TB, path string, got []byte) {
+	t.Helper()
+	if os.Getenv(UpdateEnv) != "" {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("reading golden file: %v; set %s=1 to create it", err, UpdateEnv)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("output differs from %s; set %s=1 to update it\ngot:\n%s\nwant:\n%s", path, UpdateEnv, got, want)
+	}
+}
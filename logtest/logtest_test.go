@@ -0,0 +1,61 @@
+package logtest
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/nexuer/log"
+)
+
+func renderAll(h log.Handler) []byte {
+	return Render(h, func(l *log.Logger) {
+		l = l.WithFields(log.Dynamic("ts", log.Timestamp(time.RFC3339)), log.Dynamic("caller", log.CallSite()))
+		l.DebugS("connecting", "attempt", 1)
+		l.WithGroup("req").ErrorS("failed", "id", 7, "trace", "a1b2c3")
+	})
+}
+
+func TestGolden(t *testing.T) {
+	trace := regexp.MustCompile(`a1b2c3`)
+	opts := Options(&log.HandlerOptions{AddTime: true, AddSource: true})
+	for _, test := range []struct {
+		name    string
+		handler log.Handler
+	}{
+		{"text", log.Text(opts)},
+		{"json", log.Json(opts)},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			AssertGolden(t, filepath.Join("testdata", test.name+".golden"), Normalize(renderAll(test.handler), trace))
+		})
+	}
+}
+
+type recordingTB struct {
+	testing.TB
+	errors int
+}
+
+func (t *recordingTB) Helper()                   {}
+func (t *recordingTB) Errorf(string, ...any)     { t.errors++ }
+func (t *recordingTB) Fatalf(f string, a ...any) { t.TB.Fatalf(f, a...) }
+
+func TestAssertGoldenUpdate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out.golden")
+	t.Setenv(UpdateEnv, "1")
+	AssertGolden(t, path, []byte("one\n"))
+	if got, err := os.ReadFile(path); err != nil || string(got) != "one\n" {
+		t.Fatalf("golden file = %q, %v", got, err)
+	}
+
+	t.Setenv(UpdateEnv, "")
+	rt := &recordingTB{TB: t}
+	AssertGolden(rt, path, []byte("one\n"))
+	AssertGolden(rt, path, []byte("two\n"))
+	if rt.errors != 1 {
+		t.Fatalf("errors = %d, want 1 for the changed output", rt.errors)
+	}
+}
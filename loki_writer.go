@@ -0,0 +1,148 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultLokiLabelFields promotes the level and logger name of JSON records to
+// Loki labels when LokiOptions.LabelFields is nil.
+var DefaultLokiLabelFields = map[string]string{LevelKey: "level", NameKey: "logger"}
+
+// LokiOptions configures the writer returned by LokiWriter.
+type LokiOptions struct {
+	// URL is the push endpoint, such as http://loki:3100/loki/api/v1/push.
+	URL string
+	// Labels are added to every stream.
+	Labels map[string]string
+	// LabelFields maps top-level fields of JSON records to label names. Level
+	// values are lower-cased. Records that are not JSON only get Labels.
+	LabelFields map[string]string
+	// Header is sent with every request, for example X-Scope-OrgID or
+	// Authorization.
+	Header http.Header
+	// Client sends the requests. The default is http.DefaultClient.
+	Client *http.Client
+
+	// BatchSize is the maximum number of records per push. The default is 1000.
+	BatchSize int
+	// BatchBytes is the maximum size of the records in a push. The default is
+	// 1 MiB.
+	BatchBytes int
+	// MaxBufferedBytes bounds the records waiting to be pushed, including
+	// failed pushes being retried. The default is 8 times BatchBytes.
+	MaxBufferedBytes int
+	// FlushInterval pushes a partial batch after this long. The default is 1
+	// second.
+	FlushInterval time.Duration
+	// Backoff retries pushes that fail with a transport error, 429 or 5xx.
+	Backoff Backoff
+}
+
+// LokiWriter returns a writer that pushes records to the Grafana Loki HTTP
+// API. Records are grouped into streams by their labels:
+//
+//	w, err := log.LokiWriter(log.LokiOptions{
+//		URL:    "http://loki:3100/loki/api/v1/push",
+//		Labels: map[string]string{"app": "billing"},
+//	})
+//	logger := log.New(w, log.Json(&log.HandlerOptions{Name: "api"}))
+//	defer logger.Close()
+func LokiWriter(opts LokiOptions) (*BatchWriter, error) {
+	if err := checkHTTPURL(opts.URL); err != nil {
+		return nil, err
+	}
+	if opts.LabelFields == nil {
+		opts.LabelFields = DefaultLokiLabelFields
+	}
+	return newBatchWriter(batchConfig{
+		maxRecords:  opts.BatchSize,
+		maxBytes:    opts.BatchBytes,
+		maxBuffered: opts.MaxBufferedBytes,
+		interval:    opts.FlushInterval,
+		backoff:     opts.Backoff,
+		send: func(batch []batchRecord) ([]batchRecord, error) {
+			body, err := lokiPushBody(opts, batch)
+			if err != nil {
+				return nil, permanentError{err}
+			}
+			_, err = httpPost(opts.Client, opts.URL, opts.Header, "application/json", body)
+			return batch, err
+		},
+	}), nil
+}
+
+type lokiStream struct {
+	Stream map[string]string `json:"stream"`
+	Values [][2]string       `json:"values"`
+}
+
+func lokiPushBody(opts LokiOptions, batch []batchRecord) ([]byte, error) {
+	var streams []*lokiStream
+	index := make(map[string]*lokiStream)
+	for _, r := range batch {
+		line := strings.TrimSuffix(string(r.data), "\n")
+		labels := lokiLabels(opts, r.data)
+		id := lokiStreamID(labels)
+		stream := index[id]
+		if stream == nil {
+			stream = &lokiStream{Stream: labels}
+			index[id] = stream
+			streams = append(streams, stream)
+		}
+		stream.Values = append(stream.Values, [2]string{strconv.FormatInt(r.time.UnixNano(), 10), line})
+	}
+	return json.Marshal(struct {
+		Streams []*lokiStream `json:"streams"`
+	}{streams})
+}
+
+func lokiLabels(opts LokiOptions, record []byte) map[string]string {
+	labels := make(map[string]string, len(opts.Labels)+len(opts.LabelFields))
+	for k, v := range opts.Labels {
+		labels[k] = v
+	}
+	if len(opts.LabelFields) == 0 || !bytes.HasPrefix(record, []byte{'{'}) {
+		return labels
+	}
+	var fields map[string]json.RawMessage
+	if json.Unmarshal(record, &fields) != nil {
+		return labels
+	}
+	for key, label := range opts.LabelFields {
+		raw, ok := fields[key]
+		if !ok {
+			continue
+		}
+		var value string
+		if json.Unmarshal(raw, &value) != nil {
+			value = string(raw)
+		}
+		if key == LevelKey {
+			value = strings.ToLower(value)
+		}
+		labels[label] = value
+	}
+	return labels
+}
+
+func lokiStreamID(labels map[string]string) string {
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var b strings.Builder
+	for _, k := range keys {
+		b.WriteString(strconv.Quote(k))
+		b.WriteByte('=')
+		b.WriteString(strconv.Quote(labels[k]))
+		b.WriteByte(',')
+	}
+	return b.String()
+}
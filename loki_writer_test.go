@@ -0,0 +1,83 @@
+package log
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+type lokiPush struct {
+	Streams []struct {
+		Stream map[string]string `json:"stream"`
+		Values [][2]string       `json:"values"`
+	} `json:"streams"`
+}
+
+func TestLokiWriterPushesStreams(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		pushes   []lokiPush
+		attempts int
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		attempts++
+		if attempts == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		if got := r.Header.Get("X-Scope-OrgID"); got != "team" {
+			t.Errorf("X-Scope-OrgID = %q", got)
+		}
+		var push lokiPush
+		if err := json.NewDecoder(r.Body).Decode(&push); err != nil {
+			t.Error(err)
+		}
+		pushes = append(pushes, push)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	w, err := LokiWriter(LokiOptions{
+		URL:           srv.URL,
+		Labels:        map[string]string{"app": "billing"},
+		Header:        http.Header{"X-Scope-OrgID": {"team"}},
+		FlushInterval: time.Hour,
+		Backoff:       Backoff{Min: time.Millisecond},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger := New(w, Json(&HandlerOptions{Name: "api"}))
+	logger.InfoS("one")
+	logger.ErrorS("two")
+	logger.InfoS("three")
+	if err := logger.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if attempts != 2 || len(pushes) != 1 {
+		t.Fatalf("attempts = %d, pushes = %d, want a retried single push", attempts, len(pushes))
+	}
+	streams := pushes[0].Streams
+	if len(streams) != 2 {
+		t.Fatalf("streams = %+v, want info and error streams", streams)
+	}
+	info, errs := streams[0], streams[1]
+	if info.Stream["level"] != "info" || info.Stream["logger"] != "api" || info.Stream["app"] != "billing" || len(info.Values) != 2 {
+		t.Fatalf("info stream = %+v", info)
+	}
+	if errs.Stream["level"] != "error" || len(errs.Values) != 1 || errs.Values[0][1] != `{"logger":"api","level":"ERROR","msg":"two"}` {
+		t.Fatalf("error stream = %+v", errs)
+	}
+}
+
+func TestLokiWriterRejectsInvalidURL(t *testing.T) {
+	if _, err := LokiWriter(LokiOptions{URL: "loki:3100"}); err == nil {
+		t.Fatal("LokiWriter accepted a URL without a scheme")
+	}
+}
@@ -0,0 +1,62 @@
+package log
+
+import "context"
+
+// namespaceMark is the group of a Namespace field. As an empty group, it is
+// omitted by handlers that receive it directly.
+var namespaceMark Field
+
+// Namespace returns a Field that nests the fields after it under key. In a
+// logging call, it nests the rest of the fields of the call; in Logger.With
+// and Logger.WithFields, it works like Logger.WithGroup, so the fields after
+// it and those of every later call are nested.
+func Namespace(key string) Field {
+	return Field{key, Value{kind: KindGroup, any: groupptr(&namespaceMark)}}
+}
+
+func (v Value) isNamespace() bool {
+	p, ok := v.any.(groupptr)
+	return ok && p == &namespaceMark
+}
+
+// nestNamespaces returns kvs with the arguments after each Namespace field
+// replaced by a group of them. kvs is not modified.
+func nestNamespaces(kvs []any) []any {
+	for i, kv := range kvs {
+		if field, ok := kv.(Field); ok && field.Value.isNamespace() {
+			return append(kvs[:i:i], Field{field.Key, GroupValue(kvsToFieldSlice(nestNamespaces(kvs[i+1:]))...)})
+		}
+	}
+	return kvs
+}
+
+// nestNamespaceFields is nestNamespaces for Fields.
+func nestNamespaceFields(fields []Field) []Field {
+	for i, field := range fields {
+		if field.Value.isNamespace() {
+			return append(fields[:i:i], Field{field.Key, GroupValue(nestNamespaceFields(fields[i+1:])...)})
+		}
+	}
+	return fields
+}
+
+// withFields adds fields to h, turning each Namespace field into a
+// Handler.WithGroup.
+func withFields(ctx context.Context, h Handler, fields []Field) Handler {
+	for i, field := range fields {
+		if !field.Value.isNamespace() {
+			continue
+		}
+		if i > 0 {
+			h = h.WithFields(ctx, fields[:i]...)
+		}
+		if field.Key != "" {
+			h = h.WithGroup(field.Key)
+		}
+		return withFields(ctx, h, fields[i+1:])
+	}
+	if len(fields) == 0 {
+		return h
+	}
+	return h.WithFields(ctx, fields...)
+}
@@ -0,0 +1,122 @@
+package log
+
+import "context"
+
+// An Option configures the Logger returned by NewWithOptions or
+// Logger.WithOptions. Options are returned by WithHandler, WithLevel,
+// WithFields and WithName, and are applied in order, except that fields are
+// added after the handler is set.
+type Option interface {
+	applyLogger(o *loggerOptions)
+}
+
+type loggerOptions struct {
+	handler Handler
+	level   *Level
+	name    *string
+	fields  []Field
+}
+
+type optionFunc func(o *loggerOptions)
+
+func (f optionFunc) applyLogger(o *loggerOptions) { f(o) }
+
+// WithHandler sets the handler of the Logger, and drops the fields added to
+// the previous handler by WithOptions.
+func WithHandler(h Handler) Option {
+	return optionFunc(func(o *loggerOptions) { o.handler = h })
+}
+
+// WithLevel sets the minimum level of the Logger.
+func WithLevel(level Level) Option {
+	return optionFunc(func(o *loggerOptions) { o.level = &level })
+}
+
+// WithFields adds fields to every record of the Logger, as Logger.WithFields
+// does.
+func WithFields(fields ...Field) Option {
+	return optionFunc(func(o *loggerOptions) { o.fields = append(o.fields, fields...) })
+}
+
+// WithName sets the name of the Logger, which the built-in handlers emit as
+// the NameKey field after the Name of their HandlerOptions, joined with a dot.
+func WithName(name string) Option {
+	return optionFunc(func(o *loggerOptions) { o.name = &name })
+}
+
+func newLoggerOptions(opts []Option) *loggerOptions {
+	o := new(loggerOptions)
+	for _, opt := range opts {
+		if opt != nil {
+			opt.applyLogger(o)
+		}
+	}
+	return o
+}
+
+// WithOptions returns a logger derived from l with opts applied. It shares
+// the output and stats of l.
+func (l *Logger) WithOptions(opts ...Option) *Logger {
+	if len(opts) == 0 {
+		return l
+	}
+	l2 := l.clone()
+	l2.applyOptions(newLoggerOptions(opts))
+	return l2
+}
+
+func (l *Logger) applyOptions(o *loggerOptions) {
+	if o.handler != nil {
+		l.setHandler(o.handler, handlerNeedsPC(o.handler))
+	}
+	if o.level != nil {
+		l.level = *o.level
+	}
+	if o.name != nil {
+		l.setName(*o.name)
+	}
+	if len(o.fields) > 0 && l.Handler() != nil {
+		l.addFields(o.fields)
+	}
+}
+
+// nameKey is the context key of the Logger name.
+type nameKey struct{}
+
+// setName sets the name of l and the context that carries it to the handler.
+func (l *Logger) setName(name string) {
+	l.name = name
+	ctx := l.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	l.ctx = context.WithValue(ctx, nameKey{}, name)
+}
+
+// nameContext returns ctx carrying the name of l, for a context passed to Log
+// or LogAttrs.
+func (l *Logger) nameContext(ctx context.Context) context.Context {
+	if l.name == "" {
+		return ctx
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return context.WithValue(ctx, nameKey{}, l.name)
+}
+
+// recordName returns the logger name of a record: the name of a handler
+// joined with the name of the Logger, if any.
+func recordName(ctx context.Context, name string) string {
+	if ctx == nil {
+		return name
+	}
+	n, _ := ctx.Value(nameKey{}).(string)
+	switch {
+	case n == "":
+		return name
+	case name == "":
+		return n
+	}
+	return name + "." + n
+}
@@ -0,0 +1,69 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"testing"
+)
+
+func TestNewOptions(t *testing.T) {
+	var buf bytes.Buffer
+	logger := NewWithOptions(&buf, WithFields(String("svc", "api")), WithHandler(Json()), WithLevel(LevelWarn), WithName("api"))
+	logger.Info("hidden")
+	logger.Warn("shown")
+	want := `{"logger":"api","level":"WARN","svc":"api","msg":"shown"}` + "\n"
+	if got := buf.String(); got != want {
+		t.Fatalf("output = %q, want %q", got, want)
+	}
+
+	buf.Reset()
+	NewWithOptions(&buf, WithLevel(LevelDebug), nil).Debug("plain")
+	if got := buf.String(); got != "DEBUG msg=plain\n" {
+		t.Fatalf("output with a nil option = %q", got)
+	}
+}
+
+func TestWithOptions(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New(&buf, Json(&HandlerOptions{Name: "server"}))
+	derived := logger.WithOptions(WithName("db"), WithLevel(LevelError), WithFields(Int("shard", 2)))
+	derived.Warn("hidden")
+	derived.Error("failed")
+	derived.Log(context.Background(), LevelError, "logged")
+	logger.Info("parent")
+	want := `{"logger":"server.db","level":"ERROR","shard":2,"msg":"failed"}` + "\n" +
+		`{"logger":"server.db","level":"ERROR","shard":2,"msg":"logged"}` + "\n" +
+		`{"logger":"server","level":"INFO","msg":"parent"}` + "\n"
+	if got := buf.String(); got != want {
+		t.Fatalf("output = %q, want %q", got, want)
+	}
+	if derived.Stats().Records != logger.Stats().Records {
+		t.Fatal("WithOptions does not share the stats of the parent")
+	}
+
+	buf.Reset()
+	named := NewWithOptions(&buf, WithName("db"))
+	named.WithContext(context.Background()).Info("ctx")
+	slog.New(NewSlogHandler(named)).Info("slog")
+	want = "[db] INFO msg=ctx\n[db] INFO msg=slog\n"
+	if got := buf.String(); got != want {
+		t.Fatalf("output = %q, want %q", got, want)
+	}
+}
+
+func TestLoggerNamed(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New(&buf, Json(&HandlerOptions{Name: "server"}))
+	pool := logger.Named("db").Named("pool")
+	if pool.Name() != "db.pool" || logger.Name() != "" || logger.Named("") != logger {
+		t.Fatalf("names = %q, %q", pool.Name(), logger.Name())
+	}
+	pool.Info("connected")
+	pool.WithOptions(WithName("cache")).Info("renamed")
+	want := `{"logger":"server.db.pool","level":"INFO","msg":"connected"}` + "\n" +
+		`{"logger":"server.cache","level":"INFO","msg":"renamed"}` + "\n"
+	if got := buf.String(); got != want {
+		t.Fatalf("output = %q, want %q", got, want)
+	}
+}
@@ -24,7 +24,7 @@ func NewPrinter(log *Logger) Printer {
 	if log == nil {
 		log = defaultLogger.Load().global
 	} else {
-		log = log.WithContext(AddCallerDepth(log.ctx, 1))
+		log = log.AddCallerSkip(1)
 	}
 	return &printer{
 		logger: log,
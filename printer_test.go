@@ -86,3 +86,42 @@ func TestPrinterWrite(t *testing.T) {
 		t.Fatalf("printer output = %q, want %q", got, want)
 	}
 }
+
+// infoVia logs through a wrapper function, as an adapter package does.
+func infoVia(logger *log.Logger, msg string) {
+	logger.Info(msg)
+}
+
+// logVia logs with Log through a wrapper function.
+func logVia(logger *log.Logger, msg string) {
+	logger.Log(context.Background(), log.LevelInfo, msg)
+}
+
+func TestAddCallerSkip(t *testing.T) {
+	var wrapped bytes.Buffer
+	skipped := log.New(&wrapped, log.Json()).WithFields(log.DefaultFields...).AddCallerSkip(1)
+	for _, logger := range []*log.Logger{skipped, skipped.WithContext(context.Background())} {
+		wrapped.Reset()
+		_, _, line, _ := runtime.Caller(0)
+		infoVia(logger, "caller")
+		if caller := callerSource(t, wrapped.Bytes()); !strings.HasSuffix(caller.Function, ".TestAddCallerSkip") || caller.Line != line+1 {
+			t.Fatalf("caller = %s:%d (%s), want line %d in TestAddCallerSkip", caller.File, caller.Line, caller.Function, line+1)
+		}
+		wrapped.Reset()
+		_, _, line, _ = runtime.Caller(0)
+		logVia(logger, "caller")
+		if caller := callerSource(t, wrapped.Bytes()); !strings.HasSuffix(caller.Function, ".TestAddCallerSkip") || caller.Line != line+1 {
+			t.Fatalf("Log caller = %s:%d (%s), want line %d in TestAddCallerSkip", caller.File, caller.Line, caller.Function, line+1)
+		}
+	}
+
+	// Caller walks the stack from where it is resolved, so the wrapper frame
+	// must be added to its depth too. Depth 9 is the caller of Info.
+	wrapped.Reset()
+	skipped = log.New(&wrapped, log.Json()).With("caller", log.Caller(9)).AddCallerSkip(1)
+	_, _, line, _ := runtime.Caller(0)
+	infoVia(skipped, "caller")
+	if caller := callerSource(t, wrapped.Bytes()); !strings.HasSuffix(caller.Function, ".TestAddCallerSkip") || caller.Line != line+1 {
+		t.Fatalf("Caller = %s:%d (%s), want line %d in TestAddCallerSkip", caller.File, caller.Line, caller.Function, line+1)
+	}
+}
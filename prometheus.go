@@ -0,0 +1,143 @@
+package log
+
+import (
+	"context"
+	"io"
+	"sync/atomic"
+)
+
+// Names of the metrics recorded through PrometheusMetrics. Both have the
+// labels "level" and "logger".
+const (
+	PrometheusMessagesMetric   = "log_messages_total"
+	PrometheusRecordSizeMetric = "log_record_size_bytes"
+)
+
+// PrometheusSizeBuckets are suggested histogram buckets for
+// PrometheusRecordSizeMetric, from 64 bytes to 64 KiB.
+var PrometheusSizeBuckets = []float64{64, 128, 256, 512, 1024, 2048, 4096, 8192, 16384, 65536}
+
+// PrometheusMetrics records the metrics of the records written through a
+// PrometheusHandler. The logprom package implements it with a
+// prometheus.CounterVec and a prometheus.HistogramVec registered on a
+// prometheus.Registerer, which keeps this package free of the Prometheus
+// client.
+type PrometheusMetrics interface {
+	// IncMessages increments PrometheusMessagesMetric.
+	IncMessages(level, logger string)
+	// ObserveSize observes size in PrometheusRecordSizeMetric.
+	ObserveSize(level, logger string, size float64)
+}
+
+// PrometheusOptions configures the handler returned by Prometheus.
+type PrometheusOptions struct {
+	Metrics PrometheusMetrics
+	// Name is the logger label, usually the HandlerOptions.Name of next.
+	Name string
+}
+
+// PrometheusHandler counts records per level and logger name. It is created
+// by Prometheus.
+type PrometheusHandler struct {
+	next Handler
+	opts PrometheusOptions
+}
+
+// Prometheus wraps next so that every record it writes increments the
+// messages counter of opts.Metrics and observes its size in bytes. The level
+// label is one of "debug", "info", "warn", "error" and "fatal". Place it
+// outside Sample or RateLimit to count only the records that are written; the
+// summaries RateLimit and Dedup write later are not counted. Discard is passed
+// to next as is, and the records the built-in handlers skip writing to it
+// are counted with the size they would have had. Prometheus panics if
+// opts.Metrics is nil.
+func Prometheus(next Handler, opts PrometheusOptions) *PrometheusHandler {
+	if opts.Metrics == nil {
+		panic("log: Prometheus: nil Metrics")
+	}
+	return &PrometheusHandler{next: next, opts: opts}
+}
+
+func (h *PrometheusHandler) WithFields(ctx context.Context, fields ...Field) Handler {
+	return &PrometheusHandler{next: h.next.WithFields(ctx, fields...), opts: h.opts}
+}
+
+func (h *PrometheusHandler) WithGroup(name string) Handler {
+	return &PrometheusHandler{next: h.next.WithGroup(name), opts: h.opts}
+}
+
+func (h *PrometheusHandler) Handle(ctx context.Context, w io.Writer, level Level, msg string, kvs ...any) error {
+	ctx = AddCallerDepth(ctx, 1)
+	// The handlers after next, such as RateLimit and Dedup, may keep w to
+	// write a summary later, so it is not pooled.
+	size := new(atomic.Int64)
+	if w == nil || w == io.Discard || w == Discard {
+		// Pass Discard as is, so the handlers skip the write. The built-in
+		// handlers report the size of the record they skipped instead.
+		ctx = context.WithValue(ctx, discardSizeKey{}, size)
+	} else {
+		w = &sizeWriter{w: w, n: size}
+	}
+	err := h.next.Handle(ctx, w, level, msg, kvs...)
+	n := size.Load()
+	if err != nil || n == 0 {
+		// Nothing was written, such as when a wrapped handler sampled the
+		// record away.
+		return err
+	}
+	label := prometheusLevel(level)
+	h.opts.Metrics.IncMessages(label, h.opts.Name)
+	h.opts.Metrics.ObserveSize(label, h.opts.Name, float64(n))
+	return nil
+}
+
+// sizeWriter counts the bytes a handler writes for one record.
+type sizeWriter struct {
+	w io.Writer
+	n *atomic.Int64
+}
+
+func (w *sizeWriter) Write(p []byte) (int, error) {
+	n, err := w.w.Write(p)
+	w.n.Add(int64(n))
+	return n, err
+}
+
+// discardSizeKey is the context key of the *atomic.Int64 that discarded adds
+// to.
+type discardSizeKey struct{}
+
+// discarded reports the size of a record that a handler skipped writing to
+// Discard, to a PrometheusHandler before it.
+func discarded(ctx context.Context, n int) {
+	if ctx == nil {
+		return
+	}
+	if size, ok := ctx.Value(discardSizeKey{}).(*atomic.Int64); ok {
+		size.Add(int64(n))
+	}
+}
+
+// Close closes next if it is an io.Closer, so RateLimit and Dedup write their
+// pending summaries.
+func (h *PrometheusHandler) Close() error {
+	if c, ok := h.next.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+func prometheusLevel(level Level) string {
+	switch {
+	case level < LevelInfo:
+		return "debug"
+	case level < LevelWarn:
+		return "info"
+	case level < LevelError:
+		return "warn"
+	case level < LevelFatal:
+		return "error"
+	default:
+		return "fatal"
+	}
+}
@@ -0,0 +1,99 @@
+package log
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+type recordingMetrics struct {
+	messages map[string]int
+	sizes    []float64
+}
+
+func (m *recordingMetrics) IncMessages(level, logger string) {
+	m.messages[level+"/"+logger]++
+}
+
+func (m *recordingMetrics) ObserveSize(level, logger string, size float64) {
+	m.sizes = append(m.sizes, size)
+}
+
+func TestPrometheusCountsWrittenRecords(t *testing.T) {
+	var buf bytes.Buffer
+	metrics := &recordingMetrics{messages: make(map[string]int)}
+	h := Prometheus(Sample(Text(), SamplingOptions{First: 1}), PrometheusOptions{Metrics: metrics, Name: "db"})
+	logger := New(&buf, h).With("k", "v")
+
+	logger.Info("query")
+	logger.Info("query") // sampled away
+	logger.ErrorS("failed")
+	logger.Debug("hidden")
+
+	if got, want := fmt.Sprint(metrics.messages), "map[error/db:1 info/db:1]"; got != want {
+		t.Fatalf("messages = %s, want %s", got, want)
+	}
+	var total float64
+	for _, size := range metrics.sizes {
+		total += size
+	}
+	if len(metrics.sizes) != 2 || int(total) != buf.Len() {
+		t.Fatalf("sizes = %v, want 2 records of %d bytes in total", metrics.sizes, buf.Len())
+	}
+}
+
+func TestPrometheusDiscardCountsOnlyWrittenRecords(t *testing.T) {
+	metrics := &recordingMetrics{messages: make(map[string]int)}
+	logger := New(Discard, Prometheus(Sample(Text(), SamplingOptions{First: 1}), PrometheusOptions{Metrics: metrics}))
+	logger.Info("query")
+	logger.Info("query") // sampled away
+	if got, want := fmt.Sprint(metrics.messages), "map[info/:1]"; got != want {
+		t.Fatalf("messages = %s, want %s", got, want)
+	}
+	if len(metrics.sizes) != 1 || metrics.sizes[0] != float64(len("INFO msg=query\n")) {
+		t.Fatalf("sizes = %v", metrics.sizes)
+	}
+}
+
+func TestPrometheusRejectsNilMetrics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("Prometheus without Metrics did not panic")
+		}
+	}()
+	Prometheus(Text(), PrometheusOptions{})
+}
+
+func TestPrometheusWrappedSummaries(t *testing.T) {
+	out := &lockedBuffer{}
+	metrics := &recordingMetrics{messages: make(map[string]int)}
+	rl := RateLimit(Json(), RateLimitOptions{Rate: 1, Timeout: 10 * time.Millisecond})
+	rl.limiter.now = func() time.Time { return time.Unix(0, 0) }
+	logger := New(out, Prometheus(rl, PrometheusOptions{Metrics: metrics}))
+	logger.Info("storm")
+	logger.Info("storm")
+	deadline := time.Now().Add(5 * time.Second)
+	for !strings.Contains(out.String(), "suppressed") {
+		if time.Now().After(deadline) {
+			t.Fatalf("no rate limit summary in %q", out.String())
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	out = &lockedBuffer{}
+	logger = New(out, Prometheus(Dedup(Text(), nil), PrometheusOptions{Metrics: metrics}))
+	for i := 0; i < 3; i++ {
+		logger.Info("repeat")
+	}
+	if err := logger.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "repeated") {
+		t.Fatalf("no dedup summary in %q", out.String())
+	}
+	if got, want := fmt.Sprint(metrics.messages), "map[info/:2]"; got != want {
+		t.Fatalf("messages = %s, want %s", got, want)
+	}
+}
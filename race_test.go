@@ -0,0 +1,7 @@
+//go:build race
+
+package log
+
+// raceEnabled reports whether the tests run with the race detector, which
+// makes allocation counts unreliable.
+const raceEnabled = true
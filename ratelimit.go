@@ -0,0 +1,236 @@
+package log
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// RateLimitOptions configures the handler returned by RateLimit.
+type RateLimitOptions struct {
+	// Rate is the sustained number of records per second. It must be
+	// positive.
+	Rate float64
+	// Burst is the number of records that can be written at once after a
+	// quiet period. The default is Rate, and at least 1.
+	Burst int
+	// PerLevel gives every level its own budget, so a storm of debug records
+	// cannot suppress errors.
+	PerLevel bool
+	// PerName gives every logger name, as set by WithName or Named, its own
+	// budget, so a noisy component cannot suppress the others. It combines
+	// with PerLevel.
+	PerName bool
+	// Timeout writes the summary of suppressed records that no later record
+	// of the same budget has reported after this long, so a storm that stops
+	// is still reported. The default is 30 seconds.
+	Timeout time.Duration
+}
+
+// RateLimitHandler drops records above a token bucket rate. It is created by
+// RateLimit.
+type RateLimitHandler struct {
+	next    Handler
+	limiter *rateLimiter
+}
+
+type rateLimiter struct {
+	rate     float64
+	burst    float64
+	perLevel bool
+	perName  bool
+	timeout  time.Duration
+	now      func() time.Time
+
+	mu      sync.Mutex
+	buckets map[rateKey]*tokenBucket
+
+	dropped atomic.Uint64
+}
+
+// rateKey identifies a budget.
+type rateKey struct {
+	level Level
+	name  string
+}
+
+type tokenBucket struct {
+	tokens     float64
+	last       time.Time
+	suppressed uint64
+	// suppressedRecord is the last suppressed record, whose handler and
+	// writer the timeout summary is written with.
+	suppressedRecord dedupRecord
+	timer            *time.Timer
+}
+
+// RateLimit wraps next so that at most opts.Rate records per second are
+// written, across all loggers derived from the returned handler, or per level
+// and logger name with PerLevel and PerName.
+//
+// When records have been suppressed, the next record that is written is
+// preceded by a summary record at the same level with the number of
+// suppressed records in the "suppressed" field. If no record is written
+// within opts.Timeout, or the handler is closed, the summary is written on its
+// own.
+//
+// RateLimit panics if opts.Rate is not positive.
+func RateLimit(next Handler, opts RateLimitOptions) *RateLimitHandler {
+	if !(opts.Rate > 0) {
+		panic("log: RateLimit: Rate must be positive")
+	}
+	burst := float64(opts.Burst)
+	if burst <= 0 {
+		burst = opts.Rate
+	}
+	if burst < 1 {
+		burst = 1
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = 30 * time.Second
+	}
+	return &RateLimitHandler{
+		next: next,
+		limiter: &rateLimiter{
+			rate:     opts.Rate,
+			burst:    burst,
+			perLevel: opts.PerLevel,
+			perName:  opts.PerName,
+			timeout:  opts.Timeout,
+			now:      time.Now,
+			buckets:  make(map[rateKey]*tokenBucket),
+		},
+	}
+}
+
+func (h *RateLimitHandler) WithFields(ctx context.Context, fields ...Field) Handler {
+	return &RateLimitHandler{next: h.next.WithFields(ctx, fields...), limiter: h.limiter}
+}
+
+func (h *RateLimitHandler) WithGroup(name string) Handler {
+	return &RateLimitHandler{next: h.next.WithGroup(name), limiter: h.limiter}
+}
+
+const rateLimitSummary = "log records suppressed by rate limit"
+
+func (h *RateLimitHandler) Handle(ctx context.Context, w io.Writer, level Level, msg string, kvs ...any) error {
+	allowed, suppressed := h.limiter.allow(ctx, level, dedupRecord{ctx: ctx, next: h.next, w: w, level: level})
+	if !allowed {
+		return nil
+	}
+	ctx = AddCallerDepth(ctx, 1)
+	if suppressed > 0 {
+		if err := h.next.Handle(ctx, w, level, rateLimitSummary, "suppressed", suppressed); err != nil {
+			return err
+		}
+	}
+	return h.next.Handle(ctx, w, level, msg, kvs...)
+}
+
+// Dropped returns the number of records suppressed by the rate limit.
+func (h *RateLimitHandler) Dropped() uint64 {
+	return h.limiter.dropped.Load()
+}
+
+// Close writes the summaries of the records suppressed since the last record
+// of their budget, if any.
+func (h *RateLimitHandler) Close() error {
+	l := h.limiter
+	var summaries []rateSummary
+	l.mu.Lock()
+	for _, b := range l.buckets {
+		if s := b.takeSummaryLocked(); s.n > 0 {
+			summaries = append(summaries, s)
+		}
+	}
+	l.mu.Unlock()
+	// Write the summaries without the lock, so a slow writer does not stall
+	// the records of other budgets.
+	var errs []error
+	for _, s := range summaries {
+		errs = append(errs, s.write())
+	}
+	return errors.Join(errs...)
+}
+
+func (l *rateLimiter) timeoutFlush(b *tokenBucket) {
+	l.mu.Lock()
+	b.timer = nil
+	s := b.takeSummaryLocked()
+	l.mu.Unlock()
+	if s.n > 0 {
+		errorHandler(s.write())
+	}
+}
+
+// rateSummary is the summary of the records suppressed in a bucket.
+type rateSummary struct {
+	r dedupRecord
+	n uint64
+}
+
+func (s rateSummary) write() error {
+	return s.r.next.Handle(s.r.ctx, s.r.w, s.r.level, rateLimitSummary, "suppressed", s.n)
+}
+
+// takeSummaryLocked returns the summary of the records suppressed in b and
+// resets b.
+func (b *tokenBucket) takeSummaryLocked() rateSummary {
+	if b.timer != nil {
+		b.timer.Stop()
+		b.timer = nil
+	}
+	s := rateSummary{r: b.suppressedRecord, n: b.suppressed}
+	b.suppressed = 0
+	b.suppressedRecord = dedupRecord{}
+	return s
+}
+
+// allow takes a token for a record at level. If the record may be written it
+// also returns the number of records suppressed since the last one. Otherwise
+// r is kept to write the summary with.
+func (l *rateLimiter) allow(ctx context.Context, level Level, r dedupRecord) (bool, uint64) {
+	var key rateKey
+	if l.perLevel {
+		key.level = level
+	}
+	if l.perName {
+		key.name = recordName(ctx, "")
+	}
+	now := l.now()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	b := l.buckets[key]
+	if b == nil {
+		b = &tokenBucket{tokens: l.burst, last: now}
+		l.buckets[key] = b
+	}
+	if elapsed := now.Sub(b.last); elapsed > 0 {
+		b.tokens += elapsed.Seconds() * l.rate
+		if b.tokens > l.burst {
+			b.tokens = l.burst
+		}
+		b.last = now
+	}
+	if b.tokens < 1 {
+		b.suppressed++
+		b.suppressedRecord = r
+		l.dropped.Add(1)
+		if b.timer == nil {
+			b.timer = time.AfterFunc(l.timeout, func() { l.timeoutFlush(b) })
+		}
+		return false, 0
+	}
+	b.tokens--
+	suppressed := b.suppressed
+	b.suppressed = 0
+	b.suppressedRecord = dedupRecord{}
+	if b.timer != nil {
+		b.timer.Stop()
+		b.timer = nil
+	}
+	return true, suppressed
+}
@@ -0,0 +1,156 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestRateLimitSuppressesAndSummarizes(t *testing.T) {
+	now := time.Unix(0, 0)
+	var buf bytes.Buffer
+	h := RateLimit(Text(), RateLimitOptions{Rate: 1, Burst: 2})
+	h.limiter.now = func() time.Time { return now }
+	logger := New(&buf, h)
+
+	for i := 0; i < 5; i++ {
+		logger.InfoS("storm", "i", i)
+	}
+	now = now.Add(time.Second)
+	logger.InfoS("calm")
+
+	want := "INFO msg=storm i=0\n" +
+		"INFO msg=storm i=1\n" +
+		"INFO msg=\"log records suppressed by rate limit\" suppressed=3\n" +
+		"INFO msg=calm\n"
+	if got := buf.String(); got != want {
+		t.Fatalf("output = %q, want %q", got, want)
+	}
+	if got := h.Dropped(); got != 3 {
+		t.Fatalf("Dropped() = %d, want 3", got)
+	}
+}
+
+func TestRateLimitPerLevel(t *testing.T) {
+	var buf bytes.Buffer
+	h := RateLimit(Text(), RateLimitOptions{Rate: 1, PerLevel: true})
+	h.limiter.now = func() time.Time { return time.Unix(0, 0) }
+	logger := New(&buf, h).With("svc", "api")
+
+	logger.InfoS("first")
+	logger.InfoS("second")
+	logger.ErrorS("failed")
+
+	want := "INFO svc=api msg=first\nERROR svc=api msg=failed\n"
+	if got := buf.String(); got != want {
+		t.Fatalf("output = %q, want %q", got, want)
+	}
+}
+
+func TestRateLimitPerName(t *testing.T) {
+	var buf bytes.Buffer
+	h := RateLimit(Text(), RateLimitOptions{Rate: 1, PerName: true})
+	h.limiter.now = func() time.Time { return time.Unix(0, 0) }
+	logger := New(&buf, h)
+
+	logger.Named("db").InfoS("first")
+	logger.Named("db").InfoS("second")
+	logger.Named("http").InfoS("request")
+	logger.InfoS("unnamed")
+
+	want := "[db] INFO msg=first\n[http] INFO msg=request\nINFO msg=unnamed\n"
+	if got := buf.String(); got != want {
+		t.Fatalf("output = %q, want %q", got, want)
+	}
+}
+
+func TestRateLimitSummaryWithoutLaterRecord(t *testing.T) {
+	var buf bytes.Buffer
+	h := RateLimit(Text(), RateLimitOptions{Rate: 1})
+	h.limiter.now = func() time.Time { return time.Unix(0, 0) }
+	logger := New(&buf, h)
+	for i := 0; i < 3; i++ {
+		logger.WarnS("storm")
+	}
+	if err := logger.Close(); err != nil {
+		t.Fatal(err)
+	}
+	want := "WARN msg=storm\nWARN msg=\"log records suppressed by rate limit\" suppressed=2\n"
+	if got := buf.String(); got != want {
+		t.Fatalf("output after Close = %q, want %q", got, want)
+	}
+
+	out := &lockedBuffer{}
+	h = RateLimit(Text(), RateLimitOptions{Rate: 1, Timeout: 10 * time.Millisecond})
+	h.limiter.now = func() time.Time { return time.Unix(0, 0) }
+	logger = New(out, h)
+	logger.InfoS("storm")
+	logger.InfoS("storm")
+	want = "INFO msg=storm\nINFO msg=\"log records suppressed by rate limit\" suppressed=1\n"
+	deadline := time.Now().Add(5 * time.Second)
+	for out.String() != want {
+		if time.Now().After(deadline) {
+			t.Fatalf("output after Timeout = %q, want %q", out.String(), want)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestRateLimitSummaryWrittenWithoutLock(t *testing.T) {
+	// Text would serialize the writes itself.
+	h := RateLimit(messageHandler{}, RateLimitOptions{Rate: 1, PerLevel: true})
+	h.limiter.now = func() time.Time { return time.Unix(0, 0) }
+	var buf lockedBuffer
+	gate := &gateWriter{open: make(chan struct{})}
+	ctx := context.Background()
+	_ = h.Handle(ctx, &buf, LevelWarn, "storm")
+	_ = h.Handle(ctx, gate, LevelWarn, "storm") // suppressed, summarized to gate
+
+	closed := make(chan error)
+	go func() { closed <- h.Close() }()
+	// Wait until Close has taken the summary and is blocked writing it.
+	for taken := false; !taken; time.Sleep(time.Millisecond) {
+		h.limiter.mu.Lock()
+		taken = h.limiter.buckets[rateKey{level: LevelWarn}].suppressed == 0
+		h.limiter.mu.Unlock()
+	}
+	done := make(chan struct{})
+	go func() {
+		_ = h.Handle(ctx, &buf, LevelInfo, "other")
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("a record waited for the summary of another budget")
+	}
+	close(gate.open)
+	if err := <-closed; err != nil {
+		t.Fatal(err)
+	}
+	if got, want := gate.buf.String(), rateLimitSummary+"\n"; got != want {
+		t.Fatalf("summary = %q, want %q", got, want)
+	}
+}
+
+func TestRateLimitRejectsNonPositiveRate(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("RateLimit with a zero Rate did not panic")
+		}
+	}()
+	RateLimit(Text(), RateLimitOptions{})
+}
+
+// messageHandler writes the message of each record on a line.
+type messageHandler struct{}
+
+func (h messageHandler) WithFields(context.Context, ...Field) Handler { return h }
+func (h messageHandler) WithGroup(string) Handler                     { return h }
+
+func (h messageHandler) Handle(_ context.Context, w io.Writer, _ Level, msg string, _ ...any) error {
+	_, err := io.WriteString(w, msg+"\n")
+	return err
+}
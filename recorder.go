@@ -0,0 +1,193 @@
+package log
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+)
+
+// RecordedEntry is a record captured by a Recorder.
+type RecordedEntry struct {
+	Level   Level
+	Message string
+	// Fields holds the fields added with With and WithFields, the fields of
+	// the record context and the fields of the call, in that order. Fields
+	// added after WithGroup are nested in groups, and Valuers are resolved.
+	Fields []Field
+}
+
+// Lookup returns the value of the first top-level field with key.
+func (e RecordedEntry) Lookup(key string) (Value, bool) {
+	for _, field := range e.Fields {
+		if field.Key == key {
+			return field.Value, true
+		}
+	}
+	return Value{}, false
+}
+
+// RecordedEntries is a list of captured records that can be filtered.
+type RecordedEntries []RecordedEntry
+
+// FilterLevel returns the entries logged at level.
+func (es RecordedEntries) FilterLevel(level Level) RecordedEntries {
+	return es.filter(func(e RecordedEntry) bool { return e.Level == level })
+}
+
+// FilterMessage returns the entries whose message contains substr.
+func (es RecordedEntries) FilterMessage(substr string) RecordedEntries {
+	return es.filter(func(e RecordedEntry) bool { return strings.Contains(e.Message, substr) })
+}
+
+// FilterField returns the entries that have a top-level field equal to field.
+func (es RecordedEntries) FilterField(field Field) RecordedEntries {
+	return es.filter(func(e RecordedEntry) bool { return e.hasField(field) })
+}
+
+func (es RecordedEntries) filter(keep func(RecordedEntry) bool) RecordedEntries {
+	var kept RecordedEntries
+	for _, e := range es {
+		if keep(e) {
+			kept = append(kept, e)
+		}
+	}
+	return kept
+}
+
+// Recorder is a Handler that keeps the records it handles in memory instead
+// of writing them, so that tests can assert on logging without parsing the
+// output. The handlers derived from it by WithFields and WithGroup record
+// into the same list. A Recorder is safe for concurrent use.
+type Recorder struct {
+	store  *recorderStore
+	fields []Field
+	groups []string
+}
+
+type recorderStore struct {
+	mu      sync.Mutex
+	entries RecordedEntries
+}
+
+// NewRecorder returns an empty Recorder:
+//
+//	rec := log.NewRecorder()
+//	logger := log.New(nil, rec)
+func NewRecorder() *Recorder {
+	return &Recorder{store: new(recorderStore)}
+}
+
+func (r *Recorder) WithFields(ctx context.Context, fields ...Field) Handler {
+	r2 := *r
+	r2.fields = append(r.fields[:len(r.fields):len(r.fields)], nestFields(r.groups, fields)...)
+	return &r2
+}
+
+func (r *Recorder) WithGroup(name string) Handler {
+	if name == "" {
+		return r
+	}
+	r2 := *r
+	r2.groups = append(r.groups[:len(r.groups):len(r.groups)], name)
+	return &r2
+}
+
+// Handle records the record. The writer is not used.
+func (r *Recorder) Handle(ctx context.Context, _ io.Writer, level Level, msg string, kvs ...any) error {
+	fields := resolveFields(ctx, r.fields)
+	call := FieldsFromContext(ctx)
+	call = append(call[:len(call):len(call)], kvsToFieldSlice(kvs)...)
+	if len(call) > 0 {
+		fields = append(fields, nestFields(r.groups, resolveFields(ctx, call))...)
+	}
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	r.store.entries = append(r.store.entries, RecordedEntry{Level: level, Message: msg, Fields: fields})
+	return nil
+}
+
+// All returns the records captured so far.
+func (r *Recorder) All() RecordedEntries {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	return append(RecordedEntries(nil), r.store.entries...)
+}
+
+// TakeAll returns the records captured so far and removes them from r, so
+// that later assertions only see newer records.
+func (r *Recorder) TakeAll() RecordedEntries {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	entries := r.store.entries
+	r.store.entries = nil
+	return entries
+}
+
+// AssertLogged reports a test error with t.Error unless a record was
+// captured at level with message msg and the top-level fields, among others.
+// It returns whether the record was found.
+func (r *Recorder) AssertLogged(t TestingT, level Level, msg string, fields ...Field) bool {
+	t.Helper()
+	entries := r.All()
+	for _, e := range entries {
+		if e.Level == level && e.Message == msg && e.hasFields(fields) {
+			return true
+		}
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "no %s record %q", level, msg)
+	if len(fields) > 0 {
+		fmt.Fprintf(&b, " with %v", fields)
+	}
+	if len(entries) == 0 {
+		b.WriteString(" was logged; nothing was recorded")
+	} else {
+		b.WriteString(" was logged; recorded:")
+		for _, e := range entries {
+			fmt.Fprintf(&b, "\n\t%s %q %v", e.Level, e.Message, e.Fields)
+		}
+	}
+	t.Error(b.String())
+	return false
+}
+
+func (e RecordedEntry) hasFields(fields []Field) bool {
+	for _, field := range fields {
+		if !e.hasField(field) {
+			return false
+		}
+	}
+	return true
+}
+
+func (e RecordedEntry) hasField(field Field) bool {
+	for _, f := range e.Fields {
+		if f.Equal(field) {
+			return true
+		}
+	}
+	return false
+}
+
+// Len returns the number of records captured so far.
+func (r *Recorder) Len() int {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	return len(r.store.entries)
+}
+
+// resolveFields returns fields with their Valuers resolved, in groups too.
+func resolveFields(ctx context.Context, fields []Field) []Field {
+	resolved := make([]Field, 0, len(fields))
+	for _, field := range fields {
+		v := field.Value.Resolve(ctx)
+		if v.Kind() == KindGroup {
+			v = GroupValue(resolveFields(ctx, v.group())...)
+		}
+		resolved = append(resolved, Field{Key: field.Key, Value: v})
+	}
+	return resolved
+}
@@ -0,0 +1,76 @@
+package log
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestRecorder(t *testing.T) {
+	rec := NewRecorder()
+	calls := 0
+	logger := New(nil, rec).With("svc", "api", Dynamic("n", func(context.Context) Value {
+		calls++
+		return IntValue(calls)
+	})).WithGroup("req")
+
+	logger.InfoS("started", "id", 1)
+	logger.ErrorS("request failed", "id", 2, Err(errors.New("timeout")))
+	logger.DebugS("hidden")
+
+	if got := rec.Len(); got != 2 {
+		t.Fatalf("Len() = %d, want 2", got)
+	}
+	all := rec.All()
+	want := RecordedEntry{
+		Level:   LevelInfo,
+		Message: "started",
+		Fields:  []Field{String("svc", "api"), Int("n", 1), Dict("req", Int("id", 1))},
+	}
+	if got := all[0]; got.Level != want.Level || got.Message != want.Message || !fieldsEqual(got.Fields, want.Fields) {
+		t.Fatalf("first entry = %v, want %v", got, want)
+	}
+
+	errs := all.FilterLevel(LevelError)
+	if len(errs) != 1 || errs[0].Message != "request failed" {
+		t.Fatalf("FilterLevel(Error) = %v", errs)
+	}
+	if got := all.FilterMessage("fail").FilterField(Int("n", 2)); len(got) != 1 {
+		t.Fatalf("FilterMessage.FilterField = %v, want one entry", got)
+	}
+	if got := all.FilterField(String("svc", "web")); len(got) != 0 {
+		t.Fatalf("FilterField(svc=web) = %v, want none", got)
+	}
+	if v, ok := all[1].Lookup("svc"); !ok || v.String() != "api" {
+		t.Fatalf("Lookup(svc) = %v, %v", v, ok)
+	}
+}
+
+func TestRecorderAssertLogged(t *testing.T) {
+	rec := NewRecorder()
+	logger := New(nil, rec).With("svc", "api")
+	logger.ErrorS("charge failed", "user", "alice")
+
+	ft := &fakeT{}
+	if !rec.AssertLogged(ft, LevelError, "charge failed", String("user", "alice"), String("svc", "api")) || len(ft.errors) != 0 {
+		t.Fatalf("AssertLogged failed: %q", ft.errors)
+	}
+	if rec.AssertLogged(ft, LevelError, "charge failed", String("user", "bob")) || len(ft.errors) != 1 {
+		t.Fatalf("AssertLogged with a wrong field succeeded: %q", ft.errors)
+	}
+	want := `no ERROR record "charge failed" with [user=bob] was logged; recorded:` + "\n\t" + `ERROR "charge failed" [svc=api user=alice]`
+	if ft.errors[0] != want {
+		t.Fatalf("error = %q, want %q", ft.errors[0], want)
+	}
+
+	if got := rec.TakeAll(); len(got) != 1 {
+		t.Fatalf("TakeAll() = %v, want one entry", got)
+	}
+	if got := rec.Len(); got != 0 {
+		t.Fatalf("Len() after TakeAll = %d, want 0", got)
+	}
+	logger.Info("next")
+	if got := rec.TakeAll(); len(got) != 1 || got[0].Message != "next" {
+		t.Fatalf("TakeAll() = %v, want the new entry", got)
+	}
+}
@@ -0,0 +1,198 @@
+package log
+
+import (
+	"context"
+	"regexp"
+	"strings"
+)
+
+// DefaultRedactMask replaces redacted text when RedactorOptions.Mask is empty.
+const DefaultRedactMask = "[REDACTED]"
+
+var (
+	creditCardPattern  = regexp.MustCompile(`\b\d(?:[ -]?\d){12,18}\b`)
+	bearerTokenPattern = regexp.MustCompile(`(?i)\bbearer\s+[A-Za-z0-9\-._~+/]+=*`)
+	emailPattern       = regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9\-]+(?:\.[A-Za-z0-9\-]+)*\.[A-Za-z]{2,}`)
+)
+
+// RedactorOptions configures the Redactor returned by NewRedactor.
+type RedactorOptions struct {
+	// CreditCards masks numbers of 13 to 19 digits, optionally separated by
+	// spaces or dashes, that pass the Luhn check.
+	CreditCards bool
+	// BearerTokens masks "Bearer <token>" credentials.
+	BearerTokens bool
+	// Emails masks email addresses.
+	Emails bool
+	// Patterns masks every match of these expressions.
+	Patterns []*regexp.Regexp
+	// Keys masks the whole value of fields whose key matches one of these
+	// path.Match patterns, such as "password" or "*_token". Matching ignores
+	// case.
+	Keys []string
+	// Mask replaces redacted text. The default is DefaultRedactMask.
+	Mask string
+}
+
+// Redactor masks sensitive text in the message and string field values of a
+// record before it is encoded. Set it in HandlerOptions; it runs before the
+// Replacer. A Redactor is safe for concurrent use.
+type Redactor struct {
+	rules []redactRule
+	keys  keyMatcher
+	mask  string
+}
+
+type redactRule struct {
+	re *regexp.Regexp
+	// maybe is a cheap test that s can contain a match.
+	maybe func(s string) bool
+	// valid, if set, rejects matches that are not really sensitive.
+	valid func(match string) bool
+}
+
+// NewRedactor returns a Redactor for opts.
+func NewRedactor(opts RedactorOptions) *Redactor {
+	r := &Redactor{mask: opts.Mask}
+	if r.mask == "" {
+		r.mask = DefaultRedactMask
+	}
+	if opts.CreditCards {
+		r.rules = append(r.rules, redactRule{re: creditCardPattern, maybe: hasDigits(13), valid: luhnValid})
+	}
+	if opts.BearerTokens {
+		r.rules = append(r.rules, redactRule{re: bearerTokenPattern, maybe: hasBearer})
+	}
+	if opts.Emails {
+		r.rules = append(r.rules, redactRule{re: emailPattern, maybe: hasAt})
+	}
+	for _, re := range opts.Patterns {
+		r.rules = append(r.rules, redactRule{re: re})
+	}
+	r.keys = newKeyMatcher(opts.Keys)
+	return r
+}
+
+// Redact returns s with every sensitive match masked. A nil Redactor returns
+// s unchanged.
+func (r *Redactor) Redact(s string) string {
+	if r == nil {
+		return s
+	}
+	for _, rule := range r.rules {
+		if rule.maybe != nil && !rule.maybe(s) || !rule.re.MatchString(s) {
+			continue
+		}
+		s = rule.re.ReplaceAllStringFunc(s, func(match string) string {
+			if rule.valid != nil && !rule.valid(match) {
+				return match
+			}
+			return r.mask
+		})
+	}
+	return s
+}
+
+// replacer returns a Replacer that redacts fields and then calls next. A nil
+// Redactor returns next.
+func (r *Redactor) replacer(next Replacer) Replacer {
+	if r == nil {
+		return next
+	}
+	return func(ctx context.Context, groups []string, field Field) Field {
+		field = r.redactField(field, groups == nil)
+		if next != nil {
+			field = next(ctx, groups, field)
+		}
+		return field
+	}
+}
+
+// redactField masks the value of field. Only the message of the built-in
+// fields is redacted.
+func (r *Redactor) redactField(field Field, builtIn bool) Field {
+	if builtIn && field.Key != MessageKey {
+		return field
+	}
+	if !builtIn && r.keys.match(field.Key) {
+		return String(field.Key, r.mask)
+	}
+	switch field.Value.Kind() {
+	case KindString:
+		field.Value = StringValue(r.Redact(field.Value.str()))
+	case KindError:
+		// The error is kept unless its message has something to mask.
+		if err := field.Value.err(); err != nil {
+			msg := err.Error()
+			if masked := r.Redact(msg); masked != msg {
+				field.Value = StringValue(masked)
+			}
+		}
+	case KindValuer:
+		valuer := field.Value.valuer()
+		field.Value = ValuerValue(func(ctx context.Context) Value {
+			// One frame is added between Resolve and valuer.
+			v := valuer(AddCallerDepth(ctx, 1))
+			if v.Kind() == KindValuer {
+				v = v.Resolve(ctx)
+			}
+			if v.Kind() == KindString {
+				v = StringValue(r.Redact(v.str()))
+			}
+			return v
+		})
+	}
+	return field
+}
+
+func hasDigits(n int) func(string) bool {
+	return func(s string) bool {
+		digits := 0
+		for i := 0; i < len(s); i++ {
+			if s[i] >= '0' && s[i] <= '9' {
+				if digits++; digits >= n {
+					return true
+				}
+			}
+		}
+		return false
+	}
+}
+
+func hasAt(s string) bool {
+	return strings.IndexByte(s, '@') >= 0
+}
+
+func hasBearer(s string) bool {
+	for i := strings.IndexAny(s, "bB"); i >= 0 && i+6 <= len(s); {
+		if strings.EqualFold(s[i:i+6], "bearer") {
+			return true
+		}
+		j := strings.IndexAny(s[i+1:], "bB")
+		if j < 0 {
+			return false
+		}
+		i += j + 1
+	}
+	return false
+}
+
+// luhnValid reports whether the digits of s pass the Luhn checksum.
+func luhnValid(s string) bool {
+	sum, double := 0, false
+	for i := len(s) - 1; i >= 0; i-- {
+		c := s[i]
+		if c < '0' || c > '9' {
+			continue
+		}
+		d := int(c - '0')
+		if double {
+			if d *= 2; d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+	}
+	return sum%10 == 0
+}
@@ -0,0 +1,88 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestRedactor(t *testing.T) {
+	r := NewRedactor(RedactorOptions{
+		CreditCards:  true,
+		BearerTokens: true,
+		Emails:       true,
+		Patterns:     []*regexp.Regexp{regexp.MustCompile(`sk_live_\w+`)},
+	})
+	for _, test := range []struct{ in, want string }{
+		{"card 4111 1111 1111 1111 declined", "card [REDACTED] declined"},
+		{"order 1234567890123456789", "order 1234567890123456789"}, // fails the Luhn check
+		{"Authorization: Bearer abc.def-123", "Authorization: [REDACTED]"},
+		{"mail alice@example.com now", "mail [REDACTED] now"},
+		{"key sk_live_42", "key [REDACTED]"},
+		{"nothing to hide", "nothing to hide"},
+	} {
+		if got := r.Redact(test.in); got != test.want {
+			t.Errorf("Redact(%q) = %q, want %q", test.in, got, test.want)
+		}
+	}
+}
+
+func TestRedactorInHandlers(t *testing.T) {
+	r := NewRedactor(RedactorOptions{Emails: true, Keys: []string{"password", "*_token"}})
+	email := Dynamic("email", func(ctx context.Context) Value {
+		return StringValue("bob@example.com")
+	})
+
+	var buf bytes.Buffer
+	logger := New(&buf, Json(&HandlerOptions{Redactor: r})).WithFields(email)
+	logger.InfoS("signup from carol@example.com", "Password", "hunter2", "api_token", 42, "n", 1)
+	want := `{"level":"INFO","email":"[REDACTED]","msg":"signup from [REDACTED]","Password":"[REDACTED]","api_token":"[REDACTED]","n":1}` + "\n"
+	if got := buf.String(); got != want {
+		t.Fatalf("json output = %s, want %s", got, want)
+	}
+
+	buf.Reset()
+	New(&buf, Text(&HandlerOptions{Redactor: r})).InfoS("failed", Err(errors.New("no user frank@example.com")))
+	if got, want := buf.String(), "INFO msg=failed err=\"no user [REDACTED]\"\n"; got != want {
+		t.Fatalf("error output = %q, want %q", got, want)
+	}
+
+	buf.Reset()
+	logger = New(&buf, CSV(&CSVOptions{
+		HandlerOptions: HandlerOptions{Redactor: r},
+		Columns:        []string{MessageKey, "user"},
+	}))
+	logger.InfoS("mail dave@example.com", "user", "erin@example.com")
+	if got := buf.String(); !strings.HasSuffix(got, "[REDACTED],[REDACTED]\n") {
+		t.Fatalf("csv output = %q", got)
+	}
+}
+
+func benchmarkRedactor(b *testing.B, r *Redactor) {
+	logger := New(discardWriter{}, Json(&HandlerOptions{Redactor: r}))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		logger.InfoS("request handled", "path", "/api/v1/orders", "user", "user-1234", "status", 200)
+	}
+}
+
+// discardWriter is not Discard, so records are encoded.
+type discardWriter struct{}
+
+func (discardWriter) Write(p []byte) (int, error) { return len(p), nil }
+
+func BenchmarkRedactorNone(b *testing.B) {
+	benchmarkRedactor(b, nil)
+}
+
+func BenchmarkRedactorBuiltIns(b *testing.B) {
+	benchmarkRedactor(b, NewRedactor(RedactorOptions{CreditCards: true, BearerTokens: true, Emails: true}))
+}
+
+func BenchmarkRedactorKeys(b *testing.B) {
+	benchmarkRedactor(b, NewRedactor(RedactorOptions{Keys: []string{"password", "*_token", "secret*"}}))
+}
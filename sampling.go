@@ -0,0 +1,109 @@
+package log
+
+import (
+	"context"
+	"io"
+	"sync/atomic"
+	"time"
+)
+
+// SamplingOptions configures the handler returned by Sample.
+type SamplingOptions struct {
+	// Tick is the sampling window. The default is 1 second.
+	Tick time.Duration
+	// First is the number of records with the same level and message that are
+	// written in each window.
+	First int
+	// Thereafter writes every Thereafter-th record after First in the window.
+	// Zero drops them all.
+	Thereafter int
+}
+
+// samplingCounters is the number of counters records are spread over by
+// level and message. Messages that share a counter are sampled together.
+const samplingCounters = 4096
+
+// SamplingHandler writes a sample of repeated records. It is created by
+// Sample.
+type SamplingHandler struct {
+	next    Handler
+	sampler *sampler
+}
+
+type sampler struct {
+	opts     SamplingOptions
+	now      func() time.Time
+	counters [samplingCounters]samplingCounter
+	dropped  atomic.Uint64
+}
+
+type samplingCounter struct {
+	resetAt atomic.Int64
+	count   atomic.Uint64
+}
+
+// Sample wraps next so that within each opts.Tick window the first opts.First
+// records with the same level and message are written, and after that every
+// opts.Thereafter-th one. Counters are shared by all loggers derived from the
+// returned handler.
+func Sample(next Handler, opts SamplingOptions) *SamplingHandler {
+	if opts.Tick <= 0 {
+		opts.Tick = time.Second
+	}
+	return &SamplingHandler{next: next, sampler: &sampler{opts: opts, now: time.Now}}
+}
+
+func (h *SamplingHandler) WithFields(ctx context.Context, fields ...Field) Handler {
+	return &SamplingHandler{next: h.next.WithFields(ctx, fields...), sampler: h.sampler}
+}
+
+func (h *SamplingHandler) WithGroup(name string) Handler {
+	return &SamplingHandler{next: h.next.WithGroup(name), sampler: h.sampler}
+}
+
+func (h *SamplingHandler) Handle(ctx context.Context, w io.Writer, level Level, msg string, kvs ...any) error {
+	if !h.sampler.allow(level, msg) {
+		return nil
+	}
+	return h.next.Handle(AddCallerDepth(ctx, 1), w, level, msg, kvs...)
+}
+
+// Dropped returns the number of records sampled away.
+func (h *SamplingHandler) Dropped() uint64 {
+	return h.sampler.dropped.Load()
+}
+
+func (s *sampler) allow(level Level, msg string) bool {
+	n := s.counters[samplingKey(level, msg)%samplingCounters].inc(s.now(), s.opts.Tick)
+	first := uint64(s.opts.First)
+	if n <= first || s.opts.Thereafter > 0 && (n-first)%uint64(s.opts.Thereafter) == 0 {
+		return true
+	}
+	s.dropped.Add(1)
+	return false
+}
+
+// samplingKey hashes level and msg with 32-bit FNV-1a.
+func samplingKey(level Level, msg string) uint32 {
+	h := uint32(2166136261)
+	h = (h ^ uint32(level)) * 16777619
+	for i := 0; i < len(msg); i++ {
+		h = (h ^ uint32(msg[i])) * 16777619
+	}
+	return h
+}
+
+// inc counts a record at t and returns its position in the current window.
+func (c *samplingCounter) inc(t time.Time, tick time.Duration) uint64 {
+	now := t.UnixNano()
+	resetAt := c.resetAt.Load()
+	if resetAt > now {
+		return c.count.Add(1)
+	}
+	c.count.Store(1)
+	if !c.resetAt.CompareAndSwap(resetAt, now+tick.Nanoseconds()) {
+		// Another record started the window.
+		return c.count.Add(1)
+	}
+	return 1
+}
@@ -0,0 +1,40 @@
+package log
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSampleFirstThenEveryMth(t *testing.T) {
+	now := time.Unix(0, 0)
+	var buf bytes.Buffer
+	h := Sample(Text(), SamplingOptions{First: 2, Thereafter: 3})
+	h.sampler.now = func() time.Time { return now }
+	logger := New(&buf, h)
+
+	for i := 1; i <= 8; i++ {
+		logger.InfoS("tick", "i", i)
+	}
+	logger.InfoS("other")
+	logger.ErrorS("tick")
+	now = now.Add(time.Second)
+	logger.InfoS("tick", "i", 9)
+
+	want := []string{
+		"INFO msg=tick i=1",
+		"INFO msg=tick i=2",
+		"INFO msg=tick i=5",
+		"INFO msg=tick i=8",
+		"INFO msg=other",
+		"ERROR msg=tick",
+		"INFO msg=tick i=9",
+	}
+	if got := strings.Split(strings.TrimSpace(buf.String()), "\n"); strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Fatalf("output = %q, want %q", got, want)
+	}
+	if got := h.Dropped(); got != 4 {
+		t.Fatalf("Dropped() = %d, want 4", got)
+	}
+}
@@ -0,0 +1,75 @@
+package log
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"strconv"
+)
+
+// SecretMask is how a value created by Secret is rendered.
+const SecretMask = "***"
+
+// secret is a string that every handler renders masked. It implements the
+// interfaces handlers and fmt use to format values, so the real value is
+// never encoded by accident.
+type secret struct {
+	value  string
+	hashed bool
+}
+
+func (s secret) String() string {
+	if s.hashed {
+		sum := sha256.Sum256([]byte(s.value))
+		return fmt.Sprintf("sha256:%x", sum[:8])
+	}
+	return SecretMask
+}
+
+func (s secret) GoString() string {
+	return strconv.Quote(s.String())
+}
+
+func (s secret) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+func (s secret) MarshalJSON() ([]byte, error) {
+	return []byte(strconv.Quote(s.String())), nil
+}
+
+// Secret returns a Field whose value is rendered as SecretMask by every
+// handler. Hooks can read the value with RevealSecret.
+func Secret(key, value string) Field {
+	return Field{key, SecretValue(value)}
+}
+
+// SecretValue returns a Value that is rendered as SecretMask.
+func SecretValue(value string) Value {
+	return AnyValue(secret{value: value})
+}
+
+// HashedSecret returns a Field whose value is rendered as "sha256:" and the
+// first 16 hex digits of its SHA-256 hash, so records about the same value
+// can be correlated. The hash is not salted; do not use it for values that are
+// easy to guess, such as short PINs.
+func HashedSecret(key, value string) Field {
+	return Field{key, HashedSecretValue(value)}
+}
+
+// HashedSecretValue returns a Value that is rendered as a hash, like
+// HashedSecret.
+func HashedSecretValue(value string) Value {
+	return AnyValue(secret{value: value, hashed: true})
+}
+
+// RevealSecret returns the real value of a Value created by Secret,
+// SecretValue, HashedSecret or HashedSecretValue. It is meant for hooks that
+// are allowed to see secrets, such as one that forwards them to an encrypted
+// audit sink.
+func RevealSecret(v Value) (string, bool) {
+	if v.Kind() != KindAny {
+		return "", false
+	}
+	s, ok := v.any.(secret)
+	return s.value, ok
+}
@@ -0,0 +1,50 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"testing"
+)
+
+func TestSecretIsMaskedByHandlers(t *testing.T) {
+	for _, test := range []struct {
+		name    string
+		handler Handler
+		want    string
+	}{
+		{"text", Text(), "INFO msg=login password=*** token=sha256:9f86d081884c7d65\n"},
+		{"json", Json(), `{"level":"INFO","msg":"login","password":"***","token":"sha256:9f86d081884c7d65"}` + "\n"},
+		{"csv", CSV(&CSVOptions{Columns: []string{"password", "token"}}), "***,sha256:9f86d081884c7d65\n"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			New(&buf, test.handler).InfoS("login", Secret("password", "hunter2"), HashedSecret("token", "test"))
+			if got := buf.String(); got != test.want {
+				t.Fatalf("output = %q, want %q", got, test.want)
+			}
+		})
+	}
+	if got := fmt.Sprintf("%v %#v", SecretValue("x").Any(), SecretValue("x").Any()); got != `*** "***"` {
+		t.Fatalf("fmt = %s", got)
+	}
+}
+
+func TestRevealSecret(t *testing.T) {
+	var audited string
+	logger := New(&bytes.Buffer{}).AddHook(HookFunc(func(_ context.Context, r *Record) bool {
+		for _, field := range r.Fields {
+			if v, ok := RevealSecret(field.Value); ok {
+				audited = v
+			}
+		}
+		return true
+	}))
+	logger.InfoS("login", Secret("password", "hunter2"))
+	if audited != "hunter2" {
+		t.Fatalf("revealed %q, want hunter2", audited)
+	}
+	if _, ok := RevealSecret(StringValue("plain")); ok {
+		t.Fatal("RevealSecret accepted a plain string")
+	}
+}
@@ -0,0 +1,219 @@
+package log
+
+import (
+	"context"
+	"io"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// SentryEvent is an Error or Fatal record prepared for Sentry.
+type SentryEvent struct {
+	// Level is "error" or "fatal".
+	Level   string
+	Message string
+	// Logger is SentryOptions.Name.
+	Logger string
+	// Extra holds the record fields, with dots joining group members. Secrets
+	// are masked as in the local log, and so are structs with log tags.
+	Extra map[string]any
+	// Err is the first field value that is an error, if any.
+	Err error
+	// Stack is the call stack of the log call, innermost frame first.
+	Stack     []Source
+	Timestamp time.Time
+}
+
+// SentryClient delivers events to Sentry. It is usually a small adapter over
+// the Sentry SDK hub or client.
+type SentryClient interface {
+	CaptureEvent(event *SentryEvent)
+	// Flush waits until buffered events are sent or the timeout elapses.
+	Flush(timeout time.Duration) bool
+}
+
+// SentryOptions configures the handler returned by Sentry.
+type SentryOptions struct {
+	Client SentryClient
+	// Level is the minimum level forwarded to Sentry. Levels below LevelError
+	// are raised to LevelError.
+	Level Level
+	// Name is reported as the event logger.
+	Name string
+	// HandlerOptions applies the Replacer, Redactor, DropKeys, AllowKeys and
+	// ContextExtractor of the local handler to the event fields, so values
+	// redacted in the local log are redacted in Sentry too. Usually it is
+	// the options of next. Its other options are ignored.
+	HandlerOptions *HandlerOptions
+	// BufferSize bounds the events waiting to be delivered; events are
+	// dropped when it is full. The default is 100.
+	BufferSize int
+	// FlushTimeout bounds Close. The default is 2 seconds.
+	FlushTimeout time.Duration
+}
+
+type sentryHook struct {
+	opts    SentryOptions
+	events  chan *SentryEvent
+	done    chan struct{}
+	mu      sync.RWMutex // guards sends against Close
+	closed  bool
+	dropped atomic.Uint64
+}
+
+// SentryHandler forwards Error and Fatal records to Sentry and passes every
+// record on to the wrapped Handler. It is created by Sentry.
+type SentryHandler struct {
+	next Handler
+	hook *sentryHook
+	flat flatFields
+}
+
+// Sentry wraps next so that records at opts.Level or above are also sent to
+// opts.Client as a SentryEvent with the message, fields, logger name and call
+// stack. Events are delivered by a background goroutine; Close drains them and
+// flushes the client. Logger.Close closes its handler, so closing the Logger is
+// enough. Sentry panics if opts.Client is nil.
+func Sentry(next Handler, opts SentryOptions) *SentryHandler {
+	if opts.Client == nil {
+		panic("log: Sentry: nil Client")
+	}
+	if opts.Level < LevelError {
+		opts.Level = LevelError
+	}
+	if opts.BufferSize <= 0 {
+		opts.BufferSize = 100
+	}
+	if opts.FlushTimeout <= 0 {
+		opts.FlushTimeout = 2 * time.Second
+	}
+	hook := &sentryHook{
+		opts:   opts,
+		events: make(chan *SentryEvent, opts.BufferSize),
+		done:   make(chan struct{}),
+	}
+	go hook.run()
+	return &SentryHandler{next: next, hook: hook, flat: newFlatFields(opts.HandlerOptions)}
+}
+
+func (h *SentryHandler) WithFields(ctx context.Context, fields ...Field) Handler {
+	return &SentryHandler{next: h.next.WithFields(ctx, fields...), hook: h.hook, flat: h.flat.withFields(fields)}
+}
+
+func (h *SentryHandler) WithGroup(name string) Handler {
+	return &SentryHandler{next: h.next.WithGroup(name), hook: h.hook, flat: h.flat.withGroup(name)}
+}
+
+func (h *SentryHandler) Handle(ctx context.Context, w io.Writer, level Level, msg string, kvs ...any) error {
+	if level >= h.hook.opts.Level {
+		h.capture(ctx, level, msg, kvs)
+	}
+	return h.next.Handle(AddCallerDepth(ctx, 1), w, level, msg, kvs...)
+}
+
+func (h *SentryHandler) capture(ctx context.Context, level Level, msg string, kvs []any) {
+	event := &SentryEvent{
+		Level:     "error",
+		Message:   msg,
+		Logger:    recordName(ctx, h.hook.opts.Name),
+		Extra:     make(map[string]any),
+		Stack:     callerStack(),
+		Timestamp: time.Now(),
+	}
+	if level >= LevelFatal {
+		event.Level = "fatal"
+	}
+	h.flat.walk(ctx, kvs, func(groups []string, key string, v Value) {
+		if len(groups) > 0 {
+			key = strings.Join(groups, string(keyComponentSep)) + string(keyComponentSep) + key
+		}
+		value := v.Any()
+		if err, ok := value.(error); ok && event.Err == nil {
+			event.Err = err
+		}
+		event.Extra[key] = sentryExtra(value)
+	})
+
+	h.hook.mu.RLock()
+	defer h.hook.mu.RUnlock()
+	if h.hook.closed {
+		return
+	}
+	select {
+	case h.hook.events <- event:
+	default:
+		h.hook.dropped.Add(1)
+	}
+}
+
+// sentryExtra returns a field value as it is sent in SentryEvent.Extra.
+func sentryExtra(value any) any {
+	if s, ok := value.(secret); ok {
+		return s.String()
+	}
+	if masked, ok := maskStruct(value); ok {
+		return masked
+	}
+	return value
+}
+
+// Dropped returns the number of events discarded because the buffer was full.
+func (h *SentryHandler) Dropped() uint64 {
+	return h.hook.dropped.Load()
+}
+
+// Close stops forwarding, delivers buffered events and flushes the client.
+// It is shared by all handlers derived from the same Sentry call.
+func (h *SentryHandler) Close() error {
+	h.hook.mu.Lock()
+	if h.hook.closed {
+		h.hook.mu.Unlock()
+		return nil
+	}
+	h.hook.closed = true
+	close(h.hook.events)
+	h.hook.mu.Unlock()
+
+	<-h.hook.done
+	h.hook.opts.Client.Flush(h.hook.opts.FlushTimeout)
+	return nil
+}
+
+func (hook *sentryHook) run() {
+	defer close(hook.done)
+	for event := range hook.events {
+		hook.opts.Client.CaptureEvent(event)
+	}
+}
+
+// callerStack returns the stack above the log package and its subpackages,
+// innermost frame first.
+func callerStack() []Source {
+	var pcs [32]uintptr
+	n := runtime.Callers(2, pcs[:])
+	frames := runtime.CallersFrames(pcs[:n])
+	var stack []Source
+	inLog := true
+	for {
+		frame, more := frames.Next()
+		if inLog && !isLogPackageFrame(frame.Function) {
+			inLog = false
+		}
+		if !inLog {
+			stack = append(stack, Source{Function: frame.Function, File: frame.File, Line: frame.Line})
+		}
+		if !more {
+			break
+		}
+	}
+	return stack
+}
+
+const logPackagePath = "github.com/nexuer/log"
+
+func isLogPackageFrame(function string) bool {
+	return strings.HasPrefix(function, logPackagePath+".") || strings.HasPrefix(function, logPackagePath+"/")
+}
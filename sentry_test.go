@@ -0,0 +1,115 @@
+package log_test
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nexuer/log"
+)
+
+type recordingSentry struct {
+	mu      sync.Mutex
+	events  []*log.SentryEvent
+	flushed bool
+}
+
+func (c *recordingSentry) CaptureEvent(event *log.SentryEvent) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.events = append(c.events, event)
+}
+
+func (c *recordingSentry) Flush(time.Duration) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.flushed = true
+	return true
+}
+
+func TestSentryForwardsErrorRecords(t *testing.T) {
+	client := &recordingSentry{}
+	var buf bytes.Buffer
+	logger := log.New(&buf, log.Sentry(log.Json(), log.SentryOptions{Client: client, Name: "api"})).
+		With("service", "billing").WithGroup("req")
+
+	cause := errors.New("card declined")
+	logger.InfoS("charging", "id", 1)
+	logger.ErrorS("charge failed", "id", 1, "cause", cause)
+	if err := logger.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := strings.Count(buf.String(), "\n"); got != 2 {
+		t.Fatalf("wrapped handler wrote %d records, want 2", got)
+	}
+	if !client.flushed {
+		t.Fatal("Close did not flush the client")
+	}
+	if len(client.events) != 1 {
+		t.Fatalf("captured %d events, want 1", len(client.events))
+	}
+	event := client.events[0]
+	if event.Level != "error" || event.Message != "charge failed" || event.Logger != "api" {
+		t.Fatalf("event = %+v", event)
+	}
+	if event.Err != cause {
+		t.Fatalf("event error = %v, want %v", event.Err, cause)
+	}
+	if event.Extra["service"] != "billing" || event.Extra["req.id"] != int64(1) {
+		t.Fatalf("event extra = %#v", event.Extra)
+	}
+	if len(event.Stack) == 0 || !strings.HasSuffix(event.Stack[0].Function, ".TestSentryForwardsErrorRecords") {
+		t.Fatalf("event stack starts at %+v, want the test function", event.Stack)
+	}
+}
+
+func TestSentryCloseStopsForwarding(t *testing.T) {
+	client := &recordingSentry{}
+	h := log.Sentry(log.Text(), log.SentryOptions{Client: client})
+	logger := log.New(log.Discard, h)
+	_ = h.Close()
+	_ = h.Close()
+	logger.Error("after close")
+	if len(client.events) != 0 {
+		t.Fatalf("captured %d events after Close, want 0", len(client.events))
+	}
+}
+
+func TestSentryRejectsNilClient(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("Sentry without a Client did not panic")
+		}
+	}()
+	log.Sentry(log.Text(), log.SentryOptions{})
+}
+
+func TestSentryKeepsCallerDepth(t *testing.T) {
+	var buf bytes.Buffer
+	logger := log.New(&buf, log.Sentry(log.Text(), log.SentryOptions{Client: &recordingSentry{}})).
+		WithFields(log.Dynamic("caller", log.DefaultCaller))
+	defer logger.Close()
+
+	logger.Info("hello")
+	if got := buf.String(); !strings.Contains(got, "/sentry_test.go:") {
+		t.Fatalf("output = %q, want caller in sentry_test.go", got)
+	}
+}
+
+func TestSentryRedactsFields(t *testing.T) {
+	client := &recordingSentry{}
+	opts := &log.HandlerOptions{DropKeys: []string{"token"}}
+	logger := log.New(log.Discard, log.Sentry(log.Json(opts), log.SentryOptions{Client: client, HandlerOptions: opts}))
+	logger.ErrorS("login failed", "token", "t0k3n", log.Secret("password", "hunter2"), "user", "alice")
+	if err := logger.Close(); err != nil {
+		t.Fatal(err)
+	}
+	extra := client.events[0].Extra
+	if _, ok := extra["token"]; ok || extra["password"] != log.SecretMask || extra["user"] != "alice" {
+		t.Fatalf("event extra = %#v", extra)
+	}
+}
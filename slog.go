@@ -4,7 +4,9 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"slices"
 	"strconv"
+	"strings"
 )
 
 func slogAttrToField(attr slog.Attr) Field {
@@ -82,6 +84,9 @@ func (s *handleState) appendSlogAttr(ctx context.Context, attr slog.Attr) bool {
 			s.openGroup(attr.Key)
 		}
 
+		if s.h.opts.SortKeys {
+			attrs = sortedSlogAttrs(attrs)
+		}
 		nonEmpty := false
 		for _, child := range attrs {
 			if s.appendSlogAttr(ctx, child) {
@@ -109,6 +114,17 @@ func (s *handleState) appendSlogAttr(ctx context.Context, attr slog.Attr) bool {
 	return true
 }
 
+// sortedSlogAttrs is sortedFields for slog attributes.
+func sortedSlogAttrs(attrs []slog.Attr) []slog.Attr {
+	cmp := func(a, b slog.Attr) int { return strings.Compare(a.Key, b.Key) }
+	if slices.IsSortedFunc(attrs, cmp) {
+		return attrs
+	}
+	attrs = slices.Clone(attrs)
+	slices.SortStableFunc(attrs, cmp)
+	return attrs
+}
+
 func (s *handleState) appendSlogValue(value slog.Value) {
 	switch value.Kind() {
 	case slog.KindString:
@@ -32,7 +32,11 @@ func NewSlogHandler(logger *Logger) slog.Handler {
 	if logger == nil {
 		logger = defaultLogger.Load().logger
 	}
-	switch h := logger.handler.(type) {
+	if logger.name != "" {
+		// The built-in handlers read the name from the context of logger.
+		return &loggerSlogHandler{logger: logger.clone()}
+	}
+	switch h := logger.Handler().(type) {
 	case *jsonHandler:
 		return newBuiltinSlogHandler(h.handler, logger.Writer(), logger.level, logger.ctx)
 	case *textHandler:
@@ -60,6 +64,9 @@ func (h *slogHandler) Enabled(_ context.Context, level slog.Level) bool {
 func (h *slogHandler) Handle(ctx context.Context, record slog.Record) error {
 	// slog reaches the handler two frames sooner than Logger's convenience methods.
 	ctx = AddCallerDepth(mergeCallerDepth(ctx, h.ctx), -2)
+	if h.common.needsPC() && record.PC != 0 {
+		ctx = contextWithPC(ctx, record.PC-1)
+	}
 	if h.lazy {
 		return h.handleLazy(ctx, record)
 	}
@@ -131,7 +138,7 @@ func hasSlogLogValuer(attrs []slog.Attr) bool {
 }
 
 func (h *commonHandler) handleSlogRecord(ctx context.Context, w io.Writer, record slog.Record) error {
-	state := h.newRecordState(ctx, Level(record.Level).String(), record.Message)
+	state := h.newRecordState(ctx, Level(record.Level), record.Message)
 	defer state.free()
 
 	nOpenGroups := h.nOpenGroups
@@ -140,13 +147,14 @@ func (h *commonHandler) handleSlogRecord(ctx context.Context, w io.Writer, recor
 	if messageAppended {
 		state.appendMessage(ctx)
 	}
-	if record.NumAttrs() > 0 {
+	ctxFields := contextFields(ctx, h.opts.ContextExtractor)
+	if record.NumAttrs() > 0 || len(ctxFields) > 0 {
 		_, _ = state.prefix.WriteString(h.groupPrefix)
 		pos := state.buf.Len()
 		state.openGroups()
 		nOpenGroups = len(h.groups)
-		nonEmpty := false
-		record.Attrs(func(attr slog.Attr) bool {
+		nonEmpty := state.appendContextFields(ctx, ctxFields)
+		h.recordAttrs(record, func(attr slog.Attr) bool {
 			if state.appendSlogAttr(ctx, attr) {
 				nonEmpty = true
 			}
@@ -167,11 +175,30 @@ func (h *commonHandler) handleSlogRecord(ctx context.Context, w io.Writer, recor
 		}
 		state.appendByte('}')
 	}
-	return h.writeRecord(w, &state)
+	return h.writeRecord(ctx, w, &state)
+}
+
+// recordAttrs calls fn for each attribute of record, in key order if SortKeys
+// is set.
+func (h *commonHandler) recordAttrs(record slog.Record, fn func(slog.Attr) bool) {
+	if !h.opts.SortKeys {
+		record.Attrs(fn)
+		return
+	}
+	attrs := make([]slog.Attr, 0, record.NumAttrs())
+	record.Attrs(func(attr slog.Attr) bool {
+		attrs = append(attrs, attr)
+		return true
+	})
+	for _, attr := range sortedSlogAttrs(attrs) {
+		if !fn(attr) {
+			return
+		}
+	}
 }
 
 func (h *slogHandler) handleLazy(ctx context.Context, record slog.Record) error {
-	state := h.base.newRecordState(ctx, Level(record.Level).String(), record.Message)
+	state := h.base.newRecordState(ctx, Level(record.Level), record.Message)
 	defer state.free()
 	state.appendPreformattedAttrs(ctx)
 
@@ -187,8 +214,8 @@ func (h *slogHandler) handleLazy(ctx context.Context, record slog.Record) error
 	pos := state.buf.Len()
 	sep := state.sep
 	transitionSlogGroups(&state, current, h.groups)
-	nonEmpty := false
-	record.Attrs(func(attr slog.Attr) bool {
+	nonEmpty := state.appendContextFields(ctx, contextFields(ctx, h.base.opts.ContextExtractor))
+	h.base.recordAttrs(record, func(attr slog.Attr) bool {
 		if state.appendSlogAttr(ctx, attr) {
 			nonEmpty = true
 		}
@@ -209,13 +236,16 @@ func (h *slogHandler) handleLazy(ctx context.Context, record slog.Record) error
 		}
 		state.appendByte('}')
 	}
-	return h.base.writeRecord(h.w, &state)
+	return h.base.writeRecord(ctx, h.w, &state)
 }
 
 func appendSlogAttrsAtPath(state *handleState, ctx context.Context, current, target []string, attrs []slog.Attr) []string {
 	pos := state.buf.Len()
 	sep := state.sep
 	transitionSlogGroups(state, current, target)
+	if state.h.opts.SortKeys {
+		attrs = sortedSlogAttrs(attrs)
+	}
 	nonEmpty := false
 	for _, attr := range attrs {
 		if state.appendSlogAttr(ctx, attr) {
@@ -255,15 +285,20 @@ type loggerSlogHandler struct {
 }
 
 func (h *loggerSlogHandler) Enabled(_ context.Context, level slog.Level) bool {
-	return h.logger.level.Enable(Level(level))
+	return h.logger.Enabled(Level(level))
 }
 
 func (h *loggerSlogHandler) Handle(ctx context.Context, record slog.Record) error {
-	if h.logger.handler == nil || !h.logger.level.Enable(Level(record.Level)) {
+	lh := h.logger.handler.Load()
+	if lh.Handler == nil || !h.logger.level.Enable(Level(record.Level)) {
 		return nil
 	}
-	ctx = AddCallerDepth(mergeCallerDepth(ctx, h.logger.ctx), -2)
-	handler := h.logger.handler
+	h.logger.stats.countLevel(Level(record.Level))
+	ctx = AddCallerDepth(mergeCallerDepth(h.logger.nameContext(ctx), h.logger.ctx), -2)
+	if lh.capturePC && record.PC != 0 {
+		ctx = contextWithPC(ctx, record.PC-1)
+	}
+	handler := lh.Handler
 	nGroups := 0
 	for _, segment := range h.segments {
 		for _, group := range segment.groups[nGroups:] {
@@ -286,11 +321,15 @@ func (h *loggerSlogHandler) Handle(ctx context.Context, record slog.Record) erro
 		}
 		return true
 	})
-	return handler.Handle(ctx, h.logger.w, Level(record.Level), record.Message, attrs...)
+	level, msg, attrs, ok := h.logger.runHooks(ctx, Level(record.Level), record.Message, attrs)
+	if !ok {
+		return nil
+	}
+	return handler.Handle(ctx, h.logger.output.Load().out, level, msg, attrs...)
 }
 
 func (h *loggerSlogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
-	if len(attrs) == 0 || h.logger.handler == nil {
+	if len(attrs) == 0 || h.logger.Handler() == nil {
 		return h
 	}
 	h2 := h.clone()
@@ -302,7 +341,7 @@ func (h *loggerSlogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
 }
 
 func (h *loggerSlogHandler) WithGroup(name string) slog.Handler {
-	if name == "" || h.logger.handler == nil {
+	if name == "" || h.logger.Handler() == nil {
 		return h
 	}
 	h2 := h.clone()
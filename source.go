@@ -0,0 +1,142 @@
+package log
+
+import (
+	"context"
+	"reflect"
+	"runtime"
+)
+
+// sourceSpec resolves the built-in source field with short file names, like
+// Caller.
+var sourceSpec = &callerSpec{}
+
+// callSiteDepth is the Caller depth of the standard Logger methods. CallSite
+// falls back to it when the Logger did not capture the call site.
+const callSiteDepth = 9
+
+// CallSite returns a Valuer that resolves to the Source of the log call. The
+// Logger captures the program counter of the call once per record, in the
+// Logger method or from slog.Record.PC, and it is only turned into a file and
+// line when the field is encoded. Unlike Caller, it needs no depth; wrappers
+// that call the Logger methods add their frames with AddCallerDepth.
+//
+// The Logger captures the call site when a CallSite field was added by its
+// With or WithFields, or when its handler, possibly wrapped, is a Text, Json
+// or Datadog handler with AddSource or a CallSite field. Elsewhere, such as in the arguments of a single call,
+// CallSite walks the stack like Caller(9).
+//
+// If full is true, Source.File contains the full filename. Otherwise it is
+// shortened to its final two path components.
+func CallSite(full ...bool) Valuer {
+	spec := newCallerSpec(full)
+	return func(ctx context.Context) Value {
+		if pc := pcFromContext(ctx); pc != 0 {
+			return Value{kind: KindSource, num: uint64(pc), any: spec}
+		}
+		return stackCaller(ctx, callSiteDepth, spec)
+	}
+}
+
+// callSiteCode is the code pointer shared by the Valuers of CallSite.
+var callSiteCode = reflect.ValueOf(CallSite()).Pointer()
+
+// callerCode is the code pointer shared by the Valuers of Caller.
+var callerCode = reflect.ValueOf(Caller(0)).Pointer()
+
+// isCallSite reports whether v was returned by CallSite.
+func isCallSite(v Valuer) bool {
+	return v != nil && reflect.ValueOf(v).Pointer() == callSiteCode
+}
+
+// hasCallSite reports whether fields, or the groups in them, have a CallSite.
+func hasCallSite(fields []Field) bool {
+	for _, field := range fields {
+		switch field.Value.Kind() {
+		case KindValuer:
+			if isCallSite(field.Value.valuer()) {
+				return true
+			}
+		case KindGroup:
+			if hasCallSite(field.Value.group()) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// pcHandler is implemented by handlers that use the call site of records,
+// for AddSource or a CallSite field. The Logger captures it only for them.
+type pcHandler interface {
+	needsPC() bool
+}
+
+func (h *textHandler) needsPC() bool    { return h.handler.needsPC() }
+func (h *jsonHandler) needsPC() bool    { return h.handler.needsPC() }
+func (d *datadogHandler) needsPC() bool { return d.handler.needsPC() }
+
+func (h *commonHandler) needsPC() bool {
+	return h.opts.AddSource || h.callSite
+}
+
+// handlerNeedsPC reports whether h, or the handler it wraps, uses the call
+// site of records.
+func handlerNeedsPC(h Handler) bool {
+	for ; h != nil; h = nextHandler(h) {
+		if ph, ok := h.(pcHandler); ok {
+			return ph.needsPC()
+		}
+	}
+	return false
+}
+
+type pcKey struct{}
+
+// pcContext carries the program counter of the log call to the handler.
+type pcContext struct {
+	context.Context
+	pc uintptr
+}
+
+func (c pcContext) Value(key any) any {
+	if key == (pcKey{}) {
+		return c.pc
+	}
+	return c.Context.Value(key)
+}
+
+func contextWithPC(ctx context.Context, pc uintptr) context.Context {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return pcContext{Context: ctx, pc: pc}
+}
+
+// pcFromContext returns the program counter of the log call, or 0.
+func pcFromContext(ctx context.Context) uintptr {
+	if ctx == nil {
+		return 0
+	}
+	pc, _ := ctx.Value(pcKey{}).(uintptr)
+	return pc
+}
+
+// callerPC returns the program counter of the call instruction in the frame
+// skip frames above the function calling callerPC.
+func callerPC(skip int) uintptr {
+	if skip < 0 {
+		skip = 0
+	}
+	var pcs [1]uintptr
+	// Skip runtime.Callers and callerPC.
+	if runtime.Callers(skip+2, pcs[:]) == 0 {
+		return 0
+	}
+	// runtime.Callers returns a return PC; move it into the call instruction.
+	return pcs[0] - 1
+}
+
+// sourceValue returns the Source value of pc.
+func sourceValue(pc uintptr) Value {
+	return Value{kind: KindSource, num: uint64(pc), any: sourceSpec}
+}
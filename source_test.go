@@ -0,0 +1,163 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// sourceOf decodes the built-in source field of a JSON record.
+func sourceOf(t *testing.T, record []byte) Source {
+	t.Helper()
+	var r struct {
+		Source Source `json:"source"`
+	}
+	if err := json.Unmarshal(record, &r); err != nil {
+		t.Fatalf("%v: %s", err, record)
+	}
+	return r.Source
+}
+
+func wantSource(t *testing.T, got Source, line int) {
+	t.Helper()
+	if !strings.HasSuffix(got.File, "source_test.go") || got.Line != line || !strings.HasSuffix(got.Function, t.Name()) {
+		t.Fatalf("source = %+v, want source_test.go:%d in %s", got, line, t.Name())
+	}
+}
+
+func TestAddSource(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New(&buf, Sample(Json(&HandlerOptions{AddSource: true}), SamplingOptions{First: 10})).With("k", "v")
+
+	_, _, line, _ := runtime.Caller(0)
+	logger.InfoS("ready")
+	wantSource(t, sourceOf(t, buf.Bytes()), line+1)
+
+	buf.Reset()
+	_, _, line, _ = runtime.Caller(0)
+	_ = logger.Log(context.Background(), LevelWarn, "ready")
+	wantSource(t, sourceOf(t, buf.Bytes()), line+1)
+
+	buf.Reset()
+	_, _, line, _ = runtime.Caller(0)
+	logger.InfoAttrs("ready")
+	wantSource(t, sourceOf(t, buf.Bytes()), line+1)
+
+	buf.Reset()
+	_, _, line, _ = runtime.Caller(0)
+	_ = logger.LogAttrs(context.Background(), LevelWarn, "ready")
+	wantSource(t, sourceOf(t, buf.Bytes()), line+1)
+
+	buf.Reset()
+	_, _, line, _ = runtime.Caller(0)
+	logger.Core().Error("ready")
+	wantSource(t, sourceOf(t, buf.Bytes()), line+1)
+
+	buf.Reset()
+	_, _, line, _ = runtime.Caller(0)
+	_ = logger.Core().Log(context.Background(), LevelWarn, "ready")
+	wantSource(t, sourceOf(t, buf.Bytes()), line+1)
+
+	buf.Reset()
+	_, _, line, _ = runtime.Caller(0)
+	slog.New(NewSlogHandler(logger)).Info("ready")
+	wantSource(t, sourceOf(t, buf.Bytes()), line+1)
+}
+
+func TestAddSourceGlobal(t *testing.T) {
+	defer SetDefault(Default())
+	var buf bytes.Buffer
+	SetDefault(New(&buf, Json(&HandlerOptions{AddSource: true})))
+
+	_, _, line, _ := runtime.Caller(0)
+	Info("ready")
+	wantSource(t, sourceOf(t, buf.Bytes()), line+1)
+}
+
+func TestAddSourceReplacer(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New(&buf, Text(&HandlerOptions{AddSource: true, Replacer: func(_ context.Context, groups []string, field Field) Field {
+		if groups == nil && field.Key == SourceKey {
+			return String("line", field.Value.String())
+		}
+		return field
+	}}))
+
+	_, _, line, _ := runtime.Caller(0)
+	logger.Info("ready")
+	if got, want := buf.String(), "INFO line="; !strings.HasPrefix(got, want) || !strings.Contains(got, "source_test.go:"+strconv.Itoa(line+1)+" msg=ready") {
+		t.Fatalf("text output = %q", got)
+	}
+}
+
+func TestAddSourceOff(t *testing.T) {
+	logger := New(Discard, Json())
+	if logger.handler.Load().capturePC {
+		t.Fatal("capturePC = true for a handler without AddSource")
+	}
+	if !logger.SetHandler(RateLimit(Text(&HandlerOptions{AddSource: true}), RateLimitOptions{Rate: 1})).handler.Load().capturePC {
+		t.Fatal("capturePC = false for a wrapped handler with AddSource")
+	}
+}
+
+// unknownWrapper is a Handler wrapper that does not adjust the caller depth.
+type unknownWrapper struct{ next Handler }
+
+func (h unknownWrapper) WithFields(ctx context.Context, fields ...Field) Handler {
+	return unknownWrapper{h.next.WithFields(ctx, fields...)}
+}
+
+func (h unknownWrapper) WithGroup(name string) Handler {
+	return unknownWrapper{h.next.WithGroup(name)}
+}
+
+func (h unknownWrapper) Handle(ctx context.Context, w io.Writer, level Level, msg string, kvs ...any) error {
+	return h.next.Handle(ctx, w, level, msg, kvs...)
+}
+
+func TestCallSiteIgnoresHandlerFrames(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New(&buf, unknownWrapper{Dedup(Json())}).WithFields(DefaultFields...)
+	if !logger.handler.Load().capturePC {
+		t.Fatal("capturePC = false for a Logger with DefaultCaller")
+	}
+
+	_, _, line, _ := runtime.Caller(0)
+	logger.Info("ready")
+	var record struct {
+		Caller Source `json:"caller"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatal(err)
+	}
+	wantSource(t, record.Caller, line+1)
+}
+
+func TestCallSiteFromHandlerFields(t *testing.T) {
+	h := Json().WithFields(context.Background(), Group("meta", Dynamic("caller", CallSite(true))))
+	if !New(Discard, h).handler.Load().capturePC {
+		t.Fatal("capturePC = false for a handler with a CallSite field")
+	}
+	if New(Discard, Json().WithFields(context.Background(), DefaultFields[0])).handler.Load().capturePC {
+		t.Fatal("capturePC = true for a handler without a CallSite field")
+	}
+}
+
+func TestIsLogPackageFrame(t *testing.T) {
+	for function, want := range map[string]bool{
+		"github.com/nexuer/log.(*Logger).Error":         true,
+		"github.com/nexuer/log/logmgr.(*printer).Error": true,
+		"github.com/nexuer/log_test.TestSentry":         false,
+		"github.com/nexuer/logger.Error":                false,
+	} {
+		if got := isLogPackageFrame(function); got != want {
+			t.Errorf("isLogPackageFrame(%q) = %v, want %v", function, got, want)
+		}
+	}
+}
@@ -0,0 +1,195 @@
+package log
+
+import (
+	"io"
+	"sync/atomic"
+)
+
+// Stats counts what a Logger has written and lost, so that operators can
+// detect silent log loss.
+type Stats struct {
+	// Records is the number of records written to the output.
+	Records uint64
+	// Bytes is the number of bytes written to the output.
+	Bytes uint64
+	// WriteErrors is the number of writes to the output that failed.
+	WriteErrors uint64
+	// Dropped is the number of records discarded by hooks and by the
+	// handler, such as by sampling, rate limiting or duplicate suppression.
+	Dropped uint64
+	// QueueDropped is the number of records discarded by the output, such as
+	// by a full AsyncWriter queue or a disconnected TCPWriter.
+	QueueDropped uint64
+	// Queued is the number of records currently waiting in AsyncWriter
+	// queues.
+	Queued uint64
+	// Levels counts the records logged at each level.
+	Levels LevelCounts
+}
+
+// LevelCounts counts records by level. Levels between the named ones are
+// counted with the named level below them.
+type LevelCounts struct {
+	Debug, Info, Warn, Error, Fatal uint64
+}
+
+// Add returns the sum of s and o.
+func (s Stats) Add(o Stats) Stats {
+	return Stats{
+		Records:      s.Records + o.Records,
+		Bytes:        s.Bytes + o.Bytes,
+		WriteErrors:  s.WriteErrors + o.WriteErrors,
+		Dropped:      s.Dropped + o.Dropped,
+		QueueDropped: s.QueueDropped + o.QueueDropped,
+		Queued:       s.Queued + o.Queued,
+		Levels: LevelCounts{
+			Debug: s.Levels.Debug + o.Levels.Debug,
+			Info:  s.Levels.Info + o.Levels.Info,
+			Warn:  s.Levels.Warn + o.Levels.Warn,
+			Error: s.Levels.Error + o.Levels.Error,
+			Fatal: s.Levels.Fatal + o.Levels.Fatal,
+		},
+	}
+}
+
+// loggerStats is shared by a Logger and the loggers derived from it.
+type loggerStats struct {
+	records     atomic.Uint64
+	bytes       atomic.Uint64
+	writeErrors atomic.Uint64
+	hookDropped atomic.Uint64
+
+	levels [5]atomic.Uint64 // debug to fatal
+}
+
+func (s *loggerStats) countLevel(level Level) {
+	switch {
+	case level < LevelInfo:
+		s.levels[0].Add(1)
+	case level < LevelWarn:
+		s.levels[1].Add(1)
+	case level < LevelError:
+		s.levels[2].Add(1)
+	case level < LevelFatal:
+		s.levels[3].Add(1)
+	default:
+		s.levels[4].Add(1)
+	}
+}
+
+// statsWriter counts the writes to the output of a Logger.
+type statsWriter struct {
+	w     io.Writer
+	stats *loggerStats
+}
+
+func (w *statsWriter) Write(p []byte) (int, error) {
+	n, err := w.w.Write(p)
+	if err != nil {
+		w.stats.writeErrors.Add(1)
+	} else {
+		w.stats.records.Add(1)
+	}
+	w.stats.bytes.Add(uint64(n))
+	return n, err
+}
+
+// countedWriter returns w wrapped so that its writes are counted. Writes to
+// Discard are not counted, so handlers can still skip formatting for it.
+func countedWriter(w io.Writer, stats *loggerStats) io.Writer {
+	if w == nil || w == io.Discard || w == Discard {
+		return w
+	}
+	return &statsWriter{w: w, stats: stats}
+}
+
+// Stats returns the counters of l. They are shared with the loggers derived
+// from l by With, WithFields, WithGroup and WithContext. Dropped and
+// QueueDropped are read from the handler and output of l.
+func (l *Logger) Stats() Stats {
+	return Stats{
+		Records:      l.stats.records.Load(),
+		Bytes:        l.stats.bytes.Load(),
+		WriteErrors:  l.stats.writeErrors.Load(),
+		Dropped:      l.stats.hookDropped.Load() + handlerDropped(l.Handler()),
+		QueueDropped: writerDropped(l.Writer()),
+		Queued:       writerQueued(l.Writer()),
+		Levels: LevelCounts{
+			Debug: l.stats.levels[0].Load(),
+			Info:  l.stats.levels[1].Load(),
+			Warn:  l.stats.levels[2].Load(),
+			Error: l.stats.levels[3].Load(),
+			Fatal: l.stats.levels[4].Load(),
+		},
+	}
+}
+
+// handlerDropped sums the records dropped by the handler wrappers of h.
+func handlerDropped(h Handler) uint64 {
+	var n uint64
+	for ; h != nil; h = nextHandler(h) {
+		switch wh := h.(type) {
+		case *SamplingHandler:
+			n += wh.Dropped()
+		case *KeySamplingHandler:
+			n += wh.Dropped()
+		case *RateLimitHandler:
+			n += wh.Dropped()
+		case *DedupHandler:
+			n += wh.Dropped()
+		}
+		// The Dropped of a SentryHandler counts Sentry events; the records are
+		// still written.
+	}
+	return n
+}
+
+// nextHandler returns the handler wrapped by h, or nil if h is not one of the
+// handler wrappers of this package.
+func nextHandler(h Handler) Handler {
+	switch wh := h.(type) {
+	case *SamplingHandler:
+		return wh.next
+	case *KeySamplingHandler:
+		return wh.next
+	case *RateLimitHandler:
+		return wh.next
+	case *DedupHandler:
+		return wh.next
+	case *SentryHandler:
+		return wh.next
+	case *PrometheusHandler:
+		return wh.next
+	default:
+		return nil
+	}
+}
+
+// writerDropped sums the records dropped by w and the writers it queues for.
+func writerDropped(w io.Writer) uint64 {
+	var n uint64
+	for w != nil {
+		if d, ok := w.(interface{ Dropped() uint64 }); ok {
+			n += d.Dropped()
+		}
+		q, ok := w.(*QueueWriter)
+		if !ok {
+			return n
+		}
+		w = q.w
+	}
+	return n
+}
+
+// writerQueued sums the records waiting in the AsyncWriters in front of w.
+func writerQueued(w io.Writer) uint64 {
+	var n uint64
+	for {
+		q, ok := w.(*QueueWriter)
+		if !ok {
+			return n
+		}
+		n += uint64(q.Len())
+		w = q.w
+	}
+}
@@ -0,0 +1,52 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestLoggerStats(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New(&buf, Sample(Text(), SamplingOptions{First: 1}))
+	child := logger.With("k", "v")
+
+	logger.Info("once")
+	logger.Info("once")
+	child.Info("twice")
+
+	want := Stats{Records: 2, Bytes: uint64(buf.Len()), Dropped: 1, Levels: LevelCounts{Info: 3}}
+	if got := logger.Stats(); got != want {
+		t.Fatalf("Stats() = %+v, want %+v", got, want)
+	}
+	if got := child.Stats(); got != want {
+		t.Fatalf("child Stats() = %+v, want %+v", got, want)
+	}
+}
+
+func TestLoggerStatsCountsWriteErrors(t *testing.T) {
+	logger := New(errorWriter{err: errors.New("write failed")})
+	_ = logger.Log(context.Background(), LevelInfo, "lost")
+
+	if got := logger.Stats(); got != (Stats{WriteErrors: 1, Levels: LevelCounts{Info: 1}}) {
+		t.Fatalf("Stats() = %+v, want one write error", got)
+	}
+}
+
+func TestLoggerStatsQueueDropped(t *testing.T) {
+	gate := &gateWriter{open: make(chan struct{})}
+	q := AsyncWriter(gate, &AsyncOptions{QueueSize: 1, Policy: OverflowDropNewest})
+	logger := New(q)
+	for i := 0; i < 5; i++ {
+		logger.Info("record")
+	}
+	close(gate.open)
+	if err := logger.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := logger.Stats(); got.QueueDropped != q.Dropped() || got.QueueDropped == 0 {
+		t.Fatalf("QueueDropped = %d, want %d", got.QueueDropped, q.Dropped())
+	}
+}
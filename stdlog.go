@@ -0,0 +1,129 @@
+package log
+
+import (
+	stdlog "log"
+	"runtime"
+	"strings"
+)
+
+// StdLogOptions configures RedirectStdLog.
+type StdLogOptions struct {
+	// Level is the level of the records. The default is LevelInfo.
+	Level Level
+	// DetectLevel takes the level of a message from a leading level name,
+	// "[ERROR] ...", "error: ..." or "ERROR ..." for DEBUG, INFO, WARN,
+	// WARNING and ERROR, and removes it from the message.
+	DetectLevel bool
+}
+
+// RedirectStdLog makes the output of the standard library's global log
+// package, such as log.Printf in dependencies, records of l, or of the
+// default Logger if l is nil. The flags and prefix of the standard logger are
+// cleared, since l adds its own time and caller. The returned function
+// restores the previous output, flags and prefix.
+//
+//	undo := log.RedirectStdLog(logger, &log.StdLogOptions{DetectLevel: true})
+//	defer undo()
+func RedirectStdLog(l *Logger, opts ...*StdLogOptions) (undo func()) {
+	opt := new(StdLogOptions)
+	if len(opts) > 0 && opts[0] != nil {
+		*opt = *opts[0]
+	}
+	std := stdlog.Default()
+	flags, prefix, out := std.Flags(), std.Prefix(), std.Writer()
+	std.SetFlags(0)
+	std.SetPrefix("")
+	std.SetOutput(&stdLogWriter{logger: l, opts: *opt})
+	return func() {
+		std.SetFlags(flags)
+		std.SetPrefix(prefix)
+		std.SetOutput(out)
+	}
+}
+
+type stdLogWriter struct {
+	logger *Logger
+	opts   StdLogOptions
+}
+
+// Write logs p, one message of the standard logger.
+func (w *stdLogWriter) Write(p []byte) (int, error) {
+	l := w.logger
+	if l == nil {
+		l = Default()
+	}
+	msg := strings.TrimSuffix(string(p), "\n")
+	level := w.opts.Level
+	if w.opts.DetectLevel {
+		if detected, rest, ok := detectStdLogLevel(msg); ok {
+			level, msg = detected, rest
+		}
+	}
+	if !l.Enabled(level) {
+		return len(p), nil
+	}
+	h := l.handler.Load()
+	if h.Handler == nil {
+		return len(p), nil
+	}
+	l.stats.countLevel(level)
+	ctx := l.ctx
+	if h.capturePC {
+		ctx = contextWithPC(ctx, stdLogCallerPC())
+	}
+	if err := l.Handle(ctx, l.output.Load().out, level, msg); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// stdLogCallerPC returns the program counter of the call into the standard
+// log or slog packages. The number of their frames depends on the function
+// called and the Go version, so the stack is walked.
+func stdLogCallerPC() uintptr {
+	var pcs [32]uintptr
+	// Skip runtime.Callers, stdLogCallerPC and Write.
+	n := runtime.Callers(3, pcs[:])
+	frames := runtime.CallersFrames(pcs[:n])
+	for {
+		frame, more := frames.Next()
+		if !strings.HasPrefix(frame.Function, "log.") && !strings.HasPrefix(frame.Function, "log/slog.") {
+			return frame.PC
+		}
+		if !more {
+			return 0
+		}
+	}
+}
+
+var stdLogLevels = []struct {
+	name  string
+	level Level
+}{
+	{"DEBUG", LevelDebug},
+	{"INFO", LevelInfo},
+	{"WARNING", LevelWarn},
+	{"WARN", LevelWarn},
+	{"ERROR", LevelError},
+}
+
+// detectStdLogLevel returns the level named at the start of msg and msg
+// without it.
+func detectStdLogLevel(msg string) (Level, string, bool) {
+	s, bracket := strings.CutPrefix(msg, "[")
+	for _, l := range stdLogLevels {
+		if len(s) <= len(l.name) || !strings.EqualFold(s[:len(l.name)], l.name) {
+			continue
+		}
+		rest := s[len(l.name):]
+		switch {
+		case bracket && rest[0] == ']':
+		case !bracket && rest[0] == ':':
+		case !bracket && rest[0] == ' ' && s[:len(l.name)] == l.name:
+		default:
+			continue
+		}
+		return l.level, strings.TrimLeft(rest[1:], " "), true
+	}
+	return 0, msg, false
+}
@@ -0,0 +1,62 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	stdlog "log"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestRedirectStdLog(t *testing.T) {
+	var buf bytes.Buffer
+	std := stdlog.Default()
+	std.SetPrefix("app: ")
+	defer std.SetPrefix("")
+	undo := RedirectStdLog(NewWithOptions(&buf, WithLevel(LevelDebug)), &StdLogOptions{DetectLevel: true})
+
+	stdlog.Print("plain")
+	stdlog.Printf("[ERROR] failed %d", 1)
+	stdlog.Println("warning: low disk")
+	stdlog.Print("DEBUG cache miss")
+	stdlog.Print("Error opening file")
+	undo()
+
+	want := "INFO msg=plain\n" +
+		"ERROR msg=\"failed 1\"\n" +
+		"WARN msg=\"low disk\"\n" +
+		"DEBUG msg=\"cache miss\"\n" +
+		"INFO msg=\"Error opening file\"\n"
+	if got := buf.String(); got != want {
+		t.Fatalf("output = %q, want %q", got, want)
+	}
+	if std.Writer() == nil || std.Prefix() != "app: " || std.Flags() != stdlog.LstdFlags {
+		t.Fatalf("undo left prefix %q and flags %d", std.Prefix(), std.Flags())
+	}
+}
+
+func TestRedirectStdLogCaller(t *testing.T) {
+	var buf bytes.Buffer
+	undo := RedirectStdLog(New(&buf, Json()).WithFields(DefaultFields...), &StdLogOptions{Level: LevelWarn})
+	defer undo()
+
+	for _, logf := range []func(){
+		func() { stdlog.Print("print") },
+		func() { stdlog.Default().Printf("printf") },
+		func() { slog.Info("slog") },
+	} {
+		buf.Reset()
+		logf()
+		var record struct {
+			Level  string `json:"level"`
+			Caller Source `json:"caller"`
+		}
+		if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+			t.Fatal(err)
+		}
+		if record.Level != "WARN" || !strings.Contains(record.Caller.Function, "TestRedirectStdLogCaller.func") {
+			t.Fatalf("record = %+v", record)
+		}
+	}
+}
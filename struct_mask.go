@@ -0,0 +1,242 @@
+package log
+
+import (
+	"reflect"
+	"sync"
+)
+
+// StructTagKey is the struct tag that controls how struct fields are logged.
+// A field tagged `log:"omit"` is left out and a field tagged `log:"mask"` is
+// rendered as SecretMask, in structs logged with Any and in the structs they
+// contain, including the elements of slices, arrays and maps.
+const StructTagKey = "log"
+
+// structMask copies values of a type that contains structs with log tags into
+// a value of a generated type without the omitted fields and with masked
+// fields turned into strings. The generated type keeps the names and other
+// tags of the fields, so JSON and text encodings are unchanged otherwise.
+//
+// Generated types cannot refer to themselves, so a recursive reference to a
+// type is generated as an interface field, masked by the cached mask of the
+// type when the value is copied.
+type structMask struct {
+	typ    reflect.Type
+	kind   reflect.Kind
+	fields []maskedField // for a struct
+	elem   *structMask   // for a pointer, slice, array or map value
+	lazy   reflect.Type  // for a recursive reference
+}
+
+type maskedField struct {
+	index  int
+	mask   bool
+	nested *structMask // for a field whose type contains log tags
+}
+
+// structMasks caches the *structMask of each type, or nil for types without
+// log tags. Masks are built under structMaskMu.
+var (
+	structMasks  sync.Map
+	structMaskMu sync.Mutex
+)
+
+var anyType = reflect.TypeOf((*any)(nil)).Elem()
+
+// maskStruct returns a copy of a with the log tags applied, if the type of a
+// contains structs with log tags. A pointer is copied as the value it points
+// to.
+func maskStruct(a any) (any, bool) {
+	t := reflect.TypeOf(a)
+	if t == nil {
+		return nil, false
+	}
+	v := reflect.ValueOf(a)
+	if t.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return nil, false
+		}
+		t, v = t.Elem(), v.Elem()
+	}
+	m := structMaskFor(t)
+	if m == nil {
+		return nil, false
+	}
+	return m.copy(v).Interface(), true
+}
+
+func structMaskFor(t reflect.Type) *structMask {
+	if m, ok := structMasks.Load(t); ok {
+		return m.(*structMask)
+	}
+	structMaskMu.Lock()
+	defer structMaskMu.Unlock()
+	return buildStructMask(t, make(map[reflect.Type]bool))
+}
+
+// buildStructMask builds and caches the mask of t. Types in building are
+// being built further up; a reference to one is masked lazily.
+func buildStructMask(t reflect.Type, building map[reflect.Type]bool) *structMask {
+	if m, ok := structMasks.Load(t); ok {
+		return m.(*structMask)
+	}
+	if !hasLogTags(t, make(map[reflect.Type]bool)) {
+		structMasks.Store(t, (*structMask)(nil))
+		return nil
+	}
+	if building[t] {
+		return &structMask{typ: anyType, kind: reflect.Interface, lazy: t}
+	}
+	building[t] = true
+	m := newStructMask(t, building)
+	structMasks.Store(t, m)
+	return m
+}
+
+// hasLogTags reports whether values of t can contain a struct field with a
+// log tag. Types in visiting are being checked further up.
+func hasLogTags(t reflect.Type, visiting map[reflect.Type]bool) bool {
+	if m, ok := structMasks.Load(t); ok {
+		return m.(*structMask) != nil
+	}
+	if visiting[t] {
+		return false
+	}
+	visiting[t] = true
+	defer delete(visiting, t)
+	switch t.Kind() {
+	case reflect.Pointer, reflect.Slice, reflect.Array, reflect.Map:
+		return hasLogTags(t.Elem(), visiting)
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if !f.IsExported() {
+				continue
+			}
+			if tag := f.Tag.Get(StructTagKey); tag == "omit" || tag == "mask" || hasLogTags(f.Type, visiting) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func newStructMask(t reflect.Type, building map[reflect.Type]bool) *structMask {
+	m := &structMask{kind: t.Kind()}
+	switch t.Kind() {
+	case reflect.Pointer:
+		m.elem = buildStructMask(t.Elem(), building)
+		m.typ = reflect.PointerTo(m.elem.typ)
+	case reflect.Slice:
+		m.elem = buildStructMask(t.Elem(), building)
+		m.typ = reflect.SliceOf(m.elem.typ)
+	case reflect.Array:
+		m.elem = buildStructMask(t.Elem(), building)
+		m.typ = reflect.ArrayOf(t.Len(), m.elem.typ)
+	case reflect.Map:
+		m.elem = buildStructMask(t.Elem(), building)
+		m.typ = reflect.MapOf(t.Key(), m.elem.typ)
+	case reflect.Struct:
+		var fields []reflect.StructField
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if !f.IsExported() {
+				// Generated types cannot have unexported fields, and
+				// encoding/json skips them anyway.
+				continue
+			}
+			field := maskedField{index: i}
+			switch f.Tag.Get(StructTagKey) {
+			case "omit":
+				continue
+			case "mask":
+				field.mask = true
+				f.Type = reflect.TypeOf("")
+			default:
+				if field.nested = buildStructMask(f.Type, building); field.nested != nil {
+					f.Type = field.nested.typ
+				}
+			}
+			m.fields = append(m.fields, field)
+			fields = append(fields, reflect.StructField{Name: f.Name, Type: f.Type, Tag: f.Tag, Anonymous: f.Anonymous})
+		}
+		m.typ = structOf(fields)
+	}
+	return m
+}
+
+// structOf is reflect.StructOf, which panics for some embedded fields. They
+// are then kept as named fields.
+func structOf(fields []reflect.StructField) (t reflect.Type) {
+	defer func() {
+		if recover() != nil {
+			for i := range fields {
+				fields[i].Anonymous = false
+			}
+			t = reflect.StructOf(fields)
+		}
+	}()
+	return reflect.StructOf(fields)
+}
+
+func (m *structMask) copy(v reflect.Value) reflect.Value {
+	switch m.kind {
+	case reflect.Interface:
+		// A recursive reference: the mask of its type is complete by now.
+		if isNilValue(v) {
+			return reflect.Zero(m.typ)
+		}
+		return structMaskFor(m.lazy).copy(v)
+	case reflect.Pointer:
+		if v.IsNil() {
+			return reflect.Zero(m.typ)
+		}
+		p := reflect.New(m.elem.typ)
+		p.Elem().Set(m.elem.copy(v.Elem()))
+		return p
+	case reflect.Slice:
+		if v.IsNil() {
+			return reflect.Zero(m.typ)
+		}
+		out := reflect.MakeSlice(m.typ, v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			out.Index(i).Set(m.elem.copy(v.Index(i)))
+		}
+		return out
+	case reflect.Array:
+		out := reflect.New(m.typ).Elem()
+		for i := 0; i < v.Len(); i++ {
+			out.Index(i).Set(m.elem.copy(v.Index(i)))
+		}
+		return out
+	case reflect.Map:
+		if v.IsNil() {
+			return reflect.Zero(m.typ)
+		}
+		out := reflect.MakeMapWithSize(m.typ, v.Len())
+		for it := v.MapRange(); it.Next(); {
+			out.SetMapIndex(it.Key(), m.elem.copy(it.Value()))
+		}
+		return out
+	}
+	out := reflect.New(m.typ).Elem()
+	for i, field := range m.fields {
+		src, dst := v.Field(field.index), out.Field(i)
+		switch {
+		case field.mask:
+			dst.SetString(SecretMask)
+		case field.nested == nil:
+			dst.Set(src)
+		default:
+			dst.Set(field.nested.copy(src))
+		}
+	}
+	return out
+}
+
+func isNilValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Slice, reflect.Map:
+		return v.IsNil()
+	}
+	return false
+}
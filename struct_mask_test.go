@@ -0,0 +1,114 @@
+package log
+
+import (
+	"bytes"
+	"testing"
+)
+
+type maskedAddress struct {
+	Street string `json:"street" log:"mask"`
+	City   string `json:"city"`
+}
+
+type maskedUser struct {
+	Name     string         `json:"name"`
+	Password string         `json:"password" log:"omit"`
+	SSN      string         `json:"ssn" log:"mask"`
+	Home     maskedAddress  `json:"home"`
+	Work     *maskedAddress `json:"work,omitempty"`
+	note     string
+}
+
+func TestStructTagMasking(t *testing.T) {
+	user := &maskedUser{
+		Name:     "alice",
+		Password: "hunter2",
+		SSN:      "123-45-6789",
+		Home:     maskedAddress{Street: "1 Main St", City: "Springfield"},
+		note:     "private",
+	}
+	for _, test := range []struct {
+		name    string
+		handler Handler
+		want    string
+	}{
+		{"json", Json(), `{"level":"INFO","msg":"signup","user":{"name":"alice","ssn":"***","home":{"street":"***","city":"Springfield"}}}` + "\n"},
+		{"text", Text(), `INFO msg=signup user="{Name:alice SSN:*** Home:{Street:*** City:Springfield} Work:<nil>}"` + "\n"},
+		{"csv", CSV(&CSVOptions{Columns: []string{"user"}}), "{alice *** {*** Springfield} <nil>}\n"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			New(&buf, test.handler).InfoS("signup", "user", user)
+			if got := buf.String(); got != test.want {
+				t.Fatalf("output = %s, want %s", got, test.want)
+			}
+		})
+	}
+	if user.SSN != "123-45-6789" {
+		t.Fatal("masking changed the logged struct")
+	}
+}
+
+func TestStructWithoutTagsIsUnchanged(t *testing.T) {
+	type plain struct{ A, b int }
+	if _, ok := maskStruct(plain{1, 2}); ok {
+		t.Fatal("struct without log tags was copied")
+	}
+}
+
+type maskedNode struct {
+	Name  string      `json:"name"`
+	Email string      `json:"email" log:"mask"`
+	Next  *maskedNode `json:"next,omitempty"`
+}
+
+func TestStructTagMaskingInContainers(t *testing.T) {
+	user := maskedUser{Name: "alice", Password: "hunter2", SSN: "123-45-6789"}
+	node := &maskedNode{Name: "a", Email: "a@x.com", Next: &maskedNode{Name: "b", Email: "b@x.com"}}
+	for _, test := range []struct {
+		name  string
+		value any
+		json  string
+		text  string
+	}{
+		{
+			"slice", []maskedUser{user},
+			`[{"name":"alice","ssn":"***","home":{"street":"***","city":""}}]`,
+			`"[{Name:alice SSN:*** Home:{Street:*** City:} Work:<nil>}]"`,
+		},
+		{
+			"array", [1]maskedAddress{{Street: "1 Main St", City: "Springfield"}},
+			`[{"street":"***","city":"Springfield"}]`,
+			`"[{Street:*** City:Springfield}]"`,
+		},
+		{
+			"map", map[string]maskedAddress{"home": {Street: "1 Main St"}},
+			`{"home":{"street":"***","city":""}}`,
+			`"map[home:{Street:*** City:}]"`,
+		},
+		{
+			"recursive", node,
+			`{"name":"a","email":"***","next":{"name":"b","email":"***"}}`,
+			"",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			New(&buf, Json()).InfoS("x", "v", test.value)
+			if want := `{"level":"INFO","msg":"x","v":` + test.json + "}\n"; buf.String() != want {
+				t.Fatalf("json = %s, want %s", buf.String(), want)
+			}
+			if test.text == "" {
+				return
+			}
+			buf.Reset()
+			New(&buf, Text()).InfoS("x", "v", test.value)
+			if want := "INFO msg=x v=" + test.text + "\n"; buf.String() != want {
+				t.Fatalf("text = %s, want %s", buf.String(), want)
+			}
+		})
+	}
+	if user.SSN != "123-45-6789" || node.Next.Email != "b@x.com" {
+		t.Fatal("masking changed the logged value")
+	}
+}
@@ -0,0 +1,160 @@
+package log
+
+import (
+	"errors"
+	"io"
+	"os"
+	"sync"
+	"syscall"
+	"time"
+
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+// WriteSyncer is a writer that can commit what was written to stable storage,
+// such as an *os.File. Logger.Sync calls Sync on outputs that implement it,
+// and the buffering writers of this package implement it to flush first.
+type WriteSyncer interface {
+	io.Writer
+	Sync() error
+}
+
+// syncWriter syncs w if it can be synced. The EINVAL of a terminal or pipe is
+// ignored.
+func syncWriter(w io.Writer) error {
+	err := syncFunc(w)()
+	if errors.Is(err, syscall.EINVAL) {
+		return nil
+	}
+	return err
+}
+
+// SyncOptions configures the writer returned by SyncWriter. The zero value
+// never syncs; Records set to 1 syncs after every record.
+type SyncOptions struct {
+	// Records syncs after every Records records.
+	Records int
+	// Interval syncs the records not synced yet at most Interval after they
+	// were written.
+	Interval time.Duration
+}
+
+// Enabled reports whether o syncs at all.
+func (o SyncOptions) Enabled() bool {
+	return o.Records > 0 || o.Interval > 0
+}
+
+// SyncedWriter flushes a file to stable storage as records are written. It is
+// returned by SyncWriter.
+type SyncedWriter struct {
+	w    io.Writer
+	sync func() error
+	opts SyncOptions
+
+	mu      sync.Mutex
+	pending int
+	timer   *time.Timer
+}
+
+// SyncWriter returns a writer that writes to w and calls its Sync method, as
+// of an *os.File, after every opts.Records records and at most opts.Interval
+// after a record, so that audit records survive a crash. The files of
+// FileWriter are synced by opening their current path. Writers without Sync
+// are written to as is.
+//
+// A failed sync after a record is returned by Write; a failed sync after an
+// interval is reported to ErrorHandler. Close syncs the records not synced yet
+// and closes w if it is an io.Closer.
+func SyncWriter(w io.Writer, opts ...*SyncOptions) *SyncedWriter {
+	opt := new(SyncOptions)
+	if len(opts) > 0 && opts[0] != nil {
+		*opt = *opts[0]
+	}
+	return &SyncedWriter{w: w, sync: syncFunc(w), opts: *opt}
+}
+
+// syncFunc returns the function that syncs w.
+func syncFunc(w io.Writer) func() error {
+	switch f := w.(type) {
+	case WriteSyncer:
+		return f.Sync
+	case *lumberjack.Logger:
+		// The file of a lumberjack.Logger is not exposed, but fsync flushes
+		// the file whichever descriptor it is called on.
+		return func() error {
+			file, err := os.OpenFile(f.Filename, os.O_WRONLY, 0)
+			if os.IsNotExist(err) {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			defer file.Close()
+			return file.Sync()
+		}
+	}
+	return func() error { return nil }
+}
+
+func (w *SyncedWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	n, err := w.w.Write(p)
+	if err != nil || !w.opts.Enabled() {
+		return n, err
+	}
+	w.pending++
+	switch {
+	case w.opts.Records > 0 && w.pending >= w.opts.Records:
+		err = w.syncLocked()
+	case w.opts.Interval > 0 && w.timer == nil:
+		w.timer = time.AfterFunc(w.opts.Interval, func() {
+			w.mu.Lock()
+			defer w.mu.Unlock()
+			w.timer = nil
+			if w.pending > 0 {
+				errorHandler(w.syncLocked())
+			}
+		})
+	}
+	return n, err
+}
+
+// Sync flushes the records written so far to stable storage.
+func (w *SyncedWriter) Sync() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.syncLocked()
+}
+
+func (w *SyncedWriter) syncLocked() error {
+	w.pending = 0
+	if w.timer != nil {
+		w.timer.Stop()
+		w.timer = nil
+	}
+	return w.sync()
+}
+
+// Close syncs the records not synced yet and closes the underlying writer if
+// it is an io.Closer.
+func (w *SyncedWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	var err error
+	if w.pending > 0 {
+		err = w.syncLocked()
+	}
+	if c, ok := w.w.(io.Closer); ok {
+		if cerr := c.Close(); err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+// Writer returns the underlying writer.
+func (w *SyncedWriter) Writer() io.Writer {
+	return w.w
+}
@@ -0,0 +1,128 @@
+package log
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type syncBuffer struct {
+	bytes.Buffer
+	syncs atomic.Int32
+}
+
+func (b *syncBuffer) Sync() error {
+	b.syncs.Add(1)
+	return nil
+}
+
+func TestSyncWriterRecords(t *testing.T) {
+	var buf syncBuffer
+	w := SyncWriter(&buf, &SyncOptions{Records: 2})
+	for i := 0; i < 5; i++ {
+		if _, err := w.Write([]byte("record\n")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := buf.syncs.Load(); n != 2 {
+		t.Fatalf("syncs after 5 records = %d, want 2", n)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if n := buf.syncs.Load(); n != 3 {
+		t.Fatalf("syncs after Close = %d, want 3", n)
+	}
+
+	var never syncBuffer
+	SyncWriter(&never).Write([]byte("record\n"))
+	if n := never.syncs.Load(); n != 0 {
+		t.Fatalf("syncs without a policy = %d", n)
+	}
+}
+
+func TestSyncWriterInterval(t *testing.T) {
+	var buf syncBuffer
+	w := SyncWriter(&buf, &SyncOptions{Interval: 10 * time.Millisecond})
+	w.Write([]byte("a\n"))
+	w.Write([]byte("b\n"))
+	deadline := time.Now().Add(5 * time.Second)
+	for buf.syncs.Load() == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("records were not synced after the interval")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(30 * time.Millisecond)
+	if n := buf.syncs.Load(); n != 1 {
+		t.Fatalf("syncs = %d, want 1", n)
+	}
+}
+
+func TestSyncWriterFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	w := SyncWriter(FileWriter(path, 1, 1), &SyncOptions{Records: 1})
+	if _, err := w.Write([]byte("record\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if data, err := os.ReadFile(path); err != nil || string(data) != "record\n" {
+		t.Fatalf("file = %q, %v", data, err)
+	}
+}
+
+func TestLoggerSync(t *testing.T) {
+	var buf syncBuffer
+	q := AsyncWriter(&buf)
+	defer q.Close()
+	logger := New(MultiWriter(q, &bytes.Buffer{}))
+	for i := 0; i < 100; i++ {
+		logger.Info("record")
+	}
+	if err := logger.Sync(); err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(buf.String(), "\n"); n != 100 || buf.syncs.Load() != 1 {
+		t.Fatalf("after Sync: %d records, %d syncs", n, buf.syncs.Load())
+	}
+
+	var mu sync.Mutex
+	var lines int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		lines += strings.Count(string(body), "\n")
+		mu.Unlock()
+	}))
+	defer srv.Close()
+	hw, err := HTTPWriter(HTTPOptions{URL: srv.URL, FlushInterval: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer hw.Close()
+	logger = New(hw)
+	logger.Info("a")
+	logger.Info("b")
+	if err := logger.Sync(); err != nil {
+		t.Fatal(err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if lines != 2 {
+		t.Fatalf("delivered %d lines after Sync, want 2", lines)
+	}
+
+	if err := New(os.Stderr).Sync(); err != nil {
+		t.Errorf("Sync of stderr: %v", err)
+	}
+}
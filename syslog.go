@@ -0,0 +1,344 @@
+package log
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/nexuer/log/internal/buffer"
+)
+
+// Reference: https://www.rfc-editor.org/rfc/rfc5424
+
+// Facility is a syslog facility code.
+type Facility int
+
+const (
+	FacilityKern Facility = iota
+	FacilityUser
+	FacilityMail
+	FacilityDaemon
+	FacilityAuth
+	FacilitySyslog
+	FacilityLPR
+	FacilityNews
+	FacilityUUCP
+	FacilityCron
+	FacilityAuthPriv
+	FacilityFTP
+	FacilityNTP
+	FacilityAudit
+	FacilityAlert
+	FacilityClock
+	FacilityLocal0
+	FacilityLocal1
+	FacilityLocal2
+	FacilityLocal3
+	FacilityLocal4
+	FacilityLocal5
+	FacilityLocal6
+	FacilityLocal7
+)
+
+// Syslog severities, from RFC 5424 section 6.2.1.
+const (
+	severityEmergency = iota
+	severityAlert
+	severityCritical
+	severityError
+	severityWarning
+	severityNotice
+	severityInformational
+	severityDebug
+)
+
+// SyslogSeverity maps level to its RFC 5424 severity code. Levels between the
+// built-in levels use the severity of the nearest lower built-in level.
+func SyslogSeverity(level Level) int {
+	switch {
+	case level < LevelInfo:
+		return severityDebug
+	case level < LevelWarn:
+		return severityInformational
+	case level < LevelError:
+		return severityWarning
+	case level < LevelFatal:
+		return severityError
+	default:
+		return severityCritical
+	}
+}
+
+// DefaultStructuredDataID is the SD-ID used for record fields when
+// SyslogOptions.StructuredDataID is empty. 32473 is the private enterprise
+// number reserved for documentation.
+const DefaultStructuredDataID = "fields@32473"
+
+// SyslogOptions configures the handler returned by Syslog.
+type SyslogOptions struct {
+	// HandlerOptions.Name is used as APP-NAME. Replacer is applied to record
+	// fields before they are written as structured data.
+	HandlerOptions
+	// Facility is combined with the level severity to form PRI. FacilityKern,
+	// the zero value, is reserved for kernel messages and is replaced by
+	// FacilityUser.
+	Facility Facility
+	// Hostname defaults to os.Hostname.
+	Hostname string
+	// ProcID defaults to the process id.
+	ProcID string
+	// MsgID identifies the type of message. It is NILVALUE when empty.
+	MsgID string
+	// StructuredDataID is the SD-ID that holds record fields. The default is
+	// DefaultStructuredDataID.
+	StructuredDataID string
+}
+
+type syslogHandler struct {
+	opts SyslogOptions
+	flat flatFields
+}
+
+// Syslog returns a Handler that writes RFC 5424 messages. Record fields are
+// written as SD-PARAMs of a single SD-ELEMENT, with group members joined by
+// dots. Each message is terminated by a newline; use SyslogWriter to deliver
+// messages to a syslog daemon.
+func Syslog(opts ...*SyslogOptions) Handler {
+	opt := new(SyslogOptions)
+	if len(opts) > 0 && opts[0] != nil {
+		*opt = *opts[0]
+	}
+	if opt.Facility == FacilityKern {
+		opt.Facility = FacilityUser
+	}
+	if opt.Hostname == "" {
+		opt.Hostname, _ = os.Hostname()
+	}
+	if opt.ProcID == "" {
+		opt.ProcID = strconv.Itoa(os.Getpid())
+	}
+	if opt.StructuredDataID == "" {
+		opt.StructuredDataID = DefaultStructuredDataID
+	}
+	return &syslogHandler{opts: *opt, flat: newFlatFields(&opt.HandlerOptions)}
+}
+
+func (h *syslogHandler) WithFields(_ context.Context, fields ...Field) Handler {
+	if len(fields) == 0 {
+		return h
+	}
+	return &syslogHandler{opts: h.opts, flat: h.flat.withFields(fields)}
+}
+
+func (h *syslogHandler) WithGroup(name string) Handler {
+	if name == "" {
+		return h
+	}
+	return &syslogHandler{opts: h.opts, flat: h.flat.withGroup(name)}
+}
+
+func (h *syslogHandler) Handle(ctx context.Context, w io.Writer, level Level, msg string, kvs ...any) error {
+	msg = h.opts.Redactor.Redact(msg)
+	buf := buffer.New()
+	defer buf.Free()
+
+	pri := int(h.opts.Facility)*8 + SyslogSeverity(level)
+	_ = buf.WriteByte('<')
+	*buf = strconv.AppendInt(*buf, int64(pri), 10)
+	_, _ = buf.WriteString(">1 ")
+	*buf = time.Now().AppendFormat(*buf, "2006-01-02T15:04:05.000000Z07:00")
+	_ = buf.WriteByte(' ')
+	appendSyslogHeader(buf, h.opts.Hostname, 255)
+	_ = buf.WriteByte(' ')
+	appendSyslogHeader(buf, h.opts.Name, 48)
+	_ = buf.WriteByte(' ')
+	appendSyslogHeader(buf, h.opts.ProcID, 128)
+	_ = buf.WriteByte(' ')
+	appendSyslogHeader(buf, h.opts.MsgID, 32)
+	_ = buf.WriteByte(' ')
+
+	_ = buf.WriteByte('[')
+	_, _ = buf.WriteString(h.opts.StructuredDataID)
+	start := buf.Len()
+	h.flat.walk(ctx, kvs, func(groups []string, key string, v Value) {
+		appendSyslogParam(buf, groups, key, v)
+	})
+	if buf.Len() == start {
+		// No parameters: emit NILVALUE instead of an empty SD-ELEMENT.
+		buf.SetLen(start - len(h.opts.StructuredDataID) - 1)
+		_ = buf.WriteByte('-')
+	} else {
+		_ = buf.WriteByte(']')
+	}
+	if msg != "" {
+		_ = buf.WriteByte(' ')
+		_, _ = buf.WriteString(msg)
+	}
+	_ = buf.WriteByte('\n')
+	return h.flat.write(ctx, w, buf)
+}
+
+// appendSyslogHeader appends a header field, replacing characters outside
+// PRINTUSASCII and truncating to max bytes. Empty values become NILVALUE.
+func appendSyslogHeader(buf *buffer.Buffer, s string, max int) {
+	if s == "" {
+		_ = buf.WriteByte('-')
+		return
+	}
+	if len(s) > max {
+		s = s[:max]
+	}
+	for i := 0; i < len(s); i++ {
+		if c := s[i]; c < 33 || c > 126 {
+			_ = buf.WriteByte('_')
+		} else {
+			_ = buf.WriteByte(c)
+		}
+	}
+}
+
+func appendSyslogParam(buf *buffer.Buffer, groups []string, key string, v Value) {
+	_ = buf.WriteByte(' ')
+	n := buf.Len()
+	for _, g := range groups {
+		appendSyslogParamName(buf, g)
+		_ = buf.WriteByte(keyComponentSep)
+	}
+	appendSyslogParamName(buf, key)
+	if buf.Len()-n > 32 {
+		buf.SetLen(n + 32)
+	}
+	_, _ = buf.WriteString(`="`)
+	appendSyslogParamValue(buf, plainValueString(v))
+	_ = buf.WriteByte('"')
+}
+
+// appendSyslogParamName appends s as part of a PARAM-NAME, which may not
+// contain '=', SP, ']', '"' or characters outside PRINTUSASCII.
+func appendSyslogParamName(buf *buffer.Buffer, s string) {
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c < 33 || c > 126 || c == '=' || c == ']' || c == '"':
+			_ = buf.WriteByte('_')
+		default:
+			_ = buf.WriteByte(c)
+		}
+	}
+}
+
+// appendSyslogParamValue appends s as a PARAM-VALUE, escaping '"', '\' and ']'.
+func appendSyslogParamValue(buf *buffer.Buffer, s string) {
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case '"', '\\', ']':
+			_ = buf.WriteByte('\\')
+			_ = buf.WriteByte(c)
+		default:
+			_ = buf.WriteByte(c)
+		}
+	}
+}
+
+type syslogWriter struct {
+	mu      sync.Mutex
+	network string
+	addr    string
+	conn    net.Conn
+}
+
+// SyslogWriter returns a writer that delivers messages produced by the Syslog
+// handler to a syslog daemon. Network is one of "udp", "tcp", "unix" or
+// "unixgram". If network and addr are both empty, the local syslog socket is
+// used.
+//
+// Datagram transports send one message per packet. Stream transports use the
+// octet-counting framing from RFC 6587. A failed write is retried once on a
+// new connection.
+func SyslogWriter(network, addr string) (io.WriteCloser, error) {
+	w := &syslogWriter{network: network, addr: addr}
+	if err := w.connect(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+var syslogSockets = []string{"/dev/log", "/var/run/syslog", "/var/run/log"}
+
+func (w *syslogWriter) connect() error {
+	if w.network != "" || w.addr != "" {
+		conn, err := net.Dial(w.network, w.addr)
+		if err != nil {
+			return err
+		}
+		w.conn = conn
+		return nil
+	}
+	for _, path := range syslogSockets {
+		for _, network := range []string{"unixgram", "unix"} {
+			conn, err := net.Dial(network, path)
+			if err == nil {
+				w.network, w.addr, w.conn = network, path, conn
+				return nil
+			}
+		}
+	}
+	return errors.New("log: local syslog socket not found")
+}
+
+func (w *syslogWriter) stream() bool {
+	return w.network == "tcp" || w.network == "tcp4" || w.network == "tcp6" || w.network == "unix"
+}
+
+func (w *syslogWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	msg := p
+	if len(msg) > 0 && msg[len(msg)-1] == '\n' {
+		msg = msg[:len(msg)-1]
+	}
+	var err error
+	for i := 0; i < 2; i++ {
+		if w.conn == nil {
+			if err = w.connect(); err != nil {
+				continue
+			}
+		}
+		if err = w.write(msg); err == nil {
+			return len(p), nil
+		}
+		_ = w.conn.Close()
+		w.conn = nil
+	}
+	return 0, err
+}
+
+func (w *syslogWriter) write(msg []byte) error {
+	if !w.stream() {
+		_, err := w.conn.Write(msg)
+		return err
+	}
+	frame := buffer.New()
+	defer frame.Free()
+	*frame = strconv.AppendInt(*frame, int64(len(msg)), 10)
+	_ = frame.WriteByte(' ')
+	_, _ = frame.Write(msg)
+	_, err := w.conn.Write(*frame)
+	return err
+}
+
+func (w *syslogWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.conn == nil {
+		return nil
+	}
+	err := w.conn.Close()
+	w.conn = nil
+	return err
+}
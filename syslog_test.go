@@ -0,0 +1,113 @@
+package log
+
+import (
+	"bufio"
+	"bytes"
+	"net"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestSyslogHandlerOutput(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New(&buf, Syslog(&SyslogOptions{
+		HandlerOptions: HandlerOptions{Name: "server"},
+		Facility:       FacilityLocal0,
+		Hostname:       "host",
+		ProcID:         "42",
+	})).With("service", "api").WithGroup("req")
+
+	logger.WarnS("slow", "id", 7, "path", `/a"b]`)
+
+	re := regexp.MustCompile(`^<132>1 \d{4}-\d\d-\d\dT\d\d:\d\d:\d\d\.\d{6}(Z|[+-]\d\d:\d\d) host server 42 - ` +
+		regexp.QuoteMeta(`[fields@32473 service="api" req.id="7" req.path="/a\"b\]"] slow`) + "\n$")
+	if got := buf.String(); !re.MatchString(got) {
+		t.Fatalf("syslog output = %q, want match for %s", got, re)
+	}
+}
+
+func TestSyslogHandlerWithoutFields(t *testing.T) {
+	var buf bytes.Buffer
+	New(&buf, Syslog(&SyslogOptions{Hostname: "my host", ProcID: "1"})).Error("failed")
+
+	// FacilityKern is replaced by FacilityUser: 1*8 + 3.
+	if got := buf.String(); !strings.HasPrefix(got, "<11>1 ") || !strings.HasSuffix(got, " my_host - 1 - - failed\n") {
+		t.Fatalf("syslog output = %q", got)
+	}
+}
+
+func TestSyslogSeverity(t *testing.T) {
+	for _, test := range []struct {
+		level Level
+		want  int
+	}{
+		{LevelDebug, 7},
+		{LevelInfo, 6},
+		{LevelInfo + 2, 6},
+		{LevelWarn, 4},
+		{LevelError, 3},
+		{LevelFatal, 2},
+	} {
+		if got := SyslogSeverity(test.level); got != test.want {
+			t.Fatalf("SyslogSeverity(%s) = %d, want %d", test.level, got, test.want)
+		}
+	}
+}
+
+func TestSyslogWriterUDP(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Skip(err)
+	}
+	defer conn.Close()
+
+	w, err := SyslogWriter("udp", conn.LocalAddr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	if _, err := w.Write([]byte("<14>1 - - - - - - hello\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	packet := make([]byte, 1024)
+	n, _, err := conn.ReadFrom(packet)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(packet[:n]), "<14>1 - - - - - - hello"; got != want {
+		t.Fatalf("packet = %q, want %q", got, want)
+	}
+}
+
+func TestSyslogWriterTCPFraming(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skip(err)
+	}
+	defer ln.Close()
+	received := make(chan string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			received <- err.Error()
+			return
+		}
+		defer conn.Close()
+		line, _ := bufio.NewReader(conn).ReadString('o')
+		received <- line
+	}()
+
+	w, err := SyslogWriter("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	if _, err := w.Write([]byte("hello\n")); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := <-received, "5 hello"; got != want {
+		t.Fatalf("frame = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,197 @@
+package log
+
+import (
+	"bytes"
+	"crypto/tls"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// TCPOptions configures the writer returned by TCPWriter.
+type TCPOptions struct {
+	// DialTimeout bounds each connection attempt. The default is 5 seconds.
+	DialTimeout time.Duration
+	// WriteTimeout bounds each write. The default is 5 seconds.
+	WriteTimeout time.Duration
+	// Backoff spaces reconnection attempts. MaxRetries is ignored: the writer
+	// keeps reconnecting until it is closed.
+	Backoff Backoff
+	// BufferSize is the number of bytes kept while disconnected. Records that
+	// do not fit are dropped. The default is 1 MiB.
+	BufferSize int
+	// TLSConfig enables TLS when set.
+	TLSConfig *tls.Config
+}
+
+// NetWriter writes records over a persistent network connection. It is
+// returned by TCPWriter.
+type NetWriter struct {
+	addr string
+	opts TCPOptions
+
+	mu           sync.Mutex
+	conn         net.Conn
+	buf          []byte
+	reconnecting bool
+	closed       bool
+	stop         chan struct{}
+	done         chan struct{}
+	dropped      atomic.Uint64
+}
+
+// TCPWriter returns a writer that sends records to addr over TCP. Records are
+// written directly while connected. When the connection fails, the writer
+// reconnects in the background with exponential backoff and buffers records
+// in the meantime, so Write does not block on an unavailable collector. The
+// first connection is also made in the background.
+func TCPWriter(addr string, opts ...*TCPOptions) *NetWriter {
+	opt := new(TCPOptions)
+	if len(opts) > 0 && opts[0] != nil {
+		*opt = *opts[0]
+	}
+	if opt.DialTimeout <= 0 {
+		opt.DialTimeout = 5 * time.Second
+	}
+	if opt.WriteTimeout <= 0 {
+		opt.WriteTimeout = 5 * time.Second
+	}
+	if opt.BufferSize <= 0 {
+		opt.BufferSize = 1 << 20
+	}
+	opt.Backoff = opt.Backoff.withDefaults()
+	w := &NetWriter{addr: addr, opts: *opt, stop: make(chan struct{})}
+	w.mu.Lock()
+	w.reconnectLocked()
+	w.mu.Unlock()
+	return w
+}
+
+// Write sends p, or buffers it while the writer is disconnected. It returns an
+// error only after Close.
+func (w *NetWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return 0, errWriterClosed
+	}
+	if w.conn != nil {
+		_ = w.conn.SetWriteDeadline(time.Now().Add(w.opts.WriteTimeout))
+		n, err := w.conn.Write(p)
+		if err == nil {
+			return n, nil
+		}
+		errorHandler(err)
+		_ = w.conn.Close()
+		w.conn = nil
+		w.reconnectLocked()
+		// Buffer the whole record to resend after reconnecting, since the
+		// part already written went to the failed connection.
+	}
+	if len(w.buf)+len(p) > w.opts.BufferSize {
+		w.dropped.Add(1)
+		return len(p), nil
+	}
+	w.buf = append(w.buf, p...)
+	return len(p), nil
+}
+
+// Dropped returns the number of records discarded while disconnected because
+// the buffer was full.
+func (w *NetWriter) Dropped() uint64 {
+	return w.dropped.Load()
+}
+
+// Close closes the connection. Records still buffered because the writer is
+// disconnected are discarded.
+func (w *NetWriter) Close() error {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return nil
+	}
+	w.closed = true
+	close(w.stop)
+	done := w.done
+	w.mu.Unlock()
+	if done != nil {
+		<-done
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.buf = nil
+	if w.conn == nil {
+		return nil
+	}
+	return w.conn.Close()
+}
+
+func (w *NetWriter) reconnectLocked() {
+	if w.reconnecting {
+		return
+	}
+	w.reconnecting = true
+	w.done = make(chan struct{})
+	go w.reconnect(w.done)
+}
+
+func (w *NetWriter) reconnect(done chan struct{}) {
+	defer close(done)
+	for i := 0; ; i++ {
+		if i > 0 {
+			timer := time.NewTimer(w.opts.Backoff.delay(i - 1))
+			select {
+			case <-timer.C:
+			case <-w.stop:
+				timer.Stop()
+				return
+			}
+		}
+		conn, err := w.dial()
+		if err != nil {
+			continue
+		}
+		if w.attach(conn) {
+			return
+		}
+	}
+}
+
+func (w *NetWriter) dial() (net.Conn, error) {
+	dialer := &net.Dialer{Timeout: w.opts.DialTimeout}
+	if w.opts.TLSConfig != nil {
+		return tls.DialWithDialer(dialer, "tcp", w.addr, w.opts.TLSConfig)
+	}
+	return dialer.Dial("tcp", w.addr)
+}
+
+// attach sends the buffered records on conn and makes it the current
+// connection. It reports false if conn failed and another attempt is needed.
+func (w *NetWriter) attach(conn net.Conn) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		_ = conn.Close()
+		w.reconnecting = false
+		return true
+	}
+	if len(w.buf) > 0 {
+		_ = conn.SetWriteDeadline(time.Now().Add(w.opts.WriteTimeout))
+		n, err := conn.Write(w.buf)
+		if err != nil {
+			// Keep the record that was cut off, whole, for the next connection.
+			// Records end with a newline.
+			n = bytes.LastIndexByte(w.buf[:n], '\n') + 1
+		}
+		w.buf = w.buf[:copy(w.buf, w.buf[n:])]
+		if err != nil {
+			_ = conn.Close()
+			return false
+		}
+	}
+	w.conn = conn
+	w.reconnecting = false
+	return true
+}
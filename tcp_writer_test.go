@@ -0,0 +1,102 @@
+package log
+
+import (
+	"bufio"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestTCPWriterBuffersUntilConnected(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	_ = ln.Close()
+
+	w := TCPWriter(addr, &TCPOptions{
+		BufferSize: 26,
+		Backoff:    Backoff{Min: 5 * time.Millisecond, Max: 20 * time.Millisecond},
+	})
+	defer w.Close()
+	logger := New(w, Text())
+	logger.Info("one")
+	logger.Info("two")
+	logger.Info("dropped")
+	if got := w.Dropped(); got != 1 {
+		t.Fatalf("Dropped() = %d, want 1", got)
+	}
+
+	ln, err = net.Listen("tcp", addr)
+	if err != nil {
+		t.Skipf("relisten on %s: %v", addr, err)
+	}
+	defer ln.Close()
+	conn, err := ln.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	r := bufio.NewReader(conn)
+
+	logger.Info("three")
+	for _, want := range []string{"INFO msg=one\n", "INFO msg=two\n", "INFO msg=three\n"} {
+		got, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Fatalf("read %q, want %q", got, want)
+		}
+	}
+}
+
+// tornConn writes the first half of p and fails, like a connection that
+// breaks in the middle of a record.
+type tornConn struct{ net.Conn }
+
+func (c tornConn) Write(p []byte) (int, error) {
+	n, _ := c.Conn.Write(p[:len(p)/2])
+	return n, net.ErrClosed
+}
+
+func TestTCPWriterResendsWholeRecord(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	w := TCPWriter(ln.Addr().String(), &TCPOptions{
+		Backoff: Backoff{Min: 5 * time.Millisecond, Max: 20 * time.Millisecond},
+	})
+	defer w.Close()
+	first, err := ln.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer first.Close()
+	for {
+		w.mu.Lock()
+		if w.conn != nil {
+			w.conn = tornConn{w.conn}
+			w.mu.Unlock()
+			break
+		}
+		w.mu.Unlock()
+		time.Sleep(time.Millisecond)
+	}
+
+	New(w, Text()).Info("record")
+	second, err := ln.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer second.Close()
+	_ = second.SetReadDeadline(time.Now().Add(5 * time.Second))
+	got, err := bufio.NewReader(second).ReadString('\n')
+	if err != nil || got != "INFO msg=record\n" {
+		t.Fatalf("read %q, %v, want the whole record", got, err)
+	}
+}
@@ -0,0 +1,81 @@
+package log
+
+import (
+	"context"
+	"strings"
+)
+
+// logT is log for the T methods, whose message is the template with the
+// values of kvs interpolated.
+func (l *Logger) logT(level Level, template string, kvs []any) error {
+	if !l.level.Enable(level) {
+		return nil
+	}
+
+	if h := l.handler.Load(); h.Handler != nil {
+		l.stats.countLevel(level)
+		ctx := l.ctx
+		if h.capturePC {
+			// Skip the level method, such as InfoT.
+			ctx = contextWithPC(ctx, callerPC(2+callerDepth(ctx)))
+		}
+		msg := formatTemplate(ctx, template, kvs)
+		return l.Handle(ctx, l.output.Load().out, level, msg, kvs...)
+	}
+	return nil
+}
+
+// formatTemplate replaces each {key} in template with the value of the first
+// field of kvs with that key, formatted like fmt.Sprint. A Valuer is resolved
+// with ctx. Placeholders without a field are kept as is, and {{ and }} stand
+// for literal braces.
+func formatTemplate(ctx context.Context, template string, kvs []any) string {
+	if strings.IndexByte(template, '{') < 0 && strings.IndexByte(template, '}') < 0 {
+		return template
+	}
+	fields := kvsToFieldSlice(kvs)
+	buf := make([]byte, 0, len(template)+32)
+	for len(template) > 0 {
+		i := strings.IndexAny(template, "{}")
+		if i < 0 {
+			buf = append(buf, template...)
+			break
+		}
+		buf = append(buf, template[:i]...)
+		template = template[i:]
+		if len(template) > 1 && template[1] == template[0] {
+			buf = append(buf, template[0])
+			template = template[2:]
+			continue
+		}
+		end := strings.IndexByte(template, '}')
+		if template[0] == '}' || end < 0 {
+			buf = append(buf, template[0])
+			template = template[1:]
+			continue
+		}
+		key := template[1:end]
+		if v, ok := templateValue(fields, key); ok {
+			if v.Kind() == KindValuer {
+				if ctx == nil {
+					ctx = context.Background()
+				}
+				v = v.Resolve(ctx)
+			}
+			buf = v.append(buf)
+		} else {
+			buf = append(buf, template[:end+1]...)
+		}
+		template = template[end+1:]
+	}
+	return string(buf)
+}
+
+func templateValue(fields []Field, key string) (Value, bool) {
+	for _, f := range fields {
+		if f.Key == key {
+			return f.Value, true
+		}
+	}
+	return Value{}, false
+}
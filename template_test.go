@@ -0,0 +1,56 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestFormatTemplate(t *testing.T) {
+	tests := []struct {
+		template string
+		kvs      []any
+		want     string
+	}{
+		{"plain", nil, "plain"},
+		{"user {user_id} purchased {sku}", []any{"user_id", 42, "sku", "A-1"}, "user 42 purchased A-1"},
+		{"{a}{a}", []any{String("a", "x")}, "xx"},
+		{"missing {b} kept", []any{"a", 1}, "missing {b} kept"},
+		{"{{literal}} {a}}", []any{"a", true}, "{literal} true}"},
+		{"open { and } close", nil, "open { and } close"},
+		{"unterminated {a", []any{"a", 1}, "unterminated {a"},
+		{"took {d}", []any{"d", Duration("d", 1500000000).Value}, "took 1.5s"},
+		{"id {id}", []any{Dynamic("id", func(context.Context) Value { return IntValue(7) })}, "id 7"},
+	}
+	for _, tt := range tests {
+		if got := formatTemplate(nil, tt.template, tt.kvs); got != tt.want {
+			t.Errorf("formatTemplate(%q) = %q, want %q", tt.template, got, tt.want)
+		}
+	}
+}
+
+func TestLoggerInfoT(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New(&buf, Json()).WithFields(DefaultFields...)
+	logger.InfoT("user {user_id} purchased {sku}", "user_id", 42, "sku", "A-1")
+	var record map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatal(err)
+	}
+	if record["msg"] != "user 42 purchased A-1" || record["user_id"] != 42.0 || record["sku"] != "A-1" {
+		t.Fatalf("record = %v", record)
+	}
+	if caller, _ := record["caller"].(map[string]any); !strings.HasSuffix(caller["function"].(string), "TestLoggerInfoT") {
+		t.Fatalf("caller = %v", record["caller"])
+	}
+
+	buf.Reset()
+	logger.SetLevel(LevelWarn)
+	logger.InfoT("hidden {a}", "a", 1)
+	logger.ErrorT("failed {op}", "op", "save")
+	if got := buf.String(); !strings.Contains(got, `"msg":"failed save"`) || strings.Contains(got, "hidden") {
+		t.Fatalf("output = %q", got)
+	}
+}
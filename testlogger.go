@@ -0,0 +1,77 @@
+package log
+
+import (
+	"context"
+	"io"
+
+	"github.com/nexuer/log/internal/buffer"
+)
+
+// TestingT is the part of testing.TB used by NewTestLogger, so that this
+// package does not import testing.
+type TestingT interface {
+	Helper()
+	Log(args ...any)
+	Error(args ...any)
+}
+
+// TestLoggerOptions configures the Logger returned by NewTestLogger.
+type TestLoggerOptions struct {
+	// HandlerOptions configures the Text handler that formats the records.
+	// AddSource is always set.
+	HandlerOptions
+	// FailOnError reports records at LevelError and above with t.Error, so
+	// that logging an error fails the test.
+	FailOnError bool
+}
+
+// NewTestLogger returns a Logger that writes each record, at every level,
+// with t.Log, so the logs of a library appear interleaved with the output of
+// the test that produced them. The testing package attributes the lines to
+// this package, so each record has a source field with the file and line of
+// the log call.
+func NewTestLogger(t TestingT, opts ...*TestLoggerOptions) *Logger {
+	opt := new(TestLoggerOptions)
+	if len(opts) > 0 && opts[0] != nil {
+		opt = opts[0]
+	}
+	handlerOpts := opt.HandlerOptions
+	handlerOpts.AddSource = true
+	h := &testHandler{t: t, next: Text(&handlerOpts), failOnError: opt.FailOnError}
+	return New(nil, h).SetLevel(LevelDebug)
+}
+
+type testHandler struct {
+	t           TestingT
+	next        Handler
+	failOnError bool
+}
+
+func (h *testHandler) WithFields(ctx context.Context, fields ...Field) Handler {
+	return &testHandler{t: h.t, next: h.next.WithFields(ctx, fields...), failOnError: h.failOnError}
+}
+
+func (h *testHandler) WithGroup(name string) Handler {
+	return &testHandler{t: h.t, next: h.next.WithGroup(name), failOnError: h.failOnError}
+}
+
+func (h *testHandler) Handle(ctx context.Context, _ io.Writer, level Level, msg string, kvs ...any) error {
+	h.t.Helper()
+	buf := buffer.New()
+	defer buf.Free()
+	err := h.next.Handle(AddCallerDepth(ctx, 1), buf, level, msg, kvs...)
+	if n := buf.Len(); n > 0 && (*buf)[n-1] == '\n' {
+		buf.SetLen(n - 1)
+	}
+	if buf.Len() == 0 {
+		return err
+	}
+	if h.failOnError && level >= LevelError {
+		h.t.Error(buf.String())
+	} else {
+		h.t.Log(buf.String())
+	}
+	return err
+}
+
+func (h *testHandler) needsPC() bool { return true }
@@ -0,0 +1,42 @@
+package log
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+type fakeT struct {
+	logs, errors []string
+}
+
+func (t *fakeT) Helper()           {}
+func (t *fakeT) Log(args ...any)   { t.logs = append(t.logs, fmt.Sprint(args...)) }
+func (t *fakeT) Error(args ...any) { t.errors = append(t.errors, fmt.Sprint(args...)) }
+
+func TestNewTestLogger(t *testing.T) {
+	ft := &fakeT{}
+	logger := NewTestLogger(ft, &TestLoggerOptions{FailOnError: true}).With("svc", "api")
+
+	_, _, line, _ := runtime.Caller(0)
+	logger.DebugS("connecting", "attempt", 1)
+	logger.ErrorS("connect failed")
+
+	if len(ft.logs) != 1 || len(ft.errors) != 1 {
+		t.Fatalf("logs = %q, errors = %q, want one of each", ft.logs, ft.errors)
+	}
+	got := ft.logs[0]
+	if !strings.HasPrefix(got, "DEBUG source=") || !strings.HasSuffix(got, fmt.Sprintf("/testlogger_test.go:%d svc=api msg=connecting attempt=1", line+1)) {
+		t.Fatalf("log = %q", got)
+	}
+	if want := fmt.Sprintf("testlogger_test.go:%d", line+2); !strings.Contains(ft.errors[0], want) {
+		t.Fatalf("error = %q, want source %s", ft.errors[0], want)
+	}
+
+	ft = &fakeT{}
+	NewTestLogger(ft).Error("logged")
+	if len(ft.logs) != 1 || len(ft.errors) != 0 {
+		t.Fatalf("without FailOnError: logs = %q, errors = %q", ft.logs, ft.errors)
+	}
+}
@@ -2,6 +2,7 @@ package log
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"math"
 	"strconv"
@@ -29,6 +30,8 @@ func TestTextAnyMatchesFmtOutput(t *testing.T) {
 		{"nil strings", []string(nil)},
 		{"strings", []string{"", "plain", "with space", "quote\"", "line\nfeed", `back\\slash`}},
 		{"times", times},
+		{"durations", []time.Duration{time.Second, 1500 * time.Microsecond}},
+		{"errors", []error{errors.New("first"), nil, errors.New("second one")}},
 		{"named slice fallback", namedStrings{"a", "b"}},
 		{"struct fallback", struct {
 			ID   int
@@ -52,3 +55,30 @@ func TestTextAnyMatchesFmtOutput(t *testing.T) {
 		})
 	}
 }
+
+type countingStringer struct {
+	ID    int
+	calls *int
+}
+
+func (c countingStringer) String() string {
+	*c.calls++
+	return "stringer-" + strconv.Itoa(c.ID)
+}
+
+func TestTextAnyStringer(t *testing.T) {
+	var calls int
+	value := countingStringer{ID: 7, calls: &calls}
+
+	var buf bytes.Buffer
+	New(&buf, Text()).InfoS("done", "value", value)
+	if got, want := buf.String(), "INFO msg=done value=stringer-7\n"; got != want || calls != 1 {
+		t.Fatalf("output = %q, want %q with one String call, got %d", got, want, calls)
+	}
+
+	buf.Reset()
+	New(&buf, Text(&HandlerOptions{SkipStringer: true})).InfoS("done", "value", value)
+	if got, want := buf.String(), fmt.Sprintf("INFO msg=done value=\"{ID:7 calls:%p}\"\n", &calls); got != want || calls != 1 {
+		t.Fatalf("SkipStringer output = %q, want %q with no String call, got %d", got, want, calls-1)
+	}
+}
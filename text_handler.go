@@ -43,6 +43,10 @@ func (h *textHandler) Handle(ctx context.Context, w io.Writer, level Level, msg
 	return h.handler.handle(ctx, w, level, msg, kvs...)
 }
 
+func (h *textHandler) HandleFields(ctx context.Context, w io.Writer, level Level, msg string, fields []Field) error {
+	return h.handler.handleFields(ctx, w, level, msg, fields)
+}
+
 // byteSlice returns its argument as a []byte if the argument's
 // underlying type is []byte, along with a second return value of true.
 // Otherwise it returns nil, false.
@@ -80,16 +84,14 @@ func appendTextValue(s *handleState, v Value) error {
 		}
 	case KindTime:
 		s.appendTime(v.time())
+	case KindBytes:
+		s.appendBytes(v.bytes())
+	case KindError:
+		s.appendErr(v.err())
 	case KindAny:
-		if e, ok := v.any.(error); ok {
-			if e != nil {
-				s.appendString(e.Error())
-			} else {
-				s.appendString("<nil>")
-			}
+		if s.appendNetIP(v.any) || s.appendUUID(v.any) || s.appendBig(v.any) || appendTextComplex(s, v.any) {
 			return nil
 		}
-
 		if tm, ok := v.any.(encoding.TextMarshaler); ok {
 			data, err := tm.MarshalText()
 			if err != nil {
@@ -100,8 +102,7 @@ func appendTextValue(s *handleState, v Value) error {
 		}
 
 		if bs, ok := byteSlice(v.any); ok {
-			// As of Go 1.19, this only allocates for strings longer than 32 bytes.
-			_, _ = s.buf.WriteString(strconv.Quote(string(bs)))
+			s.appendBytes(bs)
 			return nil
 		}
 		if !appendTextSlice(s, v.any) {
@@ -113,16 +114,69 @@ func appendTextValue(s *handleState, v Value) error {
 	return nil
 }
 
+// appendTextComplex writes a complex64 or complex128 as "(re+imi)", like
+// fmt, and reports whether value is one of them.
+func appendTextComplex(s *handleState, value any) bool {
+	c, bitSize, ok := complexValue(value)
+	if !ok {
+		return false
+	}
+	_ = s.buf.WriteByte('(')
+	*s.buf = strconv.AppendFloat(*s.buf, real(c), 'g', -1, bitSize)
+	var im [32]byte
+	b := strconv.AppendFloat(im[:0], imag(c), 'g', -1, bitSize)
+	if b[0] != '+' && b[0] != '-' {
+		_ = s.buf.WriteByte('+')
+	}
+	_, _ = s.buf.Write(b)
+	_, _ = s.buf.WriteString("i)")
+	return true
+}
+
+// complexValue returns value as a complex128 with the bit size of its parts
+// if it is a complex64 or complex128.
+func complexValue(value any) (complex128, int, bool) {
+	switch v := value.(type) {
+	case complex64:
+		return complex128(v), 32, true
+	case complex128:
+		return v, 64, true
+	}
+	return 0, 0, false
+}
+
 func appendTextAny(s *handleState, value any) {
+	if masked, ok := maskStruct(value); ok {
+		value = masked
+	} else if s.h.opts.SkipStringer {
+		value = hideMethods(value)
+	} else if str, ok := value.(fmt.Stringer); ok {
+		// %+v would call String too, unless the value is a fmt.Formatter.
+		if _, ok := value.(fmt.Formatter); !ok {
+			s.appendString(str.String())
+			return
+		}
+	}
 	formatted := buffer.New()
 	defer formatted.Free()
 	*formatted = fmt.Appendf(*formatted, "%+v", value)
 	s.appendString(bytesToString(*formatted))
 }
 
+// methodsHidden holds a value in an unexported field, so that the
+// reflect.Value of the field cannot be turned back into an interface.
+type methodsHidden struct{ v any }
+
+// hideMethods returns a as a reflect.Value that fmt prints from its fields,
+// without calling String, Error or Format methods of a or of the values it
+// contains.
+func hideMethods(a any) reflect.Value {
+	return reflect.ValueOf(methodsHidden{a}).Field(0).Elem()
+}
+
 func appendTextSlice(s *handleState, value any) bool {
 	switch value.(type) {
-	case []int, []int64, []uint64, []float64, []bool, []string, []time.Time:
+	case []int, []int64, []uint64, []float64, []bool, []string, []time.Time, []time.Duration, []error:
 	default:
 		return false
 	}
@@ -181,6 +235,20 @@ func appendTextSlice(s *handleState, value any) bool {
 			}
 			*formatted = append(*formatted, value.String()...)
 		}
+	case []time.Duration:
+		for i, value := range values {
+			if i > 0 {
+				*formatted = append(*formatted, ' ')
+			}
+			*formatted = append(*formatted, value.String()...)
+		}
+	case []error:
+		for i, value := range values {
+			if i > 0 {
+				*formatted = append(*formatted, ' ')
+			}
+			*formatted = appendTextErr(*formatted, value)
+		}
 	}
 
 	*formatted = append(*formatted, ']')
@@ -188,6 +256,26 @@ func appendTextSlice(s *handleState, value any) bool {
 	return true
 }
 
+// appendTextErr appends the message of err, with the errors joined into it
+// bracketed like a slice, as the JSON handler nests them.
+func appendTextErr(buf []byte, err error) []byte {
+	if err == nil {
+		return append(buf, "<nil>"...)
+	}
+	errs := joinedErrors(err)
+	if errs == nil {
+		return append(buf, err.Error()...)
+	}
+	buf = append(buf, '[')
+	for i, e := range errs {
+		if i > 0 {
+			buf = append(buf, ' ')
+		}
+		buf = appendTextErr(buf, e)
+	}
+	return append(buf, ']')
+}
+
 func appendTextSource(s *handleState, source *Source) {
 	_, _ = s.buf.WriteString(source.File)
 	_, _ = s.buf.WriteString(":")
@@ -0,0 +1,199 @@
+package log
+
+import (
+	"context"
+	"errors"
+)
+
+// Keys for trace correlation fields.
+const (
+	TraceIDKey    = "trace_id"
+	SpanIDKey     = "span_id"
+	TraceFlagsKey = "trace_flags"
+)
+
+// SpanContext identifies the active span of a distributed trace. Its layout
+// matches the W3C Trace Context and OpenTelemetry span contexts.
+type SpanContext struct {
+	TraceID    [16]byte
+	SpanID     [8]byte
+	TraceFlags byte
+}
+
+// IsValid reports whether both the trace and span IDs are non-zero.
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID != [16]byte{} && sc.SpanID != [8]byte{}
+}
+
+// Sampled reports whether the sampled trace flag is set.
+func (sc SpanContext) Sampled() bool {
+	return sc.TraceFlags&0x01 != 0
+}
+
+// Traceparent returns sc as a version 00 W3C traceparent header value.
+func (sc SpanContext) Traceparent() string {
+	b := make([]byte, 0, 55)
+	b = append(b, "00-"...)
+	b = appendHex(b, sc.TraceID[:])
+	b = append(b, '-')
+	b = appendHex(b, sc.SpanID[:])
+	b = append(b, '-')
+	b = appendHex(b, []byte{sc.TraceFlags})
+	return string(b)
+}
+
+var errInvalidTraceparent = errors.New("log: invalid traceparent")
+
+// ParseTraceparent parses a W3C traceparent header value. Versions newer than
+// 00 are accepted as long as they start with the version 00 fields.
+func ParseTraceparent(s string) (SpanContext, error) {
+	var sc SpanContext
+	if len(s) < 55 || s[2] != '-' || s[35] != '-' || s[52] != '-' || len(s) > 55 && s[55] != '-' {
+		return sc, errInvalidTraceparent
+	}
+	var version [1]byte
+	if !decodeLowerHex(version[:], s[:2]) || version[0] == 0xff || version[0] == 0 && len(s) != 55 {
+		return sc, errInvalidTraceparent
+	}
+	var flags [1]byte
+	if !decodeLowerHex(sc.TraceID[:], s[3:35]) || !decodeLowerHex(sc.SpanID[:], s[36:52]) || !decodeLowerHex(flags[:], s[53:55]) {
+		return SpanContext{}, errInvalidTraceparent
+	}
+	sc.TraceFlags = flags[0]
+	if !sc.IsValid() {
+		return SpanContext{}, errInvalidTraceparent
+	}
+	return sc, nil
+}
+
+// appendHex appends the lowercase hex encoding of src to dst.
+func appendHex(dst, src []byte) []byte {
+	for _, b := range src {
+		dst = append(dst, hex[b>>4], hex[b&0xF])
+	}
+	return dst
+}
+
+func hexString(src []byte) string {
+	return string(appendHex(make([]byte, 0, len(src)*2), src))
+}
+
+// decodeLowerHex decodes s into dst, which must be len(s)/2 bytes long. Trace
+// Context only allows lowercase hex digits.
+func decodeLowerHex(dst []byte, s string) bool {
+	if len(s) != len(dst)*2 {
+		return false
+	}
+	for i := range dst {
+		hi, ok1 := lowerHexDigit(s[2*i])
+		lo, ok2 := lowerHexDigit(s[2*i+1])
+		if !ok1 || !ok2 {
+			return false
+		}
+		dst[i] = hi<<4 | lo
+	}
+	return true
+}
+
+func lowerHexDigit(c byte) (byte, bool) {
+	switch {
+	case c >= '0' && c <= '9':
+		return c - '0', true
+	case c >= 'a' && c <= 'f':
+		return c - 'a' + 10, true
+	}
+	return 0, false
+}
+
+type spanContextKey struct{}
+
+// ContextWithSpanContext returns a copy of ctx that carries sc, for use by
+// TraceContext. Middleware can store an incoming traceparent with:
+//
+//	if sc, err := log.ParseTraceparent(r.Header.Get("traceparent")); err == nil {
+//		ctx = log.ContextWithSpanContext(ctx, sc)
+//	}
+func ContextWithSpanContext(ctx context.Context, sc SpanContext) context.Context {
+	return context.WithValue(ctx, spanContextKey{}, sc)
+}
+
+// SpanContextFromContext returns the SpanContext stored by
+// ContextWithSpanContext.
+func SpanContextFromContext(ctx context.Context) (SpanContext, bool) {
+	if ctx == nil {
+		return SpanContext{}, false
+	}
+	sc, ok := ctx.Value(spanContextKey{}).(SpanContext)
+	return sc, ok && sc.IsValid()
+}
+
+// SpanContextFunc extracts the active span from ctx.
+//
+// OpenTelemetry spans can be correlated with an adapter such as:
+//
+//	func otelSpan(ctx context.Context) (log.SpanContext, bool) {
+//		sc := trace.SpanContextFromContext(ctx)
+//		return log.SpanContext{
+//			TraceID:    sc.TraceID(),
+//			SpanID:     sc.SpanID(),
+//			TraceFlags: byte(sc.TraceFlags()),
+//		}, sc.IsValid()
+//	}
+type SpanContextFunc func(ctx context.Context) (SpanContext, bool)
+
+// TraceContext returns a Valuer that resolves to an inline group of trace_id,
+// span_id and trace_flags fields for the active span of the record context.
+// Extractors are tried in order, followed by SpanContextFromContext. Without a
+// valid span the group is empty and nothing is emitted.
+//
+// Add it with an empty key so the fields are not nested:
+//
+//	logger = logger.WithFields(log.Dynamic("", log.TraceContext(otelSpan)))
+//	logger.WithContext(ctx).InfoS("handled")
+func TraceContext(extractors ...SpanContextFunc) Valuer {
+	return func(ctx context.Context) Value {
+		sc, ok := spanContext(ctx, extractors)
+		if !ok {
+			return GroupValue()
+		}
+		return GroupValue(
+			String(TraceIDKey, hexString(sc.TraceID[:])),
+			String(SpanIDKey, hexString(sc.SpanID[:])),
+			String(TraceFlagsKey, hexString([]byte{sc.TraceFlags})),
+		)
+	}
+}
+
+// TraceID returns a Valuer that resolves to the hex trace ID of the active span,
+// or to nil without one.
+func TraceID(extractors ...SpanContextFunc) Valuer {
+	return func(ctx context.Context) Value {
+		if sc, ok := spanContext(ctx, extractors); ok {
+			return StringValue(hexString(sc.TraceID[:]))
+		}
+		return AnyValue(nil)
+	}
+}
+
+// SpanID returns a Valuer that resolves to the hex span ID of the active span,
+// or to nil without one.
+func SpanID(extractors ...SpanContextFunc) Valuer {
+	return func(ctx context.Context) Value {
+		if sc, ok := spanContext(ctx, extractors); ok {
+			return StringValue(hexString(sc.SpanID[:]))
+		}
+		return AnyValue(nil)
+	}
+}
+
+func spanContext(ctx context.Context, extractors []SpanContextFunc) (SpanContext, bool) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	for _, extract := range extractors {
+		if sc, ok := extract(ctx); ok && sc.IsValid() {
+			return sc, true
+		}
+	}
+	return SpanContextFromContext(ctx)
+}
@@ -0,0 +1,104 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"testing"
+)
+
+const testTraceparent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+func TestParseTraceparent(t *testing.T) {
+	sc, err := ParseTraceparent(testTraceparent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !sc.IsValid() || !sc.Sampled() {
+		t.Fatalf("span context = %+v, want valid and sampled", sc)
+	}
+	if got := sc.Traceparent(); got != testTraceparent {
+		t.Fatalf("Traceparent() = %q, want %q", got, testTraceparent)
+	}
+	if _, err := ParseTraceparent("01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-future"); err != nil {
+		t.Fatalf("future version: %v", err)
+	}
+
+	for _, invalid := range []string{
+		"",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+		"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e473g-00f067aa0ba902b7-01",
+	} {
+		if _, err := ParseTraceparent(invalid); err == nil {
+			t.Fatalf("ParseTraceparent(%q) succeeded, want error", invalid)
+		}
+	}
+}
+
+func TestTraceContextValuer(t *testing.T) {
+	sc, err := ParseTraceparent(testTraceparent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := ContextWithSpanContext(context.Background(), sc)
+
+	for _, test := range []struct {
+		name    string
+		handler func() Handler
+		want    string
+		without string
+	}{
+		{
+			name:    "JSON",
+			handler: func() Handler { return Json() },
+			want:    `{"level":"INFO","trace_id":"4bf92f3577b34da6a3ce929d0e0e4736","span_id":"00f067aa0ba902b7","trace_flags":"01","svc":"api","msg":"done"}` + "\n",
+			without: `{"level":"INFO","svc":"api","msg":"done"}` + "\n",
+		},
+		{
+			name:    "Text",
+			handler: func() Handler { return Text() },
+			want:    "INFO trace_id=4bf92f3577b34da6a3ce929d0e0e4736 span_id=00f067aa0ba902b7 trace_flags=01 svc=api msg=done\n",
+			without: "INFO svc=api msg=done\n",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			logger := New(&buf, test.handler()).WithFields(Dynamic("", TraceContext()), String("svc", "api"))
+			logger.WithContext(ctx).InfoS("done")
+			if got := buf.String(); got != test.want {
+				t.Fatalf("output = %q, want %q", got, test.want)
+			}
+
+			buf.Reset()
+			logger.InfoS("done")
+			if got := buf.String(); got != test.without {
+				t.Fatalf("output without span = %q, want %q", got, test.without)
+			}
+		})
+	}
+}
+
+func TestTraceContextExtractor(t *testing.T) {
+	extract := func(ctx context.Context) (SpanContext, bool) {
+		return SpanContext{TraceID: [16]byte{1}, SpanID: [8]byte{2}}, true
+	}
+	var buf bytes.Buffer
+	New(&buf, Json()).InfoS("done", "trace", TraceContext(extract), "span", SpanID(extract))
+	want := `{"level":"INFO","msg":"done","trace":{"trace_id":"01000000000000000000000000000000","span_id":"0200000000000000","trace_flags":"00"},"span":"0200000000000000"}` + "\n"
+	if got := buf.String(); got != want {
+		t.Fatalf("output = %q, want %q", got, want)
+	}
+}
+
+func TestValuerGroupInsideWithGroup(t *testing.T) {
+	empty := Valuer(func(context.Context) Value { return GroupValue() })
+	var buf bytes.Buffer
+	New(&buf, Json()).WithGroup("g").With("empty", empty, "a", 1).InfoS("done")
+	want := `{"level":"INFO","g":{"a":1},"msg":"done"}` + "\n"
+	if got := buf.String(); got != want {
+		t.Fatalf("output = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,65 @@
+package log
+
+import (
+	"io"
+	"net"
+	"unicode/utf8"
+)
+
+// UDPOptions configures the writer returned by UDPWriter.
+type UDPOptions struct {
+	// MaxDatagramSize truncates records longer than this many bytes, keeping
+	// the trailing newline, so that they are not fragmented or rejected by the
+	// network. Zero sends records whole.
+	MaxDatagramSize int
+}
+
+type udpWriter struct {
+	conn net.Conn
+	max  int
+}
+
+// UDPWriter returns a writer that sends each record as one UDP datagram to
+// addr. Sending is fire-and-forget: records lost by the network or refused by
+// the collector are not reported.
+func UDPWriter(addr string, opts ...*UDPOptions) (io.WriteCloser, error) {
+	opt := new(UDPOptions)
+	if len(opts) > 0 && opts[0] != nil {
+		*opt = *opts[0]
+	}
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+	return &udpWriter{conn: conn, max: opt.MaxDatagramSize}, nil
+}
+
+func (w *udpWriter) Write(p []byte) (int, error) {
+	_, _ = w.conn.Write(truncateDatagram(p, w.max))
+	return len(p), nil
+}
+
+func (w *udpWriter) Close() error {
+	return w.conn.Close()
+}
+
+// truncateDatagram shortens p to max bytes without splitting a UTF-8 sequence.
+func truncateDatagram(p []byte, max int) []byte {
+	if max <= 0 || len(p) <= max {
+		return p
+	}
+	newline := p[len(p)-1] == '\n'
+	if newline {
+		max--
+	}
+	n := max
+	for i := 0; i < utf8.UTFMax && n > 0 && !utf8.RuneStart(p[n]); i++ {
+		n--
+	}
+	out := make([]byte, n, n+1)
+	copy(out, p)
+	if newline {
+		out = append(out, '\n')
+	}
+	return out
+}
@@ -0,0 +1,36 @@
+package log
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+func TestUDPWriterTruncatesDatagrams(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pc.Close()
+
+	w, err := UDPWriter(pc.LocalAddr().String(), &UDPOptions{MaxDatagramSize: 16})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	logger := New(w, Text())
+	logger.Info("short")
+	logger.Info("ab日本語")
+
+	_ = pc.SetReadDeadline(time.Now().Add(5 * time.Second))
+	buf := make([]byte, 64)
+	for _, want := range []string{"INFO msg=short\n", "INFO msg=ab日\n"} {
+		n, _, err := pc.ReadFrom(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := string(buf[:n]); got != want {
+			t.Fatalf("datagram = %q, want %q", got, want)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package log
+
+import "reflect"
+
+// uuid is a UUID logged with the UUID field. It implements the interfaces
+// fmt and other encoders use, so it renders canonically everywhere.
+type uuid [16]byte
+
+func (u uuid) String() string {
+	return string(appendUUID(make([]byte, 0, 36), u))
+}
+
+func (u uuid) MarshalText() ([]byte, error) {
+	return appendUUID(make([]byte, 0, 36), u), nil
+}
+
+// UUID returns a Field for a UUID, rendered in the canonical
+// xxxxxxxx-xxxx-xxxx-xxxx-xxxxxxxxxxxx form.
+func UUID(key string, v [16]byte) Field {
+	return Field{key, AnyValue(uuid(v))}
+}
+
+// appendUUID appends the canonical form of u to dst.
+func appendUUID(dst []byte, u [16]byte) []byte {
+	dst = appendHex(dst, u[:4])
+	dst = append(dst, '-')
+	dst = appendHex(dst, u[4:6])
+	dst = append(dst, '-')
+	dst = appendHex(dst, u[6:8])
+	dst = append(dst, '-')
+	dst = appendHex(dst, u[8:10])
+	dst = append(dst, '-')
+	return appendHex(dst, u[10:])
+}
+
+// uuidBytes returns the bytes of a if it is a value of the UUID field or of
+// a type named UUID whose underlying type is [16]byte, such as the UUID type
+// of github.com/google/uuid. Those are rendered without their String or
+// MarshalText methods, which allocate.
+func uuidBytes(a any) ([16]byte, bool) {
+	if u, ok := a.(uuid); ok {
+		return u, true
+	}
+	t := reflect.TypeOf(a)
+	if t == nil || t.Kind() != reflect.Array || t.Len() != 16 || t.Elem().Kind() != reflect.Uint8 || t.Name() != "UUID" {
+		return [16]byte{}, false
+	}
+	var u [16]byte
+	reflect.Copy(reflect.ValueOf(u[:]), reflect.ValueOf(a))
+	return u, true
+}
+
+// appendUUID writes a as a UUID string and reports whether it is one.
+func (s *handleState) appendUUID(a any) bool {
+	u, ok := uuidBytes(a)
+	if !ok {
+		return false
+	}
+	var buf [36]byte
+	s.appendString(bytesToString(appendUUID(buf[:0], u)))
+	return true
+}
@@ -40,6 +40,7 @@ type Value struct {
 
 type (
 	stringptr     *byte  // used in Value.any when the Value is a string
+	bytesptr      *byte  // used in Value.any when the Value is a []byte
 	groupptr      *Field // used in Value.any when the Value is a []Attr
 	timestampSpec struct {
 		layout   string
@@ -69,6 +70,9 @@ const (
 	// KindValuer Use KindValuer instead of slog.kindLogValuer
 	KindValuer
 	KindSource
+	KindBytes
+	KindError
+	KindFloat32
 )
 
 var kindStrings = []string{
@@ -83,6 +87,9 @@ var kindStrings = []string{
 	"Group",
 	"Valuer",
 	"Source",
+	"Bytes",
+	"Error",
+	"Float32",
 }
 
 func (k Kind) String() string {
@@ -104,6 +111,18 @@ func StringValue(value string) Value {
 	return Value{kind: KindString, num: uint64(len(value)), any: stringptr(unsafe.StringData(value))}
 }
 
+// BytesValue returns a new [Value] for a []byte. The caller must not
+// subsequently mutate the argument slice.
+func BytesValue(value []byte) Value {
+	return Value{kind: KindBytes, num: uint64(len(value)), any: bytesptr(unsafe.SliceData(value))}
+}
+
+// ErrValue returns a new [Value] for an error. Handlers render it as set by
+// HandlerOptions.ErrorFormat.
+func ErrValue(err error) Value {
+	return Value{kind: KindError, any: err}
+}
+
 func timestampStringValue(t time.Time, spec *timestampSpec) Value {
 	return Value{kind: KindString, num: uint64(t.UnixNano()), any: spec}
 }
@@ -133,6 +152,13 @@ func Float64Value(v float64) Value {
 	return Value{kind: KindFloat64, num: math.Float64bits(v)}
 }
 
+// Float32Value returns a [Value] for a 32-bit floating-point number. Unlike
+// Float64Value(float64(v)), it encodes with the shortest digits that
+// round-trip a float32.
+func Float32Value(v float32) Value {
+	return Value{kind: KindFloat32, num: uint64(math.Float32bits(v))}
+}
+
 // BoolValue returns a [Value] for a bool.
 func BoolValue(v bool) Value {
 	u := uint64(0)
@@ -229,18 +255,22 @@ func countEmptyGroups(as []Field) int {
 //
 // Given a value of one of Go's predeclared string, bool, or
 // (non-complex) numeric types, AnyValue returns a Value of kind
-// [KindString], [KindBool], [KindUint64], [KindInt64], or [KindFloat64].
-// The width of the original numeric type is not preserved.
+// [KindString], [KindBool], [KindUint64], [KindInt64], [KindFloat64] or
+// [KindFloat32]. The width of the original integer type is not preserved.
 //
 // Given a [time.Time] or [time.Duration] value, AnyValue returns a Value of kind
 // [KindTime] or [KindDuration]. The monotonic time is not preserved.
 //
 // For nil, or values of all other types, including named types whose
 // underlying type is numeric, AnyValue returns a value of kind [KindAny].
+// Handlers write complex64 and complex128 values as "(re+imi)" in text and
+// as an object with "real" and "imag" members in JSON.
 func AnyValue(v any) Value {
 	switch v := v.(type) {
 	case string:
 		return StringValue(v)
+	case []byte:
+		return BytesValue(v)
 	case *Source:
 		return SourceValue(v)
 	case int:
@@ -274,13 +304,15 @@ func AnyValue(v any) Value {
 	case float64:
 		return Float64Value(v)
 	case float32:
-		return Float64Value(float64(v))
+		return Float32Value(v)
 	case []Field:
 		return GroupValue(v...)
 	case Value:
 		return v
 	case Valuer:
 		return ValuerValue(v)
+	case error:
+		return ErrValue(v)
 	default:
 		return Value{kind: KindAny, any: v}
 	}
@@ -291,7 +323,7 @@ func AnyValue(v any) Value {
 // Any returns v's value as an any.
 func (v Value) Any() any {
 	switch v.Kind() {
-	case KindAny:
+	case KindAny, KindError:
 		return v.any
 	case KindValuer:
 		return v.any
@@ -303,6 +335,8 @@ func (v Value) Any() any {
 		return v.num
 	case KindFloat64:
 		return v.float()
+	case KindFloat32:
+		return v.float32()
 	case KindString:
 		return v.str()
 	case KindBool:
@@ -313,6 +347,8 @@ func (v Value) Any() any {
 		return v.time()
 	case KindSource:
 		return v.Source()
+	case KindBytes:
+		return v.bytes()
 	default:
 		panic(fmt.Sprintf("bad kind: %s", v.Kind()))
 	}
@@ -336,6 +372,31 @@ func (v Value) str() string {
 	return unsafe.String(v.any.(stringptr), v.num)
 }
 
+// Err returns v's value as an error. It panics if v is not an error.
+func (v Value) Err() error {
+	if v.Kind() != KindError {
+		panic(fmt.Sprintf("Value kind is %s, not %s", v.Kind(), KindError))
+	}
+	return v.err()
+}
+
+func (v Value) err() error {
+	err, _ := v.any.(error)
+	return err
+}
+
+// Bytes returns v's value as a []byte. It panics if v is not a []byte.
+func (v Value) Bytes() []byte {
+	if v.Kind() != KindBytes {
+		panic(fmt.Sprintf("Value kind is %s, not %s", v.Kind(), KindBytes))
+	}
+	return v.bytes()
+}
+
+func (v Value) bytes() []byte {
+	return unsafe.Slice((*byte)(v.any.(bytesptr)), v.num)
+}
+
 func (v Value) timestamp() (time.Time, string, bool) {
 	spec, ok := v.any.(*timestampSpec)
 	if !ok {
@@ -402,6 +463,20 @@ func (v Value) float() float64 {
 	return math.Float64frombits(v.num)
 }
 
+// Float32 returns v's value as a float32. It panics
+// if v is not a float32.
+func (v Value) Float32() float32 {
+	if v.Kind() != KindFloat32 {
+		panic(fmt.Sprintf("Value kind is %s, not %s", v.Kind(), KindFloat32))
+	}
+
+	return v.float32()
+}
+
+func (v Value) float32() float32 {
+	return math.Float32frombits(uint32(v.num))
+}
+
 // Time returns v's value as a [time.Time]. It panics
 // if v is not a time.Time.
 func (v Value) Time() time.Time {
@@ -492,8 +567,9 @@ func (v Value) callerSource() (Source, bool) {
 
 //////////////// Other
 
-// Equal reports whether v and w represent the same Go value. KindAny values
-// use Go equality when comparable and deep equality otherwise.
+// Equal reports whether v and w represent the same Go value. KindAny and
+// KindError values use Go equality when comparable and deep equality
+// otherwise.
 func (v Value) Equal(w Value) bool {
 	k1 := v.Kind()
 	k2 := w.Kind()
@@ -507,9 +583,11 @@ func (v Value) Equal(w Value) bool {
 		return v.str() == w.str()
 	case KindFloat64:
 		return v.float() == w.float()
+	case KindFloat32:
+		return v.float32() == w.float32()
 	case KindTime:
 		return v.time().Equal(w.time())
-	case KindAny:
+	case KindAny, KindError:
 		vType, wType := reflect.TypeOf(v.any), reflect.TypeOf(w.any)
 		if vType != wType {
 			return false
@@ -532,6 +610,8 @@ func (v Value) Equal(w Value) bool {
 			return vSource == nil && wSource == nil
 		}
 		return *vSource == *wSource
+	case KindBytes:
+		return slices.Equal(v.bytes(), w.bytes())
 	default:
 		panic(fmt.Sprintf("bad kind: %s", k1))
 	}
@@ -564,6 +644,8 @@ func (v Value) append(dst []byte) []byte {
 		return strconv.AppendUint(dst, v.num, 10)
 	case KindFloat64:
 		return strconv.AppendFloat(dst, v.float(), 'g', -1, 64)
+	case KindFloat32:
+		return strconv.AppendFloat(dst, float64(v.float32()), 'g', -1, 32)
 	case KindBool:
 		return strconv.AppendBool(dst, v.bool())
 	case KindDuration:
@@ -578,7 +660,9 @@ func (v Value) append(dst []byte) []byte {
 			return append(dst, "<nil>"...)
 		}
 		return append(dst, source.String()...)
-	case KindAny, KindValuer:
+	case KindBytes:
+		return fmt.Append(dst, v.bytes())
+	case KindAny, KindValuer, KindError:
 		return fmt.Append(dst, v.any)
 	default:
 		panic(fmt.Sprintf("bad kind: %s", v.Kind()))
@@ -651,17 +735,42 @@ func ValuerValue(valuer Valuer) Value {
 	return Value{kind: KindValuer, any: valuer}
 }
 
+// ResolveValuer calls valuer with ctx. Within the record of a Logger whose
+// fields have Valuers, the result is reused for later calls with the same
+// valuer.
 func ResolveValuer(ctx context.Context, valuer Valuer) Value {
-	return valuer(ctx)
+	c := valuerCacheFor(ctx, valuer)
+	if c == nil {
+		return valuer(ctx)
+	}
+	if v, ok := c.load(valuer); ok {
+		return v
+	}
+	// Called here rather than in a helper, so Valuers that walk the stack see
+	// the same frames as without the cache.
+	v := valuer(ctx)
+	c.store(valuer, v)
+	return v
 }
 
-func Timestamp(layout string) Valuer {
+// Timestamp returns a Valuer that resolves to the current time formatted
+// with layout in loc, or in the local time zone if loc is omitted or nil.
+func Timestamp(layout string, loc ...*time.Location) Valuer {
 	spec := &timestampSpec{layout: layout, location: time.Local}
+	if len(loc) > 0 && loc[0] != nil {
+		spec.location = loc[0]
+	}
 	return func(ctx context.Context) Value {
 		return timestampStringValue(time.Now(), spec)
 	}
 }
 
+// TimestampUTC returns a Valuer that resolves to the current time formatted
+// with layout in UTC.
+func TimestampUTC(layout string) Valuer {
+	return Timestamp(layout, time.UTC)
+}
+
 var callerDepthKey = struct{}{}
 
 var (
@@ -748,34 +857,40 @@ func (s *Source) String() string {
 // Depth is the base number of stack frames skipped from where the Valuer is
 // resolved, not a number relative to the application call to Logger. The
 // required base therefore depends on the logging path. Most Logger users should
-// use DefaultFields, whose caller depth is calibrated for Logger's APIs.
-// Logging wrappers should preserve that base and use AddCallerDepth to add one
-// for each wrapper frame they introduce.
+// use CallSite or DefaultFields instead, which do not depend on it. Logging
+// wrappers should preserve that base and use AddCallerDepth to add one for each
+// wrapper frame they introduce.
 //
 // If full is true, Source.File contains the full filename. Otherwise it is
 // shortened to its final two path components.
 func Caller(depth int, full ...bool) Valuer {
-	fullFilename := false
-	if len(full) > 0 && full[0] {
-		fullFilename = true
-	}
-	spec := &callerSpec{fullFilename: fullFilename}
+	spec := newCallerSpec(full)
 	return func(ctx context.Context) Value {
-		skip := depth + callerDepth(ctx)
-		if skip < 0 {
-			skip = 0
-		}
+		return stackCaller(ctx, depth, spec)
+	}
+}
 
-		var pcs [1]uintptr
-		if runtime.Callers(skip+1, pcs[:]) == 0 {
-			return SourceValue(nil)
-		}
-		// runtime.Callers returns a return PC. Move it into the call instruction
-		// so FuncForPC and FileLine identify the same frame as runtime.Caller.
-		pc := pcs[0]
-		if pc > 0 {
-			pc--
-		}
-		return Value{kind: KindSource, num: uint64(pc), any: spec}
+func newCallerSpec(full []bool) *callerSpec {
+	return &callerSpec{fullFilename: len(full) > 0 && full[0]}
+}
+
+// stackCaller returns the Source depth frames above the Valuer calling it.
+func stackCaller(ctx context.Context, depth int, spec *callerSpec) Value {
+	skip := depth + callerDepth(ctx)
+	if skip < 0 {
+		skip = 0
+	}
+
+	var pcs [1]uintptr
+	// Skip runtime.Callers and stackCaller, so depth counts from the Valuer.
+	if runtime.Callers(skip+2, pcs[:]) == 0 {
+		return SourceValue(nil)
+	}
+	// runtime.Callers returns a return PC. Move it into the call instruction
+	// so FuncForPC and FileLine identify the same frame as runtime.Caller.
+	pc := pcs[0]
+	if pc > 0 {
+		pc--
 	}
+	return Value{kind: KindSource, num: uint64(pc), any: spec}
 }
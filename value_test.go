@@ -161,6 +161,7 @@ func TestValueString(t *testing.T) {
 		{Int64Value(-3), "-3"},
 		{Uint64Value(1), "1"},
 		{Float64Value(.15), "0.15"},
+		{Float32Value(.1), "0.1"},
 		{BoolValue(true), "true"},
 		{StringValue("foo"), "foo"},
 		{TimeValue(testTime), "2000-01-02 03:04:05 +0000 UTC"},
@@ -184,7 +185,7 @@ func TestAnyValue(t *testing.T) {
 	}{
 		{1, IntValue(1)},
 		{1.5, Float64Value(1.5)},
-		{float32(2.5), Float64Value(2.5)},
+		{float32(2.5), Float32Value(2.5)},
 		{"s", StringValue("s")},
 		{true, BoolValue(true)},
 		{testTime, TimeValue(testTime)},
@@ -210,6 +211,19 @@ func TestAnyValue(t *testing.T) {
 	}
 }
 
+func TestHexAndBase64(t *testing.T) {
+	data := []byte{0xde, 0xad, 0xbe, 0xef, 0x01}
+	if got, want := Hex("digest", data), String("digest", "deadbeef01"); !got.Equal(want) {
+		t.Errorf("Hex = %v, want %v", got, want)
+	}
+	if got, want := Base64("token", data), String("token", "3q2+7wE="); !got.Equal(want) {
+		t.Errorf("Base64 = %v, want %v", got, want)
+	}
+	if got := testing.AllocsPerRun(10, func() { _ = Hex("digest", data) }); got > 1 {
+		t.Errorf("Hex allocs = %v, want at most 1", got)
+	}
+}
+
 func TestValueAny(t *testing.T) {
 	for _, want := range []any{
 		nil,
@@ -301,6 +315,34 @@ func TestValuer(t *testing.T) {
 	}
 }
 
+func TestTimestampLocation(t *testing.T) {
+	const layout = "15:04 MST"
+	east := time.FixedZone("EST", -5*60*60)
+	for _, test := range []struct {
+		name   string
+		valuer Valuer
+		loc    *time.Location
+	}{
+		{"UTC", TimestampUTC(layout), time.UTC},
+		{"Location", Timestamp(layout, east), east},
+		{"NilLocation", Timestamp(layout, nil), time.Local},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			v := test.valuer(context.Background())
+			ts, gotLayout, ok := v.timestamp()
+			if !ok || gotLayout != layout {
+				t.Fatalf("timestamp() = %v, %q, %v", ts, gotLayout, ok)
+			}
+			if ts.Location() != test.loc {
+				t.Fatalf("location = %v, want %v", ts.Location(), test.loc)
+			}
+			if got, want := v.String(), ts.Format(layout); got != want {
+				t.Fatalf("String() = %q, want %q", got, want)
+			}
+		})
+	}
+}
+
 func TestNilValuerResolve(t *testing.T) {
 	var valuer Valuer
 	resolved := ValuerValue(valuer).Resolve(context.Background())
@@ -0,0 +1,95 @@
+package log
+
+import (
+	"context"
+	"reflect"
+	"unsafe"
+)
+
+// valuerCache is the context of a record logged by a Logger whose fields
+// have Valuers. It keeps the result of each Valuer for the record, so a Valuer
+// in several positions, or resolved by several handlers such as a
+// SentryHandler and the handler it wraps, is called once and has the same
+// value everywhere. A record is handled by one goroutine, so it is not
+// locked.
+type valuerCache struct {
+	context.Context
+	entries []valuerEntry
+	buf     [4]valuerEntry
+}
+
+type valuerEntry struct {
+	fn unsafe.Pointer
+	v  Value
+}
+
+type valuerCacheKey struct{}
+
+func (c *valuerCache) Value(key any) any {
+	if key == (valuerCacheKey{}) {
+		return c
+	}
+	return c.Context.Value(key)
+}
+
+func withValuerCache(ctx context.Context) context.Context {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	c := &valuerCache{Context: ctx}
+	c.entries = c.buf[:0]
+	return c
+}
+
+// valuerCacheFor returns the cache of the record context ctx that valuer may
+// use, or nil. Caller and CallSite depend on the frame they are resolved
+// from, so their results are not shared.
+func valuerCacheFor(ctx context.Context, valuer Valuer) *valuerCache {
+	if ctx == nil {
+		return nil
+	}
+	c, _ := ctx.Value(valuerCacheKey{}).(*valuerCache)
+	if c == nil {
+		return nil
+	}
+	if code := reflect.ValueOf(valuer).Pointer(); code == callerCode || code == callSiteCode {
+		return nil
+	}
+	return c
+}
+
+// valuerID identifies the closure of valuer. Valuers made by separate calls
+// of a constructor, such as Timestamp, share their code but not their
+// closure.
+func valuerID(valuer Valuer) unsafe.Pointer {
+	return *(*unsafe.Pointer)(unsafe.Pointer(&valuer))
+}
+
+func (c *valuerCache) load(valuer Valuer) (Value, bool) {
+	id := valuerID(valuer)
+	for _, e := range c.entries {
+		if e.fn == id {
+			return e.v, true
+		}
+	}
+	return Value{}, false
+}
+
+func (c *valuerCache) store(valuer Valuer, v Value) {
+	c.entries = append(c.entries, valuerEntry{fn: valuerID(valuer), v: v})
+}
+
+// hasValuer reports whether fields, or the groups in them, have a Valuer.
+func hasValuer(fields []Field) bool {
+	for _, field := range fields {
+		switch field.Value.Kind() {
+		case KindValuer:
+			return true
+		case KindGroup:
+			if hasValuer(field.Value.group()) {
+				return true
+			}
+		}
+	}
+	return false
+}
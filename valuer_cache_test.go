@@ -0,0 +1,28 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"testing"
+)
+
+func TestValuerResolvedOncePerRecord(t *testing.T) {
+	calls := 0
+	seq := Valuer(func(context.Context) Value {
+		calls++
+		return IntValue(calls)
+	})
+
+	var buf bytes.Buffer
+	logger := New(&buf, Json()).With(Dynamic("a", seq)).WithGroup("g").WithFields(Dynamic("b", seq))
+	logger.InfoS("first", "c", seq)
+	logger.InfoAttrs("second", Dynamic("c", seq))
+	want := `{"level":"INFO","a":1,"g":{"b":1,"c":1},"msg":"first"}` + "\n" +
+		`{"level":"INFO","a":2,"g":{"b":2,"c":2},"msg":"second"}` + "\n"
+	if got := buf.String(); got != want {
+		t.Fatalf("output = %q, want %q", got, want)
+	}
+	if calls != 2 {
+		t.Fatalf("valuer called %d times, want 2", calls)
+	}
+}
@@ -0,0 +1,186 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"runtime"
+	"runtime/debug"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrValuerTimeout is the error value of a Valuer returned by Timeout that
+// did not finish in time.
+var ErrValuerTimeout = errors.New("log: valuer timed out")
+
+// Timeout returns a Valuer that calls valuer with a context that is canceled
+// after d, and resolves to an error value with ErrValuerTimeout if valuer has
+// not returned by then, so a slow Valuer, such as one doing a DNS lookup,
+// cannot stall log calls. valuer runs in its own goroutine, which keeps
+// running after a timeout unless valuer stops when its context is done; it
+// must not walk the stack like Caller.
+func Timeout(valuer Valuer, d time.Duration) Valuer {
+	if valuer == nil || d <= 0 {
+		return valuer
+	}
+	return func(ctx context.Context) Value {
+		if ctx == nil {
+			ctx = context.Background()
+		}
+		ctx, cancel := context.WithTimeout(ctx, d)
+		defer cancel()
+
+		done := make(chan Value, 1)
+		go func() {
+			defer func() {
+				if r := recover(); r != nil {
+					done <- AnyValue(fmt.Errorf("valuer panicked: %v", r))
+				}
+			}()
+			done <- valuer(ctx).Resolve(ctx)
+		}()
+		select {
+		case v := <-done:
+			return v
+		case <-ctx.Done():
+			return ErrValue(ErrValuerTimeout)
+		}
+	}
+}
+
+// GoroutineID returns a Valuer that resolves to the ID of the goroutine
+// writing the record, for debugging concurrency issues. The ID is parsed from
+// runtime.Stack, which is much slower than the other Valuers, so it is meant
+// for development builds. Valuers run in the goroutine of the log call unless
+// wrapped by Timeout or resolved by a handler in the background.
+func GoroutineID() Valuer {
+	return func(context.Context) Value {
+		return Uint64Value(goroutineID())
+	}
+}
+
+var goroutinePrefix = []byte("goroutine ")
+
+func goroutineID() uint64 {
+	var buf [64]byte
+	b := buf[:runtime.Stack(buf[:], false)]
+	b = bytes.TrimPrefix(b, goroutinePrefix)
+	if i := bytes.IndexByte(b, ' '); i > 0 {
+		b = b[:i]
+	}
+	id, _ := strconv.ParseUint(string(b), 10, 64)
+	return id
+}
+
+var hostname = sync.OnceValue(func() Value {
+	name, err := os.Hostname()
+	if err != nil {
+		return ErrValue(err)
+	}
+	return StringValue(name)
+})
+
+// Hostname returns a Valuer that resolves to the host name reported by the
+// kernel, or to the error of os.Hostname. It is looked up once per process.
+func Hostname() Valuer {
+	return func(context.Context) Value {
+		return hostname()
+	}
+}
+
+var pid = sync.OnceValue(func() Value {
+	return IntValue(os.Getpid())
+})
+
+// PID returns a Valuer that resolves to the process ID. It is looked up once
+// per process.
+func PID() Valuer {
+	return func(context.Context) Value {
+		return pid()
+	}
+}
+
+// Keys of the fields of BuildInfo.
+const (
+	BuildVersionKey  = "version"
+	BuildRevisionKey = "revision"
+	BuildDirtyKey    = "dirty"
+)
+
+var buildInfo = sync.OnceValue(func() Value {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return GroupValue()
+	}
+	return GroupValue(buildInfoFields(info)...)
+})
+
+// BuildInfo returns a Valuer that resolves to a group of the main module
+// version, the VCS revision and whether the working tree had uncommitted
+// changes, as recorded by the Go toolchain. Fields the binary does not record
+// are omitted. The build information is read once per process:
+//
+//	logger = logger.WithFields(log.Dynamic("build", log.BuildInfo()))
+func BuildInfo() Valuer {
+	return func(context.Context) Value {
+		return buildInfo()
+	}
+}
+
+func buildInfoFields(info *debug.BuildInfo) []Field {
+	var fields []Field
+	if v := info.Main.Version; v != "" {
+		fields = append(fields, String(BuildVersionKey, v))
+	}
+	for _, setting := range info.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			fields = append(fields, String(BuildRevisionKey, setting.Value))
+		case "vcs.modified":
+			fields = append(fields, Bool(BuildDirtyKey, setting.Value == "true"))
+		}
+	}
+	return fields
+}
+
+// RuntimeStats returns a Valuer that resolves to a group of runtime
+// statistics: the number of goroutines, the bytes of heap spans in use, the
+// number of completed GC cycles and the total and last GC pause. Reading them
+// briefly stops the world, so it suits periodic stats lines and incident
+// triage rather than every record:
+//
+//	logger.InfoS("runtime", log.Dynamic("runtime", log.RuntimeStats()))
+func RuntimeStats() Valuer {
+	return func(context.Context) Value {
+		var m runtime.MemStats
+		runtime.ReadMemStats(&m)
+		var lastPause time.Duration
+		if m.NumGC > 0 {
+			lastPause = time.Duration(m.PauseNs[(m.NumGC+255)%256])
+		}
+		return GroupValue(
+			Int("goroutines", runtime.NumGoroutine()),
+			Uint64("heap_inuse", m.HeapInuse),
+			Uint64("gc_count", uint64(m.NumGC)),
+			Duration("gc_pause_total", time.Duration(m.PauseTotalNs)),
+			Duration("gc_pause_last", lastPause),
+		)
+	}
+}
+
+var sequence atomic.Uint64
+
+// Sequence returns a Valuer that resolves to the next value of a counter
+// shared by the whole process, starting at 1. Records shipped through async
+// writers or other pipelines can then be checked for gaps and reordering.
+// Like other Valuers added by With, it is resolved once per record.
+func Sequence() Valuer {
+	return func(context.Context) Value {
+		return Uint64Value(sequence.Add(1))
+	}
+}
@@ -0,0 +1,125 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"runtime"
+	"runtime/debug"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestTimeoutValuer(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	slow := Valuer(func(context.Context) Value {
+		<-release
+		return StringValue("late")
+	})
+	fast := Valuer(func(context.Context) Value { return StringValue("ok") })
+
+	var buf bytes.Buffer
+	logger := New(&buf).With(Dynamic("slow", Timeout(slow, time.Millisecond)), Dynamic("fast", Timeout(fast, time.Second)))
+	logger.Info("done")
+	if got, want := buf.String(), `INFO slow="log: valuer timed out" fast=ok msg=done`+"\n"; got != want {
+		t.Fatalf("output = %q, want %q", got, want)
+	}
+}
+
+func TestGoroutineID(t *testing.T) {
+	id := goroutineID()
+	if id == 0 {
+		t.Fatal("goroutineID() = 0")
+	}
+	other := make(chan uint64)
+	go func() { other <- goroutineID() }()
+	if got := <-other; got == id || got == 0 {
+		t.Fatalf("goroutineID() in another goroutine = %d, want non-zero and not %d", got, id)
+	}
+
+	var buf bytes.Buffer
+	New(&buf).With(Dynamic("goroutine", GoroutineID())).Info("done")
+	if got, want := buf.String(), "INFO goroutine="+strconv.FormatUint(id, 10)+" msg=done\n"; got != want {
+		t.Fatalf("output = %q, want %q", got, want)
+	}
+}
+
+func TestHostnameAndPID(t *testing.T) {
+	host, err := os.Hostname()
+	if err != nil {
+		t.Skip(err)
+	}
+	var buf bytes.Buffer
+	New(&buf).With(Dynamic("host", Hostname()), Dynamic("pid", PID())).Info("done")
+	want := "INFO host=" + host + " pid=" + strconv.Itoa(os.Getpid()) + " msg=done\n"
+	if got := buf.String(); got != want {
+		t.Fatalf("output = %q, want %q", got, want)
+	}
+}
+
+func TestBuildInfoFields(t *testing.T) {
+	info := &debug.BuildInfo{
+		Main: debug.Module{Path: "example.com/app", Version: "v1.2.3"},
+		Settings: []debug.BuildSetting{
+			{Key: "vcs", Value: "git"},
+			{Key: "vcs.revision", Value: "4bf92f35"},
+			{Key: "vcs.modified", Value: "true"},
+		},
+	}
+	var buf bytes.Buffer
+	New(&buf, Json()).InfoAttrs("started", Dict("build", buildInfoFields(info)...))
+	if got, want := buf.String(), `{"level":"INFO","msg":"started","build":{"version":"v1.2.3","revision":"4bf92f35","dirty":true}}`+"\n"; got != want {
+		t.Fatalf("output = %q, want %q", got, want)
+	}
+
+	if v := BuildInfo()(context.Background()); v.Kind() != KindGroup {
+		t.Fatalf("BuildInfo() kind = %v, want Group", v.Kind())
+	}
+}
+
+func TestRuntimeStats(t *testing.T) {
+	runtime.GC()
+	v := RuntimeStats()(context.Background())
+	if v.Kind() != KindGroup {
+		t.Fatalf("RuntimeStats() kind = %v, want Group", v.Kind())
+	}
+	stats := map[string]Value{}
+	for _, field := range v.Group() {
+		stats[field.Key] = field.Value
+	}
+	if got := stats["goroutines"].Int64(); got < 1 {
+		t.Fatalf("goroutines = %d, want at least 1", got)
+	}
+	if got := stats["heap_inuse"].Uint64(); got == 0 {
+		t.Fatal("heap_inuse = 0")
+	}
+	if got := stats["gc_count"].Uint64(); got == 0 {
+		t.Fatal("gc_count = 0 after runtime.GC")
+	}
+	if _, ok := stats["gc_pause_last"]; !ok || len(stats) != 5 {
+		t.Fatalf("fields = %v", stats)
+	}
+}
+
+func TestSequence(t *testing.T) {
+	var buf bytes.Buffer
+	seq := Sequence()
+	logger := New(&buf, Json()).With(Dynamic("seq", seq), Dynamic("again", seq))
+	logger.Info("first")
+	logger.Info("second")
+
+	var first, second struct{ Seq, Again uint64 }
+	dec := json.NewDecoder(&buf)
+	if err := dec.Decode(&first); err != nil {
+		t.Fatal(err)
+	}
+	if err := dec.Decode(&second); err != nil {
+		t.Fatal(err)
+	}
+	if first.Seq == 0 || first.Seq != first.Again || second.Seq != first.Seq+1 || second.Again != second.Seq {
+		t.Fatalf("sequence = %+v, %+v, want consecutive numbers shared within a record", first, second)
+	}
+}
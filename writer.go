@@ -48,6 +48,16 @@ func (t multiWriter) Write(p []byte) (n int, err error) {
 	return len(p), nil
 }
 
+// Sync syncs the underlying writers, stopping at the first error.
+func (t multiWriter) Sync() error {
+	for _, w := range t.writers {
+		if err := syncWriter(w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // Close on all the underlying writers that are io.Closers. If any of the
 // Close methods return an error, the remainder of the closers are not closed
 // and the error is returned.
@@ -140,6 +150,15 @@ func (t *tryMultiWriter) Write(p []byte) (n int, err error) {
 	}
 }
 
+// Sync syncs all the underlying writers, joining their errors.
+func (t *tryMultiWriter) Sync() error {
+	var errs []error
+	for _, w := range t.writers {
+		errs = append(errs, syncWriter(w))
+	}
+	return errors.Join(errs...)
+}
+
 func (t *tryMultiWriter) Close() error {
 	var errs []error
 	for _, w := range t.writers {